@@ -15,6 +15,10 @@ type Admin interface {
 	AdminLoginOut(c *gin.Context)
 	AdminInfo(c *gin.Context)
 	AdminChangePwd(c *gin.Context)
+	AdminList(c *gin.Context)
+	AdminAdd(c *gin.Context)
+	AdminUpdate(c *gin.Context)
+	AdminDelete(c *gin.Context)
 }
 
 type adminController struct {
@@ -122,3 +126,111 @@ func (a *adminController) AdminChangePwd(c *gin.Context) {
 
 	response.ResponseSuccess(c, "Password changed successfully", "")
 }
+
+// AdminList godoc
+// @Summary 管理员账号列表
+// @Description 管理员账号列表, 需要 admin 角色
+// @Tags Admin
+// @ID /admin/user_list
+// @Accept  json
+// @Produce  json
+// @Param info query string false "关键词"
+// @Param page_size query int true "每页个数"
+// @Param page_no query int true "当前页数"
+// @Success 200 {object} response.Response{data=dto.AdminListOutput} "success"
+// @Router /admin/user_list [get]
+func (a *adminController) AdminList(c *gin.Context) {
+	params := &dto.AdminListInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	list, total, err := a.AdminUserList(c, params)
+	if err != nil {
+		response.ResponseError(c, response.AdminListErrCode, err)
+		log.Error("Get admin list failed", zap.Error(err))
+		return
+	}
+
+	out := &dto.AdminListOutput{List: list, Total: total}
+	response.ResponseSuccess(c, "Get admin list successfully", out)
+}
+
+// AdminAdd godoc
+// @Summary 添加管理员账号
+// @Description 添加管理员账号, 需要 admin 角色
+// @Tags Admin
+// @ID /admin/user_add
+// @Accept  json
+// @Produce  json
+// @Param body body dto.AdminAddInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /admin/user_add [post]
+func (a *adminController) AdminAdd(c *gin.Context) {
+	params := &dto.AdminAddInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := a.AdminUserAdd(c, params); err != nil {
+		response.ResponseError(c, response.AdminAddErrCode, err)
+		log.Error("Add admin failed", zap.String("username", params.UserName), zap.Error(err))
+		return
+	}
+
+	response.ResponseSuccess(c, "Admin added successfully", "")
+}
+
+// AdminUpdate godoc
+// @Summary 修改管理员账号
+// @Description 修改管理员的角色、状态、服务范围和密码, 需要 admin 角色
+// @Tags Admin
+// @ID /admin/user_update
+// @Accept  json
+// @Produce  json
+// @Param body body dto.AdminUpdateInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /admin/user_update [post]
+func (a *adminController) AdminUpdate(c *gin.Context) {
+	params := &dto.AdminUpdateInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := a.AdminUserUpdate(c, params); err != nil {
+		response.ResponseError(c, response.AdminUpdateErrCode, err)
+		log.Error("Update admin failed", zap.Int("id", params.ID), zap.Error(err))
+		return
+	}
+
+	response.ResponseSuccess(c, "Admin updated successfully", "")
+}
+
+// AdminDelete godoc
+// @Summary 删除管理员账号
+// @Description 删除管理员账号, 需要 admin 角色
+// @Tags Admin
+// @ID /admin/user_delete
+// @Accept  json
+// @Produce  json
+// @Param id query int true "管理员ID"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /admin/user_delete [get]
+func (a *adminController) AdminDelete(c *gin.Context) {
+	params := &dto.AdminDeleteInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := a.AdminUserDelete(c, params); err != nil {
+		response.ResponseError(c, response.AdminDeleteErrCode, err)
+		log.Error("Delete admin failed", zap.Int("id", params.ID), zap.Error(err))
+		return
+	}
+
+	response.ResponseSuccess(c, "Admin deleted successfully", "")
+}
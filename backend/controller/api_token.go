@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"gateway/backend/dto"
+	"gateway/backend/logic"
+	"gateway/pkg/log"
+	"gateway/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ApiToken interface {
+	ApiTokenList(c *gin.Context)
+	ApiTokenAdd(c *gin.Context)
+	ApiTokenRevoke(c *gin.Context)
+}
+
+type apiTokenController struct {
+	logic logic.ApiTokenLogic
+}
+
+func NewApiTokenController() *apiTokenController {
+	return &apiTokenController{logic.NewApiTokenLogic()}
+}
+
+// ApiTokenList godoc
+// @Summary 接口令牌列表
+// @Description 分页返回接口令牌, admin 角色返回全部令牌, 其他角色只返回自己签发的令牌
+// @Tags Admin
+// @ID /admin/token_list
+// @Accept  json
+// @Produce  json
+// @Param page_size query int true "每页个数"
+// @Param page_no query int true "当前页数"
+// @Success 200 {object} response.Response{data=dto.ApiTokenListOutput} "success"
+// @Router /admin/token_list [get]
+func (ac *apiTokenController) ApiTokenList(c *gin.Context) {
+	params := &dto.ApiTokenListInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	list, total, err := ac.logic.ApiTokenList(c, params)
+	if err != nil {
+		response.ResponseError(c, response.ApiTokenListErrCode, err)
+		log.Error("failed to get api token list", zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "get api token list successfully", &dto.ApiTokenListOutput{List: list, Total: total})
+}
+
+// ApiTokenAdd godoc
+// @Summary 签发接口令牌
+// @Description 签发接口令牌, 请求时使用 Authorization: Bearer <token>; 令牌明文只在签发时返回一次
+// @Tags Admin
+// @ID /admin/token_add
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ApiTokenAddInput true "body"
+// @Success 200 {object} response.Response{data=dto.ApiTokenAddOutput} "success"
+// @Router /admin/token_add [post]
+func (ac *apiTokenController) ApiTokenAdd(c *gin.Context) {
+	params := &dto.ApiTokenAddInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	out, err := ac.logic.ApiTokenAdd(c, params)
+	if err != nil {
+		response.ResponseError(c, response.ApiTokenAddErrCode, err)
+		log.Error("failed to add api token", zap.String("name", params.Name), zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "api token added successfully", out)
+}
+
+// ApiTokenRevoke godoc
+// @Summary 吊销接口令牌
+// @Description 吊销接口令牌, 吊销后立即失效
+// @Tags Admin
+// @ID /admin/token_revoke
+// @Accept  json
+// @Produce  json
+// @Param id query int true "令牌ID"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /admin/token_revoke [get]
+func (ac *apiTokenController) ApiTokenRevoke(c *gin.Context) {
+	params := &dto.ApiTokenRevokeInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := ac.logic.ApiTokenRevoke(c, params); err != nil {
+		response.ResponseError(c, response.ApiTokenRevokeErrCode, err)
+		log.Error("failed to revoke api token", zap.Int64("id", params.ID), zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "api token revoked successfully", "")
+}
@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"gateway/backend/dto"
+	"gateway/backend/logic"
+	"gateway/pkg/log"
+	"gateway/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Audit interface {
+	AuditList(c *gin.Context)
+}
+
+type auditController struct {
+	logic logic.AuditLogic
+}
+
+func NewAuditController() *auditController {
+	return &auditController{logic.NewAuditLogic()}
+}
+
+// AuditList godoc
+// @Summary 审计日志
+// @Description 分页查询服务、租户、管理员的变更记录, 包含操作人、trace id、客户端ip和变更前后的差异
+// @Tags 审计
+// @ID /audit/audit_list
+// @Accept  json
+// @Produce  json
+// @Param admin_name query string false "操作人"
+// @Param resource query string false "资源类型 service/app/admin"
+// @Param action query string false "操作类型 add/update/delete"
+// @Param target query string false "操作对象"
+// @Param trace_id query string false "trace id"
+// @Param from query int false "开始时间, unix 秒"
+// @Param to query int false "结束时间, unix 秒"
+// @Param page_size query int true "每页个数"
+// @Param page_no query int true "当前页数"
+// @Success 200 {object} response.Response{data=dto.AuditListOutput} "success"
+// @Router /audit/audit_list [get]
+func (ac *auditController) AuditList(c *gin.Context) {
+	params := &dto.AuditListInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	list, total, err := ac.logic.AuditList(c, params)
+	if err != nil {
+		response.ResponseError(c, response.AuditListErrCode, err)
+		log.Error("failed to get audit log list", zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "get audit log list successfully", &dto.AuditListOutput{List: list, Total: total})
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/backend/logic"
+	"gateway/pkg/log"
+	"gateway/pkg/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Config interface {
+	ConfigExport(c *gin.Context)
+	ConfigImport(c *gin.Context)
+}
+
+type configController struct {
+	logic logic.ConfigLogic
+}
+
+func NewConfigController() *configController {
+	return &configController{logic.NewConfigLogic()}
+}
+
+// ConfigExport godoc
+// @Summary 导出配置
+// @Description 以 yaml/json 导出当前账号服务范围内的全部服务和租户, 不包含主键、时间等字段
+// @Tags 配置
+// @ID /config/export
+// @Accept  json
+// @Produce  application/x-yaml
+// @Param format query string true "导出格式 yaml/json"
+// @Success 200 {string} string "配置文档"
+// @Router /config/export [get]
+func (cc *configController) ConfigExport(c *gin.Context) {
+	params := &dto.ConfigExportInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	content, err := cc.logic.ExportConfig(c, params)
+	if err != nil {
+		response.ResponseError(c, response.ConfigExportErrCode, err)
+		log.Error("failed to export config", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return
+	}
+
+	contentType := "application/x-yaml; charset=utf-8"
+	if params.Format == logic.ConfigFormatJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=gateway.%s", params.Format))
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// ConfigImport godoc
+// @Summary 导入配置
+// @Description 按配置文档新增或修改服务和租户, 全部校验通过后在一个事务中写入; dry_run=1 时只返回变更预览, prune=1 时删除文档中没有的配置
+// @Tags 配置
+// @ID /config/import
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ConfigImportInput true "body"
+// @Success 200 {object} response.Response{data=dto.ConfigImportOutput} "success"
+// @Router /config/import [post]
+func (cc *configController) ConfigImport(c *gin.Context) {
+	params := &dto.ConfigImportInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	out, err := cc.logic.ImportConfig(c, params)
+	if err != nil {
+		response.ResponseError(c, response.ConfigImportErrCode, err)
+		log.Error("failed to import config", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return
+	}
+	msg := "import config successfully"
+	if out.DryRun {
+		msg = "config import preview"
+	}
+	response.ResponseSuccess(c, msg, out)
+}
@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"fmt"
 	"gateway/backend/dto"
 	"gateway/backend/logic"
+	"gateway/enity"
 	"gateway/pkg/log"
 	"gateway/pkg/response"
 
@@ -20,7 +22,15 @@ type Service interface {
 	ServiceUpdateTcp(c *gin.Context)
 	ServiceAddGrpc(c *gin.Context)
 	ServiceUpdateGrpc(c *gin.Context)
+	ServiceAddUdp(c *gin.Context)
+	ServiceUpdateUdp(c *gin.Context)
 	ServiceStat(c *gin.Context)
+	ServiceRevisionList(c *gin.Context)
+	ServiceRevisionDiff(c *gin.Context)
+	ServiceRollback(c *gin.Context)
+	ServiceNodeDrain(c *gin.Context)
+	ServiceNodeDisable(c *gin.Context)
+	ServiceNodeEnable(c *gin.Context)
 }
 type serviceController struct {
 	logic.ServiceLogic
@@ -227,6 +237,58 @@ func (s *serviceController) ServiceUpdateTcp(c *gin.Context) {
 	response.ResponseSuccess(c, "update tcpService success", nil)
 }
 
+// ServiceAddUdp godoc
+// @Summary 添加UDP服务
+// @Description 添加UDP服务
+// @Tags Service
+// @ID /service/service_add_udp
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceAddUdpInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /service/service_add_udp [post]
+func (s *serviceController) ServiceAddUdp(c *gin.Context) {
+	params := &dto.ServiceAddUdpInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	err := s.AddUDP(c, params)
+	if err != nil {
+		response.ResponseError(c, response.AddUDPServiceErrCode, err)
+		log.Error("Failed to add udp service", zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "add udpService success", nil)
+}
+
+// ServiceUpdateUdp godoc
+// @Summary 更新UDP服务
+// @Description 更新UDP服务
+// @Tags Service
+// @ID /service/service_update_udp
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceUpdateUdpInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /service/service_update_udp [post]
+func (s *serviceController) ServiceUpdateUdp(c *gin.Context) {
+	params := &dto.ServiceUpdateUdpInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	err := s.UpdateUDP(c, params)
+	if err != nil {
+		response.ResponseError(c, response.UpdateUDPServiceErrCode, err)
+		log.Error("Failed to update udp service", zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "update udpService success", nil)
+}
+
 // ServiceAddGrpc godoc
 // @Summary 添加GRPC服务
 // @Description 添加GRPC服务
@@ -305,3 +367,145 @@ func (s *serviceController) ServiceStat(c *gin.Context) {
 
 	response.ResponseSuccess(c, "", output)
 }
+
+// ServiceRevisionList godoc
+// @Summary 服务版本列表
+// @Description 服务每次保存生成的历史版本
+// @Tags Service
+// @ID /service/service_revision_list
+// @Accept  json
+// @Produce  json
+// @Param id query int true "服务ID"
+// @Param page_no query int true "页码"
+// @Param page_size query int true "每页条数"
+// @Success 200 {object} response.Response{data=dto.ServiceRevisionListOutput} "success"
+// @Router /service/service_revision_list [get]
+func (s *serviceController) ServiceRevisionList(c *gin.Context) {
+	params := &dto.ServiceRevisionListInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	list, total, err := s.ServiceLogic.ServiceRevisionList(c, params)
+	if err != nil {
+		response.ResponseError(c, response.ServiceRevisionListErrCode, err)
+		log.Error("Failed to get service revision list", zap.Error(err))
+		return
+	}
+
+	response.ResponseSuccess(c, "", &dto.ServiceRevisionListOutput{List: list, Total: total})
+}
+
+// ServiceRevisionDiff godoc
+// @Summary 服务版本对比
+// @Description 对比两个版本的配置, to_version 为 0 时与当前配置对比
+// @Tags Service
+// @ID /service/service_revision_diff
+// @Accept  json
+// @Produce  json
+// @Param id query int true "服务ID"
+// @Param from_version query int true "起始版本"
+// @Param to_version query int false "目标版本"
+// @Success 200 {object} response.Response{data=dto.ServiceRevisionDiffOutput} "success"
+// @Router /service/service_revision_diff [get]
+func (s *serviceController) ServiceRevisionDiff(c *gin.Context) {
+	params := &dto.ServiceRevisionDiffInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	out, err := s.ServiceLogic.ServiceRevisionDiff(c, params)
+	if err != nil {
+		response.ResponseError(c, response.ServiceRevisionDiffErrCode, err)
+		log.Error("Failed to diff service revision", zap.Error(err))
+		return
+	}
+
+	response.ResponseSuccess(c, "", out)
+}
+
+// ServiceRollback godoc
+// @Summary 服务回滚
+// @Description 将服务配置恢复到指定版本并通知代理重新加载
+// @Tags Service
+// @ID /service/service_rollback
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceRollbackInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /service/service_rollback [post]
+func (s *serviceController) ServiceRollback(c *gin.Context) {
+	params := &dto.ServiceRollbackInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := s.RollbackService(c, params); err != nil {
+		response.ResponseError(c, response.ServiceRollbackErrCode, err)
+		log.Error("Failed to rollback service", zap.Int64("id", params.ID), zap.Int("version", params.Version), zap.Error(err))
+		return
+	}
+
+	response.ResponseSuccess(c, "rollback service success", nil)
+}
+
+// ServiceNodeDrain godoc
+// @Summary 排空节点
+// @Description 节点不再接收新请求, 处理中的请求继续完成, 用于主机维护
+// @Tags Service
+// @ID /service/node_drain
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceNodeInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /service/node_drain [post]
+func (s *serviceController) ServiceNodeDrain(c *gin.Context) {
+	s.setNodeState(c, enity.NodeStateDraining)
+}
+
+// ServiceNodeDisable godoc
+// @Summary 禁用节点
+// @Description 将节点加入 forbid_list, 不再参与负载均衡
+// @Tags Service
+// @ID /service/node_disable
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceNodeInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /service/node_disable [post]
+func (s *serviceController) ServiceNodeDisable(c *gin.Context) {
+	s.setNodeState(c, enity.NodeStateDisabled)
+}
+
+// ServiceNodeEnable godoc
+// @Summary 启用节点
+// @Description 将节点从 forbid_list 和 drain_list 中移除, 重新参与负载均衡
+// @Tags Service
+// @ID /service/node_enable
+// @Accept  json
+// @Produce  json
+// @Param body body dto.ServiceNodeInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /service/node_enable [post]
+func (s *serviceController) ServiceNodeEnable(c *gin.Context) {
+	s.setNodeState(c, enity.NodeStateEnabled)
+}
+
+func (s *serviceController) setNodeState(c *gin.Context, state string) {
+	params := &dto.ServiceNodeInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := s.SetServiceNodeState(c, params, state); err != nil {
+		response.ResponseError(c, response.ServiceNodeStateErrCode, err)
+		log.Error("Failed to set node state", zap.Int64("id", params.ID), zap.String("node", params.Node), zap.String("state", state), zap.Error(err))
+		return
+	}
+
+	response.ResponseSuccess(c, fmt.Sprintf("%s node success", state), nil)
+}
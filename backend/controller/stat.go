@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/backend/logic"
+	"gateway/pkg/log"
+	"gateway/pkg/response"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Stat interface {
+	FlowStat(c *gin.Context)
+	FlowHistory(c *gin.Context)
+	FlowHistoryExport(c *gin.Context)
+}
+
+type statController struct {
+	logic.StatLogic
+}
+
+func NewStatController() *statController {
+	return &statController{logic.NewStatLogic()}
+}
+
+// FlowStat godoc
+// @Summary 流量统计
+// @Description 按维度(total/service/app/node/status)和粒度(minute/hour/day)查询任意时间区间的请求数
+// @Tags 统计
+// @ID /stat/flow
+// @Accept  json
+// @Produce  json
+// @Param dimension query string true "统计维度"
+// @Param name query string false "服务名称或租户id"
+// @Param from query int true "开始时间, unix 秒"
+// @Param to query int false "结束时间, unix 秒"
+// @Param step query string true "统计粒度"
+// @Success 200 {object} response.Response{data=dto.StatFlowOutput} "success"
+// @Router /stat/flow [get]
+func (sc *statController) FlowStat(c *gin.Context) {
+	params := &dto.StatFlowInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	out, err := sc.GetFlowStat(c, params)
+	if err != nil {
+		response.ResponseError(c, response.FlowStatErrCode, err)
+		log.Error("failed to get flow stat", zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "get flow stat successfully", out)
+}
+
+// FlowHistory godoc
+// @Summary 历史流量统计
+// @Description 查询持久化到 mysql 的历史请求数, 按小时/天/月汇总
+// @Tags 统计
+// @ID /stat/history
+// @Accept  json
+// @Produce  json
+// @Param dimension query string true "统计维度"
+// @Param name query string false "服务名称或租户id"
+// @Param from_day query string true "开始日期 yyyy-mm-dd"
+// @Param to_day query string true "结束日期 yyyy-mm-dd"
+// @Param granularity query string true "汇总粒度"
+// @Success 200 {object} response.Response{data=dto.StatHistoryOutput} "success"
+// @Router /stat/history [get]
+func (sc *statController) FlowHistory(c *gin.Context) {
+	params := &dto.StatHistoryInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	out, err := sc.GetFlowHistory(c, params)
+	if err != nil {
+		response.ResponseError(c, response.FlowHistoryErrCode, err)
+		log.Error("failed to get flow history", zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "get flow history successfully", out)
+}
+
+// FlowHistoryExport godoc
+// @Summary 导出历史流量统计
+// @Description 以 CSV 格式导出历史请求数, 参数同 /stat/history
+// @Tags 统计
+// @ID /stat/history_export
+// @Produce  text/csv
+// @Param dimension query string true "统计维度"
+// @Param name query string false "服务名称或租户id"
+// @Param from_day query string true "开始日期 yyyy-mm-dd"
+// @Param to_day query string true "结束日期 yyyy-mm-dd"
+// @Param granularity query string true "汇总粒度"
+// @Success 200 {string} string "csv"
+// @Router /stat/history_export [get]
+func (sc *statController) FlowHistoryExport(c *gin.Context) {
+	params := &dto.StatHistoryInput{}
+	if err := params.BindValidParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	out, err := sc.GetFlowHistory(c, params)
+	if err != nil {
+		response.ResponseError(c, response.FlowHistoryErrCode, err)
+		log.Error("failed to export flow history", zap.Error(err))
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	// 写入 BOM, 便于 Excel 正确识别中文
+	buf.WriteString("\xEF\xBB\xBF")
+	w := csv.NewWriter(buf)
+	w.Write([]string{"dimension", "name", "period", "request_count"})
+	for _, item := range out.List {
+		w.Write([]string{
+			out.Dimension,
+			item.Name,
+			logic.FormatStatPeriod(out.Granularity, item.Period),
+			strconv.FormatInt(item.RequestCount, 10),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		response.ResponseError(c, response.FlowHistoryErrCode, err)
+		log.Error("failed to write flow history csv", zap.Error(err))
+		return
+	}
+
+	filename := fmt.Sprintf("flow_stat_%s_%s_%s.csv", params.Dimension, params.FromDay, params.ToDay)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"gateway/backend/dto"
+	"gateway/backend/logic"
+	"gateway/pkg/log"
+	"gateway/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Upstream interface {
+	UpstreamList(c *gin.Context)
+	UpstreamDetail(c *gin.Context)
+	UpstreamAdd(c *gin.Context)
+	UpstreamUpdate(c *gin.Context)
+	UpstreamDelete(c *gin.Context)
+}
+
+type upstreamController struct {
+	logic logic.UpstreamLogic
+}
+
+func NewUpstreamController() *upstreamController {
+	return &upstreamController{logic.NewUpstreamLogic()}
+}
+
+// UpstreamList godoc
+// @Summary 上游列表
+// @Description 分页返回上游及引用它的服务
+// @Tags Upstream
+// @ID /upstream/upstream_list
+// @Accept  json
+// @Produce  json
+// @Param info query string false "关键词"
+// @Param page_size query int true "每页个数"
+// @Param page_no query int true "当前页数"
+// @Success 200 {object} response.Response{data=dto.UpstreamListOutput} "success"
+// @Router /upstream/upstream_list [get]
+func (uc *upstreamController) UpstreamList(c *gin.Context) {
+	params := &dto.UpstreamListInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	list, total, err := uc.logic.UpstreamList(c, params)
+	if err != nil {
+		response.ResponseError(c, response.UpstreamListErrCode, err)
+		log.Error("failed to get upstream list", zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "get upstream list successfully", &dto.UpstreamListOutput{List: list, Total: total})
+}
+
+// UpstreamDetail godoc
+// @Summary 上游详情
+// @Description 上游详情
+// @Tags Upstream
+// @ID /upstream/upstream_detail
+// @Accept  json
+// @Produce  json
+// @Param id query int true "上游ID"
+// @Success 200 {object} response.Response{data=enity.Upstream} "success"
+// @Router /upstream/upstream_detail [get]
+func (uc *upstreamController) UpstreamDetail(c *gin.Context) {
+	params := &dto.UpstreamDetailInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	detail, err := uc.logic.UpstreamDetail(c, params)
+	if err != nil {
+		response.ResponseError(c, response.UpstreamDetailErrCode, err)
+		log.Error("failed to get upstream detail", zap.Int64("id", params.ID), zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "get upstream detail successfully", detail)
+}
+
+// UpstreamAdd godoc
+// @Summary 添加上游
+// @Description 添加上游, 服务通过 upstream_id 引用后使用上游的节点、探活策略、超时和 TLS 配置
+// @Tags Upstream
+// @ID /upstream/upstream_add
+// @Accept  json
+// @Produce  json
+// @Param body body dto.UpstreamAddInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /upstream/upstream_add [post]
+func (uc *upstreamController) UpstreamAdd(c *gin.Context) {
+	params := &dto.UpstreamAddInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := uc.logic.UpstreamAdd(c, params); err != nil {
+		response.ResponseError(c, response.UpstreamAddErrCode, err)
+		log.Error("failed to add upstream", zap.String("name", params.Name), zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "upstream added successfully", "")
+}
+
+// UpstreamUpdate godoc
+// @Summary 修改上游
+// @Description 修改上游, 所有引用该上游的服务同时生效
+// @Tags Upstream
+// @ID /upstream/upstream_update
+// @Accept  json
+// @Produce  json
+// @Param body body dto.UpstreamUpdateInput true "body"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /upstream/upstream_update [post]
+func (uc *upstreamController) UpstreamUpdate(c *gin.Context) {
+	params := &dto.UpstreamUpdateInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := uc.logic.UpstreamUpdate(c, params); err != nil {
+		response.ResponseError(c, response.UpstreamUpdateErrCode, err)
+		log.Error("failed to update upstream", zap.Int64("id", params.ID), zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "upstream updated successfully", "")
+}
+
+// UpstreamDelete godoc
+// @Summary 删除上游
+// @Description 删除上游, 仍被服务引用时不能删除
+// @Tags Upstream
+// @ID /upstream/upstream_delete
+// @Accept  json
+// @Produce  json
+// @Param id query int true "上游ID"
+// @Success 200 {object} response.Response{data=string} "success"
+// @Router /upstream/upstream_delete [get]
+func (uc *upstreamController) UpstreamDelete(c *gin.Context) {
+	params := &dto.UpstreamDetailInput{}
+	if err := params.BindValParam(c); err != nil {
+		response.ResponseError(c, response.ParamBindingErrCode, err)
+		return
+	}
+
+	if err := uc.logic.UpstreamDelete(c, params); err != nil {
+		response.ResponseError(c, response.UpstreamDeleteErrCode, err)
+		log.Error("failed to delete upstream", zap.Int64("id", params.ID), zap.Error(err))
+		return
+	}
+	response.ResponseSuccess(c, "upstream deleted successfully", "")
+}
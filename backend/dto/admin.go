@@ -32,6 +32,7 @@ type AminInfoOutput struct {
 type AdminSessionInfo struct {
 	ID        int       `json:"id" form:"id" comment:"管理员ID" example:"1" validate:""`                                         //管理员ID
 	UserName  string    `json:"username" form:"username" comment:"管理员用户名" example:"admin" validate:"required,valid_username"` //管理员用户名
+	Role      string    `json:"role" form:"role" comment:"角色" example:"admin" validate:""`                                    //角色
 	LoginTime time.Time `json:"login_time" form:"login_time" comment:"登录时间" example:"2020-10-10 10:10:10" validate:""`        //登录时间
 }
 type AdminChangePwdInput struct {
@@ -41,3 +42,59 @@ type AdminChangePwdInput struct {
 func (param *AdminChangePwdInput) BindValParam(c *gin.Context) error {
 	return utils.DefaultGetValidParams(c, param)
 }
+
+type AdminListInput struct {
+	Info     string `json:"info" form:"info" comment:"查找信息" validate:""`
+	PageSize int    `json:"page_size" form:"page_size" comment:"页数" validate:"required,min=1,max=999"`
+	PageNo   int    `json:"page_no" form:"page_no" comment:"页码" validate:"required,min=1,max=999"`
+}
+
+func (param *AdminListInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, param)
+}
+
+type AdminListOutput struct {
+	List  []AdminListItemOutput `json:"list" form:"list" comment:"管理员列表"`
+	Total int64                 `json:"total" form:"total" comment:"管理员总数"`
+}
+
+type AdminListItemOutput struct {
+	ID           int       `json:"id"`
+	UserName     string    `json:"user_name"`
+	Role         string    `json:"role"`
+	Status       int       `json:"status"`
+	ServiceScope string    `json:"service_scope"`
+	UpdateAt     time.Time `json:"update_at"`
+	CreateAt     time.Time `json:"create_at"`
+}
+
+type AdminAddInput struct {
+	UserName     string `json:"user_name" form:"user_name" comment:"管理员用户名" example:"ops_team" validate:"required,valid_username"`               //管理员用户名
+	Password     string `json:"password" form:"password" comment:"密码" example:"123456" validate:"required,min=6"`                                //密码
+	Role         string `json:"role" form:"role" comment:"角色" example:"operator" validate:"required,oneof=viewer operator admin"`                //角色
+	ServiceScope string `json:"service_scope" form:"service_scope" comment:"服务范围" example:"order_*,user_service" validate:"valid_service_scope"` //服务范围, 逗号分隔, 以*结尾表示前缀, 为空表示全部
+}
+
+func (param *AdminAddInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, param)
+}
+
+type AdminUpdateInput struct {
+	ID           int    `json:"id" form:"id" comment:"管理员ID" example:"2" validate:"required"`                                       //管理员ID
+	Password     string `json:"password" form:"password" comment:"密码" example:"123456" validate:"omitempty,min=6"`                  //密码, 为空时不修改
+	Role         string `json:"role" form:"role" comment:"角色" example:"operator" validate:"required,oneof=viewer operator admin"`   //角色
+	Status       int    `json:"status" form:"status" comment:"状态" example:"1" validate:"oneof=0 1"`                                 //状态 0=禁用 1=启用
+	ServiceScope string `json:"service_scope" form:"service_scope" comment:"服务范围" example:"order_*" validate:"valid_service_scope"` //服务范围
+}
+
+func (param *AdminUpdateInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, param)
+}
+
+type AdminDeleteInput struct {
+	ID int `json:"id" form:"id" comment:"管理员ID" example:"2" validate:"required"` //管理员ID
+}
+
+func (param *AdminDeleteInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, param)
+}
@@ -0,0 +1,61 @@
+package dto
+
+import (
+	"gateway/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ApiTokenListInput struct {
+	PageSize int `json:"page_size" form:"page_size" comment:"页数" validate:"required,min=1,max=999"`
+	PageNo   int `json:"page_no" form:"page_no" comment:"页码" validate:"required,min=1,max=999"`
+}
+
+func (param *ApiTokenListInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, param)
+}
+
+type ApiTokenListOutput struct {
+	List  []ApiTokenListItemOutput `json:"list" form:"list" comment:"令牌列表"`
+	Total int64                    `json:"total" form:"total" comment:"令牌总数"`
+}
+
+type ApiTokenListItemOutput struct {
+	ID           int64     `json:"id"`
+	AdminID      int       `json:"admin_id"`
+	Name         string    `json:"name"`
+	TokenPrefix  string    `json:"token_prefix"`
+	Role         string    `json:"role"`
+	ServiceScope string    `json:"service_scope"`
+	ExpireAt     time.Time `json:"expire_at"`
+	LastUsedAt   time.Time `json:"last_used_at"`
+	LastUsedIP   string    `json:"last_used_ip"`
+	Revoked      int       `json:"revoked"`
+	CreateAt     time.Time `json:"create_at"`
+}
+
+type ApiTokenAddInput struct {
+	Name         string `json:"name" form:"name" comment:"令牌名称" example:"deploy-pipeline" validate:"required,max=64"`               //令牌名称
+	Role         string `json:"role" form:"role" comment:"角色" example:"operator" validate:"required,oneof=viewer operator"`         //令牌角色, 不能高于签发人角色
+	ServiceScope string `json:"service_scope" form:"service_scope" comment:"服务范围" example:"order_*" validate:"valid_service_scope"` //服务范围, 为空表示沿用签发人的服务范围
+	ExpireDays   int    `json:"expire_days" form:"expire_days" comment:"有效天数" example:"90" validate:"required,min=1,max=3650"`      //有效天数
+}
+
+func (param *ApiTokenAddInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, param)
+}
+
+type ApiTokenAddOutput struct {
+	ID       int64     `json:"id"`
+	Token    string    `json:"token"` //令牌明文, 只返回这一次
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+type ApiTokenRevokeInput struct {
+	ID int64 `json:"id" form:"id" comment:"令牌ID" example:"1" validate:"required"` //令牌ID
+}
+
+func (param *ApiTokenRevokeInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, param)
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"encoding/json"
+	"gateway/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditListInput struct {
+	AdminName string `json:"admin_name" form:"admin_name" comment:"操作人" example:"admin" validate:""`                                 //操作人用户名
+	Resource  string `json:"resource" form:"resource" comment:"资源类型" example:"service" validate:"omitempty,oneof=service app admin"` //资源类型 service/app/admin
+	Action    string `json:"action" form:"action" comment:"操作类型" example:"update" validate:"omitempty,oneof=add update delete"`      //操作类型 add/update/delete
+	Target    string `json:"target" form:"target" comment:"操作对象" example:"test_http" validate:""`                                    //服务名称/租户id/管理员用户名, 模糊匹配
+	TraceID   string `json:"trace_id" form:"trace_id" comment:"trace id" example:"" validate:""`                                     //请求trace id
+	From      int64  `json:"from" form:"from" comment:"开始时间" example:"1700000000" validate:"min=0"`                                  //开始时间, unix 秒
+	To        int64  `json:"to" form:"to" comment:"结束时间" example:"1700086400" validate:"min=0"`                                      //结束时间, unix 秒
+	PageSize  int    `json:"page_size" form:"page_size" comment:"页数" validate:"required,min=1,max=999"`
+	PageNo    int    `json:"page_no" form:"page_no" comment:"页码" validate:"required,min=1,max=999"`
+}
+
+func (params *AuditListInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type AuditListItemOutput struct {
+	ID        int64           `json:"id"`
+	AdminID   int             `json:"admin_id"`
+	AdminName string          `json:"admin_name"`
+	Resource  string          `json:"resource"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target"`
+	TraceID   string          `json:"trace_id"`
+	ClientIP  string          `json:"client_ip"`
+	Before    json.RawMessage `json:"before"` //变更前的数据, 新增时为 null
+	After     json.RawMessage `json:"after"`  //变更后的数据, 删除时为 null
+	Diff      json.RawMessage `json:"diff"`   //变更的字段, {"字段路径": {"before": 旧值, "after": 新值}}
+	CreatedAt time.Time       `json:"create_at"`
+}
+
+type AuditListOutput struct {
+	List  []AuditListItemOutput `json:"list" form:"list" comment:"审计日志列表"`
+	Total int64                 `json:"total" form:"total" comment:"审计日志总数"`
+}
@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"gateway/enity"
+	"gateway/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigDocumentVersion 声明式配置文档的格式版本
+const ConfigDocumentVersion = 1
+
+// ConfigDocument 声明式配置文档, 导出时去掉主键、时间等与环境相关的字段, 可以直接提交到 git
+// tcp_rule 中的 cert_file/key_file 为证书路径, 证书文件需要在各环境单独分发
+type ConfigDocument struct {
+	Version  int                    `json:"version"`  //文档格式版本
+	Services []*enity.ServiceDetail `json:"services"` //服务完整配置
+	Apps     []*enity.App           `json:"apps"`     //租户配置
+}
+
+type ConfigExportInput struct {
+	Format string `json:"format" form:"format" comment:"导出格式" example:"yaml" validate:"required,oneof=yaml json"` //导出格式 yaml/json
+}
+
+func (params *ConfigExportInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type ConfigImportInput struct {
+	Format  string `json:"format" form:"format" comment:"文档格式" example:"yaml" validate:"required,oneof=yaml json"` //文档格式 yaml/json
+	Content string `json:"content" form:"content" comment:"配置文档" example:"" validate:"required"`                   //配置文档内容
+	DryRun  int    `json:"dry_run" form:"dry_run" comment:"只预览" example:"1" validate:"min=0,max=1"`                //1=只返回变更预览, 不写入
+	Prune   int    `json:"prune" form:"prune" comment:"删除文档中没有的配置" example:"0" validate:"min=0,max=1"`             //1=删除文档中不存在的服务和租户
+}
+
+func (params *ConfigImportInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type ConfigChangeField struct {
+	Field  string      `json:"field"`  //字段路径, 如 load_balance.ip_list
+	Before interface{} `json:"before"` //当前值
+	After  interface{} `json:"after"`  //文档中的值
+}
+
+type ConfigChange struct {
+	Resource string              `json:"resource"` //service/app
+	Name     string              `json:"name"`     //服务名称或租户id
+	Action   string              `json:"action"`   //add/update/delete
+	Diff     []ConfigChangeField `json:"diff"`     //变更的字段
+}
+
+type ConfigImportOutput struct {
+	DryRun  bool           `json:"dry_run"` //是否只是预览
+	Changes []ConfigChange `json:"changes"` //变更列表, 没有变化的配置不会出现
+}
@@ -50,6 +50,7 @@ type ServiceAddHTTPInput struct {
 	NeedHttps      int    `json:"need_https" form:"need_https" comment:"支持https"  validate:"max=1,min=0"`                      //支持https
 	NeedStripUri   int    `json:"need_strip_uri" form:"need_strip_uri" comment:"启用strip_uri"  validate:"max=1,min=0"`          //启用strip_uri
 	NeedWebsocket  int    `json:"need_websocket" form:"need_websocket" comment:"是否支持websocket"  validate:"max=1,min=0"`        //是否支持websocket
+	NeedLogBody    int    `json:"need_log_body" form:"need_log_body" comment:"访问日志记录body"  validate:"max=1,min=0"`             //访问日志记录请求和响应body
 	UrlRewrite     string `json:"url_rewrite" form:"url_rewrite" comment:"url重写功能"  validate:"valid_url_rewrite"`              //url重写功能
 	HeaderTransfor string `json:"header_transfor" form:"header_transfor" comment:"header转换"  validate:"valid_header_transfor"` //header转换
 
@@ -59,9 +60,18 @@ type ServiceAddHTTPInput struct {
 	ClientipFlowLimit int    `json:"clientip_flow_limit" form:"clientip_flow_limit" comment:"客户端ip限流	"  validate:"min=0"` //客户端ip限流
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流"  validate:"min=0"`      //服务端限流
 
-	RoundType              int    `json:"round_type" form:"round_type" comment:"轮询方式"  validate:"max=3,min=0"`                                //轮询方式
-	IpList                 string `json:"ip_list" form:"ip_list" comment:"ip列表"  validate:"required,valid_ipportlist"`                        //ip列表
-	WeightList             string `json:"weight_list" form:"weight_list" comment:"权重列表"  validate:"required,valid_weightlist"`                //权重列表
+	RoundType              int    `json:"round_type" form:"round_type" comment:"轮询方式"  validate:"max=6,min=0"`                                //轮询方式 0=random 1=round 2=weight_round 3=ip_hash 4=least_conn 5=p2c 6=bounded_hash
+	HashKey                string `json:"hash_key" form:"hash_key" comment:"一致性hash的key来源"  validate:"valid_hash_key"`                        //url/path/client_ip/app_id/header:<name>/cookie:<name>/query:<name>
+	HashReplicas           int    `json:"hash_replicas" form:"hash_replicas" comment:"虚拟节点数"  validate:"min=0,max=1000"`                      //每个节点的虚拟节点数, 0表示默认值
+	HashLoadFactor         int    `json:"hash_load_factor" form:"hash_load_factor" comment:"负载上限百分比"  validate:"omitempty,min=100,max=1000"`  //有界负载一致性hash的负载上限, 0表示默认值
+	SlowStart              int    `json:"slow_start" form:"slow_start" comment:"慢启动时长, 单位s" validate:"min=0,max=3600"`                        //新增或恢复的节点权重逐步增长, 0表示不开启
+	IpList                 string `json:"ip_list" form:"ip_list" comment:"ip列表"  validate:"omitempty,valid_ipportlist"`                       //ip列表
+	WeightList             string `json:"weight_list" form:"weight_list" comment:"权重列表"  validate:"omitempty,valid_weightlist"`               //权重列表
+	PriorityList           string `json:"priority_list" form:"priority_list" comment:"优先级列表" validate:"omitempty,valid_weightlist"`           //与ip列表一一对应, 0为最高优先级, 为空时不做切换
+	FailoverThreshold      int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值百分比" validate:"min=0,max=100"`            //可用节点的权重低于该百分比时同时使用下一优先级的节点
+	DiscoveryType          string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`   //空表示使用ip列表, file/redis/http
+	DiscoveryTarget        string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`                       //文件路径/注册的服务名/接口地址
+	UpstreamID             int64  `json:"upstream_id" form:"upstream_id" comment:"引用的上游ID" validate:"min=0"`                                  //不为0时使用上游的节点、探活、超时和TLS配置
 	UpstreamConnectTimeout int    `json:"upstream_connect_timeout" form:"upstream_connect_timeout" comment:"建立连接超时, 单位s"  validate:"min=0"`   //建立连接超时, 单位s
 	UpstreamHeaderTimeout  int    `json:"upstream_header_timeout" form:"upstream_header_timeout" comment:"获取header超时, 单位s"  validate:"min=0"` //获取header超时, 单位s
 	UpstreamIdleTimeout    int    `json:"upstream_idle_timeout" form:"upstream_idle_timeout" comment:"链接最大空闲时间, 单位s"  validate:"min=0"`       //链接最大空闲时间, 单位s
@@ -82,6 +92,7 @@ type ServiceUpdateHTTPInput struct {
 	NeedHttps      int    `json:"need_https" form:"need_https" comment:"支持https"  validate:"max=1,min=0"`                                  //支持https
 	NeedStripUri   int    `json:"need_strip_uri" form:"need_strip_uri" comment:"启用strip_uri"  validate:"max=1,min=0"`                      //启用strip_uri
 	NeedWebsocket  int    `json:"need_websocket" form:"need_websocket" comment:"是否支持websocket"  validate:"max=1,min=0"`                    //是否支持websocket
+	NeedLogBody    int    `json:"need_log_body" form:"need_log_body" comment:"访问日志记录body"  validate:"max=1,min=0"`                         //访问日志记录请求和响应body
 	UrlRewrite     string `json:"url_rewrite" form:"url_rewrite" comment:"url重写功能"  validate:"valid_url_rewrite"`                          //url重写功能
 	HeaderTransfor string `json:"header_transfor" form:"header_transfor" comment:"header转换"  validate:"valid_header_transfor"`             //header转换
 
@@ -91,9 +102,18 @@ type ServiceUpdateHTTPInput struct {
 	ClientipFlowLimit int    `json:"clientip_flow_limit" form:"clientip_flow_limit" comment:"客户端ip限流	"  validate:"min=0"` //客户端ip限流
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流"  validate:"min=0"`      //服务端限流
 
-	RoundType              int    `json:"round_type" form:"round_type" comment:"轮询方式"  validate:"max=3,min=0"`                                //轮询方式
-	IpList                 string `json:"ip_list" form:"ip_list" comment:"ip列表" example:"127.0.0.1:80" validate:"required,valid_ipportlist"`  //ip列表
-	WeightList             string `json:"weight_list" form:"weight_list" comment:"权重列表" example:"50" validate:"required,valid_weightlist"`    //权重列表
+	RoundType              int    `json:"round_type" form:"round_type" comment:"轮询方式"  validate:"max=6,min=0"`                                //轮询方式 0=random 1=round 2=weight_round 3=ip_hash 4=least_conn 5=p2c 6=bounded_hash
+	HashKey                string `json:"hash_key" form:"hash_key" comment:"一致性hash的key来源"  validate:"valid_hash_key"`                        //url/path/client_ip/app_id/header:<name>/cookie:<name>/query:<name>
+	HashReplicas           int    `json:"hash_replicas" form:"hash_replicas" comment:"虚拟节点数"  validate:"min=0,max=1000"`                      //每个节点的虚拟节点数, 0表示默认值
+	HashLoadFactor         int    `json:"hash_load_factor" form:"hash_load_factor" comment:"负载上限百分比"  validate:"omitempty,min=100,max=1000"`  //有界负载一致性hash的负载上限, 0表示默认值
+	SlowStart              int    `json:"slow_start" form:"slow_start" comment:"慢启动时长, 单位s" validate:"min=0,max=3600"`                        //新增或恢复的节点权重逐步增长, 0表示不开启
+	IpList                 string `json:"ip_list" form:"ip_list" comment:"ip列表" example:"127.0.0.1:80" validate:"omitempty,valid_ipportlist"` //ip列表
+	WeightList             string `json:"weight_list" form:"weight_list" comment:"权重列表" example:"50" validate:"omitempty,valid_weightlist"`   //权重列表
+	PriorityList           string `json:"priority_list" form:"priority_list" comment:"优先级列表" validate:"omitempty,valid_weightlist"`           //与ip列表一一对应, 0为最高优先级, 为空时不做切换
+	FailoverThreshold      int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值百分比" validate:"min=0,max=100"`            //可用节点的权重低于该百分比时同时使用下一优先级的节点
+	DiscoveryType          string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`   //空表示使用ip列表, file/redis/http
+	DiscoveryTarget        string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`                       //文件路径/注册的服务名/接口地址
+	UpstreamID             int64  `json:"upstream_id" form:"upstream_id" comment:"引用的上游ID" validate:"min=0"`                                  //不为0时使用上游的节点、探活、超时和TLS配置
 	UpstreamConnectTimeout int    `json:"upstream_connect_timeout" form:"upstream_connect_timeout" comment:"建立连接超时, 单位s"  validate:"min=0"`   //建立连接超时, 单位s
 	UpstreamHeaderTimeout  int    `json:"upstream_header_timeout" form:"upstream_header_timeout" comment:"获取header超时, 单位s"  validate:"min=0"` //获取header超时, 单位s
 	UpstreamIdleTimeout    int    `json:"upstream_idle_timeout" form:"upstream_idle_timeout" comment:"链接最大空闲时间, 单位s"  validate:"min=0"`       //链接最大空闲时间, 单位s
@@ -113,6 +133,12 @@ type ServiceAddGrpcInput struct {
 	ServiceDesc       string `json:"service_desc" form:"service_desc" comment:"服务描述" validate:"required"`
 	Port              int    `json:"port" form:"port" comment:"端口,需要设置8001-8999范围内" validate:"required,min=8001,max=8999"`
 	HeaderTransfor    string `json:"header_transfor" form:"header_transfor" comment:"metadata转换" validate:"valid_header_transfor"`
+	MethodRoute       string `json:"method_route" form:"method_route" comment:"方法路由" validate:"valid_method_route"`
+	MethodLimit       string `json:"method_limit" form:"method_limit" comment:"方法限流" validate:"valid_method_limit"`
+	MethodAllow       string `json:"method_allow" form:"method_allow" comment:"方法允许列表" validate:"valid_method_list"`
+	MethodDeny        string `json:"method_deny" form:"method_deny" comment:"方法禁止列表" validate:"valid_method_list"`
+	NeedGrpcWeb       int    `json:"need_grpc_web" form:"need_grpc_web" comment:"支持grpc-web" validate:"min=0,max=1"`
+	GrpcWebOrigins    string `json:"grpc_web_origins" form:"grpc_web_origins" comment:"grpc-web跨域来源,多个逗号间隔,*表示任意来源但不携带凭证" validate:"max=2000,valid_origin_list"`
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限验证" validate:""`
 	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单IP,以逗号间隔，白名单优先级高于黑名单" validate:"valid_iplist"`
 	WhiteList         string `json:"white_list" form:"white_list" comment:"白名单IP,以逗号间隔，白名单优先级高于黑名单" validate:"valid_iplist"`
@@ -120,8 +146,17 @@ type ServiceAddGrpcInput struct {
 	ClientIPFlowLimit int    `json:"clientip_flow_limit" form:"clientip_flow_limit" comment:"客户端IP限流" validate:""`
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
 	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
-	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"required,valid_ipportlist"`
-	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"required,valid_weightlist"`
+	HashKey           string `json:"hash_key" form:"hash_key" comment:"一致性hash的key来源" validate:"valid_hash_key"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"虚拟节点数,0表示默认值" validate:"min=0,max=1000"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"负载上限,平均负载的百分比,0表示默认值" validate:"omitempty,min=100,max=1000"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时长,单位s,0表示不开启" validate:"min=0,max=3600"`
+	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"omitempty,valid_ipportlist"`
+	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"omitempty,valid_weightlist"`
+	PriorityList      string `json:"priority_list" form:"priority_list" comment:"优先级列表,与IP列表一一对应,0为最高优先级" validate:"omitempty,valid_weightlist"`
+	FailoverThreshold int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值,可用权重的百分比" validate:"min=0,max=100"`
+	DiscoveryType     string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`
+	DiscoveryTarget   string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`
+	UpstreamID        int64  `json:"upstream_id" form:"upstream_id" comment:"引用的上游ID,不为0时使用上游的配置" validate:"min=0"`
 	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
 }
 
@@ -135,6 +170,12 @@ type ServiceUpdateGrpcInput struct {
 	ServiceDesc       string `json:"service_desc" form:"service_desc" comment:"服务描述" validate:"required"`
 	Port              int    `json:"port" form:"port" comment:"端口,需要设置8001-8999范围内" validate:"required,min=8001,max=8999"`
 	HeaderTransfor    string `json:"header_transfor" form:"header_transfor" comment:"metadata转换" validate:"valid_header_transfor"`
+	MethodRoute       string `json:"method_route" form:"method_route" comment:"方法路由" validate:"valid_method_route"`
+	MethodLimit       string `json:"method_limit" form:"method_limit" comment:"方法限流" validate:"valid_method_limit"`
+	MethodAllow       string `json:"method_allow" form:"method_allow" comment:"方法允许列表" validate:"valid_method_list"`
+	MethodDeny        string `json:"method_deny" form:"method_deny" comment:"方法禁止列表" validate:"valid_method_list"`
+	NeedGrpcWeb       int    `json:"need_grpc_web" form:"need_grpc_web" comment:"支持grpc-web" validate:"min=0,max=1"`
+	GrpcWebOrigins    string `json:"grpc_web_origins" form:"grpc_web_origins" comment:"grpc-web跨域来源,多个逗号间隔,*表示任意来源但不携带凭证" validate:"max=2000,valid_origin_list"`
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限验证" validate:""`
 	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单IP,以逗号间隔,白名单优先级高于黑名单" validate:"valid_iplist"`
 	WhiteList         string `json:"white_list" form:"white_list" comment:"白名单IP,以逗号间隔,白名单优先级高于黑名单" validate:"valid_iplist"`
@@ -142,8 +183,17 @@ type ServiceUpdateGrpcInput struct {
 	ClientIPFlowLimit int    `json:"clientip_flow_limit" form:"clientip_flow_limit" comment:"客户端IP限流" validate:""`
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
 	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
-	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"required,valid_ipportlist"`
-	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"required,valid_weightlist"`
+	HashKey           string `json:"hash_key" form:"hash_key" comment:"一致性hash的key来源" validate:"valid_hash_key"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"虚拟节点数,0表示默认值" validate:"min=0,max=1000"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"负载上限,平均负载的百分比,0表示默认值" validate:"omitempty,min=100,max=1000"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时长,单位s,0表示不开启" validate:"min=0,max=3600"`
+	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"omitempty,valid_ipportlist"`
+	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"omitempty,valid_weightlist"`
+	PriorityList      string `json:"priority_list" form:"priority_list" comment:"优先级列表,与IP列表一一对应,0为最高优先级" validate:"omitempty,valid_weightlist"`
+	FailoverThreshold int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值,可用权重的百分比" validate:"min=0,max=100"`
+	DiscoveryType     string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`
+	DiscoveryTarget   string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`
+	UpstreamID        int64  `json:"upstream_id" form:"upstream_id" comment:"引用的上游ID,不为0时使用上游的配置" validate:"min=0"`
 	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
 }
 
@@ -154,7 +204,13 @@ func (params *ServiceUpdateGrpcInput) BindValidParam(c *gin.Context) error {
 type ServiceAddTcpInput struct {
 	ServiceName       string `json:"service_name" form:"service_name" comment:"服务名称" validate:"required,valid_service_name"`
 	ServiceDesc       string `json:"service_desc" form:"service_desc" comment:"服务描述" validate:"required"`
-	Port              int    `json:"port" form:"port" comment:"端口,需要设置8001-8999范围内" validate:"required,min=8001,max=8999"`
+	Port              int    `json:"port" form:"port" comment:"端口,需要设置8001-8999范围内,只使用SNI接入时可以为0" validate:"omitempty,min=8001,max=8999"`
+	IdleTimeout       int    `json:"idle_timeout" form:"idle_timeout" comment:"连接空闲超时,单位s,0表示不限制" validate:"min=0"`
+	MaxConns          int    `json:"max_conns" form:"max_conns" comment:"最大并发连接数,0表示不限制" validate:"min=0"`
+	NeedTls           int    `json:"need_tls" form:"need_tls" comment:"是否在网关终止TLS" validate:"min=0,max=1"`
+	CertFile          string `json:"cert_file" form:"cert_file" comment:"TLS证书文件路径" validate:""`
+	KeyFile           string `json:"key_file" form:"key_file" comment:"TLS私钥文件路径" validate:""`
+	SniHost           string `json:"sni_host" form:"sni_host" comment:"共享端口SNI路由域名,以逗号间隔" validate:"valid_hostlist"`
 	HeaderTransfor    string `json:"header_transfor" form:"header_transfor" comment:"header头转换" validate:""`
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限验证" validate:""`
 	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单IP,以逗号间隔，白名单优先级高于黑名单" validate:"valid_iplist"`
@@ -163,8 +219,17 @@ type ServiceAddTcpInput struct {
 	ClientIPFlowLimit int    `json:"clientip_flow_limit" form:"clientip_flow_limit" comment:"客户端IP限流" validate:""`
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
 	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
-	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"required,valid_ipportlist"`
-	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"required,valid_weightlist"`
+	HashKey           string `json:"hash_key" form:"hash_key" comment:"一致性hash的key来源" validate:"valid_hash_key"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"虚拟节点数,0表示默认值" validate:"min=0,max=1000"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"负载上限,平均负载的百分比,0表示默认值" validate:"omitempty,min=100,max=1000"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时长,单位s,0表示不开启" validate:"min=0,max=3600"`
+	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"omitempty,valid_ipportlist"`
+	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"omitempty,valid_weightlist"`
+	PriorityList      string `json:"priority_list" form:"priority_list" comment:"优先级列表,与IP列表一一对应,0为最高优先级" validate:"omitempty,valid_weightlist"`
+	FailoverThreshold int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值,可用权重的百分比" validate:"min=0,max=100"`
+	DiscoveryType     string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`
+	DiscoveryTarget   string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`
+	UpstreamID        int64  `json:"upstream_id" form:"upstream_id" comment:"引用的上游ID,不为0时使用上游的配置" validate:"min=0"`
 	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
 }
 
@@ -176,7 +241,13 @@ type ServiceUpdateTcpInput struct {
 	ID                int64  `json:"id" form:"id" comment:"服务ID" validate:"required"`
 	ServiceName       string `json:"service_name" form:"service_name" comment:"服务名称" validate:"required,valid_service_name"`
 	ServiceDesc       string `json:"service_desc" form:"service_desc" comment:"服务描述" validate:"required"`
-	Port              int    `json:"port" form:"port" comment:"端口,需要设置8001-8999范围内" validate:"required,min=8001,max=8999"`
+	Port              int    `json:"port" form:"port" comment:"端口,需要设置8001-8999范围内,只使用SNI接入时可以为0" validate:"omitempty,min=8001,max=8999"`
+	IdleTimeout       int    `json:"idle_timeout" form:"idle_timeout" comment:"连接空闲超时,单位s,0表示不限制" validate:"min=0"`
+	MaxConns          int    `json:"max_conns" form:"max_conns" comment:"最大并发连接数,0表示不限制" validate:"min=0"`
+	NeedTls           int    `json:"need_tls" form:"need_tls" comment:"是否在网关终止TLS" validate:"min=0,max=1"`
+	CertFile          string `json:"cert_file" form:"cert_file" comment:"TLS证书文件路径" validate:""`
+	KeyFile           string `json:"key_file" form:"key_file" comment:"TLS私钥文件路径" validate:""`
+	SniHost           string `json:"sni_host" form:"sni_host" comment:"共享端口SNI路由域名,以逗号间隔" validate:"valid_hostlist"`
 	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限验证" validate:""`
 	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单IP,以逗号间隔,白名单优先级高于黑名单" validate:"valid_iplist"`
 	WhiteList         string `json:"white_list" form:"white_list" comment:"白名单IP,以逗号间隔,白名单优先级高于黑名单" validate:"valid_iplist"`
@@ -184,11 +255,81 @@ type ServiceUpdateTcpInput struct {
 	ClientIPFlowLimit int    `json:"clientip_flow_limit" form:"clientip_flow_limit" comment:"客户端IP限流" validate:""`
 	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
 	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
-	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"required,valid_ipportlist"`
-	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"required,valid_weightlist"`
+	HashKey           string `json:"hash_key" form:"hash_key" comment:"一致性hash的key来源" validate:"valid_hash_key"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"虚拟节点数,0表示默认值" validate:"min=0,max=1000"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"负载上限,平均负载的百分比,0表示默认值" validate:"omitempty,min=100,max=1000"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时长,单位s,0表示不开启" validate:"min=0,max=3600"`
+	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"omitempty,valid_ipportlist"`
+	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"omitempty,valid_weightlist"`
+	PriorityList      string `json:"priority_list" form:"priority_list" comment:"优先级列表,与IP列表一一对应,0为最高优先级" validate:"omitempty,valid_weightlist"`
+	FailoverThreshold int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值,可用权重的百分比" validate:"min=0,max=100"`
+	DiscoveryType     string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`
+	DiscoveryTarget   string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`
+	UpstreamID        int64  `json:"upstream_id" form:"upstream_id" comment:"引用的上游ID,不为0时使用上游的配置" validate:"min=0"`
 	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
 }
 
 func (params *ServiceUpdateTcpInput) BindValidParam(c *gin.Context) error {
 	return utils.DefaultGetValidParams(c, params)
 }
+
+type ServiceAddUdpInput struct {
+	ServiceName       string `json:"service_name" form:"service_name" comment:"服务名称" validate:"required,valid_service_name"`
+	ServiceDesc       string `json:"service_desc" form:"service_desc" comment:"服务描述" validate:"required"`
+	Port              int    `json:"port" form:"port" comment:"端口,需要设置8001-8999范围内" validate:"required,min=8001,max=8999"`
+	SessionTimeout    int    `json:"session_timeout" form:"session_timeout" comment:"会话空闲超时,单位s,0表示使用默认值" validate:"min=0"`
+	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限验证" validate:""`
+	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单IP,以逗号间隔，白名单优先级高于黑名单" validate:"valid_iplist"`
+	WhiteList         string `json:"white_list" form:"white_list" comment:"白名单IP,以逗号间隔，白名单优先级高于黑名单" validate:"valid_iplist"`
+	WhiteHostName     string `json:"white_host_name" form:"white_host_name" comment:"白名单主机，以逗号间隔" validate:"valid_iplist"`
+	ClientIPFlowLimit int    `json:"clientip_flow_limit" form:"clientip_flow_limit" comment:"客户端IP限流" validate:""`
+	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
+	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
+	HashKey           string `json:"hash_key" form:"hash_key" comment:"一致性hash的key来源" validate:"valid_hash_key"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"虚拟节点数,0表示默认值" validate:"min=0,max=1000"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"负载上限,平均负载的百分比,0表示默认值" validate:"omitempty,min=100,max=1000"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时长,单位s,0表示不开启" validate:"min=0,max=3600"`
+	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"omitempty,valid_ipportlist"`
+	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"omitempty,valid_weightlist"`
+	PriorityList      string `json:"priority_list" form:"priority_list" comment:"优先级列表,与IP列表一一对应,0为最高优先级" validate:"omitempty,valid_weightlist"`
+	FailoverThreshold int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值,可用权重的百分比" validate:"min=0,max=100"`
+	DiscoveryType     string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`
+	DiscoveryTarget   string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`
+	UpstreamID        int64  `json:"upstream_id" form:"upstream_id" comment:"引用的上游ID,不为0时使用上游的配置" validate:"min=0"`
+	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
+}
+
+func (params *ServiceAddUdpInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type ServiceUpdateUdpInput struct {
+	ID                int64  `json:"id" form:"id" comment:"服务ID" validate:"required"`
+	ServiceName       string `json:"service_name" form:"service_name" comment:"服务名称" validate:"required,valid_service_name"`
+	ServiceDesc       string `json:"service_desc" form:"service_desc" comment:"服务描述" validate:"required"`
+	Port              int    `json:"port" form:"port" comment:"端口,需要设置8001-8999范围内" validate:"required,min=8001,max=8999"`
+	SessionTimeout    int    `json:"session_timeout" form:"session_timeout" comment:"会话空闲超时,单位s,0表示使用默认值" validate:"min=0"`
+	OpenAuth          int    `json:"open_auth" form:"open_auth" comment:"是否开启权限验证" validate:""`
+	BlackList         string `json:"black_list" form:"black_list" comment:"黑名单IP,以逗号间隔,白名单优先级高于黑名单" validate:"valid_iplist"`
+	WhiteList         string `json:"white_list" form:"white_list" comment:"白名单IP,以逗号间隔,白名单优先级高于黑名单" validate:"valid_iplist"`
+	WhiteHostName     string `json:"white_host_name" form:"white_host_name" comment:"白名单主机,以逗号间隔" validate:"valid_iplist"`
+	ClientIPFlowLimit int    `json:"clientip_flow_limit" form:"clientip_flow_limit" comment:"客户端IP限流" validate:""`
+	ServiceFlowLimit  int    `json:"service_flow_limit" form:"service_flow_limit" comment:"服务端限流" validate:""`
+	RoundType         int    `json:"round_type" form:"round_type" comment:"轮询策略" validate:""`
+	HashKey           string `json:"hash_key" form:"hash_key" comment:"一致性hash的key来源" validate:"valid_hash_key"`
+	HashReplicas      int    `json:"hash_replicas" form:"hash_replicas" comment:"虚拟节点数,0表示默认值" validate:"min=0,max=1000"`
+	HashLoadFactor    int    `json:"hash_load_factor" form:"hash_load_factor" comment:"负载上限,平均负载的百分比,0表示默认值" validate:"omitempty,min=100,max=1000"`
+	SlowStart         int    `json:"slow_start" form:"slow_start" comment:"慢启动时长,单位s,0表示不开启" validate:"min=0,max=3600"`
+	IpList            string `json:"ip_list" form:"ip_list" comment:"IP列表" validate:"omitempty,valid_ipportlist"`
+	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" validate:"omitempty,valid_weightlist"`
+	PriorityList      string `json:"priority_list" form:"priority_list" comment:"优先级列表,与IP列表一一对应,0为最高优先级" validate:"omitempty,valid_weightlist"`
+	FailoverThreshold int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值,可用权重的百分比" validate:"min=0,max=100"`
+	DiscoveryType     string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`
+	DiscoveryTarget   string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`
+	UpstreamID        int64  `json:"upstream_id" form:"upstream_id" comment:"引用的上游ID,不为0时使用上游的配置" validate:"min=0"`
+	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用IP列表" validate:"valid_iplist"`
+}
+
+func (params *ServiceUpdateUdpInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
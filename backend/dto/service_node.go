@@ -0,0 +1,16 @@
+package dto
+
+import (
+	"gateway/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceNodeInput struct {
+	ID   int64  `json:"id" form:"id" comment:"服务ID" example:"56" validate:"required"`                                //服务ID
+	Node string `json:"node" form:"node" comment:"节点" example:"127.0.0.1:8080" validate:"required,valid_ipportlist"` //节点, 必须是 ip_list 中的一项
+}
+
+func (params *ServiceNodeInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
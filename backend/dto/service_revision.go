@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"gateway/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceRevisionListInput struct {
+	ID       int64 `json:"id" form:"id" comment:"服务ID" example:"56" validate:"required"` //服务ID
+	PageNo   int   `json:"page_no" form:"page_no" comment:"页码" example:"1" validate:"required,min=1,max=999"`
+	PageSize int   `json:"page_size" form:"page_size" comment:"每页条数" example:"20" validate:"required,min=1,max=999"`
+}
+
+func (params *ServiceRevisionListInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type ServiceRevisionListItem struct {
+	Version   int       `json:"version"`    //版本号
+	Action    string    `json:"action"`     //产生版本的操作 add/update/rollback
+	AdminName string    `json:"admin_name"` //操作人
+	Remark    string    `json:"remark"`     //备注
+	CreatedAt time.Time `json:"create_at"`  //创建时间
+}
+
+type ServiceRevisionListOutput struct {
+	List  []ServiceRevisionListItem `json:"list" form:"list" comment:"版本列表"`
+	Total int64                     `json:"total" form:"total" comment:"版本总数"`
+}
+
+type ServiceRevisionDiffInput struct {
+	ID          int64 `json:"id" form:"id" comment:"服务ID" example:"56" validate:"required"`                          //服务ID
+	FromVersion int   `json:"from_version" form:"from_version" comment:"起始版本" example:"1" validate:"required,min=1"` //起始版本
+	ToVersion   int   `json:"to_version" form:"to_version" comment:"目标版本" example:"2" validate:"min=0"`              //目标版本, 为 0 时与当前配置对比
+}
+
+func (params *ServiceRevisionDiffInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type ServiceRevisionDiffItem struct {
+	Field  string      `json:"field"`  //字段路径, 如 load_balance.ip_list
+	Before interface{} `json:"before"` //起始版本的值
+	After  interface{} `json:"after"`  //目标版本的值
+}
+
+type ServiceRevisionDiffOutput struct {
+	FromVersion int                       `json:"from_version"`
+	ToVersion   int                       `json:"to_version"`
+	Diff        []ServiceRevisionDiffItem `json:"diff"`
+}
+
+type ServiceRollbackInput struct {
+	ID      int64 `json:"id" form:"id" comment:"服务ID" example:"56" validate:"required"`               //服务ID
+	Version int   `json:"version" form:"version" comment:"版本号" example:"3" validate:"required,min=1"` //回滚到的版本
+}
+
+func (params *ServiceRollbackInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"gateway/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatFlowInput struct {
+	Dimension string `json:"dimension" form:"dimension" comment:"统计维度" example:"service" validate:"required,oneof=total service app node status"` //统计维度 total/service/app/node/status
+	Name      string `json:"name" form:"name" comment:"名称" example:"test_http" validate:""`                                                       //服务名称或租户id, node/status 维度必填, 为空时返回全部服务或租户
+	From      int64  `json:"from" form:"from" comment:"开始时间" example:"1700000000" validate:"required,min=1"`                                      //开始时间, unix 秒
+	To        int64  `json:"to" form:"to" comment:"结束时间" example:"1700086400" validate:"min=0"`                                                   //结束时间, unix 秒, 为空时取当前时间
+	Step      string `json:"step" form:"step" comment:"统计粒度" example:"hour" validate:"required,oneof=minute hour day"`                            //统计粒度 minute/hour/day
+}
+
+func (params *StatFlowInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type StatFlowPoint struct {
+	Time  int64 `json:"time"`  //统计桶开始时间, unix 秒
+	Value int64 `json:"value"` //请求数
+}
+
+type StatFlowSeries struct {
+	Name   string          `json:"name"`   //服务名称/租户id/节点/状态分类
+	Total  int64           `json:"total"`  //区间内请求总数
+	Points []StatFlowPoint `json:"points"` //各统计桶的请求数
+}
+
+type StatFlowOutput struct {
+	Dimension string           `json:"dimension"`
+	Step      string           `json:"step"`
+	From      int64            `json:"from"`
+	To        int64            `json:"to"`
+	Series    []StatFlowSeries `json:"series"`
+}
+
+type StatHistoryInput struct {
+	Dimension   string `json:"dimension" form:"dimension" comment:"统计维度" example:"app" validate:"required,oneof=total service app"`  //统计维度 total/service/app
+	Name        string `json:"name" form:"name" comment:"名称" example:"app_id_a" validate:""`                                         //服务名称或租户id, 为空时返回全部
+	FromDay     string `json:"from_day" form:"from_day" comment:"开始日期" example:"2024-01-01" validate:"required"`                     //开始日期 yyyy-mm-dd
+	ToDay       string `json:"to_day" form:"to_day" comment:"结束日期" example:"2024-01-31" validate:"required"`                         //结束日期 yyyy-mm-dd, 包含当天
+	Granularity string `json:"granularity" form:"granularity" comment:"汇总粒度" example:"day" validate:"required,oneof=hour day month"` //汇总粒度 hour/day/month
+}
+
+func (params *StatHistoryInput) BindValidParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type StatHistoryItem struct {
+	Name         string `json:"name"`          //服务名称或租户id
+	Period       int64  `json:"period"`        //统计周期 yyyymmddhh/yyyymmdd/yyyymm
+	RequestCount int64  `json:"request_count"` //请求数
+}
+
+type StatHistoryOutput struct {
+	Dimension   string            `json:"dimension"`
+	Granularity string            `json:"granularity"`
+	List        []StatHistoryItem `json:"list"`
+}
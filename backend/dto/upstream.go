@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"gateway/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UpstreamListInput struct {
+	Info     string `json:"info" form:"info" comment:"关键词" validate:""`
+	PageSize int    `json:"page_size" form:"page_size" comment:"页数" validate:"required,min=1,max=999"`
+	PageNo   int    `json:"page_no" form:"page_no" comment:"页码" validate:"required,min=1,max=999"`
+}
+
+func (params *UpstreamListInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type UpstreamListOutput struct {
+	List  []UpstreamListItemOutput `json:"list" form:"list" comment:"上游列表"`
+	Total int64                    `json:"total" form:"total" comment:"上游总数"`
+}
+
+type UpstreamListItemOutput struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	Description   string    `json:"description"`
+	IpList        string    `json:"ip_list"`
+	DiscoveryType string    `json:"discovery_type"`
+	CheckMethod   int       `json:"check_method"`
+	TLSEnable     int       `json:"tls_enable"`
+	Services      []string  `json:"services"` //引用该上游的服务
+	UpdatedAt     time.Time `json:"update_at"`
+}
+
+type UpstreamDetailInput struct {
+	ID int64 `json:"id" form:"id" comment:"上游ID" example:"1" validate:"required"` //上游ID
+}
+
+func (params *UpstreamDetailInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type UpstreamAddInput struct {
+	Name        string `json:"name" form:"name" comment:"上游名称" example:"order_cluster" validate:"required,valid_service_name"` //上游名称
+	Description string `json:"description" form:"description" comment:"描述" validate:"max=255"`                                 //描述
+
+	IpList            string `json:"ip_list" form:"ip_list" comment:"ip列表" example:"127.0.0.1:80" validate:"omitempty,valid_ipportlist"` //ip列表
+	WeightList        string `json:"weight_list" form:"weight_list" comment:"权重列表" example:"50" validate:"omitempty,valid_weightlist"`   //权重列表
+	PriorityList      string `json:"priority_list" form:"priority_list" comment:"优先级列表" validate:"omitempty,valid_weightlist"`           //与ip列表一一对应, 0为最高优先级, 为空时不做切换
+	FailoverThreshold int    `json:"failover_threshold" form:"failover_threshold" comment:"切换阈值百分比" validate:"min=0,max=100"`            //可用节点的权重低于该百分比时同时使用下一优先级的节点
+	ForbidList        string `json:"forbid_list" form:"forbid_list" comment:"禁用ip列表" validate:"valid_iplist"`                            //禁用ip列表, 对所有引用的服务生效
+	DiscoveryType     string `json:"discovery_type" form:"discovery_type" comment:"服务发现方式" validate:"omitempty,oneof=file redis http"`   //空表示使用ip列表, file/redis/http
+	DiscoveryTarget   string `json:"discovery_target" form:"discovery_target" comment:"服务发现目标" validate:"max=255"`                       //文件路径/注册的服务名/接口地址
+
+	CheckMethod    int    `json:"check_method" form:"check_method" comment:"探活方式" validate:"min=0,max=2"`           //0=tcp 1=不探活 2=http
+	CheckPath      string `json:"check_path" form:"check_path" comment:"探活路径" example:"/health" validate:"max=255"` //http 探活的请求路径
+	CheckTimeout   int    `json:"check_timeout" form:"check_timeout" comment:"探活超时, 单位s" validate:"min=0"`          //0表示默认值
+	CheckInterval  int    `json:"check_interval" form:"check_interval" comment:"探活间隔, 单位s" validate:"min=0"`        //0表示默认值
+	CheckMaxErrNum int    `json:"check_max_err_num" form:"check_max_err_num" comment:"摘除前连续失败次数" validate:"min=0"`  //0表示默认值
+	SlowStart      int    `json:"slow_start" form:"slow_start" comment:"慢启动时长, 单位s" validate:"min=0,max=3600"`      //新增或恢复的节点权重逐步增长, 0表示不开启
+
+	ConnectTimeout int `json:"connect_timeout" form:"connect_timeout" comment:"建立连接超时, 单位s" validate:"min=0"`   //建立连接超时, 单位s
+	HeaderTimeout  int `json:"header_timeout" form:"header_timeout" comment:"获取header超时, 单位s" validate:"min=0"` //获取header超时, 单位s
+	IdleTimeout    int `json:"idle_timeout" form:"idle_timeout" comment:"链接最大空闲时间, 单位s" validate:"min=0"`       //链接最大空闲时间, 单位s
+	MaxIdle        int `json:"max_idle" form:"max_idle" comment:"最大空闲链接数" validate:"min=0"`                     //最大空闲链接数
+
+	TLSEnable             int    `json:"tls_enable" form:"tls_enable" comment:"使用https访问上游" validate:"min=0,max=1"`                        //使用https访问上游
+	TLSServerName         string `json:"tls_server_name" form:"tls_server_name" comment:"证书校验的服务名" validate:"max=255"`                     //为空时使用节点地址
+	TLSInsecureSkipVerify int    `json:"tls_insecure_skip_verify" form:"tls_insecure_skip_verify" comment:"跳过证书校验" validate:"min=0,max=1"` //跳过证书校验
+	TLSCACert             string `json:"tls_ca_cert" form:"tls_ca_cert" comment:"CA证书" validate:""`                                        //PEM 格式, 为空时使用系统证书
+}
+
+func (params *UpstreamAddInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
+
+type UpstreamUpdateInput struct {
+	ID int64 `json:"id" form:"id" comment:"上游ID" example:"1" validate:"required,min=1"` //上游ID
+	UpstreamAddInput
+}
+
+func (params *UpstreamUpdateInput) BindValParam(c *gin.Context) error {
+	return utils.DefaultGetValidParams(c, params)
+}
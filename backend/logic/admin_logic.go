@@ -10,6 +10,7 @@ import (
 	"gateway/pkg/database/mysql"
 	"gateway/pkg/log"
 	"gateway/utils"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/contrib/sessions"
@@ -24,6 +25,10 @@ type AdminLogic interface {
 	AdminLogout(c *gin.Context) error
 	GetAdminInfo(c *gin.Context) (*dto.AminInfoOutput, error)
 	ChangeAdminPassword(c *gin.Context, params *dto.AdminChangePwdInput) error
+	AdminUserList(c *gin.Context, params *dto.AdminListInput) ([]dto.AdminListItemOutput, int64, error)
+	AdminUserAdd(c *gin.Context, params *dto.AdminAddInput) error
+	AdminUserUpdate(c *gin.Context, params *dto.AdminUpdateInput) error
+	AdminUserDelete(c *gin.Context, params *dto.AdminDeleteInput) error
 }
 
 type adminLogic struct {
@@ -50,10 +55,15 @@ func (s *adminLogic) Login(c *gin.Context, params *dto.AdminLoginInput) (*dto.Ad
 		return nil, fmt.Errorf("incorrect password, please try again")
 	}
 
+	if !admin.Enabled() {
+		return nil, fmt.Errorf("the account has been disabled")
+	}
+
 	// 创建新的会话信息
 	sessInfo := &dto.AdminSessionInfo{
 		ID:        admin.ID,
 		UserName:  admin.UserName,
+		Role:      admin.Role,
 		LoginTime: time.Now(),
 	}
 
@@ -96,14 +106,20 @@ func (s *adminLogic) GetAdminInfo(c *gin.Context) (*dto.AminInfoOutput, error) {
 		return nil, fmt.Errorf("invalid session info")
 	}
 
+	// 角色以数据库为准, 修改后无需重新登录
+	role := adminSessionInfo.Role
+	if admin := sessionAdmin(c); admin != nil {
+		role = admin.Role
+	}
+
 	out := &dto.AminInfoOutput{
 		ID:            adminSessionInfo.ID,
 		Name:          adminSessionInfo.UserName,
 		LoginTime:     adminSessionInfo.LoginTime,
 		Avatar:        "https://images.unsplash.com/photo-1521747116042-5a810fda9664",
-		Introduceions: "I am a super administrator",
+		Introduceions: fmt.Sprintf("I am a gateway %s", role),
 		Roles: []string{
-			"admin",
+			role,
 		},
 	}
 	return out, nil
@@ -129,11 +145,187 @@ func (s *adminLogic) ChangeAdminPassword(c *gin.Context, params *dto.AdminChange
 		return fmt.Errorf("failed to generate hashed password")
 	}
 
+	before := auditSnapshot(adminInfo)
 	adminInfo.Password = hashedPassword
 
-	if err := s.Save(c, s.db, adminInfo); err != nil {
+	tx := s.db.Begin()
+	if err := s.Save(c, tx, adminInfo); err != nil {
+		tx.Rollback()
 		return fmt.Errorf("failed to change password")
 	}
+	if err := recordAudit(c, tx, enity.AuditResourceAdmin, enity.AuditActionUpdate, adminInfo.UserName, before, adminInfo); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+
+	return nil
+}
+
+// AdminUserList 分页返回管理员账号列表
+func (s *adminLogic) AdminUserList(c *gin.Context, params *dto.AdminListInput) ([]dto.AdminListItemOutput, int64, error) {
+	queryConditions := []func(db *gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Where("user_name like ?", "%"+params.Info+"%")
+		},
+	}
+	list, total, err := s.PageList(c, s.db, queryConditions, params.PageNo, params.PageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get admin list")
+	}
 
+	outputList := []dto.AdminListItemOutput{}
+	for _, item := range list {
+		outputList = append(outputList, dto.AdminListItemOutput{
+			ID:           item.ID,
+			UserName:     item.UserName,
+			Role:         item.Role,
+			Status:       item.Status,
+			ServiceScope: item.ServiceScope,
+			UpdateAt:     item.UpdateAt,
+			CreateAt:     item.CreateAt,
+		})
+	}
+	return outputList, total, nil
+}
+
+// AdminUserAdd 添加管理员账号, 新账号默认启用
+func (s *adminLogic) AdminUserAdd(c *gin.Context, params *dto.AdminAddInput) error {
+	if _, err := s.Get(c, s.db, &enity.Admin{UserName: params.UserName}); err == nil {
+		return fmt.Errorf("username %s is already taken", params.UserName)
+	}
+
+	hashedPassword, err := utils.HashPassword(params.Password)
+	if err != nil {
+		log.Error("failed to generate hashed password", zap.Error(err))
+		return fmt.Errorf("failed to generate hashed password")
+	}
+
+	admin := &enity.Admin{
+		UserName:     params.UserName,
+		Password:     hashedPassword,
+		Role:         params.Role,
+		Status:       enity.AdminStatusEnabled,
+		ServiceScope: params.ServiceScope,
+		UpdateAt:     time.Now(),
+		CreateAt:     time.Now(),
+	}
+	tx := s.db.Begin()
+	if err := s.Save(c, tx, admin); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to add admin")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceAdmin, enity.AuditActionAdd, admin.UserName, nil, admin); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
 	return nil
 }
+
+// AdminUserUpdate 修改管理员的角色、状态、服务范围和密码
+func (s *adminLogic) AdminUserUpdate(c *gin.Context, params *dto.AdminUpdateInput) error {
+	admin, err := s.Get(c, s.db, &enity.Admin{ID: params.ID})
+	if err != nil || admin.IsDelete == 1 {
+		return fmt.Errorf("admin not found")
+	}
+
+	// 不允许修改自己的角色和状态, 避免没有可用的管理员
+	if current := sessionAdmin(c); current != nil && current.ID == admin.ID {
+		if params.Role != admin.Role || params.Status != admin.Status {
+			return fmt.Errorf("cannot change your own role or status")
+		}
+	}
+
+	before := auditSnapshot(admin)
+	if params.Password != "" {
+		hashedPassword, err := utils.HashPassword(params.Password)
+		if err != nil {
+			log.Error("failed to generate hashed password", zap.Error(err))
+			return fmt.Errorf("failed to generate hashed password")
+		}
+		admin.Password = hashedPassword
+	}
+	admin.Role = params.Role
+	admin.Status = params.Status
+	admin.ServiceScope = params.ServiceScope
+	admin.UpdateAt = time.Now()
+
+	tx := s.db.Begin()
+	if err := s.Save(c, tx, admin); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update admin")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceAdmin, enity.AuditActionUpdate, admin.UserName, before, admin); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// AdminUserDelete 软删除管理员账号, 已登录的会话在下一次请求时失效
+func (s *adminLogic) AdminUserDelete(c *gin.Context, params *dto.AdminDeleteInput) error {
+	admin, err := s.Get(c, s.db, &enity.Admin{ID: params.ID})
+	if err != nil || admin.IsDelete == 1 {
+		return fmt.Errorf("admin not found")
+	}
+	if current := sessionAdmin(c); current != nil && current.ID == admin.ID {
+		return fmt.Errorf("cannot delete yourself")
+	}
+
+	before := auditSnapshot(admin)
+	admin.IsDelete = 1
+	admin.UpdateAt = time.Now()
+	tx := s.db.Begin()
+	if err := s.Save(c, tx, admin); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete admin")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceAdmin, enity.AuditActionDelete, admin.UserName, before, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// sessionAdmin 返回 SessionAuthMiddleware 加载的当前账号
+func sessionAdmin(c *gin.Context) *enity.Admin {
+	admin, _ := c.Get(globals.AdminInfoKey)
+	a, _ := admin.(*enity.Admin)
+	return a
+}
+
+// likeEscaper 转义 like 通配符, 避免 order_* 中的 _ 匹配任意字符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// serviceScopeCondition 按当前账号的服务范围过滤服务, 没有限制时返回 nil
+func serviceScopeCondition(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	admin := sessionAdmin(c)
+	if admin == nil || admin.ScopeAll() {
+		return nil
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		names := []string{}
+		likes := []string{}
+		args := []interface{}{}
+		for _, scope := range admin.Scopes() {
+			if prefix, ok := strings.CutSuffix(scope, "*"); ok {
+				likes = append(likes, `service_name like ? escape '\\'`)
+				args = append(args, likeEscaper.Replace(prefix)+"%")
+			} else {
+				names = append(names, scope)
+			}
+		}
+		conds := likes
+		if len(names) > 0 {
+			conds = append(conds, "service_name in ?")
+			args = append(args, names)
+		}
+		if len(conds) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where("("+strings.Join(conds, " or ")+")", args...)
+	}
+}
@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"gateway/enity"
+	"gateway/globals"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// TestServiceScopeCondition 服务范围中的 % 和 _ 按字面匹配, 不作为 like 通配符
+func TestServiceScopeCondition(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		admin *enity.Admin
+		sql   string
+		vars  []interface{}
+	}{
+		{&enity.Admin{Role: enity.AdminRoleOperator}, "SELECT * FROM `gateway_service_info`", nil},
+		{&enity.Admin{Role: enity.AdminRoleAdmin, ServiceScope: "order_*"}, "SELECT * FROM `gateway_service_info`", nil},
+		{&enity.Admin{Role: enity.AdminRoleOperator, ServiceScope: "order_*"},
+			"SELECT * FROM `gateway_service_info` WHERE (service_name like ? escape '\\\\')",
+			[]interface{}{`order\_%`}},
+		{&enity.Admin{Role: enity.AdminRoleOperator, ServiceScope: `100%_off\*`},
+			"SELECT * FROM `gateway_service_info` WHERE (service_name like ? escape '\\\\')",
+			[]interface{}{`100\%\_off\\%`}},
+		{&enity.Admin{Role: enity.AdminRoleViewer, ServiceScope: "order_*,pay_api"},
+			"SELECT * FROM `gateway_service_info` WHERE (service_name like ? escape '\\\\' or service_name in (?))",
+			[]interface{}{`order\_%`, "pay_api"}},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set(globals.AdminInfoKey, tc.admin)
+		query := db.Session(&gorm.Session{NewDB: true}).Model(&enity.ServiceInfo{})
+		if cond := serviceScopeCondition(c); cond != nil {
+			query = query.Scopes(cond)
+		}
+		stmt := query.Find(&[]enity.ServiceInfo{}).Statement
+		if sql := stmt.SQL.String(); sql != tc.sql {
+			t.Fatalf("scope %q: sql = %s, want %s", tc.admin.ServiceScope, sql, tc.sql)
+		}
+		if len(stmt.Vars) != len(tc.vars) || (len(tc.vars) > 0 && !reflect.DeepEqual(stmt.Vars, tc.vars)) {
+			t.Fatalf("scope %q: vars = %#v, want %#v", tc.admin.ServiceScope, stmt.Vars, tc.vars)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package logic
+
+import (
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/globals"
+	"gateway/pkg/database/mysql"
+	"gateway/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ApiTokenLogic interface {
+	ApiTokenList(c *gin.Context, params *dto.ApiTokenListInput) ([]dto.ApiTokenListItemOutput, int64, error)
+	ApiTokenAdd(c *gin.Context, params *dto.ApiTokenAddInput) (*dto.ApiTokenAddOutput, error)
+	ApiTokenRevoke(c *gin.Context, params *dto.ApiTokenRevokeInput) error
+}
+
+type apiTokenLogic struct {
+	token dao.ApiTokenService
+	db    *gorm.DB
+}
+
+func NewApiTokenLogic() *apiTokenLogic {
+	return &apiTokenLogic{
+		dao.NewApiTokenService(),
+		mysql.GetDB(),
+	}
+}
+
+// ApiTokenList 分页返回令牌, admin 角色可以看到全部令牌, 其他角色只能看到自己签发的令牌
+func (s *apiTokenLogic) ApiTokenList(c *gin.Context, params *dto.ApiTokenListInput) ([]dto.ApiTokenListItemOutput, int64, error) {
+	admin, err := tokenManager(c)
+	if err != nil {
+		return nil, 0, err
+	}
+	adminID := admin.ID
+	if admin.HasRole(enity.AdminRoleAdmin) {
+		adminID = 0
+	}
+	list, total, err := s.token.PageList(c, s.db, adminID, params.PageNo, params.PageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get api token list")
+	}
+
+	outputList := []dto.ApiTokenListItemOutput{}
+	for _, item := range list {
+		outputList = append(outputList, dto.ApiTokenListItemOutput{
+			ID:           item.ID,
+			AdminID:      item.AdminID,
+			Name:         item.Name,
+			TokenPrefix:  item.TokenPrefix,
+			Role:         item.Role,
+			ServiceScope: item.ServiceScope,
+			ExpireAt:     item.ExpireAt,
+			LastUsedAt:   item.LastUsedAt,
+			LastUsedIP:   item.LastUsedIP,
+			Revoked:      item.Revoked,
+			CreateAt:     item.CreateAt,
+		})
+	}
+	return outputList, total, nil
+}
+
+// ApiTokenAdd 签发令牌, 令牌的角色和服务范围不能超过签发人, 明文只在这里返回一次
+func (s *apiTokenLogic) ApiTokenAdd(c *gin.Context, params *dto.ApiTokenAddInput) (*dto.ApiTokenAddOutput, error) {
+	admin, err := tokenManager(c)
+	if err != nil {
+		return nil, err
+	}
+	if !admin.HasRole(params.Role) {
+		return nil, fmt.Errorf("token role %s is higher than your role %s", params.Role, admin.Role)
+	}
+	for _, item := range strings.Split(params.ServiceScope, ",") {
+		if item = strings.TrimSpace(item); item != "" && !admin.CoversScope(item) {
+			return nil, fmt.Errorf("service scope %s is out of your scope", item)
+		}
+	}
+
+	token, err := utils.GenApiToken(globals.ApiTokenPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate api token")
+	}
+	now := time.Now()
+	apiToken := &enity.ApiToken{
+		AdminID:      admin.ID,
+		Name:         params.Name,
+		TokenPrefix:  token[:len(globals.ApiTokenPrefix)+8],
+		TokenHash:    utils.HashApiToken(token),
+		Role:         params.Role,
+		ServiceScope: params.ServiceScope,
+		ExpireAt:     now.AddDate(0, 0, params.ExpireDays),
+		LastUsedAt:   time.Unix(0, 0),
+		CreateAt:     now,
+		UpdateAt:     now,
+	}
+
+	tx := s.db.Begin()
+	if err := s.token.Create(c, tx, apiToken); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to add api token")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceApiToken, enity.AuditActionAdd, apiToken.Name, nil, apiToken); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	tx.Commit()
+
+	return &dto.ApiTokenAddOutput{ID: apiToken.ID, Token: token, ExpireAt: apiToken.ExpireAt}, nil
+}
+
+// ApiTokenRevoke 吊销令牌, 签发人和 admin 角色可以吊销
+func (s *apiTokenLogic) ApiTokenRevoke(c *gin.Context, params *dto.ApiTokenRevokeInput) error {
+	admin, err := tokenManager(c)
+	if err != nil {
+		return err
+	}
+	apiToken, err := s.token.Get(c, s.db, params.ID)
+	if err != nil {
+		return fmt.Errorf("api token not found")
+	}
+	if apiToken.AdminID != admin.ID && !admin.HasRole(enity.AdminRoleAdmin) {
+		return fmt.Errorf("api token not found")
+	}
+	if apiToken.Revoked == 1 {
+		return nil
+	}
+
+	before := auditSnapshot(apiToken)
+	apiToken.Revoked = 1
+	tx := s.db.Begin()
+	if err := s.token.Revoke(c, tx, apiToken.ID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to revoke api token")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceApiToken, enity.AuditActionDelete, apiToken.Name, before, apiToken); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return nil
+}
+
+// tokenManager 返回当前账号, 令牌只能通过登录后的 session 管理, 避免令牌自我续期或提权
+func tokenManager(c *gin.Context) (*enity.Admin, error) {
+	if _, ok := c.Get(globals.ApiTokenKey); ok {
+		return nil, fmt.Errorf("api tokens cannot be managed with an api token")
+	}
+	admin := sessionAdmin(c)
+	if admin == nil {
+		return nil, fmt.Errorf("user not login")
+	}
+	return admin, nil
+}
@@ -0,0 +1,63 @@
+package logic
+
+import (
+	"gateway/backend/dto"
+	"gateway/enity"
+	"gateway/globals"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestApiTokenAddRejected 令牌的角色和服务范围不能超过签发人, 也不能用令牌签发令牌, 拒绝时不访问数据库
+func TestApiTokenAddRejected(t *testing.T) {
+	cases := []struct {
+		name   string
+		admin  *enity.Admin
+		token  *enity.ApiToken
+		params dto.ApiTokenAddInput
+		err    string
+	}{
+		{"not login", nil, nil,
+			dto.ApiTokenAddInput{Role: enity.AdminRoleViewer}, "not login"},
+		{"role above issuer", &enity.Admin{Role: enity.AdminRoleViewer}, nil,
+			dto.ApiTokenAddInput{Role: enity.AdminRoleOperator}, "higher than your role"},
+		{"scope outside issuer", &enity.Admin{Role: enity.AdminRoleOperator, ServiceScope: "order_*"}, nil,
+			dto.ApiTokenAddInput{Role: enity.AdminRoleOperator, ServiceScope: "order_api,pay_*"}, "pay_* is out of your scope"},
+		{"prefix wider than issuer", &enity.Admin{Role: enity.AdminRoleOperator, ServiceScope: "order_*"}, nil,
+			dto.ApiTokenAddInput{Role: enity.AdminRoleOperator, ServiceScope: "order*"}, "out of your scope"},
+		{"token minting a token", &enity.Admin{Role: enity.AdminRoleAdmin}, &enity.ApiToken{Role: enity.AdminRoleOperator},
+			dto.ApiTokenAddInput{Role: enity.AdminRoleViewer}, "cannot be managed with an api token"},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		if tc.admin != nil {
+			c.Set(globals.AdminInfoKey, tc.admin)
+		}
+		if tc.token != nil {
+			c.Set(globals.ApiTokenKey, tc.token)
+		}
+		s := &apiTokenLogic{}
+		params := tc.params
+		if _, err := s.ApiTokenAdd(c, &params); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: err = %v, want %q", tc.name, err, tc.err)
+		}
+	}
+}
+
+// TestTokenManager 令牌认证的请求不能列出、签发或吊销令牌
+func TestTokenManager(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	admin := &enity.Admin{ID: 1, Role: enity.AdminRoleOperator}
+	c.Set(globals.AdminInfoKey, admin)
+	if got, err := tokenManager(c); err != nil || got != admin {
+		t.Fatalf("session admin: got %v, %v", got, err)
+	}
+
+	c.Set(globals.ApiTokenKey, &enity.ApiToken{AdminID: 1, Role: enity.AdminRoleOperator})
+	if _, err := tokenManager(c); err == nil {
+		t.Fatal("a request authenticated with an api token should not manage api tokens")
+	}
+}
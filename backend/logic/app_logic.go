@@ -9,7 +9,6 @@ import (
 	"gateway/pkg/database/mysql"
 	"gateway/pkg/log"
 	"gateway/utils"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -103,11 +102,19 @@ func (al *appLogic) AppDelete(c *gin.Context, params *dto.APPDetailInput) error
 	if err != nil {
 		return fmt.Errorf("app not found")
 	}
+	before := auditSnapshot(info)
 	// 将应用程序标记为已删除
 	info.IsDelete = 1
-	if err := al.Save(c, al.db, info); err != nil {
+	tx := al.db.Begin()
+	if err := al.Save(c, tx, info); err != nil {
+		tx.Rollback()
 		return fmt.Errorf("failed to delete app")
 	}
+	if err := recordAudit(c, tx, enity.AuditResourceApp, enity.AuditActionDelete, info.AppID, before, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
 
 	// Publish data change message
 	message := &globals.DataChangeMessage{
@@ -147,9 +154,16 @@ func (al *appLogic) AppAdd(c *gin.Context, params *dto.APPAddHttpInput) error {
 		Qps:      params.Qps,
 	}
 	// 保存应用程序对象
-	if err := al.Save(c, al.db, app); err != nil {
+	tx := al.db.Begin()
+	if err := al.Save(c, tx, app); err != nil {
+		tx.Rollback()
 		return fmt.Errorf("failed to add app")
 	}
+	if err := recordAudit(c, tx, enity.AuditResourceApp, enity.AuditActionAdd, app.AppID, nil, app); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
 	// Publish data change message
 	message := &globals.DataChangeMessage{
 		Type:      "service",
@@ -175,14 +189,22 @@ func (al *appLogic) AppUpdate(c *gin.Context, params *dto.APPUpdateHttpInput) er
 	if err != nil {
 		return fmt.Errorf("app not found")
 	}
+	before := auditSnapshot(info)
 	// 更新应用程序信息
 	info.Name = params.Name
 	info.WhiteIPS = params.WhiteIPS
 	info.Qpd = params.Qpd
 	info.Qps = params.Qps
-	if err := al.Save(c, al.db, info); err != nil {
+	tx := al.db.Begin()
+	if err := al.Save(c, tx, info); err != nil {
+		tx.Rollback()
 		return fmt.Errorf("failed to Save app information")
 	}
+	if err := recordAudit(c, tx, enity.AuditResourceApp, enity.AuditActionUpdate, info.AppID, before, info); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
 
 	// Publish data change message
 	message := &globals.DataChangeMessage{
@@ -209,24 +231,9 @@ func (al *appLogic) AppStat(c *gin.Context, params *dto.APPDetailInput) (*dto.St
 	if err != nil {
 		return nil, fmt.Errorf("app not found")
 	}
-	counter, err := globals.FlowCounter.GetCounter(detail.AppID)
+	todayList, yesterdayList, err := todayAndYesterday(detail.AppID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get app flow counter")
-	}
-	todayList := []int64{}
-	currentTime := time.Now()
-	for i := 0; i <= currentTime.Hour(); i++ {
-		dateTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), i, 0, 0, 0, time.UTC)
-		hourData, _ := counter.GetHourData(dateTime)
-		todayList = append(todayList, hourData)
-	}
-
-	yesterdayList := []int64{}
-	yesterTime := currentTime.Add(-1 * time.Duration(time.Hour*24))
-	for i := 0; i <= 23; i++ {
-		dateTime := time.Date(yesterTime.Year(), yesterTime.Month(), yesterTime.Day(), i, 0, 0, 0, time.UTC)
-		hourData, _ := counter.GetHourData(dateTime)
-		yesterdayList = append(yesterdayList, hourData)
+		return nil, fmt.Errorf("failed to get app flow stat: %v", err)
 	}
 
 	out := &dto.StatisticsOutput{
@@ -0,0 +1,219 @@
+package logic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/pkg/database/mysql"
+	"gateway/pkg/log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// auditSecretFields 审计日志中不保存明文的字段
+var auditSecretFields = map[string]bool{
+	"password":   true,
+	"salt":       true,
+	"secret":     true,
+	"token_hash": true,
+}
+
+type AuditLogic interface {
+	AuditList(c *gin.Context, params *dto.AuditListInput) ([]dto.AuditListItemOutput, int64, error)
+}
+
+type auditLogic struct {
+	audit dao.AuditLogService
+	db    *gorm.DB
+}
+
+func NewAuditLogic() *auditLogic {
+	return &auditLogic{
+		dao.NewAuditLogService(),
+		mysql.GetDB(),
+	}
+}
+
+// AuditList 分页查询审计日志
+func (s *auditLogic) AuditList(c *gin.Context, params *dto.AuditListInput) ([]dto.AuditListItemOutput, int64, error) {
+	query := &dao.AuditLogQuery{
+		AdminName: params.AdminName,
+		Resource:  params.Resource,
+		Action:    params.Action,
+		Target:    params.Target,
+		TraceID:   params.TraceID,
+	}
+	if params.From > 0 {
+		query.From = time.Unix(params.From, 0)
+	}
+	if params.To > 0 {
+		query.To = time.Unix(params.To, 0)
+	}
+	list, total, err := s.audit.PageList(c, s.db, query, params.PageNo, params.PageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get audit log list")
+	}
+
+	outputList := []dto.AuditListItemOutput{}
+	for _, item := range list {
+		outputList = append(outputList, dto.AuditListItemOutput{
+			ID:        item.ID,
+			AdminID:   item.AdminID,
+			AdminName: item.AdminName,
+			Resource:  item.Resource,
+			Action:    item.Action,
+			Target:    item.Target,
+			TraceID:   item.TraceID,
+			ClientIP:  item.ClientIP,
+			Before:    rawJSON(item.Before),
+			After:     rawJSON(item.After),
+			Diff:      rawJSON(item.Diff),
+			CreatedAt: item.CreatedAt,
+		})
+	}
+	return outputList, total, nil
+}
+
+// auditSnapshot 将对象转换为 json map, 修改对象前调用以保留旧值
+func auditSnapshot(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(bts, &out); err != nil {
+		return nil
+	}
+	maskAuditSecrets(out)
+	return out
+}
+
+// recordAudit 记录一次变更, 与变更使用同一个 db/事务写入, 保证有变更就有审计记录
+func recordAudit(c *gin.Context, db *gorm.DB, resource, action, target string, before, after interface{}) error {
+	beforeMap := auditSnapshot(before)
+	afterMap := auditSnapshot(after)
+
+	entry := &enity.AuditLog{
+		Resource:  resource,
+		Action:    action,
+		Target:    target,
+		TraceID:   c.GetString("TraceID"),
+		Before:    marshalAudit(beforeMap),
+		After:     marshalAudit(afterMap),
+		Diff:      marshalAudit(auditDiff(beforeMap, afterMap)),
+		CreatedAt: time.Now(),
+	}
+	if c.Request != nil {
+		entry.ClientIP = c.ClientIP()
+	}
+	if admin := sessionAdmin(c); admin != nil {
+		entry.AdminID = admin.ID
+		entry.AdminName = admin.UserName
+	}
+
+	if err := dao.NewAuditLogService().Create(c, db, entry); err != nil {
+		log.Error("failed to record audit log", zap.String("resource", resource), zap.String("target", target), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return fmt.Errorf("failed to record audit log")
+	}
+	return nil
+}
+
+type auditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// auditDiff 对比变更前后的数据, 嵌套字段用 . 连接, 只返回有变化的字段
+func auditDiff(before, after map[string]interface{}) map[string]auditChange {
+	flatBefore := map[string]interface{}{}
+	flatAfter := map[string]interface{}{}
+	flattenAudit("", before, flatBefore)
+	flattenAudit("", after, flatAfter)
+
+	keys := map[string]struct{}{}
+	for k := range flatBefore {
+		keys[k] = struct{}{}
+	}
+	for k := range flatAfter {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	diff := map[string]auditChange{}
+	for _, k := range sorted {
+		b, a := flatBefore[k], flatAfter[k]
+		if !reflect.DeepEqual(b, a) {
+			diff[k] = auditChange{Before: b, After: a}
+		}
+	}
+	return diff
+}
+
+func flattenAudit(prefix string, data map[string]interface{}, out map[string]interface{}) {
+	for k, v := range data {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok {
+			flattenAudit(key, child, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// maskAuditSecrets 敏感字段只保存摘要, 仍然可以看出是否发生了变化
+func maskAuditSecrets(data map[string]interface{}) {
+	for k, v := range data {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			maskAuditSecrets(val)
+		case string:
+			if auditSecretFields[k] && val != "" {
+				sum := sha256.Sum256([]byte(val))
+				data[k] = "******" + hex.EncodeToString(sum[:])[:8]
+			}
+		}
+	}
+}
+
+func marshalAudit(v interface{}) string {
+	if rv := reflect.ValueOf(v); !rv.IsValid() || (rv.Kind() == reflect.Map && rv.IsNil()) {
+		return "null"
+	}
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(bts)
+}
+
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(s)
+}
@@ -0,0 +1,712 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/globals"
+	"gateway/pkg/database/mysql"
+	"gateway/pkg/log"
+	"gateway/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// 配置文档格式
+const (
+	ConfigFormatYAML = "yaml"
+	ConfigFormatJSON = "json"
+)
+
+// configVolatileFields 与环境相关的字段, 导出和对比时忽略
+var configVolatileFields = map[string]bool{
+	"id":         true,
+	"service_id": true,
+	"create_at":  true,
+	"update_at":  true,
+	"is_delete":  true,
+}
+
+type ConfigLogic interface {
+	ExportConfig(c *gin.Context, params *dto.ConfigExportInput) ([]byte, error)
+	ImportConfig(c *gin.Context, params *dto.ConfigImportInput) (*dto.ConfigImportOutput, error)
+}
+
+type configLogic struct {
+	serviceStore
+	app dao.APP
+	db  *gorm.DB
+}
+
+func NewConfigLogic() *configLogic {
+	return &configLogic{
+		newServiceStore(),
+		dao.NewApp(),
+		mysql.GetDB(),
+	}
+}
+
+// servicePlan 导入时单个服务的变更
+type servicePlan struct {
+	action  string
+	current *enity.ServiceDetail
+	target  *enity.ServiceDetail
+}
+
+// appPlan 导入时单个租户的变更
+type appPlan struct {
+	action  string
+	current *enity.App
+	target  *enity.App
+}
+
+// ExportConfig 导出当前账号服务范围内的全部服务和租户
+func (s *configLogic) ExportConfig(c *gin.Context, params *dto.ConfigExportInput) ([]byte, error) {
+	doc, err := s.currentDocument(c)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeConfigDocument(doc, params.Format)
+}
+
+// ImportConfig 校验配置文档并计算变更, 非 dry run 时在一个事务中写入全部变更
+func (s *configLogic) ImportConfig(c *gin.Context, params *dto.ConfigImportInput) (*dto.ConfigImportOutput, error) {
+	doc, err := DecodeConfigDocument([]byte(params.Content), params.Format)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateDocument(c, doc); err != nil {
+		return nil, err
+	}
+
+	current, err := s.currentDocument(c)
+	if err != nil {
+		return nil, err
+	}
+	services, apps, err := s.plan(c, current, doc, params.Prune == 1)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.ConfigImportOutput{DryRun: params.DryRun == 1, Changes: []dto.ConfigChange{}}
+	for _, p := range services {
+		name := p.target.Info.ServiceName
+		out.Changes = append(out.Changes, configChange(enity.AuditResourceService, name, p.action, p.current, p.target))
+	}
+	for _, p := range apps {
+		out.Changes = append(out.Changes, configChange(enity.AuditResourceApp, p.target.AppID, p.action, p.current, p.target))
+	}
+	if out.DryRun || len(out.Changes) == 0 {
+		return out, nil
+	}
+
+	if err := s.apply(c, services, apps); err != nil {
+		return nil, err
+	}
+	s.publish(c, services, apps)
+	return out, nil
+}
+
+// currentDocument 读取数据库中的配置, 按名称排序保证导出结果稳定
+func (s *configLogic) currentDocument(c *gin.Context) (*dto.ConfigDocument, error) {
+	conditions := []func(db *gorm.DB) *gorm.DB{}
+	if scope := serviceScopeCondition(c); scope != nil {
+		conditions = append(conditions, scope)
+	}
+	infos, err := s.info.GetAll(c, s.db, conditions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service list")
+	}
+	doc := &dto.ConfigDocument{Version: dto.ConfigDocumentVersion}
+	for i := range infos {
+		detail, err := s.info.GetServiceDetail(c, s.db, &infos[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get service detail of %s", infos[i].ServiceName)
+		}
+		doc.Services = append(doc.Services, detail)
+	}
+	sort.Slice(doc.Services, func(i, j int) bool {
+		return doc.Services[i].Info.ServiceName < doc.Services[j].Info.ServiceName
+	})
+
+	apps, err := s.app.GetAll(c, s.db, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get app list")
+	}
+	for i := range apps {
+		doc.Apps = append(doc.Apps, &apps[i])
+	}
+	sort.Slice(doc.Apps, func(i, j int) bool {
+		return doc.Apps[i].AppID < doc.Apps[j].AppID
+	})
+	return doc, nil
+}
+
+// validateDocument 使用与新增接口相同的校验规则检查文档中的每一项, 返回全部错误
+func (s *configLogic) validateDocument(c *gin.Context, doc *dto.ConfigDocument) error {
+	errs := []string{}
+	admin := sessionAdmin(c)
+
+	names := map[string]bool{}
+	valid := []*enity.ServiceDetail{}
+	for i, detail := range doc.Services {
+		if detail == nil || detail.Info == nil {
+			errs = append(errs, fmt.Sprintf("services[%d]: info is required", i))
+			continue
+		}
+		name := detail.Info.ServiceName
+		if names[name] {
+			errs = append(errs, fmt.Sprintf("service %s: duplicated", name))
+			continue
+		}
+		names[name] = true
+		if admin != nil && !admin.CanAccessService(name) {
+			errs = append(errs, fmt.Sprintf("service %s: out of your scope", name))
+			continue
+		}
+		if err := validateServiceDetail(c, detail); err != nil {
+			errs = append(errs, fmt.Sprintf("service %s: %v", name, err))
+			continue
+		}
+		valid = append(valid, detail)
+	}
+	errs = append(errs, documentRuleConflicts(valid)...)
+
+	appIDs := map[string]bool{}
+	for i, app := range doc.Apps {
+		if app == nil {
+			errs = append(errs, fmt.Sprintf("apps[%d]: empty", i))
+			continue
+		}
+		if appIDs[app.AppID] {
+			errs = append(errs, fmt.Sprintf("app %s: duplicated", app.AppID))
+			continue
+		}
+		appIDs[app.AppID] = true
+		input := &dto.APPAddHttpInput{
+			AppID:    app.AppID,
+			Name:     app.Name,
+			Secret:   app.Secret,
+			WhiteIPS: app.WhiteIPS,
+			Qpd:      app.Qpd,
+			Qps:      app.Qps,
+		}
+		if err := utils.ValidStruct(c, input); err != nil {
+			errs = append(errs, fmt.Sprintf("app %s: %v", app.AppID, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// documentRuleConflicts 检查文档中的服务之间是否使用了相同的端口或接入规则
+// 与数据库中其他服务的冲突在写入时检查
+func documentRuleConflicts(services []*enity.ServiceDetail) []string {
+	errs := []string{}
+	owners := map[string]string{}
+	for _, detail := range services {
+		name := detail.Info.ServiceName
+		for _, rule := range serviceRules(detail) {
+			key := serviceRuleKey(rule)
+			if owner, ok := owners[key]; ok && owner != name {
+				errs = append(errs, fmt.Sprintf("service %s: %v", name, ruleConflictError(detail, owner)))
+				break
+			}
+			owners[key] = name
+		}
+	}
+	return errs
+}
+
+// plan 对比数据库与文档, 得到需要新增、修改、删除的服务和租户
+// 当前配置按服务范围查询得到, 修改和删除前仍按账号的服务范围逐个确认
+func (s *configLogic) plan(c *gin.Context, current, doc *dto.ConfigDocument, prune bool) ([]servicePlan, []appPlan, error) {
+	admin := sessionAdmin(c)
+	services := []servicePlan{}
+	currentServices := map[string]*enity.ServiceDetail{}
+	for _, detail := range current.Services {
+		currentServices[detail.Info.ServiceName] = detail
+	}
+	desiredServices := map[string]bool{}
+	for _, target := range doc.Services {
+		name := target.Info.ServiceName
+		desiredServices[name] = true
+		cur, ok := currentServices[name]
+		if !ok {
+			// 不在当前范围内的同名服务(包括已删除的)同样视为冲突
+			if _, err := s.info.Get(c, s.db, &enity.ServiceInfo{ServiceName: name}); err != gorm.ErrRecordNotFound {
+				return nil, nil, fmt.Errorf("service %s: name already exists", name)
+			}
+			services = append(services, servicePlan{action: enity.AuditActionAdd, target: target})
+			continue
+		}
+		if admin != nil && !admin.CanAccessService(cur.Info.ServiceName) {
+			return nil, nil, fmt.Errorf("service %s: out of your scope", name)
+		}
+		if cur.Info.LoadType != target.Info.LoadType {
+			return nil, nil, fmt.Errorf("service %s: load type cannot be changed", name)
+		}
+		if len(configDiff(cur, target)) > 0 {
+			services = append(services, servicePlan{action: enity.AuditActionUpdate, current: cur, target: target})
+		}
+	}
+	if prune {
+		for _, cur := range current.Services {
+			// 不在服务范围内的服务不参与清理
+			if admin != nil && !admin.CanAccessService(cur.Info.ServiceName) {
+				continue
+			}
+			if !desiredServices[cur.Info.ServiceName] {
+				services = append(services, servicePlan{action: enity.AuditActionDelete, current: cur, target: cur})
+			}
+		}
+	}
+
+	apps := []appPlan{}
+	currentApps := map[string]*enity.App{}
+	for _, app := range current.Apps {
+		currentApps[app.AppID] = app
+	}
+	desiredApps := map[string]bool{}
+	for _, target := range doc.Apps {
+		desiredApps[target.AppID] = true
+		cur, ok := currentApps[target.AppID]
+		if !ok {
+			if target.Secret == "" {
+				target.Secret, _ = utils.HashPassword(target.AppID)
+			}
+			apps = append(apps, appPlan{action: enity.AuditActionAdd, target: target})
+			continue
+		}
+		// 文档中没有密钥时沿用当前的密钥
+		if target.Secret == "" {
+			target.Secret = cur.Secret
+		}
+		if len(configDiff(cur, target)) > 0 {
+			apps = append(apps, appPlan{action: enity.AuditActionUpdate, current: cur, target: target})
+		}
+	}
+	if prune {
+		for _, cur := range current.Apps {
+			if !desiredApps[cur.AppID] {
+				apps = append(apps, appPlan{action: enity.AuditActionDelete, current: cur, target: cur})
+			}
+		}
+	}
+	return services, apps, nil
+}
+
+// apply 在一个事务中写入全部变更, 任意一项失败时全部回滚
+func (s *configLogic) apply(c *gin.Context, services []servicePlan, apps []appPlan) error {
+	tx := s.db.Begin()
+	saved := []*enity.ServiceDetail{}
+	for _, p := range services {
+		name := p.target.Info.ServiceName
+		before := auditSnapshot(p.current)
+		if p.action == enity.AuditActionDelete {
+			info := *p.current.Info
+			info.IsDelete = 1
+			info.UpdateAt = time.Now()
+			if err := s.info.Save(c, tx, &info); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("service %s: failed to delete", name)
+			}
+			if err := recordAudit(c, tx, enity.AuditResourceService, p.action, name, before, nil); err != nil {
+				tx.Rollback()
+				return err
+			}
+			continue
+		}
+
+		detail, err := s.saveDetail(c, tx, p.current, p.target)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("service %s: %v", name, err)
+		}
+		action := enity.ServiceRevisionUpdate
+		if p.action == enity.AuditActionAdd {
+			action = enity.ServiceRevisionAdd
+		}
+		if err := recordAudit(c, tx, enity.AuditResourceService, p.action, name, before, detail); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := recordRevision(c, tx, action, detail, "config import"); err != nil {
+			tx.Rollback()
+			return err
+		}
+		saved = append(saved, detail)
+	}
+	// 全部服务写入后再检查端口和接入规则, 文档中的服务互换端口或前缀时不会误报
+	for _, detail := range saved {
+		if err := s.checkServiceRules(c, tx, detail.Info.ID, detail); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("service %s: %v", detail.Info.ServiceName, err)
+		}
+	}
+
+	for _, p := range apps {
+		before := auditSnapshot(p.current)
+		app := &enity.App{}
+		if p.current != nil {
+			*app = *p.current
+		} else {
+			app.CreatedAt = time.Now()
+		}
+		app.UpdatedAt = time.Now()
+		var after interface{} = app
+		if p.action == enity.AuditActionDelete {
+			app.IsDelete = 1
+			after = nil
+		} else {
+			app.AppID = p.target.AppID
+			app.Name = p.target.Name
+			app.Secret = p.target.Secret
+			app.WhiteIPS = p.target.WhiteIPS
+			app.Qpd = p.target.Qpd
+			app.Qps = p.target.Qps
+		}
+		if err := s.app.Save(c, tx, app); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("app %s: failed to save", p.target.AppID)
+		}
+		if err := recordAudit(c, tx, enity.AuditResourceApp, p.action, app.AppID, before, after); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Error("failed to commit config import", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return fmt.Errorf("failed to commit config import")
+	}
+	return nil
+}
+
+// publish 通知代理重新加载变更的服务和租户, 配置已经写入, 发布失败只记录日志
+func (s *configLogic) publish(c *gin.Context, services []servicePlan, apps []appPlan) {
+	operations := map[string]string{
+		enity.AuditActionAdd:    globals.DataInsert,
+		enity.AuditActionUpdate: globals.DataUpdate,
+		enity.AuditActionDelete: globals.DataDelete,
+	}
+	messages := []*globals.DataChangeMessage{}
+	for _, p := range services {
+		messages = append(messages, &globals.DataChangeMessage{
+			Type:        "service",
+			Payload:     p.target.Info.ServiceName,
+			ServiceType: p.target.Info.LoadType,
+			Operation:   operations[p.action],
+		})
+	}
+	for _, p := range apps {
+		messages = append(messages, &globals.DataChangeMessage{
+			Type:      "app",
+			Payload:   p.target.AppID,
+			Operation: operations[p.action],
+		})
+	}
+	for _, message := range messages {
+		if err := globals.MessageQueue.Publish(globals.DataChange, message); err != nil {
+			log.Error("error publishing message", zap.Any("message", message), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		}
+	}
+}
+
+// validateServiceDetail 将服务配置转换为对应类型的新增参数, 复用新增接口的校验规则
+func validateServiceDetail(c *gin.Context, detail *enity.ServiceDetail) error {
+	if _, ok := globals.LoadTypeMap[detail.Info.LoadType]; !ok {
+		return fmt.Errorf("unsupported load type %d", detail.Info.LoadType)
+	}
+	fillServiceDetail(detail)
+	info, lb, ac := detail.Info, detail.LoadBalance, detail.AccessControl
+
+	var input interface{}
+	switch info.LoadType {
+	case globals.LoadTypeHTTP:
+		rule := detail.HTTPRule
+		input = &dto.ServiceAddHTTPInput{
+			ServiceName:            info.ServiceName,
+			ServiceDesc:            info.ServiceDesc,
+			RuleType:               rule.RuleType,
+			Rule:                   rule.Rule,
+			NeedHttps:              rule.NeedHttps,
+			NeedStripUri:           rule.NeedStripUri,
+			NeedWebsocket:          rule.NeedWebsocket,
+			NeedLogBody:            rule.NeedLogBody,
+			UrlRewrite:             rule.UrlRewrite,
+			HeaderTransfor:         rule.HeaderTransfor,
+			OpenAuth:               ac.OpenAuth,
+			BlackList:              ac.BlackList,
+			WhiteList:              ac.WhiteList,
+			ClientipFlowLimit:      ac.ClientIPFlowLimit,
+			ServiceFlowLimit:       ac.ServiceFlowLimit,
+			RoundType:              lb.RoundType,
+			HashKey:                lb.HashKey,
+			HashReplicas:           lb.HashReplicas,
+			HashLoadFactor:         lb.HashLoadFactor,
+			SlowStart:              lb.SlowStart,
+			IpList:                 lb.IpList,
+			WeightList:             lb.WeightList,
+			PriorityList:           lb.PriorityList,
+			FailoverThreshold:      lb.FailoverThreshold,
+			DiscoveryType:          lb.DiscoveryType,
+			DiscoveryTarget:        lb.DiscoveryTarget,
+			UpstreamID:             lb.UpstreamID,
+			UpstreamConnectTimeout: lb.UpstreamConnectTimeout,
+			UpstreamHeaderTimeout:  lb.UpstreamHeaderTimeout,
+			UpstreamIdleTimeout:    lb.UpstreamIdleTimeout,
+			UpstreamMaxIdle:        lb.UpstreamMaxIdle,
+		}
+	case globals.LoadTypeTCP:
+		rule := detail.TCPRule
+		if err := checkTcpListenRule(rule.Port, rule.NeedTls, rule.CertFile, rule.KeyFile, rule.SniHost); err != nil {
+			return err
+		}
+		input = &dto.ServiceAddTcpInput{
+			ServiceName:       info.ServiceName,
+			ServiceDesc:       info.ServiceDesc,
+			Port:              rule.Port,
+			IdleTimeout:       rule.IdleTimeout,
+			MaxConns:          rule.MaxConns,
+			NeedTls:           rule.NeedTls,
+			CertFile:          rule.CertFile,
+			KeyFile:           rule.KeyFile,
+			SniHost:           rule.SniHost,
+			OpenAuth:          ac.OpenAuth,
+			BlackList:         ac.BlackList,
+			WhiteList:         ac.WhiteList,
+			WhiteHostName:     ac.WhiteHostName,
+			ClientIPFlowLimit: ac.ClientIPFlowLimit,
+			ServiceFlowLimit:  ac.ServiceFlowLimit,
+			RoundType:         lb.RoundType,
+			HashKey:           lb.HashKey,
+			HashReplicas:      lb.HashReplicas,
+			HashLoadFactor:    lb.HashLoadFactor,
+			SlowStart:         lb.SlowStart,
+			IpList:            lb.IpList,
+			WeightList:        lb.WeightList,
+			PriorityList:      lb.PriorityList,
+			FailoverThreshold: lb.FailoverThreshold,
+			DiscoveryType:     lb.DiscoveryType,
+			DiscoveryTarget:   lb.DiscoveryTarget,
+			UpstreamID:        lb.UpstreamID,
+			ForbidList:        lb.ForbidList,
+		}
+	case globals.LoadTypeGRPC:
+		rule := detail.GRPCRule
+		input = &dto.ServiceAddGrpcInput{
+			ServiceName:       info.ServiceName,
+			ServiceDesc:       info.ServiceDesc,
+			Port:              rule.Port,
+			HeaderTransfor:    rule.HeaderTransfor,
+			MethodRoute:       rule.MethodRoute,
+			MethodLimit:       rule.MethodLimit,
+			MethodAllow:       rule.MethodAllow,
+			MethodDeny:        rule.MethodDeny,
+			NeedGrpcWeb:       rule.NeedGrpcWeb,
+			GrpcWebOrigins:    rule.GrpcWebOrigins,
+			OpenAuth:          ac.OpenAuth,
+			BlackList:         ac.BlackList,
+			WhiteList:         ac.WhiteList,
+			WhiteHostName:     ac.WhiteHostName,
+			ClientIPFlowLimit: ac.ClientIPFlowLimit,
+			ServiceFlowLimit:  ac.ServiceFlowLimit,
+			RoundType:         lb.RoundType,
+			HashKey:           lb.HashKey,
+			HashReplicas:      lb.HashReplicas,
+			HashLoadFactor:    lb.HashLoadFactor,
+			SlowStart:         lb.SlowStart,
+			IpList:            lb.IpList,
+			WeightList:        lb.WeightList,
+			PriorityList:      lb.PriorityList,
+			FailoverThreshold: lb.FailoverThreshold,
+			DiscoveryType:     lb.DiscoveryType,
+			DiscoveryTarget:   lb.DiscoveryTarget,
+			UpstreamID:        lb.UpstreamID,
+			ForbidList:        lb.ForbidList,
+		}
+	case globals.LoadTypeUDP:
+		rule := detail.UDPRule
+		input = &dto.ServiceAddUdpInput{
+			ServiceName:       info.ServiceName,
+			ServiceDesc:       info.ServiceDesc,
+			Port:              rule.Port,
+			SessionTimeout:    rule.SessionTimeout,
+			OpenAuth:          ac.OpenAuth,
+			BlackList:         ac.BlackList,
+			WhiteList:         ac.WhiteList,
+			WhiteHostName:     ac.WhiteHostName,
+			ClientIPFlowLimit: ac.ClientIPFlowLimit,
+			ServiceFlowLimit:  ac.ServiceFlowLimit,
+			RoundType:         lb.RoundType,
+			HashKey:           lb.HashKey,
+			HashReplicas:      lb.HashReplicas,
+			HashLoadFactor:    lb.HashLoadFactor,
+			SlowStart:         lb.SlowStart,
+			IpList:            lb.IpList,
+			WeightList:        lb.WeightList,
+			PriorityList:      lb.PriorityList,
+			FailoverThreshold: lb.FailoverThreshold,
+			DiscoveryType:     lb.DiscoveryType,
+			DiscoveryTarget:   lb.DiscoveryTarget,
+			UpstreamID:        lb.UpstreamID,
+			ForbidList:        lb.ForbidList,
+		}
+	}
+	if err := utils.ValidStruct(c, input); err != nil {
+		return err
+	}
+	return checkServiceUpstream(c, mysql.GetDB(), lb.UpstreamID, lb.IpList, lb.WeightList, lb.PriorityList, lb.DiscoveryType, lb.DiscoveryTarget)
+}
+
+// fillServiceDetail 补齐文档中省略的部分, 并去掉与服务类型无关的规则
+func fillServiceDetail(detail *enity.ServiceDetail) {
+	if detail.LoadBalance == nil {
+		detail.LoadBalance = &enity.LoadBalance{}
+	}
+	if detail.AccessControl == nil {
+		detail.AccessControl = &enity.AccessControl{}
+	}
+	httpRule, tcpRule, grpcRule, udpRule := detail.HTTPRule, detail.TCPRule, detail.GRPCRule, detail.UDPRule
+	detail.HTTPRule, detail.TCPRule, detail.GRPCRule, detail.UDPRule = nil, nil, nil, nil
+	switch detail.Info.LoadType {
+	case globals.LoadTypeHTTP:
+		if detail.HTTPRule = httpRule; httpRule == nil {
+			detail.HTTPRule = &enity.HttpRule{}
+		}
+	case globals.LoadTypeTCP:
+		if detail.TCPRule = tcpRule; tcpRule == nil {
+			detail.TCPRule = &enity.TcpRule{}
+		}
+	case globals.LoadTypeGRPC:
+		if detail.GRPCRule = grpcRule; grpcRule == nil {
+			detail.GRPCRule = &enity.GrpcRule{}
+		}
+	case globals.LoadTypeUDP:
+		if detail.UDPRule = udpRule; udpRule == nil {
+			detail.UDPRule = &enity.UdpRule{}
+		}
+	}
+}
+
+// configDiff 忽略主键、时间等字段后对比两份配置
+func configDiff(current, target interface{}) []dto.ConfigChangeField {
+	before, _ := stripConfigFields(auditSnapshot(current)).(map[string]interface{})
+	after, _ := stripConfigFields(auditSnapshot(target)).(map[string]interface{})
+	changes := auditDiff(before, after)
+
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	out := []dto.ConfigChangeField{}
+	for _, field := range fields {
+		out = append(out, dto.ConfigChangeField{Field: field, Before: changes[field].Before, After: changes[field].After})
+	}
+	return out
+}
+
+func configChange(resource, name, action string, current, target interface{}) dto.ConfigChange {
+	change := dto.ConfigChange{Resource: resource, Name: name, Action: action}
+	switch action {
+	case enity.AuditActionAdd:
+		change.Diff = configDiff(nil, target)
+	case enity.AuditActionDelete:
+		change.Diff = configDiff(current, nil)
+	default:
+		change.Diff = configDiff(current, target)
+	}
+	return change
+}
+
+// EncodeConfigDocument 将配置文档编码为 yaml/json, 字段名与接口的 json 字段一致
+func EncodeConfigDocument(doc *dto.ConfigDocument, format string) ([]byte, error) {
+	bts, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(bts, &generic); err != nil {
+		return nil, err
+	}
+	generic = stripConfigFields(generic)
+
+	switch format {
+	case ConfigFormatJSON:
+		return json.MarshalIndent(generic, "", "  ")
+	case ConfigFormatYAML:
+		return yaml.Marshal(generic)
+	default:
+		return nil, fmt.Errorf("unsupported config format %s", format)
+	}
+}
+
+// DecodeConfigDocument 解析 yaml/json 配置文档, 不认识的字段视为错误, 避免拼写错误被静默忽略
+func DecodeConfigDocument(content []byte, format string) (*dto.ConfigDocument, error) {
+	var generic interface{}
+	switch format {
+	case ConfigFormatJSON:
+		if err := json.Unmarshal(content, &generic); err != nil {
+			return nil, fmt.Errorf("invalid json config: %v", err)
+		}
+	case ConfigFormatYAML:
+		if err := yaml.Unmarshal(content, &generic); err != nil {
+			return nil, fmt.Errorf("invalid yaml config: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %s", format)
+	}
+
+	bts, err := json.Marshal(generic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(bts))
+	decoder.DisallowUnknownFields()
+	doc := &dto.ConfigDocument{}
+	if err := decoder.Decode(doc); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	if doc.Version != dto.ConfigDocumentVersion {
+		return nil, fmt.Errorf("unsupported config version %d, expect %d", doc.Version, dto.ConfigDocumentVersion)
+	}
+	return doc, nil
+}
+
+// stripConfigFields 去掉与环境相关的字段和空值
+func stripConfigFields(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, item := range val {
+			if configVolatileFields[k] || item == nil {
+				continue
+			}
+			out[k] = stripConfigFields(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			out = append(out, stripConfigFields(item))
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,99 @@
+package logic
+
+import (
+	"gateway/backend/dto"
+	"gateway/enity"
+	"gateway/globals"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPlanPruneScope 受服务范围限制的账号清理时只删除范围内的服务
+// order_* 在 like 查询中会多匹配到 orderXsvc, 这里模拟查询结果多出范围外服务的情况
+func TestPlanPruneScope(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(globals.AdminInfoKey, &enity.Admin{Role: enity.AdminRoleOperator, ServiceScope: "order_*"})
+
+	service := func(name string) *enity.ServiceDetail {
+		return &enity.ServiceDetail{Info: &enity.ServiceInfo{ServiceName: name, LoadType: globals.LoadTypeHTTP}}
+	}
+	current := &dto.ConfigDocument{Services: []*enity.ServiceDetail{
+		service("order_api"), service("order_job"), service("orderXsvc"),
+	}}
+	doc := &dto.ConfigDocument{Services: []*enity.ServiceDetail{service("order_api")}}
+
+	s := &configLogic{}
+	services, _, err := s.plan(c, current, doc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].action != enity.AuditActionDelete || services[0].current.Info.ServiceName != "order_job" {
+		for _, p := range services {
+			t.Logf("%s %s", p.action, p.current.Info.ServiceName)
+		}
+		t.Fatalf("got %d service plans, want only deleting order_job", len(services))
+	}
+
+	// 范围外的服务出现在文档中时不允许修改
+	doc.Services = append(doc.Services, service("orderXsvc"))
+	if _, _, err := s.plan(c, current, doc, false); err == nil {
+		t.Fatal("updating an out-of-scope service should fail")
+	}
+}
+
+// TestDocumentRuleConflicts 同一个文档中的服务不能使用相同的端口或接入规则
+func TestDocumentRuleConflicts(t *testing.T) {
+	httpService := func(name, rule string) *enity.ServiceDetail {
+		return &enity.ServiceDetail{
+			Info:     &enity.ServiceInfo{ServiceName: name, LoadType: globals.LoadTypeHTTP},
+			HTTPRule: &enity.HttpRule{Rule: rule},
+		}
+	}
+	tcpService := func(name string, port int) *enity.ServiceDetail {
+		return &enity.ServiceDetail{
+			Info:    &enity.ServiceInfo{ServiceName: name, LoadType: globals.LoadTypeTCP},
+			TCPRule: &enity.TcpRule{Port: port},
+		}
+	}
+	grpcService := func(name string, port int) *enity.ServiceDetail {
+		return &enity.ServiceDetail{
+			Info:     &enity.ServiceInfo{ServiceName: name, LoadType: globals.LoadTypeGRPC},
+			GRPCRule: &enity.GrpcRule{Port: port},
+		}
+	}
+	udpService := func(name string, port int) *enity.ServiceDetail {
+		return &enity.ServiceDetail{
+			Info:    &enity.ServiceInfo{ServiceName: name, LoadType: globals.LoadTypeUDP},
+			UDPRule: &enity.UdpRule{Port: port},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		services []*enity.ServiceDetail
+		conflict string
+	}{
+		{"same http prefix", []*enity.ServiceDetail{httpService("order_http", "/order"), httpService("pay_http", "/order")}, "order_http"},
+		{"different http prefix", []*enity.ServiceDetail{httpService("order_http", "/order"), httpService("pay_http", "/pay")}, ""},
+		{"same tcp port", []*enity.ServiceDetail{tcpService("redis_tcp", 8001), tcpService("mysql_tcp", 8001)}, "redis_tcp"},
+		{"tcp and grpc share ports", []*enity.ServiceDetail{tcpService("redis_tcp", 8001), grpcService("user_grpc", 8001)}, "redis_tcp"},
+		{"sni only tcp services", []*enity.ServiceDetail{tcpService("redis_tcp", 0), tcpService("mysql_tcp", 0)}, ""},
+		{"udp does not share tcp ports", []*enity.ServiceDetail{tcpService("redis_tcp", 8001), udpService("dns_udp", 8001)}, ""},
+		{"same udp port", []*enity.ServiceDetail{udpService("dns_udp", 8003), udpService("syslog_udp", 8003)}, "dns_udp"},
+	}
+	for _, tc := range cases {
+		errs := documentRuleConflicts(tc.services)
+		if tc.conflict == "" {
+			if len(errs) != 0 {
+				t.Fatalf("%s: unexpected conflicts %v", tc.name, errs)
+			}
+			continue
+		}
+		if len(errs) != 1 || !strings.Contains(errs[0], tc.conflict) {
+			t.Fatalf("%s: conflicts = %v, want one conflict with %s", tc.name, errs, tc.conflict)
+		}
+	}
+}
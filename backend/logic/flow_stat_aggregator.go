@@ -0,0 +1,172 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"gateway/configs"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/flow_counter"
+	"gateway/globals"
+	"gateway/pkg/database/mysql"
+	"gateway/pkg/database/redis"
+	"gateway/pkg/log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	flowStatLockKey = "flow_stat_aggregate_lock"
+
+	defaultPersistInterval  = 300 * time.Second
+	defaultPersistLookback  = 3 * time.Hour
+	defaultHistoryRetention = 13
+	// 启动时回溯的时长, 补齐停机期间仍保存在 redis 中的小时桶
+	startupLookback = 48 * time.Hour
+)
+
+// FlowStatAggregator 定期把 redis 小时桶汇总到 gateway_flow_stat 表
+// 多个后台实例同时运行时通过 redis 锁保证每个周期只有一个实例执行
+type FlowStatAggregator struct {
+	service dao.AllGetter[enity.ServiceInfo]
+	app     dao.AllGetter[enity.App]
+	stat    dao.FlowStatService
+	db      *gorm.DB
+	owner   string
+}
+
+func NewFlowStatAggregator() *FlowStatAggregator {
+	hostname, _ := os.Hostname()
+	return &FlowStatAggregator{
+		service: dao.New[enity.ServiceInfo](),
+		app:     dao.New[enity.App](),
+		stat:    dao.NewFlowStatService(),
+		db:      mysql.GetDB(),
+		owner:   hostname + "-" + strconv.Itoa(os.Getpid()),
+	}
+}
+
+// Run 周期性执行汇总, ctx 取消后退出
+func (a *FlowStatAggregator) Run(ctx context.Context) {
+	conf := configs.GetFlowStatConfig()
+	interval := defaultPersistInterval
+	if conf.PersistInterval > 0 {
+		interval = time.Duration(conf.PersistInterval) * time.Second
+	}
+	lookback := defaultPersistLookback
+	if conf.PersistLookback > 0 {
+		lookback = time.Duration(conf.PersistLookback) * time.Hour
+	}
+
+	a.runOnce(ctx, interval, startupLookback)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.runOnce(ctx, interval, lookback)
+		}
+	}
+}
+
+func (a *FlowStatAggregator) runOnce(ctx context.Context, interval, lookback time.Duration) {
+	// 锁的有效期取周期的一半, 持有者崩溃后下个周期可由其他实例接管
+	ok, err := redis.SetNX(flowStatLockKey, a.owner, interval/2)
+	if err != nil {
+		log.Error("failed to acquire flow stat lock", zap.Error(err))
+		return
+	}
+	if !ok {
+		log.Debug("flow stat aggregate is running on another instance")
+		return
+	}
+	if err := a.Aggregate(ctx, time.Now().Add(-lookback), time.Now()); err != nil {
+		log.Error("failed to aggregate flow stat", zap.Error(err))
+	}
+	if err := a.Purge(ctx); err != nil {
+		log.Error("failed to purge flow stat", zap.Error(err))
+	}
+}
+
+// Aggregate 汇总 [from, to] 区间内的小时桶, 以 redis 中的值覆盖表中的记录, 可重复执行
+func (a *FlowStatAggregator) Aggregate(ctx context.Context, from, to time.Time) error {
+	// 后台任务没有请求上下文, dao 只用它读取 trace id
+	c := &gin.Context{}
+	counters := map[string][]string{
+		StatDimensionTotal: {globals.FlowTotal},
+	}
+	services, err := a.service.GetAll(c, a.db, nil)
+	if err != nil {
+		return err
+	}
+	for _, item := range services {
+		counters[StatDimensionService] = append(counters[StatDimensionService], item.ServiceName)
+	}
+	apps, err := a.app.GetAll(c, a.db, nil)
+	if err != nil {
+		return err
+	}
+	for _, item := range apps {
+		counters[StatDimensionApp] = append(counters[StatDimensionApp], item.AppID)
+	}
+
+	rows := []enity.FlowStat{}
+	now := time.Now()
+	for dimension, names := range counters {
+		for _, name := range names {
+			points, err := flow_counter.GetRangeData(name, from, to, flow_counter.StepHour)
+			if err != nil {
+				return fmt.Errorf("get %s %s flow data: %v", dimension, name, err)
+			}
+			for _, point := range points {
+				// 没有流量的小时不落表, 查询时视为 0
+				if point.Value == 0 {
+					continue
+				}
+				rows = append(rows, enity.FlowStat{
+					Dimension:    dimension,
+					Name:         name,
+					StatDay:      statDay(point.Time),
+					StatHour:     point.Time.Hour(),
+					RequestCount: point.Value,
+					CreatedAt:    now,
+					UpdatedAt:    now,
+				})
+			}
+		}
+	}
+	if err := a.stat.Upsert(ctx, a.db, rows); err != nil {
+		return err
+	}
+	log.Info("flow stat aggregated", zap.Int("rows", len(rows)), zap.Time("from", from), zap.Time("to", to))
+	return nil
+}
+
+// Purge 删除超过保留时间的历史统计
+func (a *FlowStatAggregator) Purge(ctx context.Context) error {
+	months := configs.GetFlowStatConfig().HistoryRetention
+	if months <= 0 {
+		months = defaultHistoryRetention
+	}
+	cutoff := statDay(time.Now().AddDate(0, -months, 0))
+	n, err := a.stat.DeleteBefore(ctx, a.db, cutoff)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info("expired flow stat purged", zap.Int64("rows", n), zap.Int("before", cutoff))
+	}
+	return nil
+}
+
+// statDay 返回 yyyymmdd 格式的日期
+func statDay(t time.Time) int {
+	return t.Year()*10000 + int(t.Month())*100 + t.Day()
+}
@@ -9,7 +9,6 @@ import (
 	"gateway/pkg/database/mysql"
 	"gateway/pkg/log"
 
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -74,8 +73,8 @@ func (s *grpcServiceLogic) AddGrpc(c *gin.Context, params *dto.ServiceAddGrpcInp
 	}
 
 	// 检查 IP 列表与权重列表数量是否一致
-	if len(strings.Split(params.IpList, ",")) != len(strings.Split(params.WeightList, ",")) {
-		return fmt.Errorf("ip list is inconsistent with the number of weight lists")
+	if err := checkServiceUpstream(c, s.db, params.UpstreamID, params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
 	}
 
 	// 开始事务
@@ -94,11 +93,20 @@ func (s *grpcServiceLogic) AddGrpc(c *gin.Context, params *dto.ServiceAddGrpcInp
 
 	// 保存负载均衡信息
 	loadBalance := &enity.LoadBalance{
-		ServiceID:  info.ID,
-		RoundType:  params.RoundType,
-		IpList:     params.IpList,
-		WeightList: params.WeightList,
-		ForbidList: params.ForbidList,
+		ServiceID:         info.ID,
+		RoundType:         params.RoundType,
+		HashKey:           params.HashKey,
+		HashReplicas:      params.HashReplicas,
+		HashLoadFactor:    params.HashLoadFactor,
+		SlowStart:         params.SlowStart,
+		IpList:            params.IpList,
+		WeightList:        params.WeightList,
+		PriorityList:      params.PriorityList,
+		FailoverThreshold: params.FailoverThreshold,
+		DiscoveryType:     params.DiscoveryType,
+		DiscoveryTarget:   params.DiscoveryTarget,
+		UpstreamID:        params.UpstreamID,
+		ForbidList:        params.ForbidList,
 	}
 	if err := s.lb.Save(c, tx, loadBalance); err != nil {
 		tx.Rollback()
@@ -110,6 +118,12 @@ func (s *grpcServiceLogic) AddGrpc(c *gin.Context, params *dto.ServiceAddGrpcInp
 		ServiceID:      info.ID,
 		Port:           params.Port,
 		HeaderTransfor: params.HeaderTransfor,
+		MethodRoute:    params.MethodRoute,
+		MethodLimit:    params.MethodLimit,
+		MethodAllow:    params.MethodAllow,
+		MethodDeny:     params.MethodDeny,
+		NeedGrpcWeb:    params.NeedGrpcWeb,
+		GrpcWebOrigins: params.GrpcWebOrigins,
 	}
 	if err := s.grpc.Save(c, tx, grpcRule); err != nil {
 		tx.Rollback()
@@ -130,6 +144,15 @@ func (s *grpcServiceLogic) AddGrpc(c *gin.Context, params *dto.ServiceAddGrpcInp
 		tx.Rollback()
 		return fmt.Errorf("failed to add GRPC service permission")
 	}
+	after := &enity.ServiceDetail{Info: info, GRPCRule: grpcRule, LoadBalance: loadBalance, AccessControl: accessControl}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionAdd, params.ServiceName, nil, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionAdd, after, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
 	tx.Commit()
 
 	// Publish data change message
@@ -150,8 +173,8 @@ func (s *grpcServiceLogic) AddGrpc(c *gin.Context, params *dto.ServiceAddGrpcInp
 // UpdateGrpc 更新 GRPC 服务
 func (s *grpcServiceLogic) UpdateGrpc(c *gin.Context, params *dto.ServiceUpdateGrpcInput) error {
 	// 检查 IP 列表与权重列表数量是否一致
-	if len(strings.Split(params.IpList, ",")) != len(strings.Split(params.WeightList, ",")) {
-		return fmt.Errorf("ip list is inconsistent with the number of weight lists")
+	if err := checkServiceUpstream(c, s.db, params.UpstreamID, params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
 	}
 	// 开始事务
 	tx := s.db.Begin()
@@ -165,6 +188,8 @@ func (s *grpcServiceLogic) UpdateGrpc(c *gin.Context, params *dto.ServiceUpdateG
 		return fmt.Errorf("gRPC service does not exist")
 	}
 
+	before := auditSnapshot(detail)
+
 	// 更新服务信息
 	info := detail.Info
 	info.ServiceDesc = params.ServiceDesc
@@ -180,8 +205,17 @@ func (s *grpcServiceLogic) UpdateGrpc(c *gin.Context, params *dto.ServiceUpdateG
 	}
 	loadBalance.ServiceID = info.ID
 	loadBalance.RoundType = params.RoundType
+	loadBalance.HashKey = params.HashKey
+	loadBalance.HashReplicas = params.HashReplicas
+	loadBalance.HashLoadFactor = params.HashLoadFactor
+	loadBalance.SlowStart = params.SlowStart
 	loadBalance.IpList = params.IpList
 	loadBalance.WeightList = params.WeightList
+	loadBalance.PriorityList = params.PriorityList
+	loadBalance.FailoverThreshold = params.FailoverThreshold
+	loadBalance.DiscoveryType = params.DiscoveryType
+	loadBalance.DiscoveryTarget = params.DiscoveryTarget
+	loadBalance.UpstreamID = params.UpstreamID
 	loadBalance.ForbidList = params.ForbidList
 	if err := s.lb.Save(c, tx, loadBalance); err != nil {
 		tx.Rollback()
@@ -195,6 +229,12 @@ func (s *grpcServiceLogic) UpdateGrpc(c *gin.Context, params *dto.ServiceUpdateG
 	}
 	grpcRule.ServiceID = info.ID
 	grpcRule.HeaderTransfor = params.HeaderTransfor
+	grpcRule.MethodRoute = params.MethodRoute
+	grpcRule.MethodLimit = params.MethodLimit
+	grpcRule.MethodAllow = params.MethodAllow
+	grpcRule.MethodDeny = params.MethodDeny
+	grpcRule.NeedGrpcWeb = params.NeedGrpcWeb
+	grpcRule.GrpcWebOrigins = params.GrpcWebOrigins
 	if err := s.grpc.Save(c, tx, grpcRule); err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to Save GRPC service rules")
@@ -217,6 +257,17 @@ func (s *grpcServiceLogic) UpdateGrpc(c *gin.Context, params *dto.ServiceUpdateG
 		return fmt.Errorf("failed to Save GRPC service permissions")
 	}
 
+	// 记录审计日志
+	after := &enity.ServiceDetail{Info: info, GRPCRule: grpcRule, LoadBalance: loadBalance, AccessControl: accessControl}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionUpdate, info.ServiceName, before, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionUpdate, after, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// 提交事务
 	tx.Commit()
 
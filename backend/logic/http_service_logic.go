@@ -9,7 +9,6 @@ import (
 	"gateway/globals"
 	"gateway/pkg/database/mysql"
 	"gateway/pkg/log"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -46,8 +45,8 @@ func NewHttpServiceLogic() *httpServiceLogic {
 
 // 添加HTTP服务
 func (s *httpServiceLogic) AddHTTP(c *gin.Context, params *dto.ServiceAddHTTPInput) error {
-	if len(strings.Split(params.IpList, ",")) != len(strings.Split(params.WeightList, ",")) {
-		return fmt.Errorf("the IP list is inconsistent with the number of weight lists")
+	if err := checkServiceUpstream(c, s.db, params.UpstreamID, params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
 	}
 
 	tx := s.db.Begin()
@@ -83,6 +82,7 @@ func (s *httpServiceLogic) AddHTTP(c *gin.Context, params *dto.ServiceAddHTTPInp
 		NeedHttps:      params.NeedHttps,
 		NeedStripUri:   params.NeedStripUri,
 		NeedWebsocket:  params.NeedWebsocket,
+		NeedLogBody:    params.NeedLogBody,
 		UrlRewrite:     params.UrlRewrite,
 		HeaderTransfor: params.HeaderTransfor,
 	}
@@ -107,8 +107,17 @@ func (s *httpServiceLogic) AddHTTP(c *gin.Context, params *dto.ServiceAddHTTPInp
 	loadbalance := &enity.LoadBalance{
 		ServiceID:              serviceModel.ID,
 		RoundType:              params.RoundType,
+		HashKey:                params.HashKey,
+		HashReplicas:           params.HashReplicas,
+		HashLoadFactor:         params.HashLoadFactor,
+		SlowStart:              params.SlowStart,
 		IpList:                 params.IpList,
 		WeightList:             params.WeightList,
+		PriorityList:           params.PriorityList,
+		FailoverThreshold:      params.FailoverThreshold,
+		DiscoveryType:          params.DiscoveryType,
+		DiscoveryTarget:        params.DiscoveryTarget,
+		UpstreamID:             params.UpstreamID,
 		UpstreamConnectTimeout: params.UpstreamConnectTimeout,
 		UpstreamHeaderTimeout:  params.UpstreamHeaderTimeout,
 		UpstreamIdleTimeout:    params.UpstreamIdleTimeout,
@@ -118,6 +127,15 @@ func (s *httpServiceLogic) AddHTTP(c *gin.Context, params *dto.ServiceAddHTTPInp
 		tx.Rollback()
 		return fmt.Errorf("failed to add HTTP service load balancing error")
 	}
+	after := &enity.ServiceDetail{Info: serviceModel, HTTPRule: httpRule, LoadBalance: loadbalance, AccessControl: accessControl}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionAdd, params.ServiceName, nil, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionAdd, after, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
 	tx.Commit()
 
 	// Publish data change message
@@ -136,8 +154,8 @@ func (s *httpServiceLogic) AddHTTP(c *gin.Context, params *dto.ServiceAddHTTPInp
 }
 
 func (s *httpServiceLogic) UpdateHTTP(c *gin.Context, params *dto.ServiceUpdateHTTPInput) error {
-	if len(strings.Split(params.IpList, ",")) != len(strings.Split(params.WeightList, ",")) {
-		return fmt.Errorf("the IP list is inconsistent with the number of weight lists")
+	if err := checkServiceUpstream(c, s.db, params.UpstreamID, params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
 	}
 
 	tx := s.db.Begin()
@@ -153,6 +171,7 @@ func (s *httpServiceLogic) UpdateHTTP(c *gin.Context, params *dto.ServiceUpdateH
 		tx.Rollback()
 		return fmt.Errorf("HTTP service does not exist")
 	}
+	before := auditSnapshot(serviceDetail)
 
 	info := serviceDetail.Info
 	info.ServiceDesc = params.ServiceDesc
@@ -165,6 +184,7 @@ func (s *httpServiceLogic) UpdateHTTP(c *gin.Context, params *dto.ServiceUpdateH
 	httpRule.NeedHttps = params.NeedHttps
 	httpRule.NeedStripUri = params.NeedStripUri
 	httpRule.NeedWebsocket = params.NeedWebsocket
+	httpRule.NeedLogBody = params.NeedLogBody
 	httpRule.UrlRewrite = params.UrlRewrite
 	httpRule.HeaderTransfor = params.HeaderTransfor
 	if err := s.http.Save(c, tx, httpRule); err != nil {
@@ -185,8 +205,17 @@ func (s *httpServiceLogic) UpdateHTTP(c *gin.Context, params *dto.ServiceUpdateH
 
 	loadbalance := serviceDetail.LoadBalance
 	loadbalance.RoundType = params.RoundType
+	loadbalance.HashKey = params.HashKey
+	loadbalance.HashReplicas = params.HashReplicas
+	loadbalance.HashLoadFactor = params.HashLoadFactor
+	loadbalance.SlowStart = params.SlowStart
 	loadbalance.IpList = params.IpList
 	loadbalance.WeightList = params.WeightList
+	loadbalance.PriorityList = params.PriorityList
+	loadbalance.FailoverThreshold = params.FailoverThreshold
+	loadbalance.DiscoveryType = params.DiscoveryType
+	loadbalance.DiscoveryTarget = params.DiscoveryTarget
+	loadbalance.UpstreamID = params.UpstreamID
 	loadbalance.UpstreamConnectTimeout = params.UpstreamConnectTimeout
 	loadbalance.UpstreamHeaderTimeout = params.UpstreamHeaderTimeout
 	loadbalance.UpstreamIdleTimeout = params.UpstreamIdleTimeout
@@ -196,6 +225,14 @@ func (s *httpServiceLogic) UpdateHTTP(c *gin.Context, params *dto.ServiceUpdateH
 		return fmt.Errorf("failed to Save HTTP service load balancing error")
 	}
 
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionUpdate, params.ServiceName, before, serviceDetail); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionUpdate, serviceDetail, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
 	tx.Commit()
 
 	// Publish data change message
@@ -10,7 +10,6 @@ import (
 	"gateway/pkg/database/mysql"
 	"gateway/pkg/log"
 	"gateway/utils"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -58,6 +57,9 @@ func (s *serviceInfoLogic) GetServiceList(c *gin.Context, params *dto.ServiceLis
 			return db.Where("(service_name like ? or service_desc like ?)", "%"+params.Info+"%", "%"+params.Info+"%")
 		},
 	}
+	if scope := serviceScopeCondition(c); scope != nil {
+		queryConditions = append(queryConditions, scope)
+	}
 	list, total, err := s.info.PageList(c, s.db, queryConditions, params.PageNo, params.PageSize)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get serviceInfo list")
@@ -113,13 +115,26 @@ func (s *serviceInfoLogic) DeleteService(c *gin.Context, params *dto.ServiceDele
 		return fmt.Errorf("service does not exist")
 	}
 
+	before, err := s.info.GetServiceDetail(c, s.db, serviceInfo)
+	if err != nil {
+		return fmt.Errorf("service does not exist")
+	}
+	snapshot := auditSnapshot(before)
+
 	// 软删除，将is_delete设置为1；如果您需要物理删除，请使用dao.Delete(c, s.db, serviceInfo)
 	serviceInfo.IsDelete = 1
 
-	err = s.info.Save(c, s.db, serviceInfo)
+	tx := s.db.Begin()
+	err = s.info.Save(c, tx, serviceInfo)
 	if err != nil {
+		tx.Rollback()
 		return fmt.Errorf("failed to delete service")
 	}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionDelete, serviceInfo.ServiceName, snapshot, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
 
 	// Publish data change message
 	message := &globals.DataChangeMessage{
@@ -158,7 +173,7 @@ func (s *serviceInfoLogic) GetServiceDetail(c *gin.Context, params *dto.ServiceD
 
 // 1、http后缀接入 clusterIP+clusterPort+path
 // 2、http域名接入 domain
-// 3、tcp、grpc接入 clusterIP+servicePort
+// 3、tcp、grpc、udp接入 clusterIP+servicePort
 // 获取服务地址
 func (s *serviceInfoLogic) getServiceAddress(serviceDetail *enity.ServiceDetail) (string, error) {
 	clustCfg := configs.GetClusterConfig()
@@ -179,9 +194,15 @@ func (s *serviceInfoLogic) getServiceAddress(serviceDetail *enity.ServiceDetail)
 		}
 		return "unknown", fmt.Errorf("unsupported load type")
 	case globals.LoadTypeTCP:
+		if serviceDetail.TCPRule.Port == 0 {
+			// 只通过共享端口SNI接入
+			return fmt.Sprintf("%s%s(%s)", clusterIP, configs.GetTcpSniConfig().Addr, serviceDetail.TCPRule.SniHost), nil
+		}
 		return fmt.Sprintf("%s:%d", clusterIP, serviceDetail.TCPRule.Port), nil
 	case globals.LoadTypeGRPC:
 		return fmt.Sprintf("%s:%d", clusterIP, serviceDetail.GRPCRule.Port), nil
+	case globals.LoadTypeUDP:
+		return fmt.Sprintf("%s:%d", clusterIP, serviceDetail.UDPRule.Port), nil
 	default:
 		return "unknown", fmt.Errorf("unsupported load type")
 	}
@@ -194,24 +215,9 @@ func (s *serviceInfoLogic) GetServiceStat(c *gin.Context, params *dto.ServiceDel
 	if err != nil {
 		return nil, fmt.Errorf("service does not exist")
 	}
-	counter, err := globals.FlowCounter.GetCounter(serviceDetail.Info.ServiceName)
+	todayList, yesterdayList, err := todayAndYesterday(serviceDetail.Info.ServiceName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get service flow counter")
-	}
-	todayList := []int64{}
-	currentTime := time.Now()
-	for i := 0; i <= currentTime.Hour(); i++ {
-		dateTime := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day(), i, 0, 0, 0, time.UTC)
-		hourData, _ := counter.GetHourData(dateTime)
-		todayList = append(todayList, hourData)
-	}
-
-	yesterdayList := []int64{}
-	yesterTime := currentTime.Add(-1 * time.Duration(time.Hour*24))
-	for i := 0; i <= 23; i++ {
-		dateTime := time.Date(yesterTime.Year(), yesterTime.Month(), yesterTime.Day(), i, 0, 0, 0, time.UTC)
-		hourData, _ := counter.GetHourData(dateTime)
-		yesterdayList = append(yesterdayList, hourData)
+		return nil, fmt.Errorf("failed to get service flow stat: %v", err)
 	}
 
 	out := &dto.ServiceStatOutput{
@@ -5,6 +5,9 @@ type ServiceLogic interface {
 	TcpServiceLogic
 	HttpServiceLogic
 	GrpcServiceLogic
+	UdpServiceLogic
+	ServiceRevisionLogic
+	ServiceNodeLogic
 }
 
 type serviceLogic struct {
@@ -12,6 +15,9 @@ type serviceLogic struct {
 	HttpServiceLogic
 	TcpServiceLogic
 	GrpcServiceLogic
+	UdpServiceLogic
+	ServiceRevisionLogic
+	ServiceNodeLogic
 }
 
 func NewServiceLogic() *serviceLogic {
@@ -20,5 +26,9 @@ func NewServiceLogic() *serviceLogic {
 		HttpServiceLogic: NewHttpServiceLogic(),
 		TcpServiceLogic:  NewTcpServiceLogic(),
 		GrpcServiceLogic: NewGrpcServiceLogic(),
+		UdpServiceLogic:  NewUdpServiceLogic(),
+
+		ServiceRevisionLogic: NewServiceRevisionLogic(),
+		ServiceNodeLogic:     NewServiceNodeLogic(),
 	}
 }
@@ -0,0 +1,120 @@
+package logic
+
+import (
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/enity"
+	"gateway/globals"
+	"gateway/pkg/database/mysql"
+	"gateway/pkg/log"
+	"gateway/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ServiceNodeLogic interface {
+	SetServiceNodeState(c *gin.Context, params *dto.ServiceNodeInput, state string) error
+}
+
+type serviceNodeLogic struct {
+	serviceStore
+	db *gorm.DB
+}
+
+func NewServiceNodeLogic() *serviceNodeLogic {
+	return &serviceNodeLogic{
+		newServiceStore(),
+		mysql.GetDB(),
+	}
+}
+
+// SetServiceNodeState 修改单个上游节点的状态, 不需要改写整个 ip_list
+// 排空的节点记录在 drain_list, 禁用的节点记录在 forbid_list, 启用时从两者中移除
+func (s *serviceNodeLogic) SetServiceNodeState(c *gin.Context, params *dto.ServiceNodeInput, state string) error {
+	tx := s.db.Begin()
+	detail, err := s.info.GetServiceDetail(c, tx, &enity.ServiceInfo{ID: params.ID})
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("service does not exist")
+	}
+	info, lb := detail.Info, detail.LoadBalance
+	if info.IsDelete == 1 || lb == nil {
+		tx.Rollback()
+		return fmt.Errorf("service does not exist")
+	}
+	// 服务发现或引用上游的节点不在 ip_list 中, 直接按地址记录状态, 只作用于当前服务
+	if lb.DiscoveryType == "" && lb.UpstreamID == 0 && !utils.InStringSlice(utils.SplitStringByComma(lb.IpList), params.Node) {
+		tx.Rollback()
+		return fmt.Errorf("node %s is not in the ip list of service %s", params.Node, info.ServiceName)
+	}
+	if lb.NodeState(params.Node) == state {
+		tx.Rollback()
+		return nil
+	}
+
+	before := auditSnapshot(detail)
+	forbidList := removeNode(lb.ForbidList, params.Node)
+	drainList := removeNode(lb.DrainList, params.Node)
+	switch state {
+	case enity.NodeStateDisabled:
+		forbidList = append(forbidList, params.Node)
+	case enity.NodeStateDraining:
+		drainList = append(drainList, params.Node)
+	}
+	lb.ForbidList = strings.Join(forbidList, ",")
+	lb.DrainList = strings.Join(drainList, ",")
+	// forbid_list 中按 ip 禁用的条目会禁用该 ip 的所有端口, 需要修改 forbid_list 才能启用
+	if state == enity.NodeStateEnabled && lb.NodeState(params.Node) != enity.NodeStateEnabled {
+		tx.Rollback()
+		return fmt.Errorf("node %s is disabled by host in forbid list, please update the forbid list", params.Node)
+	}
+
+	info.UpdateAt = time.Now()
+	if err := s.info.Save(c, tx, info); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to save service info")
+	}
+	if err := s.lb.Save(c, tx, lb); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to save service load balancing")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionUpdate, info.ServiceName, before, detail); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionUpdate, detail, fmt.Sprintf("%s node %s", state, params.Node)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+
+	// 代理收到 node 消息后只更新负载均衡器的可选节点, 不重建连接池
+	message := &globals.DataChangeMessage{
+		Type:        "node",
+		Payload:     info.ServiceName,
+		ServiceType: info.LoadType,
+		Operation:   state,
+		Node:        params.Node,
+	}
+	if err := globals.MessageQueue.Publish(globals.DataChange, message); err != nil {
+		log.Error("error publishing message", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return fmt.Errorf("failed to publish node message")
+	}
+	log.Info("published node state message successfully", zap.Any("data", message), zap.String("trace_id", c.GetString("TraceID")))
+	return nil
+}
+
+// removeNode 从节点列表中移除 node, 按 ip 配置的条目保留
+func removeNode(list, node string) []string {
+	out := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" && item != node {
+			out = append(out, item)
+		}
+	}
+	return out
+}
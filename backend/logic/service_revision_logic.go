@@ -0,0 +1,204 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/globals"
+	"gateway/pkg/database/mysql"
+	"gateway/pkg/log"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ServiceRevisionLogic interface {
+	ServiceRevisionList(c *gin.Context, params *dto.ServiceRevisionListInput) ([]dto.ServiceRevisionListItem, int64, error)
+	ServiceRevisionDiff(c *gin.Context, params *dto.ServiceRevisionDiffInput) (*dto.ServiceRevisionDiffOutput, error)
+	RollbackService(c *gin.Context, params *dto.ServiceRollbackInput) error
+}
+
+type serviceRevisionLogic struct {
+	serviceStore
+	revision dao.ServiceRevisionService
+	db       *gorm.DB
+}
+
+func NewServiceRevisionLogic() *serviceRevisionLogic {
+	return &serviceRevisionLogic{
+		newServiceStore(),
+		dao.NewServiceRevisionService(),
+		mysql.GetDB(),
+	}
+}
+
+// ServiceRevisionList 分页返回服务的历史版本
+func (s *serviceRevisionLogic) ServiceRevisionList(c *gin.Context, params *dto.ServiceRevisionListInput) ([]dto.ServiceRevisionListItem, int64, error) {
+	list, total, err := s.revision.PageList(c, s.db, params.ID, params.PageNo, params.PageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get service revision list")
+	}
+	outputList := []dto.ServiceRevisionListItem{}
+	for _, item := range list {
+		outputList = append(outputList, dto.ServiceRevisionListItem{
+			Version:   item.Version,
+			Action:    item.Action,
+			AdminName: item.AdminName,
+			Remark:    item.Remark,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+	return outputList, total, nil
+}
+
+// ServiceRevisionDiff 对比两个版本的配置, ToVersion 为 0 时与当前配置对比
+func (s *serviceRevisionLogic) ServiceRevisionDiff(c *gin.Context, params *dto.ServiceRevisionDiffInput) (*dto.ServiceRevisionDiffOutput, error) {
+	from, err := s.revisionDetail(c, s.db, params.ID, params.FromVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var to *enity.ServiceDetail
+	if params.ToVersion == 0 {
+		to, err = s.info.GetServiceDetail(c, s.db, &enity.ServiceInfo{ID: params.ID})
+		if err != nil {
+			return nil, fmt.Errorf("service does not exist")
+		}
+	} else if to, err = s.revisionDetail(c, s.db, params.ID, params.ToVersion); err != nil {
+		return nil, err
+	}
+
+	changes := auditDiff(auditSnapshot(from), auditSnapshot(to))
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	out := &dto.ServiceRevisionDiffOutput{
+		FromVersion: params.FromVersion,
+		ToVersion:   params.ToVersion,
+		Diff:        []dto.ServiceRevisionDiffItem{},
+	}
+	for _, field := range fields {
+		out.Diff = append(out.Diff, dto.ServiceRevisionDiffItem{
+			Field:  field,
+			Before: changes[field].Before,
+			After:  changes[field].After,
+		})
+	}
+	return out, nil
+}
+
+// RollbackService 将服务配置恢复到指定版本, 回滚本身也会生成一个新版本, 并通过消息队列通知代理
+func (s *serviceRevisionLogic) RollbackService(c *gin.Context, params *dto.ServiceRollbackInput) error {
+	target, err := s.revisionDetail(c, s.db, params.ID, params.Version)
+	if err != nil {
+		return err
+	}
+	if target.Info == nil {
+		return fmt.Errorf("revision %d has no service info", params.Version)
+	}
+
+	tx := s.db.Begin()
+	current, err := s.info.GetServiceDetail(c, tx, &enity.ServiceInfo{ID: params.ID})
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("service does not exist")
+	}
+	if current.Info.IsDelete == 1 {
+		tx.Rollback()
+		return fmt.Errorf("service %s has been deleted", current.Info.ServiceName)
+	}
+	before := auditSnapshot(current)
+
+	// 保存快照之后, 端口、接入规则可能已被其他服务占用, 引用的上游可能已删除, 按新增和更新接口的规则重新校验
+	if err := validateServiceDetail(c, target); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("revision %d is no longer valid: %v", params.Version, err)
+	}
+	if err := s.checkServiceRules(c, tx, current.Info.ID, target); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("revision %d is no longer valid: %v", params.Version, err)
+	}
+
+	// 服务名和类型不允许修改, 只恢复描述和各项规则
+	current, err = s.saveDetail(c, tx, current, target)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	info := current.Info
+
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionUpdate, info.ServiceName, before, current); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionRollback, current, fmt.Sprintf("rollback to version %d", params.Version)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+
+	// Publish data change message
+	message := &globals.DataChangeMessage{
+		Type:        "service",
+		Payload:     info.ServiceName,
+		ServiceType: info.LoadType,
+		Operation:   globals.DataUpdate,
+	}
+	if err := globals.MessageQueue.Publish(globals.DataChange, message); err != nil {
+		log.Error("error publishing message", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return fmt.Errorf("failed to publish save message")
+	}
+	log.Info("published rollback message successfully", zap.Any("data", params), zap.String("trace_id", c.GetString("TraceID")))
+	return nil
+}
+
+func (s *serviceRevisionLogic) revisionDetail(c *gin.Context, db *gorm.DB, serviceID int64, version int) (*enity.ServiceDetail, error) {
+	rev, err := s.revision.Get(c, db, serviceID, version)
+	if err != nil {
+		return nil, fmt.Errorf("revision %d does not exist", version)
+	}
+	detail := &enity.ServiceDetail{}
+	if err := json.Unmarshal([]byte(rev.Detail), detail); err != nil {
+		log.Error("invalid service revision", zap.Int64("service_id", serviceID), zap.Int("version", version), zap.Error(err))
+		return nil, fmt.Errorf("revision %d is broken", version)
+	}
+	return detail, nil
+}
+
+// recordRevision 保存服务的完整配置作为一个新版本, 需要与服务的修改在同一个事务中调用
+func recordRevision(c *gin.Context, db *gorm.DB, action string, detail *enity.ServiceDetail, remark string) error {
+	revisionDao := dao.NewServiceRevisionService()
+	version, err := revisionDao.LatestVersion(c, db, detail.Info.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record service revision")
+	}
+	bts, err := json.Marshal(detail)
+	if err != nil {
+		return fmt.Errorf("failed to record service revision")
+	}
+
+	rev := &enity.ServiceRevision{
+		ServiceID:   detail.Info.ID,
+		ServiceName: detail.Info.ServiceName,
+		Version:     version + 1,
+		Action:      action,
+		Detail:      string(bts),
+		Remark:      remark,
+		CreatedAt:   time.Now(),
+	}
+	if admin := sessionAdmin(c); admin != nil {
+		rev.AdminName = admin.UserName
+	}
+	if err := revisionDao.Create(c, db, rev); err != nil {
+		return fmt.Errorf("failed to record service revision")
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package logic
+
+import (
+	"fmt"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/globals"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// serviceStore 服务各张表的 dao, 用于整体保存一个 ServiceDetail
+type serviceStore struct {
+	info dao.ServiceInfoService
+	http dao.HttpService
+	tcp  dao.TcpService
+	grpc dao.GrpcService
+	udp  dao.UdpService
+	lb   dao.LoadBalanceService
+	ac   dao.AccessControlService
+}
+
+func newServiceStore() serviceStore {
+	return serviceStore{
+		info: dao.NewServiceInfoService(),
+		http: dao.NewHttpService(),
+		tcp:  dao.NewTcpService(),
+		grpc: dao.NewGrpcService(),
+		udp:  dao.NewUdpService(),
+		lb:   dao.NewLoadBalanceService(),
+		ac:   dao.NewAccessControlService(),
+	}
+}
+
+// saveDetail 将 target 写入数据库, current 为空时新建服务
+// 服务名和类型不会被修改, 已有记录沿用当前的主键, target 中为空的部分保持不变
+func (st serviceStore) saveDetail(c *gin.Context, tx *gorm.DB, current, target *enity.ServiceDetail) (*enity.ServiceDetail, error) {
+	if target.Info == nil {
+		return nil, fmt.Errorf("service info is required")
+	}
+
+	now := time.Now()
+	if current == nil {
+		current = &enity.ServiceDetail{Info: &enity.ServiceInfo{
+			LoadType:    target.Info.LoadType,
+			ServiceName: target.Info.ServiceName,
+			CreateAt:    now,
+		}}
+	}
+	info := current.Info
+	info.ServiceDesc = target.Info.ServiceDesc
+	info.UpdateAt = now
+	if err := st.info.Save(c, tx, info); err != nil {
+		return nil, fmt.Errorf("failed to save service info")
+	}
+
+	var err error
+	switch info.LoadType {
+	case globals.LoadTypeHTTP:
+		if target.HTTPRule != nil {
+			rule := *target.HTTPRule
+			rule.ID, rule.ServiceID = 0, info.ID
+			if current.HTTPRule != nil {
+				rule.ID = current.HTTPRule.ID
+			}
+			err = st.http.Save(c, tx, &rule)
+			current.HTTPRule = &rule
+		}
+	case globals.LoadTypeTCP:
+		if target.TCPRule != nil {
+			rule := *target.TCPRule
+			rule.ID, rule.ServiceID = 0, info.ID
+			if current.TCPRule != nil {
+				rule.ID = current.TCPRule.ID
+			}
+			err = st.tcp.Save(c, tx, &rule)
+			current.TCPRule = &rule
+		}
+	case globals.LoadTypeGRPC:
+		if target.GRPCRule != nil {
+			rule := *target.GRPCRule
+			rule.ID, rule.ServiceID = 0, info.ID
+			if current.GRPCRule != nil {
+				rule.ID = current.GRPCRule.ID
+			}
+			err = st.grpc.Save(c, tx, &rule)
+			current.GRPCRule = &rule
+		}
+	case globals.LoadTypeUDP:
+		if target.UDPRule != nil {
+			rule := *target.UDPRule
+			rule.ID, rule.ServiceID = 0, info.ID
+			if current.UDPRule != nil {
+				rule.ID = current.UDPRule.ID
+			}
+			err = st.udp.Save(c, tx, &rule)
+			current.UDPRule = &rule
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save service rule")
+	}
+
+	if target.LoadBalance != nil {
+		loadBalance := *target.LoadBalance
+		loadBalance.ID, loadBalance.ServiceID = 0, info.ID
+		if current.LoadBalance != nil {
+			loadBalance.ID = current.LoadBalance.ID
+		}
+		if err := st.lb.Save(c, tx, &loadBalance); err != nil {
+			return nil, fmt.Errorf("failed to save service load balancing")
+		}
+		current.LoadBalance = &loadBalance
+	}
+
+	if target.AccessControl != nil {
+		accessControl := *target.AccessControl
+		accessControl.ID, accessControl.ServiceID = 0, info.ID
+		if current.AccessControl != nil {
+			accessControl.ID = current.AccessControl.ID
+		}
+		if err := st.ac.Save(c, tx, &accessControl); err != nil {
+			return nil, fmt.Errorf("failed to save service permissions")
+		}
+		current.AccessControl = &accessControl
+	}
+	return current, nil
+}
+
+// checkServiceRules 检查端口和接入规则是否被其他服务占用, 与新增接口的检查一致
+// TCP 与 GRPC 共用端口空间, UDP 端口单独计算, 只使用 SNI 接入的 TCP 服务不占用端口
+func (st serviceStore) checkServiceRules(c *gin.Context, db *gorm.DB, serviceID int64, detail *enity.ServiceDetail) error {
+	for _, rule := range serviceRules(detail) {
+		owner, err := st.info.RuleOwner(c, db, serviceID, rule)
+		if err != nil {
+			return fmt.Errorf("failed to check service rules")
+		}
+		if owner != "" {
+			return ruleConflictError(detail, owner)
+		}
+	}
+	return nil
+}
+
+// serviceRules 返回服务占用的接入规则, tcp 与 grpc 共用端口, 两种规则都要检查
+func serviceRules(detail *enity.ServiceDetail) []interface{} {
+	rules := []interface{}{}
+	switch detail.Info.LoadType {
+	case globals.LoadTypeHTTP:
+		if detail.HTTPRule != nil {
+			rules = append(rules, &enity.HttpRule{RuleType: detail.HTTPRule.RuleType, Rule: detail.HTTPRule.Rule})
+		}
+	case globals.LoadTypeTCP:
+		if detail.TCPRule != nil && detail.TCPRule.Port != 0 {
+			rules = append(rules, &enity.TcpRule{Port: detail.TCPRule.Port}, &enity.GrpcRule{Port: detail.TCPRule.Port})
+		}
+	case globals.LoadTypeGRPC:
+		if detail.GRPCRule != nil {
+			rules = append(rules, &enity.TcpRule{Port: detail.GRPCRule.Port}, &enity.GrpcRule{Port: detail.GRPCRule.Port})
+		}
+	case globals.LoadTypeUDP:
+		if detail.UDPRule != nil {
+			rules = append(rules, &enity.UdpRule{Port: detail.UDPRule.Port})
+		}
+	}
+	return rules
+}
+
+// serviceRuleKey 相同 key 的接入规则不能被两个服务使用
+func serviceRuleKey(rule interface{}) string {
+	switch r := rule.(type) {
+	case *enity.HttpRule:
+		return fmt.Sprintf("http %d %s", r.RuleType, r.Rule)
+	case *enity.TcpRule:
+		return fmt.Sprintf("tcp %d", r.Port)
+	case *enity.GrpcRule:
+		return fmt.Sprintf("tcp %d", r.Port)
+	case *enity.UdpRule:
+		return fmt.Sprintf("udp %d", r.Port)
+	}
+	return ""
+}
+
+func ruleConflictError(detail *enity.ServiceDetail, owner string) error {
+	if detail.Info.LoadType == globals.LoadTypeHTTP {
+		return fmt.Errorf("the access prefix or domain name is already used by service %s", owner)
+	}
+	return fmt.Errorf("the port is already used by service %s", owner)
+}
+
+// checkServiceUpstream 检查服务的节点配置, upstreamID 不为 0 时节点来自引用的上游, 只检查上游是否存在
+func checkServiceUpstream(c *gin.Context, db *gorm.DB, upstreamID int64, ipList, weightList, priorityList, discoveryType, discoveryTarget string) error {
+	if upstreamID == 0 {
+		return checkUpstreamNodes(ipList, weightList, priorityList, discoveryType, discoveryTarget)
+	}
+	upstream, err := dao.NewUpstream().Get(c, db, &enity.Upstream{ID: upstreamID})
+	if err != nil || upstream.IsDelete == 1 {
+		return fmt.Errorf("upstream %d not found", upstreamID)
+	}
+	return nil
+}
+
+// checkUpstreamNodes 检查上游节点配置: ip 列表与权重列表数量一致, 优先级列表不为空时同样一致, 使用服务发现时 ip 列表为可选的初始节点
+func checkUpstreamNodes(ipList, weightList, priorityList, discoveryType, discoveryTarget string) error {
+	if err := enity.ValidDiscovery(discoveryType, discoveryTarget); err != nil {
+		return err
+	}
+	if discoveryType == "" && ipList == "" {
+		return fmt.Errorf("ip list is required when service discovery is not used")
+	}
+	if len(strings.Split(ipList, ",")) != len(strings.Split(weightList, ",")) {
+		return fmt.Errorf("the IP list is inconsistent with the number of weight lists")
+	}
+	if priorityList != "" && len(strings.Split(ipList, ",")) != len(strings.Split(priorityList, ",")) {
+		return fmt.Errorf("the IP list is inconsistent with the number of priority lists")
+	}
+	return nil
+}
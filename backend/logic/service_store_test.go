@@ -0,0 +1,92 @@
+package logic
+
+import (
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/globals"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ruleOwnerStub 按端口或接入规则返回占用的服务, 不访问数据库
+type ruleOwnerStub struct {
+	dao.ServiceInfoService
+	rules     map[string]string
+	tcpPorts  map[int]string
+	grpcPorts map[int]string
+	udpPorts  map[int]string
+}
+
+func (s ruleOwnerStub) RuleOwner(c *gin.Context, db *gorm.DB, serviceID int64, rule interface{}) (string, error) {
+	switch r := rule.(type) {
+	case *enity.HttpRule:
+		return s.rules[r.Rule], nil
+	case *enity.TcpRule:
+		return s.tcpPorts[r.Port], nil
+	case *enity.GrpcRule:
+		return s.grpcPorts[r.Port], nil
+	case *enity.UdpRule:
+		return s.udpPorts[r.Port], nil
+	}
+	return "", nil
+}
+
+// TestCheckServiceRules 回滚时快照中的端口和接入规则已被其他服务占用需要拒绝
+func TestCheckServiceRules(t *testing.T) {
+	st := serviceStore{info: ruleOwnerStub{
+		rules:     map[string]string{"/order": "order_http"},
+		tcpPorts:  map[int]string{8001: "redis_tcp"},
+		grpcPorts: map[int]string{8002: "user_grpc"},
+		udpPorts:  map[int]string{8003: "dns_udp"},
+	}}
+
+	cases := []struct {
+		name   string
+		detail *enity.ServiceDetail
+		owner  string
+	}{
+		{"http rule taken", &enity.ServiceDetail{
+			Info:     &enity.ServiceInfo{LoadType: globals.LoadTypeHTTP},
+			HTTPRule: &enity.HttpRule{Rule: "/order"},
+		}, "order_http"},
+		{"http rule free", &enity.ServiceDetail{
+			Info:     &enity.ServiceInfo{LoadType: globals.LoadTypeHTTP},
+			HTTPRule: &enity.HttpRule{Rule: "/pay"},
+		}, ""},
+		{"tcp port taken by grpc", &enity.ServiceDetail{
+			Info:    &enity.ServiceInfo{LoadType: globals.LoadTypeTCP},
+			TCPRule: &enity.TcpRule{Port: 8002},
+		}, "user_grpc"},
+		{"tcp sni only", &enity.ServiceDetail{
+			Info:    &enity.ServiceInfo{LoadType: globals.LoadTypeTCP},
+			TCPRule: &enity.TcpRule{SniHost: "db.example.com"},
+		}, ""},
+		{"grpc port taken by tcp", &enity.ServiceDetail{
+			Info:     &enity.ServiceInfo{LoadType: globals.LoadTypeGRPC},
+			GRPCRule: &enity.GrpcRule{Port: 8001},
+		}, "redis_tcp"},
+		{"udp port taken", &enity.ServiceDetail{
+			Info:    &enity.ServiceInfo{LoadType: globals.LoadTypeUDP},
+			UDPRule: &enity.UdpRule{Port: 8003},
+		}, "dns_udp"},
+		{"udp does not share tcp ports", &enity.ServiceDetail{
+			Info:    &enity.ServiceInfo{LoadType: globals.LoadTypeUDP},
+			UDPRule: &enity.UdpRule{Port: 8001},
+		}, ""},
+	}
+	for _, c := range cases {
+		err := st.checkServiceRules(nil, nil, 1, c.detail)
+		if c.owner == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.owner) {
+			t.Fatalf("%s: err = %v, want conflict with %s", c.name, err, c.owner)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package logic
+
+import (
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/flow_counter"
+	"gateway/globals"
+	"gateway/pkg/database/mysql"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"gorm.io/gorm"
+)
+
+// 统计维度
+const (
+	StatDimensionTotal   = "total"
+	StatDimensionService = "service"
+	StatDimensionApp     = "app"
+	StatDimensionNode    = "node"
+	StatDimensionStatus  = "status"
+)
+
+type StatLogic interface {
+	GetFlowStat(c *gin.Context, params *dto.StatFlowInput) (*dto.StatFlowOutput, error)
+	GetFlowHistory(c *gin.Context, params *dto.StatHistoryInput) (*dto.StatHistoryOutput, error)
+}
+
+type statLogic struct {
+	info dao.ServiceInfoService
+	app  dao.AllGetter[enity.App]
+	stat dao.FlowStatService
+	db   *gorm.DB
+}
+
+func NewStatLogic() *statLogic {
+	return &statLogic{
+		dao.NewServiceInfoService(),
+		dao.New[enity.App](),
+		dao.NewFlowStatService(),
+		mysql.GetDB(),
+	}
+}
+
+// GetFlowStat 按维度和粒度查询区间内的请求数
+func (s *statLogic) GetFlowStat(c *gin.Context, params *dto.StatFlowInput) (*dto.StatFlowOutput, error) {
+	from := time.Unix(params.From, 0)
+	to := time.Now()
+	if params.To > 0 {
+		to = time.Unix(params.To, 0)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+
+	// counters 为 序列名称 -> 计数器名称
+	names, counters, err := s.counters(c, params, from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := &dto.StatFlowOutput{
+		Dimension: params.Dimension,
+		Step:      params.Step,
+		From:      from.Unix(),
+		To:        to.Unix(),
+		Series:    []dto.StatFlowSeries{},
+	}
+	for _, name := range names {
+		points, err := flow_counter.GetRangeData(counters[name], from, to, params.Step)
+		if err != nil {
+			return nil, err
+		}
+		series := dto.StatFlowSeries{Name: name, Points: make([]dto.StatFlowPoint, 0, len(points))}
+		for _, point := range points {
+			series.Total += point.Value
+			series.Points = append(series.Points, dto.StatFlowPoint{Time: point.Time.Unix(), Value: point.Value})
+		}
+		out.Series = append(out.Series, series)
+	}
+	return out, nil
+}
+
+// GetFlowHistory 查询 mysql 中持久化的历史统计, 按小时/天/月汇总
+func (s *statLogic) GetFlowHistory(c *gin.Context, params *dto.StatHistoryInput) (*dto.StatHistoryOutput, error) {
+	fromDay, err := time.ParseInLocation("2006-01-02", params.FromDay, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from_day %s", params.FromDay)
+	}
+	toDay, err := time.ParseInLocation("2006-01-02", params.ToDay, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to_day %s", params.ToDay)
+	}
+	if toDay.Before(fromDay) {
+		return nil, fmt.Errorf("to_day must not be before from_day")
+	}
+
+	list, err := s.stat.Summary(c, s.db, &dao.FlowStatQuery{
+		Dimension:   params.Dimension,
+		Name:        params.Name,
+		FromDay:     statDay(fromDay),
+		ToDay:       statDay(toDay),
+		Granularity: params.Granularity,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 服务、节点和状态维度只返回账号服务范围内的服务, 不指定名称时同样过滤
+	if admin := sessionAdmin(c); admin != nil {
+		scoped := []dto.StatHistoryItem{}
+		for _, item := range list {
+			if service, ok := statServiceName(params.Dimension, item.Name); ok && !admin.CanAccessService(service) {
+				continue
+			}
+			scoped = append(scoped, item)
+		}
+		list = scoped
+	}
+	return &dto.StatHistoryOutput{
+		Dimension:   params.Dimension,
+		Granularity: params.Granularity,
+		List:        list,
+	}, nil
+}
+
+// FormatStatPeriod 将汇总周期格式化为可读的时间
+func FormatStatPeriod(granularity string, period int64) string {
+	switch granularity {
+	case dao.FlowStatHour:
+		return fmt.Sprintf("%04d-%02d-%02d %02d:00", period/1000000, period/10000%100, period/100%100, period%100)
+	case dao.FlowStatDay:
+		return fmt.Sprintf("%04d-%02d-%02d", period/10000, period/100%100, period%100)
+	default:
+		return fmt.Sprintf("%04d-%02d", period/100, period%100)
+	}
+}
+
+// statServiceName 返回统计项所属的服务, 节点和状态维度的名称为 服务名#node#节点 / 服务名#status#分类
+func statServiceName(dimension, name string) (string, bool) {
+	switch dimension {
+	case StatDimensionService, StatDimensionNode, StatDimensionStatus:
+		service, _, _ := strings.Cut(name, "#")
+		return service, true
+	}
+	return "", false
+}
+
+func (s *statLogic) counters(c *gin.Context, params *dto.StatFlowInput, from, to time.Time) ([]string, map[string]string, error) {
+	names := []string{}
+	counters := map[string]string{}
+	add := func(name, counter string) {
+		names = append(names, name)
+		counters[name] = counter
+	}
+
+	switch params.Dimension {
+	case StatDimensionTotal:
+		add(globals.FlowTotal, globals.FlowTotal)
+	case StatDimensionService:
+		if params.Name != "" {
+			if _, err := s.service(c, params.Name); err != nil {
+				return nil, nil, err
+			}
+			add(params.Name, params.Name)
+			break
+		}
+		conditions := []func(db *gorm.DB) *gorm.DB{
+			func(db *gorm.DB) *gorm.DB { return db.Where("is_delete = 0") },
+		}
+		if scope := serviceScopeCondition(c); scope != nil {
+			conditions = append(conditions, scope)
+		}
+		list, err := s.info.GetAll(c, s.db, conditions)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, item := range list {
+			add(item.ServiceName, item.ServiceName)
+		}
+	case StatDimensionApp:
+		list, err := s.app.GetAll(c, s.db, []func(db *gorm.DB) *gorm.DB{
+			func(db *gorm.DB) *gorm.DB { return db.Where("is_delete = 0") },
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, item := range list {
+			if params.Name == "" || params.Name == item.AppID {
+				add(item.AppID, item.AppID)
+			}
+		}
+		if params.Name != "" && len(names) == 0 {
+			return nil, nil, fmt.Errorf("app %s not found", params.Name)
+		}
+	case StatDimensionNode:
+		if _, err := s.service(c, params.Name); err != nil {
+			return nil, nil, err
+		}
+		// 节点可能来自上游组、服务发现或域名解析, 以代理实际记录的节点为准
+		nodes, err := flow_counter.NodeNames(params.Name, from, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, node := range nodes {
+			add(node, flow_counter.NodeCounterName(params.Name, node))
+		}
+	case StatDimensionStatus:
+		detail, err := s.service(c, params.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, class := range statusClasses(detail.Info.LoadType) {
+			add(class, flow_counter.StatusCounterName(params.Name, class))
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported dimension %s", params.Dimension)
+	}
+	return names, counters, nil
+}
+
+func (s *statLogic) service(c *gin.Context, serviceName string) (*enity.ServiceDetail, error) {
+	if serviceName == "" {
+		return nil, fmt.Errorf("name is required for this dimension")
+	}
+	if admin := sessionAdmin(c); admin != nil && !admin.CanAccessService(serviceName) {
+		return nil, fmt.Errorf("service %s is out of your scope", serviceName)
+	}
+	info, err := s.info.Get(c, s.db, &enity.ServiceInfo{ServiceName: serviceName})
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found", serviceName)
+	}
+	return s.info.GetServiceDetail(c, s.db, info)
+}
+
+// statusClasses 各协议记录的状态分类, 与代理侧写入计数器时使用的分类一致
+func statusClasses(loadType int) []string {
+	switch loadType {
+	case globals.LoadTypeHTTP:
+		return []string{"1xx", "2xx", "3xx", "4xx", "5xx"}
+	case globals.LoadTypeGRPC:
+		classes := []string{}
+		for code := codes.OK; code <= codes.Unauthenticated; code++ {
+			classes = append(classes, code.String())
+		}
+		return classes
+	default:
+		return []string{"ok", "error"}
+	}
+}
+
+// todayAndYesterday 按小时返回今天(截止当前小时)和昨天的请求数
+func todayAndYesterday(counterName string) ([]int64, []int64, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	yesterday := today.AddDate(0, 0, -1)
+
+	todayPoints, err := flow_counter.GetRangeData(counterName, today, now, flow_counter.StepHour)
+	if err != nil {
+		return nil, nil, err
+	}
+	yesterdayPoints, err := flow_counter.GetRangeData(counterName, yesterday, today.Add(-time.Second), flow_counter.StepHour)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pointValues(todayPoints), pointValues(yesterdayPoints), nil
+}
+
+func pointValues(points []flow_counter.FlowPoint) []int64 {
+	values := make([]int64, 0, len(points))
+	for _, point := range points {
+		values = append(values, point.Value)
+	}
+	return values
+}
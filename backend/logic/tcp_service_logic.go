@@ -10,7 +10,6 @@ import (
 	"gateway/pkg/database/mysql"
 	"gateway/pkg/log"
 
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -56,17 +55,23 @@ func (s *tcpServiceLogic) AddTCP(c *gin.Context, params *dto.ServiceAddTcpInput)
 		return fmt.Errorf("an error occurred while querying the TCP service name")
 	}
 
-	// 检查端口是否被占用
-	if _, err := s.tcp.Get(c, s.db, &enity.TcpRule{Port: params.Port}); err == nil {
-		return fmt.Errorf("the port already exists, please change the port")
+	if err := checkTcpListenRule(params.Port, params.NeedTls, params.CertFile, params.KeyFile, params.SniHost); err != nil {
+		return err
 	}
-	if _, err := s.grpc.Get(c, s.db, &enity.GrpcRule{Port: params.Port}); err == nil {
-		return fmt.Errorf("the port already exists, please change the port")
+
+	// 检查端口是否被占用，只使用SNI接入时不占用端口
+	if params.Port != 0 {
+		if _, err := s.tcp.Get(c, s.db, &enity.TcpRule{Port: params.Port}); err == nil {
+			return fmt.Errorf("the port already exists, please change the port")
+		}
+		if _, err := s.grpc.Get(c, s.db, &enity.GrpcRule{Port: params.Port}); err == nil {
+			return fmt.Errorf("the port already exists, please change the port")
+		}
 	}
 
 	// ip列表与权重列表数量是否一致
-	if len(strings.Split(params.IpList, ",")) != len(strings.Split(params.WeightList, ",")) {
-		return fmt.Errorf("the IP list is inconsistent with the number of weight lists")
+	if err := checkServiceUpstream(c, s.db, params.UpstreamID, params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
 	}
 
 	tx := s.db.Begin()
@@ -81,19 +86,34 @@ func (s *tcpServiceLogic) AddTCP(c *gin.Context, params *dto.ServiceAddTcpInput)
 		return fmt.Errorf("failed to add TCP service information")
 	}
 	loadBalance := &enity.LoadBalance{
-		ServiceID:  info.ID,
-		RoundType:  params.RoundType,
-		IpList:     params.IpList,
-		WeightList: params.WeightList,
-		ForbidList: params.ForbidList,
+		ServiceID:         info.ID,
+		RoundType:         params.RoundType,
+		HashKey:           params.HashKey,
+		HashReplicas:      params.HashReplicas,
+		HashLoadFactor:    params.HashLoadFactor,
+		SlowStart:         params.SlowStart,
+		IpList:            params.IpList,
+		WeightList:        params.WeightList,
+		PriorityList:      params.PriorityList,
+		FailoverThreshold: params.FailoverThreshold,
+		DiscoveryType:     params.DiscoveryType,
+		DiscoveryTarget:   params.DiscoveryTarget,
+		UpstreamID:        params.UpstreamID,
+		ForbidList:        params.ForbidList,
 	}
 	if err := s.lb.Save(c, tx, loadBalance); err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to add TCP service load balancing information")
 	}
 	tcpRule := &enity.TcpRule{
-		ServiceID: info.ID,
-		Port:      params.Port,
+		ServiceID:   info.ID,
+		Port:        params.Port,
+		IdleTimeout: params.IdleTimeout,
+		MaxConns:    params.MaxConns,
+		NeedTls:     params.NeedTls,
+		CertFile:    params.CertFile,
+		KeyFile:     params.KeyFile,
+		SniHost:     params.SniHost,
 	}
 	if err := s.tcp.Save(c, tx, tcpRule); err != nil {
 		tx.Rollback()
@@ -112,6 +132,15 @@ func (s *tcpServiceLogic) AddTCP(c *gin.Context, params *dto.ServiceAddTcpInput)
 		tx.Rollback()
 		return fmt.Errorf("failed to add TCP service permission information")
 	}
+	after := &enity.ServiceDetail{Info: info, TCPRule: tcpRule, LoadBalance: loadBalance, AccessControl: accessControl}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionAdd, params.ServiceName, nil, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionAdd, after, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
 	tx.Commit()
 
 	// Publish data change message
@@ -132,9 +161,13 @@ func (s *tcpServiceLogic) AddTCP(c *gin.Context, params *dto.ServiceAddTcpInput)
 
 // UpdateTCP 更新TCP服务
 func (s *tcpServiceLogic) UpdateTCP(c *gin.Context, params *dto.ServiceUpdateTcpInput) error {
+	if err := checkTcpListenRule(params.Port, params.NeedTls, params.CertFile, params.KeyFile, params.SniHost); err != nil {
+		return err
+	}
+
 	// ip列表与权重列表数量是否一致
-	if len(strings.Split(params.IpList, ",")) != len(strings.Split(params.WeightList, ",")) {
-		return fmt.Errorf("the IP list is inconsistent with the number of weight lists")
+	if err := checkServiceUpstream(c, s.db, params.UpstreamID, params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
 	}
 
 	tx := s.db.Begin()
@@ -144,6 +177,8 @@ func (s *tcpServiceLogic) UpdateTCP(c *gin.Context, params *dto.ServiceUpdateTcp
 		return fmt.Errorf("TCP service does not exist")
 	}
 
+	before := auditSnapshot(detail)
+
 	info := detail.Info
 	info.ServiceDesc = params.ServiceDesc
 	if err := s.info.Save(c, tx, info); err != nil {
@@ -157,8 +192,17 @@ func (s *tcpServiceLogic) UpdateTCP(c *gin.Context, params *dto.ServiceUpdateTcp
 	}
 	loadBalance.ServiceID = info.ID
 	loadBalance.RoundType = params.RoundType
+	loadBalance.HashKey = params.HashKey
+	loadBalance.HashReplicas = params.HashReplicas
+	loadBalance.HashLoadFactor = params.HashLoadFactor
+	loadBalance.SlowStart = params.SlowStart
 	loadBalance.IpList = params.IpList
 	loadBalance.WeightList = params.WeightList
+	loadBalance.PriorityList = params.PriorityList
+	loadBalance.FailoverThreshold = params.FailoverThreshold
+	loadBalance.DiscoveryType = params.DiscoveryType
+	loadBalance.DiscoveryTarget = params.DiscoveryTarget
+	loadBalance.UpstreamID = params.UpstreamID
 	loadBalance.ForbidList = params.ForbidList
 	if err := s.lb.Save(c, tx, loadBalance); err != nil {
 		tx.Rollback()
@@ -171,6 +215,12 @@ func (s *tcpServiceLogic) UpdateTCP(c *gin.Context, params *dto.ServiceUpdateTcp
 	}
 	tcpRule.ServiceID = info.ID
 	tcpRule.Port = params.Port
+	tcpRule.IdleTimeout = params.IdleTimeout
+	tcpRule.MaxConns = params.MaxConns
+	tcpRule.NeedTls = params.NeedTls
+	tcpRule.CertFile = params.CertFile
+	tcpRule.KeyFile = params.KeyFile
+	tcpRule.SniHost = params.SniHost
 	if err := s.tcp.Save(c, tx, tcpRule); err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to Save TCP service rule information")
@@ -192,6 +242,15 @@ func (s *tcpServiceLogic) UpdateTCP(c *gin.Context, params *dto.ServiceUpdateTcp
 		return fmt.Errorf("failed to Save TCP service permission information")
 	}
 
+	after := &enity.ServiceDetail{Info: info, TCPRule: tcpRule, LoadBalance: loadBalance, AccessControl: accessControl}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionUpdate, info.ServiceName, before, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionUpdate, after, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
 	tx.Commit()
 
 	// Publish data change message
@@ -209,3 +268,14 @@ func (s *tcpServiceLogic) UpdateTCP(c *gin.Context, params *dto.ServiceUpdateTcp
 
 	return nil
 }
+
+// checkTcpListenRule 检查TCP服务的接入方式：独立端口与SNI域名至少配置一个，终止TLS时需要证书
+func checkTcpListenRule(port, needTls int, certFile, keyFile, sniHost string) error {
+	if port == 0 && sniHost == "" {
+		return fmt.Errorf("either the port or the SNI host must be set")
+	}
+	if needTls == 1 && (certFile == "" || keyFile == "") {
+		return fmt.Errorf("the certificate file and key file are required when TLS termination is enabled")
+	}
+	return nil
+}
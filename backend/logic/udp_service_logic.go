@@ -0,0 +1,243 @@
+package logic
+
+import (
+	"fmt"
+
+	"gateway/backend/dto"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/globals"
+	"gateway/pkg/database/mysql"
+	"gateway/pkg/log"
+
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type UdpServiceLogic interface {
+	AddUDP(c *gin.Context, param *dto.ServiceAddUdpInput) error
+	UpdateUDP(c *gin.Context, param *dto.ServiceUpdateUdpInput) error
+}
+
+type udpServiceLogic struct {
+	info dao.ServiceInfoService
+	udp  dao.UdpService
+	lb   dao.LoadBalanceService
+	ac   dao.AccessControlService
+	db   *gorm.DB
+}
+
+// NewUdpServiceLogic 创建udpServiceLogic
+func NewUdpServiceLogic() *udpServiceLogic {
+	return &udpServiceLogic{
+		dao.NewServiceInfoService(),
+		dao.NewUdpService(),
+		dao.NewLoadBalanceService(),
+		dao.NewAccessControlService(),
+		mysql.GetDB(),
+	}
+}
+
+// AddUDP 添加UDP服务
+func (s *udpServiceLogic) AddUDP(c *gin.Context, params *dto.ServiceAddUdpInput) error {
+	// 检查服务名是否被占用
+	infoSearch := &enity.ServiceInfo{ServiceName: params.ServiceName, IsDelete: 0}
+	if info, err := s.info.Get(c, s.db, infoSearch); err != gorm.ErrRecordNotFound {
+		if err == nil && info != nil {
+			return fmt.Errorf("the UDP service name already exists, please change the service name")
+		}
+		return fmt.Errorf("an error occurred while querying the UDP service name")
+	}
+
+	// 检查端口是否被占用，UDP 端口与 TCP、GRPC 端口互不冲突
+	if _, err := s.udp.Get(c, s.db, &enity.UdpRule{Port: params.Port}); err == nil {
+		return fmt.Errorf("the port already exists, please change the port")
+	}
+
+	// ip列表与权重列表数量是否一致
+	if err := checkServiceUpstream(c, s.db, params.UpstreamID, params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+	info := &enity.ServiceInfo{
+		LoadType:    globals.LoadTypeUDP,
+		ServiceName: params.ServiceName,
+		ServiceDesc: params.ServiceDesc,
+	}
+
+	if err := s.info.Save(c, tx, info); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to add UDP service information")
+	}
+	loadBalance := &enity.LoadBalance{
+		ServiceID:         info.ID,
+		RoundType:         params.RoundType,
+		HashKey:           params.HashKey,
+		HashReplicas:      params.HashReplicas,
+		HashLoadFactor:    params.HashLoadFactor,
+		SlowStart:         params.SlowStart,
+		IpList:            params.IpList,
+		WeightList:        params.WeightList,
+		PriorityList:      params.PriorityList,
+		FailoverThreshold: params.FailoverThreshold,
+		DiscoveryType:     params.DiscoveryType,
+		DiscoveryTarget:   params.DiscoveryTarget,
+		UpstreamID:        params.UpstreamID,
+		ForbidList:        params.ForbidList,
+	}
+	if err := s.lb.Save(c, tx, loadBalance); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to add UDP service load balancing information")
+	}
+	udpRule := &enity.UdpRule{
+		ServiceID:      info.ID,
+		Port:           params.Port,
+		SessionTimeout: params.SessionTimeout,
+	}
+	if err := s.udp.Save(c, tx, udpRule); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to add UDP service rule information")
+	}
+	accessControl := &enity.AccessControl{
+		ServiceID:         info.ID,
+		OpenAuth:          params.OpenAuth,
+		BlackList:         params.BlackList,
+		WhiteList:         params.WhiteList,
+		WhiteHostName:     params.WhiteHostName,
+		ClientIPFlowLimit: params.ClientIPFlowLimit,
+		ServiceFlowLimit:  params.ServiceFlowLimit,
+	}
+	if err := s.ac.Save(c, tx, accessControl); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to add UDP service permission information")
+	}
+	after := &enity.ServiceDetail{Info: info, UDPRule: udpRule, LoadBalance: loadBalance, AccessControl: accessControl}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionAdd, params.ServiceName, nil, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionAdd, after, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+
+	// Publish data change message
+	message := &globals.DataChangeMessage{
+		Type:        "service",
+		Payload:     params.ServiceName,
+		ServiceType: globals.LoadTypeUDP,
+		Operation:   globals.DataInsert,
+	}
+	if err := globals.MessageQueue.Publish(globals.DataChange, message); err != nil {
+		log.Error("error publishing message", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return fmt.Errorf("failed to publish save message")
+	}
+	log.Info("published save message successfully", zap.Any("data", params), zap.String("trace_id", c.GetString("TraceID")))
+
+	return nil
+}
+
+// UpdateUDP 更新UDP服务
+func (s *udpServiceLogic) UpdateUDP(c *gin.Context, params *dto.ServiceUpdateUdpInput) error {
+	// ip列表与权重列表数量是否一致
+	if err := checkServiceUpstream(c, s.db, params.UpstreamID, params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
+	}
+
+	tx := s.db.Begin()
+
+	detail, err := s.info.GetServiceDetail(c, s.db, &enity.ServiceInfo{ID: params.ID})
+	if err != nil {
+		return fmt.Errorf("UDP service does not exist")
+	}
+
+	before := auditSnapshot(detail)
+
+	info := detail.Info
+	info.ServiceDesc = params.ServiceDesc
+	if err := s.info.Save(c, tx, info); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to Save UDP service description")
+	}
+
+	loadBalance := &enity.LoadBalance{}
+	if detail.LoadBalance != nil {
+		loadBalance = detail.LoadBalance
+	}
+	loadBalance.ServiceID = info.ID
+	loadBalance.RoundType = params.RoundType
+	loadBalance.HashKey = params.HashKey
+	loadBalance.HashReplicas = params.HashReplicas
+	loadBalance.HashLoadFactor = params.HashLoadFactor
+	loadBalance.SlowStart = params.SlowStart
+	loadBalance.IpList = params.IpList
+	loadBalance.WeightList = params.WeightList
+	loadBalance.PriorityList = params.PriorityList
+	loadBalance.FailoverThreshold = params.FailoverThreshold
+	loadBalance.DiscoveryType = params.DiscoveryType
+	loadBalance.DiscoveryTarget = params.DiscoveryTarget
+	loadBalance.UpstreamID = params.UpstreamID
+	loadBalance.ForbidList = params.ForbidList
+	if err := s.lb.Save(c, tx, loadBalance); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to Save UDP service load balancing information")
+	}
+
+	udpRule := &enity.UdpRule{}
+	if detail.UDPRule != nil {
+		udpRule = detail.UDPRule
+	}
+	udpRule.ServiceID = info.ID
+	udpRule.Port = params.Port
+	udpRule.SessionTimeout = params.SessionTimeout
+	if err := s.udp.Save(c, tx, udpRule); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to Save UDP service rule information")
+	}
+
+	accessControl := &enity.AccessControl{}
+	if detail.AccessControl != nil {
+		accessControl = detail.AccessControl
+	}
+	accessControl.ServiceID = info.ID
+	accessControl.OpenAuth = params.OpenAuth
+	accessControl.BlackList = params.BlackList
+	accessControl.WhiteList = params.WhiteList
+	accessControl.WhiteHostName = params.WhiteHostName
+	accessControl.ClientIPFlowLimit = params.ClientIPFlowLimit
+	accessControl.ServiceFlowLimit = params.ServiceFlowLimit
+	if err := s.ac.Save(c, tx, accessControl); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to Save UDP service permission information")
+	}
+
+	after := &enity.ServiceDetail{Info: info, UDPRule: udpRule, LoadBalance: loadBalance, AccessControl: accessControl}
+	if err := recordAudit(c, tx, enity.AuditResourceService, enity.AuditActionUpdate, info.ServiceName, before, after); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := recordRevision(c, tx, enity.ServiceRevisionUpdate, after, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+
+	// Publish data change message
+	message := &globals.DataChangeMessage{
+		Type:        "service",
+		Payload:     params.ServiceName,
+		ServiceType: globals.LoadTypeUDP,
+		Operation:   globals.DataUpdate,
+	}
+	if err := globals.MessageQueue.Publish(globals.DataChange, message); err != nil {
+		log.Error("error publishing message", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return fmt.Errorf("failed to publish save message")
+	}
+	log.Info("published save message successfully", zap.Any("data", params), zap.String("trace_id", c.GetString("TraceID")))
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package logic
+
+import (
+	"crypto/x509"
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/globals"
+	"gateway/pkg/database/mysql"
+	"gateway/pkg/log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type UpstreamLogic interface {
+	UpstreamList(c *gin.Context, params *dto.UpstreamListInput) ([]dto.UpstreamListItemOutput, int64, error)
+	UpstreamDetail(c *gin.Context, params *dto.UpstreamDetailInput) (*enity.Upstream, error)
+	UpstreamAdd(c *gin.Context, params *dto.UpstreamAddInput) error
+	UpstreamUpdate(c *gin.Context, params *dto.UpstreamUpdateInput) error
+	UpstreamDelete(c *gin.Context, params *dto.UpstreamDetailInput) error
+}
+
+type upstreamLogic struct {
+	dao.Upstream
+	db *gorm.DB
+}
+
+func NewUpstreamLogic() *upstreamLogic {
+	return &upstreamLogic{
+		dao.NewUpstream(),
+		mysql.GetDB(),
+	}
+}
+
+// UpstreamList 分页返回上游及引用它的服务
+func (ul *upstreamLogic) UpstreamList(c *gin.Context, params *dto.UpstreamListInput) ([]dto.UpstreamListItemOutput, int64, error) {
+	queryConditions := []func(db *gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Where("(name like ? or description like ?)", "%"+params.Info+"%", "%"+params.Info+"%")
+		},
+	}
+	list, total, err := ul.PageList(c, ul.db, queryConditions, params.PageNo, params.PageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get upstream list")
+	}
+
+	outputList := []dto.UpstreamListItemOutput{}
+	for _, item := range list {
+		services, err := ul.ServiceNames(c, ul.db, item.ID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get upstream services")
+		}
+		outputList = append(outputList, dto.UpstreamListItemOutput{
+			ID:            item.ID,
+			Name:          item.Name,
+			Description:   item.Description,
+			IpList:        item.IpList,
+			DiscoveryType: item.DiscoveryType,
+			CheckMethod:   item.CheckMethod,
+			TLSEnable:     item.TLSEnable,
+			Services:      services,
+			UpdatedAt:     item.UpdatedAt,
+		})
+	}
+	return outputList, total, nil
+}
+
+func (ul *upstreamLogic) UpstreamDetail(c *gin.Context, params *dto.UpstreamDetailInput) (*enity.Upstream, error) {
+	return ul.getUpstream(c, ul.db, params.ID)
+}
+
+// getUpstream 查询未删除的上游
+func (ul *upstreamLogic) getUpstream(c *gin.Context, db *gorm.DB, id int64) (*enity.Upstream, error) {
+	info, err := ul.Get(c, db, &enity.Upstream{ID: id})
+	if err != nil || info.IsDelete == 1 {
+		return nil, fmt.Errorf("upstream not found")
+	}
+	return info, nil
+}
+
+func (ul *upstreamLogic) UpstreamAdd(c *gin.Context, params *dto.UpstreamAddInput) error {
+	if err := checkUpstreamInput(params); err != nil {
+		return err
+	}
+	tx := ul.db.Begin()
+	if err := ul.checkNameUnique(c, tx, params.Name, 0); err != nil {
+		tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	upstream := &enity.Upstream{CreatedAt: now}
+	fillUpstream(upstream, params, now)
+	if err := ul.Save(c, tx, upstream); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to add upstream")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceUpstream, enity.AuditActionAdd, upstream.Name, nil, upstream); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return publishUpstreamChange(c, upstream.ID, globals.DataInsert)
+}
+
+func (ul *upstreamLogic) UpstreamUpdate(c *gin.Context, params *dto.UpstreamUpdateInput) error {
+	if err := checkUpstreamInput(&params.UpstreamAddInput); err != nil {
+		return err
+	}
+	tx := ul.db.Begin()
+	info, err := ul.getUpstream(c, tx, params.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := ul.checkServiceScope(c, tx, info.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := ul.checkNameUnique(c, tx, params.Name, info.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	before := auditSnapshot(info)
+	fillUpstream(info, &params.UpstreamAddInput, time.Now())
+	if err := ul.Save(c, tx, info); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update upstream")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceUpstream, enity.AuditActionUpdate, info.Name, before, info); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return publishUpstreamChange(c, info.ID, globals.DataUpdate)
+}
+
+// UpstreamDelete 删除上游, 仍被服务引用时拒绝删除
+func (ul *upstreamLogic) UpstreamDelete(c *gin.Context, params *dto.UpstreamDetailInput) error {
+	tx := ul.db.Begin()
+	info, err := ul.getUpstream(c, tx, params.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := ul.checkServiceScope(c, tx, info.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	services, err := ul.ServiceNames(c, tx, info.ID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to get upstream services")
+	}
+	if len(services) > 0 {
+		tx.Rollback()
+		return fmt.Errorf("upstream is still used by services: %s", strings.Join(services, ","))
+	}
+	before := auditSnapshot(info)
+	info.IsDelete = 1
+	if err := ul.Save(c, tx, info); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete upstream")
+	}
+	if err := recordAudit(c, tx, enity.AuditResourceUpstream, enity.AuditActionDelete, info.Name, before, nil); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tx.Commit()
+	return publishUpstreamChange(c, info.ID, globals.DataDelete)
+}
+
+// checkServiceScope 修改上游会影响所有引用它的服务, 受服务范围限制的账号只能修改引用的服务都在范围内的上游
+func (ul *upstreamLogic) checkServiceScope(c *gin.Context, db *gorm.DB, id int64) error {
+	admin := sessionAdmin(c)
+	if admin == nil || admin.ScopeAll() {
+		return nil
+	}
+	services, err := ul.ServiceNames(c, db, id)
+	if err != nil {
+		return fmt.Errorf("failed to get upstream services")
+	}
+	for _, name := range services {
+		if !admin.CanAccessService(name) {
+			return fmt.Errorf("upstream is used by service %s which is out of your scope", name)
+		}
+	}
+	return nil
+}
+
+// checkNameUnique 上游名称在未删除的上游中唯一, id 为正在修改的上游
+func (ul *upstreamLogic) checkNameUnique(c *gin.Context, db *gorm.DB, name string, id int64) error {
+	list, err := ul.GetAll(c, db, []func(db *gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB {
+			return db.Where("name = ?", name)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check upstream name")
+	}
+	for _, item := range list {
+		if item.ID != id {
+			return fmt.Errorf("upstream name is already taken")
+		}
+	}
+	return nil
+}
+
+// checkUpstreamInput 检查节点配置和 CA 证书
+func checkUpstreamInput(params *dto.UpstreamAddInput) error {
+	if err := checkUpstreamNodes(params.IpList, params.WeightList, params.PriorityList, params.DiscoveryType, params.DiscoveryTarget); err != nil {
+		return err
+	}
+	if params.TLSCACert != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(params.TLSCACert)) {
+		return fmt.Errorf("invalid CA certificate: no PEM certificate found")
+	}
+	return nil
+}
+
+func fillUpstream(upstream *enity.Upstream, params *dto.UpstreamAddInput, now time.Time) {
+	upstream.Name = params.Name
+	upstream.Description = params.Description
+	upstream.IpList = params.IpList
+	upstream.WeightList = params.WeightList
+	upstream.PriorityList = params.PriorityList
+	upstream.FailoverThreshold = params.FailoverThreshold
+	upstream.ForbidList = params.ForbidList
+	upstream.DiscoveryType = params.DiscoveryType
+	upstream.DiscoveryTarget = params.DiscoveryTarget
+	upstream.CheckMethod = params.CheckMethod
+	upstream.CheckPath = params.CheckPath
+	upstream.CheckTimeout = params.CheckTimeout
+	upstream.CheckInterval = params.CheckInterval
+	upstream.CheckMaxErrNum = params.CheckMaxErrNum
+	upstream.SlowStart = params.SlowStart
+	upstream.ConnectTimeout = params.ConnectTimeout
+	upstream.HeaderTimeout = params.HeaderTimeout
+	upstream.IdleTimeout = params.IdleTimeout
+	upstream.MaxIdle = params.MaxIdle
+	upstream.TLSEnable = params.TLSEnable
+	upstream.TLSServerName = params.TLSServerName
+	upstream.TLSInsecureSkipVerify = params.TLSInsecureSkipVerify
+	upstream.TLSCACert = params.TLSCACert
+	upstream.UpdatedAt = now
+}
+
+// publishUpstreamChange 通知代理重新加载上游, 引用该上游的服务会重建负载均衡器和连接池
+func publishUpstreamChange(c *gin.Context, id int64, operation string) error {
+	message := &globals.DataChangeMessage{
+		Type:      "upstream",
+		Payload:   strconv.FormatInt(id, 10),
+		Operation: operation,
+	}
+	if err := globals.MessageQueue.Publish(globals.DataChange, message); err != nil {
+		log.Error("error publishing message", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return fmt.Errorf("failed to publish save message")
+	}
+	log.Info("published save message successfully", zap.Int64("upstream_id", id), zap.String("trace_id", c.GetString("TraceID")))
+	return nil
+}
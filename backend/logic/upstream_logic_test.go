@@ -0,0 +1,45 @@
+package logic
+
+import (
+	"gateway/dao"
+	"gateway/enity"
+	"gateway/globals"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// upstreamServicesStub 返回固定的引用服务, 不访问数据库
+type upstreamServicesStub struct {
+	dao.Upstream
+	services []string
+}
+
+func (s upstreamServicesStub) ServiceNames(c *gin.Context, db *gorm.DB, upstreamID int64) ([]string, error) {
+	return s.services, nil
+}
+
+// TestUpstreamServiceScope 引用的服务有一个不在账号范围内时不允许修改上游
+func TestUpstreamServiceScope(t *testing.T) {
+	cases := []struct {
+		admin    *enity.Admin
+		services []string
+		allowed  bool
+	}{
+		{&enity.Admin{Role: enity.AdminRoleOperator, ServiceScope: "order_*"}, []string{"order_api", "order_job"}, true},
+		{&enity.Admin{Role: enity.AdminRoleOperator, ServiceScope: "order_*"}, []string{"order_api", "pay_api"}, false},
+		{&enity.Admin{Role: enity.AdminRoleOperator, ServiceScope: "order_*"}, nil, true},
+		{&enity.Admin{Role: enity.AdminRoleOperator}, []string{"pay_api"}, true},
+		{&enity.Admin{Role: enity.AdminRoleAdmin, ServiceScope: "order_*"}, []string{"pay_api"}, true},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set(globals.AdminInfoKey, tc.admin)
+		ul := &upstreamLogic{Upstream: upstreamServicesStub{services: tc.services}}
+		if err := ul.checkServiceScope(c, nil, 1); (err == nil) != tc.allowed {
+			t.Fatalf("role %s scope %q services %v: err = %v, want allowed %v", tc.admin.Role, tc.admin.ServiceScope, tc.services, err, tc.allowed)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestParams(t *testing.T) {
+	cases := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        map[string][]string
+	}{
+		{"query", http.MethodGet, "/service/detail?id=1&service_name=order_api", "", "",
+			map[string][]string{"id": {"1"}, "service_name": {"order_api"}}},
+		{"form", http.MethodPost, "/service/delete", "application/x-www-form-urlencoded", "id=2&service_name=pay_api",
+			map[string][]string{"id": {"2"}, "service_name": {"pay_api"}}},
+		{"json", http.MethodPost, "/service/update", "application/json", `{"id":3,"service_name":"order_api","port":8001.0,"nodes":["a"]}`,
+			map[string][]string{"id": {"3"}, "service_name": {"order_api"}, "port": {"8001"}}},
+		{"json with charset", http.MethodPost, "/service/update", "application/json; charset=utf-8", `{"service_name":"order_api"}`,
+			map[string][]string{"service_name": {"order_api"}}},
+		{"invalid json", http.MethodPost, "/service/update?id=1", "application/json", `{"id":`,
+			map[string][]string{"id": {"1"}}},
+		// 同名参数在 query 和 body 中取值不同时全部保留, 由调用方逐个检查
+		{"query and json ids", http.MethodPost, "/service/update?id=1", "application/json", `{"id":2}`,
+			map[string][]string{"id": {"1", "2"}}},
+		{"query and form ids", http.MethodPost, "/service/delete?id=1", "application/x-www-form-urlencoded", "id=2&id=3",
+			map[string][]string{"id": {"1", "2", "3"}}},
+		{"repeated query", http.MethodGet, "/stat/history?dimension=app&dimension=service&name=pay_api", "", "",
+			map[string][]string{"dimension": {"app", "service"}, "name": {"pay_api"}}},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		c.Request = httptest.NewRequest(tc.method, tc.target, body)
+		if tc.contentType != "" {
+			c.Request.Header.Set("Content-Type", tc.contentType)
+		}
+
+		got := requestParams(c)
+		for _, values := range got {
+			sort.Strings(values)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: params = %v, want %v", tc.name, got, tc.want)
+		}
+		// body 需要还原, 后续绑定参数时可以再次读取
+		if tc.body != "" {
+			restored, _ := io.ReadAll(c.Request.Body)
+			if string(restored) != tc.body {
+				t.Fatalf("%s: restored body = %q, want %q", tc.name, restored, tc.body)
+			}
+		}
+	}
+}
@@ -1,26 +1,111 @@
 package middleware
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"gateway/backend/dto"
+	"gateway/dao"
+	"gateway/enity"
 	"gateway/globals"
+	"gateway/pkg/database/mysql"
 	"gateway/pkg/log"
 	"gateway/pkg/response"
+	"gateway/utils"
 
 	"github.com/gin-gonic/contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// apiTokenTouchInterval 令牌最后使用时间的最小更新间隔, 避免每个请求都写库
+const apiTokenTouchInterval = time.Minute
+
+// SessionAuthMiddleware 校验登录状态, 每次请求都重新读取账号, 禁用或删除后立即失效
+// 请求带有 Authorization: Bearer <token> 时使用接口令牌认证, 不再读取 session
 func SessionAuthMiddleware() gin.HandlerFunc {
+	adminDao := dao.NewAdmin()
+	tokenDao := dao.NewApiTokenService()
 	return func(c *gin.Context) {
+		if token, ok := bearerToken(c); ok {
+			apiTokenAuth(c, adminDao, tokenDao, token)
+			return
+		}
+
 		session := sessions.Default(c)
-		if adminInfo, ok := session.Get(globals.AdminSessionInfoKey).(string); !ok || adminInfo == "" {
+		adminInfo, ok := session.Get(globals.AdminSessionInfoKey).(string)
+		if !ok || adminInfo == "" {
 			log.Error("user not login", zap.String("trace_id", c.GetString("TraceID")))
 			response.ResponseError(c, response.UserNotLoggedInErrCode, fmt.Errorf("user not login"))
 			c.Abort()
 			return
 		}
+
+		sessInfo := &dto.AdminSessionInfo{}
+		if err := json.Unmarshal([]byte(adminInfo), sessInfo); err != nil {
+			log.Error("invalid session info", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+			response.ResponseError(c, response.UserNotLoggedInErrCode, fmt.Errorf("invalid session info"))
+			c.Abort()
+			return
+		}
+		admin, err := adminDao.Get(c, mysql.GetDB(), &enity.Admin{ID: sessInfo.ID})
+		if err != nil || !admin.Enabled() {
+			log.Error("user disabled or deleted", zap.Int("id", sessInfo.ID), zap.String("trace_id", c.GetString("TraceID")))
+			session.Delete(globals.AdminSessionInfoKey)
+			session.Save()
+			response.ResponseError(c, response.UserNotLoggedInErrCode, fmt.Errorf("user is disabled or deleted"))
+			c.Abort()
+			return
+		}
+		c.Set(globals.AdminInfoKey, admin)
 		c.Next()
 	}
 }
+
+// apiTokenAuth 校验接口令牌, 当前账号为令牌角色和服务范围生效后的签发人
+func apiTokenAuth(c *gin.Context, adminDao dao.Admin, tokenDao dao.ApiTokenService, token string) {
+	now := time.Now()
+	apiToken, err := tokenDao.GetByHash(c, mysql.GetDB(), utils.HashApiToken(token))
+	if err != nil || !apiToken.Valid(now) {
+		log.Error("invalid api token", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		response.ResponseError(c, response.UserNotLoggedInErrCode, fmt.Errorf("api token is invalid, expired or revoked"))
+		c.Abort()
+		return
+	}
+	admin, err := adminDao.Get(c, mysql.GetDB(), &enity.Admin{ID: apiToken.AdminID})
+	if err != nil || !admin.Enabled() {
+		log.Error("api token owner disabled or deleted", zap.Int64("token_id", apiToken.ID), zap.Int("admin_id", apiToken.AdminID), zap.String("trace_id", c.GetString("TraceID")))
+		response.ResponseError(c, response.UserNotLoggedInErrCode, fmt.Errorf("api token owner is disabled or deleted"))
+		c.Abort()
+		return
+	}
+
+	if now.Sub(apiToken.LastUsedAt) >= apiTokenTouchInterval {
+		if err := tokenDao.Touch(c, mysql.GetDB(), apiToken.ID, now, c.ClientIP()); err != nil {
+			log.Warn("failed to update api token last used", zap.Int64("token_id", apiToken.ID), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		}
+	}
+	c.Set(globals.AdminInfoKey, apiToken.EffectiveAdmin(admin))
+	c.Set(globals.ApiTokenKey, apiToken)
+	c.Next()
+}
+
+// bearerToken 取出 Authorization 头中的接口令牌
+func bearerToken(c *gin.Context) (string, bool) {
+	auth := c.GetHeader("Authorization")
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// AdminFromContext 取出 SessionAuthMiddleware 写入的当前账号
+func AdminFromContext(c *gin.Context) *enity.Admin {
+	admin, _ := c.Get(globals.AdminInfoKey)
+	a, _ := admin.(*enity.Admin)
+	return a
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBearerToken(t *testing.T) {
+	cases := []struct {
+		header string
+		token  string
+		ok     bool
+	}{
+		{"", "", false},
+		{"Bearer gw_abc", "gw_abc", true},
+		{"Bearer  gw_abc ", "gw_abc", true},
+		{"Bearer ", "", false},
+		{"Bearer", "", false},
+		{"Basic dXNlcjpwYXNz", "", false},
+		{"bearer gw_abc", "", false},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		if tc.header != "" {
+			c.Request.Header.Set("Authorization", tc.header)
+		}
+		token, ok := bearerToken(c)
+		if token != tc.token || ok != tc.ok {
+			t.Fatalf("Authorization %q: got (%q, %v), want (%q, %v)", tc.header, token, ok, tc.token, tc.ok)
+		}
+	}
+}
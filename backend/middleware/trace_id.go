@@ -1,14 +1,33 @@
 package middleware
 
 import (
+	"gateway/pkg/trace"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
+// SetTraceID 沿用上游传入的 traceparent 中的 trace-id 和 X-Request-Id, 没有时重新生成
+// 请求结束时结束 server span, 交给 exporter 导出
 func SetTraceID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		traceID := uuid.New().String()
-		c.Set("TraceID", traceID)
+		ctx := trace.Extract(c.Request.Context(), c.Request.Header.Get)
+		ctx, span := trace.StartSpanWithKind(ctx, "http.request", trace.SpanKindServer)
+		defer span.End()
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.target", c.Request.URL.Path)
+		c.Request = c.Request.WithContext(ctx)
+
+		requestID := trace.RequestID(c.GetHeader(trace.RequestIDHeader))
+		c.Writer.Header().Set(trace.RequestIDHeader, requestID)
+		c.Set("TraceID", span.SpanContext.TraceID.String())
+		c.Set("RequestID", requestID)
 		c.Next()
+
+		span.SetAttribute("http.status_code", c.Writer.Status())
+		span.SetAttribute("request.id", requestID)
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			span.SetStatus(trace.StatusError, http.StatusText(c.Writer.Status()))
+		}
 	}
 }
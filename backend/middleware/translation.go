@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"gateway/enity"
 	"gateway/globals"
 	"reflect"
 	"regexp"
@@ -69,6 +70,13 @@ func registerCustomValidations(val *validator.Validate) {
 	val.RegisterValidation("valid_ipportlist", validIPPortList)
 	val.RegisterValidation("valid_iplist", validIPList)
 	val.RegisterValidation("valid_weightlist", validWeightList)
+	val.RegisterValidation("valid_hostlist", validHostList)
+	val.RegisterValidation("valid_method_route", validMethodRoute)
+	val.RegisterValidation("valid_method_limit", validMethodLimit)
+	val.RegisterValidation("valid_method_list", validMethodList)
+	val.RegisterValidation("valid_service_scope", validServiceScope)
+	val.RegisterValidation("valid_hash_key", validHashKey)
+	val.RegisterValidation("valid_origin_list", validOriginList)
 }
 
 func registerCustomTranslations(val *validator.Validate, trans ut.Translator) {
@@ -85,6 +93,13 @@ func registerCustomTranslations(val *validator.Validate, trans ut.Translator) {
 		{"valid_ipportlist", registerIPPortListTranslation, translateIPPortList},
 		{"valid_iplist", registerIPListTranslation, translateIPList},
 		{"valid_weightlist", registerWeightListTranslation, translateWeightList},
+		{"valid_hostlist", registerHostListTranslation, translateHostList},
+		{"valid_method_route", registerMethodRouteTranslation, translateMethodRoute},
+		{"valid_method_limit", registerMethodLimitTranslation, translateMethodLimit},
+		{"valid_method_list", registerMethodListTranslation, translateMethodList},
+		{"valid_service_scope", registerServiceScopeTranslation, translateServiceScope},
+		{"valid_hash_key", registerHashKeyTranslation, translateHashKey},
+		{"valid_origin_list", registerOriginListTranslation, translateOriginList},
 	}
 
 	for _, t := range translations {
@@ -93,7 +108,8 @@ func registerCustomTranslations(val *validator.Validate, trans ut.Translator) {
 }
 
 func validUsername(fl validator.FieldLevel) bool {
-	return fl.Field().String() == "admin"
+	matched, _ := regexp.Match(`^[a-zA-Z0-9_.-]{3,64}$`, []byte(fl.Field().String()))
+	return matched
 }
 
 func validServiceName(fl validator.FieldLevel) bool {
@@ -130,10 +146,12 @@ func validHeaderTransfor(fl validator.FieldLevel) bool {
 	return true
 }
 
+// validIPPortList 上游列表, 每项为 ip:port, host:port 或 srv:<SRV记录名>
 func validIPPortList(fl validator.FieldLevel) bool {
 	ipPortPattern, _ := regexp.Compile(`^\S+:\d+$`)
+	srvPattern, _ := regexp.Compile(`^srv:[a-zA-Z0-9_-]+(\.[a-zA-Z0-9_-]+)*\.?$`)
 	for _, ms := range strings.Split(fl.Field().String(), ",") {
-		if !ipPortPattern.Match([]byte(ms)) {
+		if !ipPortPattern.Match([]byte(ms)) && !srvPattern.Match([]byte(ms)) {
 			return false
 		}
 	}
@@ -163,6 +181,86 @@ func validWeightList(fl validator.FieldLevel) bool {
 	return true
 }
 
+func validHostList(fl validator.FieldLevel) bool {
+	if fl.Field().String() == "" {
+		return true
+	}
+	hostPattern, _ := regexp.Compile(`^(\*\.)?[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*$`)
+	for _, ms := range strings.Split(fl.Field().String(), ",") {
+		if !hostPattern.Match([]byte(ms)) {
+			return false
+		}
+	}
+	return true
+}
+
+func validMethodRoute(fl validator.FieldLevel) bool {
+	if fl.Field().String() == "" {
+		return true
+	}
+	ipPortPattern, _ := regexp.Compile(`^\S+:\d+$`)
+	for _, ms := range strings.Split(fl.Field().String(), ",") {
+		items := strings.Fields(ms)
+		if len(items) < 2 || !strings.HasPrefix(items[0], "/") {
+			return false
+		}
+		for _, addr := range items[1:] {
+			if !ipPortPattern.Match([]byte(addr)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func validMethodLimit(fl validator.FieldLevel) bool {
+	if fl.Field().String() == "" {
+		return true
+	}
+	qpsPattern, _ := regexp.Compile(`^\d+$`)
+	for _, ms := range strings.Split(fl.Field().String(), ",") {
+		items := strings.Fields(ms)
+		if len(items) != 2 || !strings.HasPrefix(items[0], "/") || !qpsPattern.Match([]byte(items[1])) {
+			return false
+		}
+	}
+	return true
+}
+
+func validMethodList(fl validator.FieldLevel) bool {
+	if fl.Field().String() == "" {
+		return true
+	}
+	for _, ms := range strings.Split(fl.Field().String(), ",") {
+		if !strings.HasPrefix(ms, "/") || strings.ContainsAny(ms, " \t") {
+			return false
+		}
+	}
+	return true
+}
+
+// validServiceScope 服务范围, 逗号分隔的服务名, 以*结尾表示前缀匹配
+func validServiceScope(fl validator.FieldLevel) bool {
+	if fl.Field().String() == "" {
+		return true
+	}
+	for _, ms := range strings.Split(fl.Field().String(), ",") {
+		if matched, _ := regexp.Match(`^[a-zA-Z0-9_]+\*?$`, []byte(strings.TrimSpace(ms))); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// validHashKey 一致性hash的key来源
+func validHashKey(fl validator.FieldLevel) bool {
+	return enity.ValidHashKey(fl.Field().String())
+}
+
+func validOriginList(fl validator.FieldLevel) bool {
+	return enity.ValidOriginList(fl.Field().String())
+}
+
 // Register translation functions
 func registerUsernameTranslation(ut ut.Translator) error {
 	return ut.Add("valid_username", "{0} 填写不正确哦", true)
@@ -196,6 +294,34 @@ func registerWeightListTranslation(ut ut.Translator) error {
 	return ut.Add("valid_weightlist", "{0} 不符合输入格式", true)
 }
 
+func registerHostListTranslation(ut ut.Translator) error {
+	return ut.Add("valid_hostlist", "{0} 不符合输入格式", true)
+}
+
+func registerMethodRouteTranslation(ut ut.Translator) error {
+	return ut.Add("valid_method_route", "{0} 不符合输入格式", true)
+}
+
+func registerMethodLimitTranslation(ut ut.Translator) error {
+	return ut.Add("valid_method_limit", "{0} 不符合输入格式", true)
+}
+
+func registerMethodListTranslation(ut ut.Translator) error {
+	return ut.Add("valid_method_list", "{0} 不符合输入格式", true)
+}
+
+func registerServiceScopeTranslation(ut ut.Translator) error {
+	return ut.Add("valid_service_scope", "{0} 不符合输入格式", true)
+}
+
+func registerHashKeyTranslation(ut ut.Translator) error {
+	return ut.Add("valid_hash_key", "{0} 不符合输入格式", true)
+}
+
+func registerOriginListTranslation(ut ut.Translator) error {
+	return ut.Add("valid_origin_list", "{0} 不符合输入格式", true)
+}
+
 // Translate error functions
 func translateUsername(ut ut.Translator, fe validator.FieldError) string {
 	t, _ := ut.T("valid_username", fe.Field())
@@ -236,3 +362,38 @@ func translateWeightList(ut ut.Translator, fe validator.FieldError) string {
 	t, _ := ut.T("valid_weightlist", fe.Field())
 	return t
 }
+
+func translateHostList(ut ut.Translator, fe validator.FieldError) string {
+	t, _ := ut.T("valid_hostlist", fe.Field())
+	return t
+}
+
+func translateMethodRoute(ut ut.Translator, fe validator.FieldError) string {
+	t, _ := ut.T("valid_method_route", fe.Field())
+	return t
+}
+
+func translateMethodLimit(ut ut.Translator, fe validator.FieldError) string {
+	t, _ := ut.T("valid_method_limit", fe.Field())
+	return t
+}
+
+func translateMethodList(ut ut.Translator, fe validator.FieldError) string {
+	t, _ := ut.T("valid_method_list", fe.Field())
+	return t
+}
+
+func translateServiceScope(ut ut.Translator, fe validator.FieldError) string {
+	t, _ := ut.T("valid_service_scope", fe.Field())
+	return t
+}
+
+func translateHashKey(ut ut.Translator, fe validator.FieldError) string {
+	t, _ := ut.T("valid_hash_key", fe.Field())
+	return t
+}
+
+func translateOriginList(ut ut.Translator, fe validator.FieldError) string {
+	t, _ := ut.T("valid_origin_list", fe.Field())
+	return t
+}
@@ -3,6 +3,7 @@ package router
 import (
 	"gateway/backend/controller"
 	"gateway/backend/middleware"
+	"gateway/enity"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,5 +18,19 @@ func AdminRegister(router *gin.Engine) {
 		adminRouter.GET("/login_out", c.AdminLoginOut)
 		adminRouter.GET("/admin_info", middleware.SessionAuthMiddleware(), c.AdminInfo)
 		adminRouter.POST("/change_pwd", middleware.SessionAuthMiddleware(), c.AdminChangePwd)
+
+		// 管理员账号管理需要 admin 角色
+		userRouter := adminRouter.Group("", middleware.SessionAuthMiddleware(), middleware.RoleMiddleware(enity.AdminRoleAdmin))
+		userRouter.GET("/user_list", c.AdminList)
+		userRouter.POST("/user_add", c.AdminAdd)
+		userRouter.POST("/user_update", c.AdminUpdate)
+		userRouter.GET("/user_delete", c.AdminDelete)
+
+		// 接口令牌, 每个账号管理自己签发的令牌
+		tokenController := controller.NewApiTokenController()
+		tokenRouter := adminRouter.Group("", middleware.SessionAuthMiddleware())
+		tokenRouter.GET("/token_list", tokenController.ApiTokenList)
+		tokenRouter.POST("/token_add", tokenController.ApiTokenAdd)
+		tokenRouter.GET("/token_revoke", tokenController.ApiTokenRevoke)
 	}
 }
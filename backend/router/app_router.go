@@ -3,6 +3,7 @@ package router
 import (
 	"gateway/backend/controller"
 	"gateway/backend/middleware"
+	"gateway/enity"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,10 +19,13 @@ func AppRegister(router *gin.Engine) {
 
 		appRouter.GET("/app_list", controller.APPList)
 		appRouter.GET("/app_detail", controller.APPDetail)
-		appRouter.GET("/app_delete", controller.APPDelete)
-		appRouter.POST("/app_add", controller.APPAdd)
-		appRouter.POST("/app_update", controller.APPUpdate)
 		appRouter.GET("/app_stat", controller.APPStat)
 
+		// 修改租户需要 operator 及以上角色
+		writeRouter := appRouter.Group("", middleware.RoleMiddleware(enity.AdminRoleOperator))
+		writeRouter.GET("/app_delete", controller.APPDelete)
+		writeRouter.POST("/app_add", controller.APPAdd)
+		writeRouter.POST("/app_update", controller.APPUpdate)
+
 	}
 }
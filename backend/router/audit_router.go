@@ -0,0 +1,23 @@
+package router
+
+import (
+	"gateway/backend/controller"
+	"gateway/backend/middleware"
+	"gateway/enity"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AuditRegister(router *gin.Engine) {
+	auditRouter := router.Group("/audit")
+	{
+		auditRouter.Use(
+			middleware.SessionAuthMiddleware(),
+			middleware.RoleMiddleware(enity.AdminRoleAdmin),
+		)
+
+		controller := controller.NewAuditController()
+
+		auditRouter.GET("/audit_list", controller.AuditList)
+	}
+}
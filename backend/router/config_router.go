@@ -0,0 +1,23 @@
+package router
+
+import (
+	"gateway/backend/controller"
+	"gateway/backend/middleware"
+	"gateway/enity"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ConfigRegister(router *gin.Engine) {
+	configRouter := router.Group("/config")
+	{
+		configRouter.Use(
+			middleware.SessionAuthMiddleware(),
+		)
+
+		controller := controller.NewConfigController()
+
+		configRouter.GET("/export", controller.ConfigExport)
+		configRouter.POST("/import", middleware.RoleMiddleware(enity.AdminRoleOperator), controller.ConfigImport)
+	}
+}
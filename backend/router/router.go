@@ -59,5 +59,17 @@ func InitRouter() *gin.Engine {
 	// 注册dashboard路由
 	DashboardRegister(router)
 
+	// 注册stat路由
+	StatRegister(router)
+
+	// 注册audit路由
+	AuditRegister(router)
+
+	// 注册config路由
+	ConfigRegister(router)
+
+	// 注册upstream路由
+	UpstreamRegister(router)
+
 	return router
 }
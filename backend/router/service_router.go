@@ -3,6 +3,7 @@ package router
 import (
 	"gateway/backend/controller"
 	"gateway/backend/middleware"
+	"gateway/enity"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,19 +13,31 @@ func ServiceRegister(router *gin.Engine) {
 	{
 		serviceRouter.Use(
 			middleware.SessionAuthMiddleware(),
+			middleware.ServiceScopeMiddleware(),
 		)
 
 		controller := controller.NewServiceController()
 
 		serviceRouter.GET("/service_list", controller.ServiceList)
-		serviceRouter.GET("/service_delete", controller.ServiceDelete)
 		serviceRouter.GET("/service_detail", controller.ServiceDetail)
-		serviceRouter.POST("/service_add_http", controller.ServiceAddHttp)
-		serviceRouter.POST("/service_update_http", controller.ServiceUpdateHttp)
-		serviceRouter.POST("/service_add_tcp", controller.ServiceAddTcp)
-		serviceRouter.POST("/service_update_tcp", controller.ServiceUpdateTcp)
-		serviceRouter.POST("/service_add_grpc", controller.ServiceAddGrpc)
-		serviceRouter.POST("/service_update_grpc", controller.ServiceUpdateGrpc)
 		serviceRouter.GET("/service_stat", controller.ServiceStat)
+		serviceRouter.GET("/service_revision_list", controller.ServiceRevisionList)
+		serviceRouter.GET("/service_revision_diff", controller.ServiceRevisionDiff)
+
+		// 修改服务需要 operator 及以上角色
+		writeRouter := serviceRouter.Group("", middleware.RoleMiddleware(enity.AdminRoleOperator))
+		writeRouter.GET("/service_delete", controller.ServiceDelete)
+		writeRouter.POST("/service_add_http", controller.ServiceAddHttp)
+		writeRouter.POST("/service_update_http", controller.ServiceUpdateHttp)
+		writeRouter.POST("/service_add_tcp", controller.ServiceAddTcp)
+		writeRouter.POST("/service_update_tcp", controller.ServiceUpdateTcp)
+		writeRouter.POST("/service_add_udp", controller.ServiceAddUdp)
+		writeRouter.POST("/service_update_udp", controller.ServiceUpdateUdp)
+		writeRouter.POST("/service_add_grpc", controller.ServiceAddGrpc)
+		writeRouter.POST("/service_update_grpc", controller.ServiceUpdateGrpc)
+		writeRouter.POST("/service_rollback", controller.ServiceRollback)
+		writeRouter.POST("/node_drain", controller.ServiceNodeDrain)
+		writeRouter.POST("/node_disable", controller.ServiceNodeDisable)
+		writeRouter.POST("/node_enable", controller.ServiceNodeEnable)
 	}
 }
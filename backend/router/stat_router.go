@@ -0,0 +1,24 @@
+package router
+
+import (
+	"gateway/backend/controller"
+	"gateway/backend/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func StatRegister(router *gin.Engine) {
+	statRouter := router.Group("/stat")
+	{
+		statRouter.Use(
+			middleware.SessionAuthMiddleware(),
+			middleware.ServiceScopeMiddleware(),
+		)
+
+		controller := controller.NewStatController()
+
+		statRouter.GET("/flow", controller.FlowStat)
+		statRouter.GET("/history", controller.FlowHistory)
+		statRouter.GET("/history_export", controller.FlowHistoryExport)
+	}
+}
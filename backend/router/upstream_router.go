@@ -0,0 +1,27 @@
+package router
+
+import (
+	"gateway/backend/controller"
+	"gateway/backend/middleware"
+	"gateway/enity"
+
+	"github.com/gin-gonic/gin"
+)
+
+func UpstreamRegister(router *gin.Engine) {
+	upstreamRouter := router.Group("/upstream")
+	{
+		upstreamRouter.Use(middleware.SessionAuthMiddleware())
+
+		controller := controller.NewUpstreamController()
+
+		upstreamRouter.GET("/upstream_list", controller.UpstreamList)
+		upstreamRouter.GET("/upstream_detail", controller.UpstreamDetail)
+
+		// 修改上游需要 operator 及以上角色
+		writeRouter := upstreamRouter.Group("", middleware.RoleMiddleware(enity.AdminRoleOperator))
+		writeRouter.GET("/upstream_delete", controller.UpstreamDelete)
+		writeRouter.POST("/upstream_add", controller.UpstreamAdd)
+		writeRouter.POST("/upstream_update", controller.UpstreamUpdate)
+	}
+}
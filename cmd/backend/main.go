@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"gateway/backend/logic"
 	"gateway/backend/router"
 	"gateway/configs"
 	"gateway/globals"
@@ -11,6 +12,7 @@ import (
 
 	Init "gateway/init"
 	"gateway/pkg/log"
+	"gateway/pkg/trace"
 	"net/http"
 	"time"
 
@@ -22,6 +24,15 @@ func main() {
 	Init.Init()
 	defer Init.Cleanup()
 	globals.Init()
+	// 后台服务使用单独的 service.name 上报 span
+	traceConfig := *configs.GetTraceConfig()
+	traceConfig.ServiceName = traceConfig.BackendName
+	trace.Setup(&traceConfig)
+
+	// 后台定期将 redis 流量统计汇总到 mysql
+	aggCtx, aggCancel := context.WithCancel(context.Background())
+	defer aggCancel()
+	go logic.NewFlowStatAggregator().Run(aggCtx)
 
 	r := router.InitRouter()
 
@@ -53,6 +64,12 @@ func main() {
 	if err := gatewaySrv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:  ", zap.Error(err))
 	}
+	// 上报剩余的 span
+	traceCtx, traceCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer traceCancel()
+	if err := trace.Shutdown(traceCtx); err != nil {
+		log.Error("failed to flush spans", zap.Error(err))
+	}
 	log.Info("Server exiting")
 
 }
@@ -0,0 +1,251 @@
+// gatewayctl 通过后台接口导出/导入声明式配置
+//
+//	gatewayctl -addr http://localhost:8880 -user admin -password 123456 export -format yaml -o gateway.yaml
+//	gatewayctl -addr http://localhost:8880 -user admin -password 123456 apply -f gateway.yaml -dry-run
+//	GATEWAY_TOKEN=gwt_xxx gatewayctl -addr http://localhost:8880 apply -f gateway.yaml
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type response struct {
+	ErrorCode int             `json:"errno"`
+	ErrorMsg  string          `json:"errmsg"`
+	Data      json.RawMessage `json:"data"`
+	TraceID   string          `json:"trace_id"`
+}
+
+type importOutput struct {
+	DryRun  bool `json:"dry_run"`
+	Changes []struct {
+		Resource string `json:"resource"`
+		Name     string `json:"name"`
+		Action   string `json:"action"`
+		Diff     []struct {
+			Field  string      `json:"field"`
+			Before interface{} `json:"before"`
+			After  interface{} `json:"after"`
+		} `json:"diff"`
+	} `json:"changes"`
+}
+
+type client struct {
+	addr  string
+	token string
+	http  *http.Client
+}
+
+func main() {
+	addr := flag.String("addr", "http://localhost:8880", "后台地址")
+	user := flag.String("user", "admin", "管理员用户名")
+	password := flag.String("password", "", "管理员密码")
+	token := flag.String("token", os.Getenv("GATEWAY_TOKEN"), "接口令牌, 设置后不再使用用户名密码登录, 默认读取 GATEWAY_TOKEN")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	cli := &client{
+		addr:  strings.TrimRight(*addr, "/"),
+		token: *token,
+		http:  &http.Client{Jar: jar, Timeout: 30 * time.Second},
+	}
+	if cli.token == "" {
+		if err := cli.login(*user, *password); err != nil {
+			fatal(err)
+		}
+	}
+
+	var err error
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "export":
+		err = cli.export(args)
+	case "apply":
+		err = cli.apply(args)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: gatewayctl [flags] export [-format yaml|json] [-o file]\n")
+	fmt.Fprintf(os.Stderr, "       gatewayctl [flags] apply -f file [-dry-run] [-prune]\n\nflags:\n")
+	flag.PrintDefaults()
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "gatewayctl:", err)
+	os.Exit(1)
+}
+
+func (cli *client) login(user, password string) error {
+	_, err := cli.call(http.MethodPost, "/admin/login", map[string]string{"username": user, "password": password})
+	return err
+}
+
+func (cli *client) export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "yaml", "导出格式 yaml/json")
+	output := fs.String("o", "", "输出文件, 默认输出到标准输出")
+	fs.Parse(args)
+
+	req, err := cli.newRequest(http.MethodGet, "/config/export?format="+url.QueryEscape(*format), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := cli.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// 失败时返回的是统一的 json 响应, 成功时是配置文档本身
+	if err := decodeError(body); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("export: unexpected response %s", resp.Status)
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(body)
+		return err
+	}
+	return os.WriteFile(*output, body, 0644)
+}
+
+func (cli *client) apply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	file := fs.String("f", "", "配置文件, .json 按 json 解析, 其余按 yaml 解析")
+	dryRun := fs.Bool("dry-run", false, "只预览变更, 不写入")
+	prune := fs.Bool("prune", false, "删除配置文件中没有的服务和租户")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("apply: -f is required")
+	}
+	content, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(*file), ".json") {
+		format = "json"
+	}
+
+	data, err := cli.call(http.MethodPost, "/config/import", map[string]interface{}{
+		"format":  format,
+		"content": string(content),
+		"dry_run": boolInt(*dryRun),
+		"prune":   boolInt(*prune),
+	})
+	if err != nil {
+		return err
+	}
+	out := &importOutput{}
+	if err := json.Unmarshal(data, out); err != nil {
+		return err
+	}
+
+	if len(out.Changes) == 0 {
+		fmt.Println("no changes")
+		return nil
+	}
+	for _, change := range out.Changes {
+		fmt.Printf("%s %s %s\n", change.Action, change.Resource, change.Name)
+		for _, d := range change.Diff {
+			fmt.Printf("    %s: %v -> %v\n", d.Field, d.Before, d.After)
+		}
+	}
+	if out.DryRun {
+		fmt.Printf("%d change(s), dry run, nothing applied\n", len(out.Changes))
+	} else {
+		fmt.Printf("%d change(s) applied\n", len(out.Changes))
+	}
+	return nil
+}
+
+// call 发送 json 请求, 返回响应中的 data
+func (cli *client) call(method, path string, params interface{}) (json.RawMessage, error) {
+	bts, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	req, err := cli.newRequest(method, path, bytes.NewReader(bts))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := cli.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &response{}
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, fmt.Errorf("%s %s: unexpected response %s", method, path, resp.Status)
+	}
+	if out.ErrorCode != 0 {
+		return nil, fmt.Errorf("%s %s: %s (errno=%d, trace_id=%s)", method, path, out.ErrorMsg, out.ErrorCode, out.TraceID)
+	}
+	return out.Data, nil
+}
+
+// newRequest 创建请求, 配置了令牌时带上 Authorization 头
+func (cli *client) newRequest(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, cli.addr+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if cli.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cli.token)
+	}
+	return req, nil
+}
+
+// decodeError 识别统一 json 响应中的错误
+func decodeError(body []byte) error {
+	out := &response{}
+	if err := json.Unmarshal(body, out); err != nil || out.ErrorMsg == "" {
+		return nil
+	}
+	if out.ErrorCode != 0 {
+		return fmt.Errorf("export: %s (errno=%d, trace_id=%s)", out.ErrorMsg, out.ErrorCode, out.TraceID)
+	}
+	return nil
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,19 +1,27 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"gateway/configs"
 	"gateway/globals"
 	"gateway/metrics"
 	"gateway/mq"
+	"gateway/pkg/accesslog"
 	"gateway/pkg/database/redis"
 	"gateway/pkg/log"
+	"gateway/pkg/trace"
+	"gateway/proxy/grpc_proxy/grpcweb"
 	grpcRouter "gateway/proxy/grpc_proxy/router"
 	httpRouter "gateway/proxy/http_proxy/router"
 	"gateway/proxy/pkg"
 	tcpRouter "gateway/proxy/tcp_proxy/router"
+	udpRouter "gateway/proxy/udp_proxy/router"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	Init "gateway/init"
 
@@ -26,6 +34,9 @@ func main() {
 	defer Init.Cleanup()
 	pkg.Init()
 	globals.Init()
+	trace.Init()
+	accesslog.Init()
+	defer accesslog.Close()
 
 	metrics.RecordSystemMetrics()
 
@@ -37,6 +48,11 @@ func main() {
 		log.Fatal("failed to load app manager", zap.Error(err))
 	}
 
+	// Load descriptor sets for grpc-web json transcoding
+	if err := grpcweb.LoadDescriptorSets(configs.GetGrpcWebConfig().DescriptorSets); err != nil {
+		log.Fatal("failed to load grpc-web descriptor sets", zap.Error(err))
+	}
+
 	// Create a message queue instance
 	messageQueue := mq.Default(redis.GetRedisConnection())
 	// Subscribe to data change channel and reload data
@@ -69,6 +85,26 @@ func main() {
 				log.Error("failed to update service cache", zap.Error(err))
 				return
 			}
+		case "node":
+			serviceName := dataChangeMsg.Payload
+			// 只更新负载均衡器的可选节点, 处理中的请求和已有连接不受影响
+			log.Info("update service nodes", zap.String("serviceName", serviceName), zap.String("node", dataChangeMsg.Node), zap.String("state", dataChangeMsg.Operation))
+			if err := pkg.Cache.UpdateServiceNodes(serviceName, dataChangeMsg.ServiceType); err != nil {
+				log.Error("failed to update service nodes", zap.Error(err))
+				return
+			}
+		case "upstream":
+			upstreamID, err := strconv.ParseInt(dataChangeMsg.Payload, 10, 64)
+			if err != nil {
+				log.Error("invalid upstream id", zap.String("payload", dataChangeMsg.Payload), zap.Error(err))
+				return
+			}
+			// 引用该上游的服务重建负载均衡器和连接池
+			log.Info("update upstream", zap.Int64("upstreamID", upstreamID), zap.String("operation", dataChangeMsg.Operation))
+			if err := pkg.Cache.UpdateUpstream(upstreamID); err != nil {
+				log.Error("failed to update upstream", zap.Error(err))
+				return
+			}
 		default:
 			log.Warn("unknown message type", zap.String("type", dataChangeMsg.Type))
 		}
@@ -98,6 +134,11 @@ func main() {
 		tcpRouter.TcpProxyServerRun()
 	}()
 
+	// run udp proxy server
+	go func() {
+		udpRouter.UdpProxyServerRun()
+	}()
+
 	// go func() {
 	// 	// 每分钟清零错误请求计数器
 	// 	for range time.Tick(1 * time.Minute) {
@@ -115,4 +156,13 @@ func main() {
 	grpcRouter.GrpcProxyServerStop()
 	// stop tcp proxy server
 	tcpRouter.TcpProxyServerStop()
+	// stop udp proxy server
+	udpRouter.UdpProxyServerStop()
+
+	// flush the remaining spans
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := trace.Shutdown(ctx); err != nil {
+		log.Error("failed to flush spans", zap.Error(err))
+	}
 }
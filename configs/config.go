@@ -81,6 +81,70 @@ type ClusterConfig struct {
 	ClusterSslPort string `mapstructure:"cluster_ssl_port"`
 }
 
+// GrpcWebConfig - grpc-web 及 json 转码配置
+type GrpcWebConfig struct {
+	DescriptorSets []string `mapstructure:"descriptor_sets"` // protoc --include_imports --descriptor_set_out 生成的描述文件
+}
+
+// RuntimeConfig - 代理运行时信息接口, 会暴露上游地址、负载均衡器和探活状态, 默认关闭
+type RuntimeConfig struct {
+	Enable   bool     `mapstructure:"enable"`
+	AllowIPs []string `mapstructure:"allow_ips"` // 允许访问的客户端 ip, 按连接的对端地址判断, 为空时只允许本机访问
+}
+
+// TraceConfig - 链路追踪配置, span 通过 OTLP/HTTP(JSON) 上报
+type TraceConfig struct {
+	Enable        bool    `mapstructure:"enable"`
+	Endpoint      string  `mapstructure:"endpoint"`       // 例如 http://localhost:4318/v1/traces
+	ServiceName   string  `mapstructure:"service_name"`   // 上报的 service.name
+	BackendName   string  `mapstructure:"backend_name"`   // 后台服务上报的 service.name, 与代理的 span 区分
+	SampleRatio   float64 `mapstructure:"sample_ratio"`   // 无上游采样决策时的采样比例 0~1
+	BatchSize     int     `mapstructure:"batch_size"`     // 单次上报的最大 span 数
+	FlushInterval int     `mapstructure:"flush_interval"` // 上报间隔, 单位秒
+	Timeout       int     `mapstructure:"timeout"`        // 上报超时, 单位秒
+}
+
+// AccessLogConfig - 访问日志配置, 与应用日志分开输出
+// 采样用于控制整个实例写出的日志量, 所以 sample_rate 是全局配置而不按服务设置;
+// 排查单个服务时依靠总是记录的失败请求, 需要 body 时在服务上开启 need_log_body
+type AccessLogConfig struct {
+	Enable      bool     `mapstructure:"enable"`
+	Output      string   `mapstructure:"output"`        // stdout 或文件路径
+	Format      string   `mapstructure:"format"`        // json or text
+	Fields      []string `mapstructure:"fields"`        // 输出字段及顺序, 为空时输出全部字段
+	SampleRate  *float64 `mapstructure:"sample_rate"`   // 成功请求的采样比例 0~1, 未配置时为 1, 0 表示只记录失败请求, 失败请求总是记录
+	MaxBodySize int      `mapstructure:"max_body_size"` // body 最多记录的字节数, 是否记录 body 由服务的 need_log_body 决定
+	MaxSize     int      `mapstructure:"max_size"`
+	MaxBackups  int      `mapstructure:"max_backups"`
+	MaxAge      int      `mapstructure:"max_age"`
+	Compress    bool     `mapstructure:"compress"`
+}
+
+// FlowStatConfig - 流量统计配置, 各粒度统计桶在 redis 中的保留时间
+type FlowStatConfig struct {
+	MinuteRetention int `mapstructure:"minute_retention"` // 分钟桶保留时间, 单位小时
+	HourRetention   int `mapstructure:"hour_retention"`   // 小时桶保留时间, 单位天
+	DayRetention    int `mapstructure:"day_retention"`    // 天桶保留时间, 单位天
+
+	PersistInterval  int `mapstructure:"persist_interval"`  // 汇总到 mysql 的间隔, 单位秒
+	PersistLookback  int `mapstructure:"persist_lookback"`  // 每次汇总回溯的小时数
+	HistoryRetention int `mapstructure:"history_retention"` // mysql 中历史统计保留时间, 单位月
+}
+
+// DNSConfig - 上游域名解析配置, ip_list 中的 host:port 和 srv:<name> 条目按记录的 TTL 定期解析
+type DNSConfig struct {
+	Server     string `mapstructure:"server"`      // 域名服务器地址 host:port, 为空时使用系统解析并按 default_ttl 刷新
+	Timeout    int    `mapstructure:"timeout"`     // 单次查询超时, 单位秒
+	MinTTL     int    `mapstructure:"min_ttl"`     // 刷新间隔下限, 单位秒
+	MaxTTL     int    `mapstructure:"max_ttl"`     // 刷新间隔上限, 单位秒
+	DefaultTTL int    `mapstructure:"default_ttl"` // 拿不到 TTL 或解析失败时的刷新间隔, 单位秒
+}
+
+// DiscoveryConfig - 上游服务发现配置, 服务的 discovery_type 为 redis/http 时按 interval 轮询
+type DiscoveryConfig struct {
+	Interval int `mapstructure:"interval"` // 轮询间隔, 单位秒
+}
+
 // Global configuration variables
 var (
 	v                   = viper.New()
@@ -93,6 +157,14 @@ var (
 	httpsProxyConfig    *ServerConfig
 	metricsServerConfig *ServerConfig
 	clusterConfig       *ClusterConfig
+	tcpSniConfig        *ServerConfig
+	grpcWebConfig       *GrpcWebConfig
+	runtimeConfig       *RuntimeConfig
+	traceConfig         *TraceConfig
+	accessLogConfig     *AccessLogConfig
+	flowStatConfig      *FlowStatConfig
+	dnsConfig           *DNSConfig
+	discoveryConfig     *DiscoveryConfig
 
 	reloadTimer *time.Timer
 	reloadDelay = 5 * time.Second // 设置防抖动延迟时间
@@ -172,6 +244,39 @@ func loadConfig() {
 	if err != nil {
 		log.Printf("Error unmarshalling 'cluster' config: %v\n", err)
 	}
+
+	err = v.UnmarshalKey("tcp_sni", &tcpSniConfig)
+	if err != nil {
+		log.Printf("Error unmarshalling 'tcp_sni' config: %v\n", err)
+	}
+	err = v.UnmarshalKey("grpc_web", &grpcWebConfig)
+	if err != nil {
+		log.Printf("Error unmarshalling 'grpc_web' config: %v\n", err)
+	}
+	err = v.UnmarshalKey("runtime", &runtimeConfig)
+	if err != nil {
+		log.Printf("Error unmarshalling 'runtime' config: %v\n", err)
+	}
+	err = v.UnmarshalKey("trace", &traceConfig)
+	if err != nil {
+		log.Printf("Error unmarshalling 'trace' config: %v\n", err)
+	}
+	err = v.UnmarshalKey("access_log", &accessLogConfig)
+	if err != nil {
+		log.Printf("Error unmarshalling 'access_log' config: %v\n", err)
+	}
+	err = v.UnmarshalKey("flow_stat", &flowStatConfig)
+	if err != nil {
+		log.Printf("Error unmarshalling 'flow_stat' config: %v\n", err)
+	}
+	err = v.UnmarshalKey("dns", &dnsConfig)
+	if err != nil {
+		log.Printf("Error unmarshalling 'dns' config: %v\n", err)
+	}
+	err = v.UnmarshalKey("discovery", &discoveryConfig)
+	if err != nil {
+		log.Printf("Error unmarshalling 'discovery' config: %v\n", err)
+	}
 }
 
 // 向外部暴露的函数；用于取对应的配置
@@ -213,6 +318,70 @@ func GetClusterConfig() *ClusterConfig {
 	return clusterConfig
 }
 
+// GetTcpSniConfig 用于获取 TCP 共享端口 SNI 路由配置，未配置时 Addr 为空
+func GetTcpSniConfig() *ServerConfig {
+	if tcpSniConfig == nil {
+		return &ServerConfig{}
+	}
+	return tcpSniConfig
+}
+
+// GetGrpcWebConfig 用于获取 grpc-web 转码配置，未配置时返回空配置
+func GetGrpcWebConfig() *GrpcWebConfig {
+	if grpcWebConfig == nil {
+		return &GrpcWebConfig{}
+	}
+	return grpcWebConfig
+}
+
+// GetTraceConfig 用于获取链路追踪配置，未配置时不上报
+func GetTraceConfig() *TraceConfig {
+	if traceConfig == nil {
+		return &TraceConfig{}
+	}
+	return traceConfig
+}
+
+// GetRuntimeConfig 用于获取运行时信息接口配置，未配置时不开启
+func GetRuntimeConfig() *RuntimeConfig {
+	if runtimeConfig == nil {
+		return &RuntimeConfig{}
+	}
+	return runtimeConfig
+}
+
+// GetAccessLogConfig 用于获取访问日志配置，未配置时不记录
+func GetAccessLogConfig() *AccessLogConfig {
+	if accessLogConfig == nil {
+		return &AccessLogConfig{}
+	}
+	return accessLogConfig
+}
+
+// GetFlowStatConfig 用于获取流量统计配置，未配置时使用默认保留时间
+func GetFlowStatConfig() *FlowStatConfig {
+	if flowStatConfig == nil {
+		return &FlowStatConfig{}
+	}
+	return flowStatConfig
+}
+
+// GetDNSConfig 用于获取上游域名解析配置，未配置时使用系统解析
+func GetDNSConfig() *DNSConfig {
+	if dnsConfig == nil {
+		return &DNSConfig{}
+	}
+	return dnsConfig
+}
+
+// GetDiscoveryConfig 用于获取上游服务发现配置，未配置时使用默认轮询间隔
+func GetDiscoveryConfig() *DiscoveryConfig {
+	if discoveryConfig == nil {
+		return &DiscoveryConfig{}
+	}
+	return discoveryConfig
+}
+
 var rwmutex sync.RWMutex
 
 func GetInt(key string) int {
@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"gateway/enity"
+	"gateway/pkg/log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ApiTokenService interface {
+	Create(c *gin.Context, db *gorm.DB, data *enity.ApiToken) error
+	Get(c *gin.Context, db *gorm.DB, id int64) (*enity.ApiToken, error)
+	GetByHash(c *gin.Context, db *gorm.DB, hash string) (*enity.ApiToken, error)
+	PageList(c *gin.Context, db *gorm.DB, adminID int, pageNo, pageSize int) ([]enity.ApiToken, int64, error)
+	Revoke(c *gin.Context, db *gorm.DB, id int64) error
+	Touch(c *gin.Context, db *gorm.DB, id int64, usedAt time.Time, ip string) error
+}
+
+type apiTokenDao struct{}
+
+func NewApiTokenService() ApiTokenService {
+	return &apiTokenDao{}
+}
+
+// Create 保存新签发的令牌
+func (dao *apiTokenDao) Create(c *gin.Context, db *gorm.DB, data *enity.ApiToken) error {
+	if err := db.Create(data).Error; err != nil {
+		log.Error("error creating api token", zap.String("name", data.Name), zap.Int("admin_id", data.AdminID), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return err
+	}
+	return nil
+}
+
+// Get 按主键查询令牌
+func (dao *apiTokenDao) Get(c *gin.Context, db *gorm.DB, id int64) (*enity.ApiToken, error) {
+	out := &enity.ApiToken{}
+	if err := db.Where("id = ?", id).First(out).Error; err != nil {
+		log.Error("error getting api token", zap.Int64("id", id), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetByHash 按令牌摘要查询令牌
+func (dao *apiTokenDao) GetByHash(c *gin.Context, db *gorm.DB, hash string) (*enity.ApiToken, error) {
+	out := &enity.ApiToken{}
+	if err := db.Where("token_hash = ?", hash).First(out).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PageList 分页查询令牌, adminID 为 0 时查询全部签发人的令牌
+func (dao *apiTokenDao) PageList(c *gin.Context, db *gorm.DB, adminID int, pageNo, pageSize int) ([]enity.ApiToken, int64, error) {
+	tx := db.Model(&enity.ApiToken{})
+	if adminID > 0 {
+		tx = tx.Where("admin_id = ?", adminID)
+	}
+
+	total := int64(0)
+	if err := tx.Count(&total).Error; err != nil {
+		log.Error("error counting api token", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, 0, err
+	}
+	list := []enity.ApiToken{}
+	if err := tx.Order("id desc").Limit(pageSize).Offset((pageNo - 1) * pageSize).Find(&list).Error; err != nil {
+		log.Error("error listing api token", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// Revoke 吊销令牌, 吊销后不可恢复
+func (dao *apiTokenDao) Revoke(c *gin.Context, db *gorm.DB, id int64) error {
+	err := db.Model(&enity.ApiToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"revoked":   1,
+		"update_at": time.Now(),
+	}).Error
+	if err != nil {
+		log.Error("error revoking api token", zap.Int64("id", id), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+	}
+	return err
+}
+
+// Touch 记录令牌的最后使用时间和客户端ip
+func (dao *apiTokenDao) Touch(c *gin.Context, db *gorm.DB, id int64, usedAt time.Time, ip string) error {
+	return db.Model(&enity.ApiToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": usedAt,
+		"last_used_ip": ip,
+	}).Error
+}
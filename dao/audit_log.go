@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"gateway/enity"
+	"gateway/pkg/log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AuditLogService interface {
+	Create(c *gin.Context, db *gorm.DB, data *enity.AuditLog) error
+	PageList(c *gin.Context, db *gorm.DB, query *AuditLogQuery, pageNo, pageSize int) ([]enity.AuditLog, int64, error)
+}
+
+// AuditLogQuery 审计日志查询条件, 为空的条件不参与过滤
+type AuditLogQuery struct {
+	AdminName string
+	Resource  string
+	Action    string
+	Target    string
+	TraceID   string
+	From      time.Time
+	To        time.Time
+}
+
+type auditLogDao struct{}
+
+func NewAuditLogService() AuditLogService {
+	return &auditLogDao{}
+}
+
+// Create 写入一条审计日志
+func (dao *auditLogDao) Create(c *gin.Context, db *gorm.DB, data *enity.AuditLog) error {
+	if err := db.Create(data).Error; err != nil {
+		log.Error("error creating audit log", zap.Any("data", data), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return err
+	}
+	return nil
+}
+
+// PageList 按条件分页查询审计日志, 按时间倒序
+func (dao *auditLogDao) PageList(c *gin.Context, db *gorm.DB, query *AuditLogQuery, pageNo, pageSize int) ([]enity.AuditLog, int64, error) {
+	log.Info("start listing audit log", zap.Any("query", query), zap.String("trace_id", c.GetString("TraceID")))
+
+	tx := db.Model(&enity.AuditLog{})
+	if query.AdminName != "" {
+		tx = tx.Where("admin_name = ?", query.AdminName)
+	}
+	if query.Resource != "" {
+		tx = tx.Where("resource = ?", query.Resource)
+	}
+	if query.Action != "" {
+		tx = tx.Where("action = ?", query.Action)
+	}
+	if query.Target != "" {
+		tx = tx.Where("target like ?", "%"+query.Target+"%")
+	}
+	if query.TraceID != "" {
+		tx = tx.Where("trace_id = ?", query.TraceID)
+	}
+	if !query.From.IsZero() {
+		tx = tx.Where("create_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		tx = tx.Where("create_at <= ?", query.To)
+	}
+
+	total := int64(0)
+	if err := tx.Count(&total).Error; err != nil {
+		log.Error("error counting audit log", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, 0, err
+	}
+	list := []enity.AuditLog{}
+	if err := tx.Order("id desc").Limit(pageSize).Offset((pageNo - 1) * pageSize).Find(&list).Error; err != nil {
+		log.Error("error listing audit log", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, 0, err
+	}
+	return list, total, nil
+}
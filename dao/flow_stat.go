@@ -0,0 +1,99 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"gateway/backend/dto"
+	"gateway/enity"
+	"gateway/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 历史统计的汇总粒度
+const (
+	FlowStatHour  = "hour"
+	FlowStatDay   = "day"
+	FlowStatMonth = "month"
+)
+
+type FlowStatService interface {
+	Upsert(ctx context.Context, db *gorm.DB, rows []enity.FlowStat) error
+	DeleteBefore(ctx context.Context, db *gorm.DB, statDay int) (int64, error)
+	Summary(c *gin.Context, db *gorm.DB, query *FlowStatQuery) ([]dto.StatHistoryItem, error)
+}
+
+// FlowStatQuery 历史统计查询条件, 日期格式为 yyyymmdd
+type FlowStatQuery struct {
+	Dimension   string
+	Name        string
+	FromDay     int
+	ToDay       int
+	Granularity string
+}
+
+type flowStatDao struct{}
+
+func NewFlowStatService() FlowStatService {
+	return &flowStatDao{}
+}
+
+// Upsert 按 (dimension, name, stat_day, stat_hour) 写入, 已存在时覆盖请求数, 重复执行结果不变
+func (dao *flowStatDao) Upsert(ctx context.Context, db *gorm.DB, rows []enity.FlowStat) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	log.Debug("start upserting flow stat", zap.Int("rows", len(rows)))
+	err := db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "dimension"}, {Name: "name"}, {Name: "stat_day"}, {Name: "stat_hour"}},
+		DoUpdates: clause.AssignmentColumns([]string{"request_count", "update_at"}),
+	}).CreateInBatches(rows, 200).Error
+	if err != nil {
+		log.Error("error upserting flow stat", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeleteBefore 删除 statDay 之前的统计数据
+func (dao *flowStatDao) DeleteBefore(ctx context.Context, db *gorm.DB, statDay int) (int64, error) {
+	result := db.WithContext(ctx).Where("stat_day < ?", statDay).Delete(&enity.FlowStat{})
+	if result.Error != nil {
+		log.Error("error deleting expired flow stat", zap.Int("stat_day", statDay), zap.Error(result.Error))
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// Summary 按粒度汇总请求数, period 为 yyyymmddhh/yyyymmdd/yyyymm
+func (dao *flowStatDao) Summary(c *gin.Context, db *gorm.DB, query *FlowStatQuery) ([]dto.StatHistoryItem, error) {
+	log.Info("start summarizing flow stat", zap.Any("query", query), zap.String("trace_id", c.GetString("TraceID")))
+
+	var period string
+	switch query.Granularity {
+	case FlowStatHour:
+		period = "stat_day * 100 + stat_hour"
+	case FlowStatDay:
+		period = "stat_day"
+	case FlowStatMonth:
+		period = "FLOOR(stat_day / 100)"
+	default:
+		return nil, fmt.Errorf("unsupported granularity %s", query.Granularity)
+	}
+
+	tx := db.Table(enity.FlowStat{}.TableName()).
+		Where("dimension = ? and stat_day >= ? and stat_day <= ?", query.Dimension, query.FromDay, query.ToDay)
+	if query.Name != "" {
+		tx = tx.Where("name = ?", query.Name)
+	}
+	list := []dto.StatHistoryItem{}
+	if err := tx.Select(fmt.Sprintf("name, %s as period, sum(request_count) as request_count", period)).
+		Group("name, period").Order("name, period").Scan(&list).Error; err != nil {
+		log.Error("error summarizing flow stat", zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, err
+	}
+	return list, nil
+}
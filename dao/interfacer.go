@@ -27,6 +27,7 @@ func NewApp() APP {
 type Admin interface {
 	Getter[enity.Admin]
 	Saver[enity.Admin]
+	PagedLister[enity.Admin]
 }
 
 func NewAdmin() Admin {
@@ -43,6 +44,16 @@ func NewTcpService() TcpService {
 	return New[enity.TcpRule]()
 }
 
+type UdpService interface {
+	Getter[enity.UdpRule]
+	Saver[enity.UdpRule]
+	Deleter[enity.UdpRule]
+}
+
+func NewUdpService() UdpService {
+	return New[enity.UdpRule]()
+}
+
 type GrpcService interface {
 	Getter[enity.GrpcRule]
 	Saver[enity.GrpcRule]
@@ -91,6 +102,7 @@ type ServiceInfoService interface {
 	AllGetter[enity.ServiceInfo]
 	LoadTypeGrouper[enity.ServiceInfo]
 	ServiceDetailGetter[enity.ServiceInfo]
+	RuleOwnerGetter
 }
 
 func NewServiceInfoService() ServiceInfoService {
@@ -124,6 +136,12 @@ type LoadTypeGrouper[T Model] interface {
 type ServiceDetailGetter[T Model] interface {
 	GetServiceDetail(c *gin.Context, db *gorm.DB, search *enity.ServiceInfo) (*enity.ServiceDetail, error)
 }
+
+type RuleOwnerGetter interface {
+	// RuleOwner 返回使用相同端口或接入规则且未删除的其他服务名, 没有时返回空字符串
+	// rule 为 *enity.HttpRule/*enity.TcpRule/*enity.GrpcRule/*enity.UdpRule
+	RuleOwner(c *gin.Context, db *gorm.DB, serviceID int64, rule interface{}) (string, error)
+}
 type gormDao[T Model] struct{}
 
 func New[T Model]() *gormDao[T] {
@@ -231,7 +249,7 @@ func (dao *gormDao[T]) GetServiceDetail(c *gin.Context, db *gorm.DB, search *eni
 		search = info
 	}
 
-	var httpRule, tcpRule, grpcRule interface{}
+	var httpRule, tcpRule, grpcRule, udpRule interface{}
 	var err error
 
 	// 优化后的查询代码
@@ -257,6 +275,13 @@ func (dao *gormDao[T]) GetServiceDetail(c *gin.Context, db *gorm.DB, search *eni
 			return nil, err
 		}
 		log.Info("get grpc rule successful", zap.Any("grpcRule", grpcRule))
+	case globals.LoadTypeUDP:
+		udpRule, err = get(c, db, &enity.UdpRule{ServiceID: search.ID})
+		if err != nil && err != gorm.ErrRecordNotFound {
+			log.Error("error retrieving udp rule", zap.Error(err))
+			return nil, err
+		}
+		log.Info("get udp rule successful", zap.Any("udpRule", udpRule))
 	}
 
 	accessControl, err := get(c, db, &enity.AccessControl{ServiceID: search.ID})
@@ -303,12 +328,54 @@ func (dao *gormDao[T]) GetServiceDetail(c *gin.Context, db *gorm.DB, search *eni
 			return nil, fmt.Errorf("unexpected type for TCP rule: %T", tcpRule)
 		}
 	}
+	if udpRule != nil {
+		if rule, ok := udpRule.(*enity.UdpRule); ok {
+			detail.UDPRule = rule
+		} else {
+			log.Error("error retrieving udp rule: unexpected type", zap.Any("udpRule", udpRule))
+			return nil, fmt.Errorf("unexpected type for UDP rule: %T", udpRule)
+		}
+	}
 
 	// log记录成功取到信息
 	log.Info("get service detail successful", zap.Any("detail", detail))
 	return detail, nil
 }
 
+// RuleOwner HTTP 服务按接入类型和规则匹配, 其他服务按端口匹配
+func (dao *gormDao[T]) RuleOwner(c *gin.Context, db *gorm.DB, serviceID int64, rule interface{}) (string, error) {
+	var table, query string
+	var args []interface{}
+	switch r := rule.(type) {
+	case *enity.HttpRule:
+		table, query, args = r.TableName(), "r.rule_type = ? and r.rule = ?", []interface{}{r.RuleType, r.Rule}
+	case *enity.TcpRule:
+		table, query, args = r.TableName(), "r.port = ?", []interface{}{r.Port}
+	case *enity.GrpcRule:
+		table, query, args = r.TableName(), "r.port = ?", []interface{}{r.Port}
+	case *enity.UdpRule:
+		table, query, args = r.TableName(), "r.port = ?", []interface{}{r.Port}
+	default:
+		return "", fmt.Errorf("unsupported rule type %T", rule)
+	}
+
+	names := []string{}
+	err := db.Table(table+" r").
+		Joins("join "+enity.ServiceInfo{}.TableName()+" info on info.id = r.service_id").
+		Where("info.is_delete = 0 and r.service_id <> ?", serviceID).
+		Where(query, args...).
+		Limit(1).
+		Pluck("info.service_name", &names).Error
+	if err != nil {
+		log.Error("error retrieving rule owner", zap.Any("rule", rule), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	return names[0], nil
+}
+
 // GetAll
 func (dao *gormDao[T]) GetAll(c *gin.Context, db *gorm.DB, queryConditions []func(db *gorm.DB) *gorm.DB) ([]T, error) {
 	// log记录查询信息
@@ -4,8 +4,9 @@ import "gateway/enity"
 
 // Model is an interface representing various types of database models.
 // It includes Admin, ServiceInfo, AccessControl, GrpcRule,
-// HttpRule, TcpRule, LoadBalance, and App.
+// HttpRule, TcpRule, UdpRule, LoadBalance, App, and Upstream.
 type Model interface {
 	enity.Admin | enity.ServiceInfo | enity.AccessControl | enity.GrpcRule |
-		enity.HttpRule | enity.TcpRule | enity.LoadBalance | enity.App
+		enity.HttpRule | enity.TcpRule | enity.UdpRule | enity.LoadBalance | enity.App |
+		enity.Upstream
 }
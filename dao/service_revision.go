@@ -0,0 +1,71 @@
+package dao
+
+import (
+	"gateway/enity"
+	"gateway/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ServiceRevisionService interface {
+	Create(c *gin.Context, db *gorm.DB, data *enity.ServiceRevision) error
+	Get(c *gin.Context, db *gorm.DB, serviceID int64, version int) (*enity.ServiceRevision, error)
+	LatestVersion(c *gin.Context, db *gorm.DB, serviceID int64) (int, error)
+	PageList(c *gin.Context, db *gorm.DB, serviceID int64, pageNo, pageSize int) ([]enity.ServiceRevision, int64, error)
+}
+
+type serviceRevisionDao struct{}
+
+func NewServiceRevisionService() ServiceRevisionService {
+	return &serviceRevisionDao{}
+}
+
+// Create 写入一个新版本
+func (dao *serviceRevisionDao) Create(c *gin.Context, db *gorm.DB, data *enity.ServiceRevision) error {
+	if err := db.Create(data).Error; err != nil {
+		log.Error("error creating service revision", zap.Int64("service_id", data.ServiceID), zap.Int("version", data.Version), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return err
+	}
+	return nil
+}
+
+// Get 获取服务的指定版本
+func (dao *serviceRevisionDao) Get(c *gin.Context, db *gorm.DB, serviceID int64, version int) (*enity.ServiceRevision, error) {
+	out := &enity.ServiceRevision{}
+	if err := db.Where("service_id = ? and version = ?", serviceID, version).First(out).Error; err != nil {
+		log.Error("error getting service revision", zap.Int64("service_id", serviceID), zap.Int("version", version), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, err
+	}
+	return out, nil
+}
+
+// LatestVersion 返回服务当前最大的版本号, 没有版本时返回 0
+func (dao *serviceRevisionDao) LatestVersion(c *gin.Context, db *gorm.DB, serviceID int64) (int, error) {
+	var version int
+	err := db.Model(&enity.ServiceRevision{}).Where("service_id = ?", serviceID).
+		Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		log.Error("error getting latest service revision", zap.Int64("service_id", serviceID), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return 0, err
+	}
+	return version, nil
+}
+
+// PageList 分页查询服务的版本, 按版本号倒序, 不返回配置内容
+func (dao *serviceRevisionDao) PageList(c *gin.Context, db *gorm.DB, serviceID int64, pageNo, pageSize int) ([]enity.ServiceRevision, int64, error) {
+	tx := db.Model(&enity.ServiceRevision{}).Where("service_id = ?", serviceID)
+
+	total := int64(0)
+	if err := tx.Count(&total).Error; err != nil {
+		log.Error("error counting service revision", zap.Int64("service_id", serviceID), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, 0, err
+	}
+	list := []enity.ServiceRevision{}
+	if err := tx.Omit("detail").Order("version desc").Limit(pageSize).Offset((pageNo - 1) * pageSize).Find(&list).Error; err != nil {
+		log.Error("error listing service revision", zap.Int64("service_id", serviceID), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, 0, err
+	}
+	return list, total, nil
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"gateway/enity"
+	"gateway/pkg/log"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type Upstream interface {
+	Getter[enity.Upstream]
+	Saver[enity.Upstream]
+	PagedLister[enity.Upstream]
+	AllGetter[enity.Upstream]
+	// ServiceNames 返回引用该上游且未删除的服务名
+	ServiceNames(c *gin.Context, db *gorm.DB, upstreamID int64) ([]string, error)
+}
+
+type upstreamDao struct {
+	*gormDao[enity.Upstream]
+}
+
+func NewUpstream() Upstream {
+	return &upstreamDao{New[enity.Upstream]()}
+}
+
+func (dao *upstreamDao) ServiceNames(c *gin.Context, db *gorm.DB, upstreamID int64) ([]string, error) {
+	names := []string{}
+	err := db.Table(enity.LoadBalance{}.TableName()+" lb").
+		Joins("join "+enity.ServiceInfo{}.TableName()+" info on info.id = lb.service_id").
+		Where("lb.upstream_id = ? and info.is_delete = 0", upstreamID).
+		Pluck("info.service_name", &names).Error
+	if err != nil {
+		log.Error("error listing upstream services", zap.Int64("upstream_id", upstreamID), zap.Error(err), zap.String("trace_id", c.GetString("TraceID")))
+		return nil, err
+	}
+	return names, nil
+}
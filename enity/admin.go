@@ -1,18 +1,103 @@
 package enity
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+// 管理员角色, 权限依次递增
+const (
+	AdminRoleViewer   = "viewer"   // 只读
+	AdminRoleOperator = "operator" // 可以管理服务和租户
+	AdminRoleAdmin    = "admin"    // 可以管理管理员账号
+)
+
+// 管理员状态
+const (
+	AdminStatusDisabled = 0
+	AdminStatusEnabled  = 1
+)
+
+var adminRoleLevel = map[string]int{
+	AdminRoleViewer:   1,
+	AdminRoleOperator: 2,
+	AdminRoleAdmin:    3,
+}
 
 // Admin表对应的实体类
 type Admin struct {
-	ID       int       `json:"id" gorm:"primary_key" description:"主键"`
-	UserName string    `json:"user_name" gorm:"column:user_name" description:"用户名"`
-	Salt     string    `json:"salt" gorm:"column:salt" description:"盐值"`
-	Password string    `json:"password" gorm:"column:password" description:"密码"`
-	UpdateAt time.Time `json:"update_at" gorm:"column:update_at" description:"更新时间"`
-	CreateAt time.Time `json:"create_at" gorm:"column:create_at" description:"创建时间"`
-	IsDelete int       `json:"is_delete" gorm:"column:is_delete" description:"是否删除"`
+	ID           int       `json:"id" gorm:"primary_key" description:"主键"`
+	UserName     string    `json:"user_name" gorm:"column:user_name" description:"用户名"`
+	Salt         string    `json:"salt" gorm:"column:salt" description:"盐值"`
+	Password     string    `json:"password" gorm:"column:password" description:"密码"`
+	Role         string    `json:"role" gorm:"column:role" description:"角色 viewer/operator/admin"`
+	Status       int       `json:"status" gorm:"column:status" description:"状态 0=禁用 1=启用"`
+	ServiceScope string    `json:"service_scope" gorm:"column:service_scope" description:"可管理的服务, 逗号分隔, 以*结尾表示前缀, 为空表示全部服务"`
+	UpdateAt     time.Time `json:"update_at" gorm:"column:update_at" description:"更新时间"`
+	CreateAt     time.Time `json:"create_at" gorm:"column:create_at" description:"创建时间"`
+	IsDelete     int       `json:"is_delete" gorm:"column:is_delete" description:"是否删除"`
 }
 
 func (Admin) TableName() string {
 	return "gateway_admin"
 }
+
+// Enabled 判断账号是否可用
+func (a *Admin) Enabled() bool {
+	return a.IsDelete == 0 && a.Status == AdminStatusEnabled
+}
+
+// HasRole 判断账号的角色是否不低于 role
+func (a *Admin) HasRole(role string) bool {
+	return adminRoleLevel[a.Role] >= adminRoleLevel[role] && adminRoleLevel[role] > 0
+}
+
+// ScopeAll 判断账号是否可以管理全部服务, admin 角色不受服务范围限制
+func (a *Admin) ScopeAll() bool {
+	return a.Role == AdminRoleAdmin || strings.TrimSpace(a.ServiceScope) == ""
+}
+
+// Scopes 返回服务范围列表
+func (a *Admin) Scopes() []string {
+	scopes := []string{}
+	for _, item := range strings.Split(a.ServiceScope, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			scopes = append(scopes, item)
+		}
+	}
+	return scopes
+}
+
+// CanAccessService 判断服务是否在账号的服务范围内
+func (a *Admin) CanAccessService(serviceName string) bool {
+	if a.ScopeAll() {
+		return true
+	}
+	for _, scope := range a.Scopes() {
+		if prefix, ok := strings.CutSuffix(scope, "*"); ok {
+			if strings.HasPrefix(serviceName, prefix) {
+				return true
+			}
+		} else if scope == serviceName {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversScope 判断服务范围中的一项(服务名或以*结尾的前缀)是否在账号的服务范围内
+func (a *Admin) CoversScope(item string) bool {
+	if a.ScopeAll() {
+		return true
+	}
+	prefix, isPrefix := strings.CutSuffix(item, "*")
+	if !isPrefix {
+		return a.CanAccessService(item)
+	}
+	for _, scope := range a.Scopes() {
+		if p, ok := strings.CutSuffix(scope, "*"); ok && strings.HasPrefix(prefix, p) {
+			return true
+		}
+	}
+	return false
+}
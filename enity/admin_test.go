@@ -0,0 +1,86 @@
+package enity
+
+import "testing"
+
+func TestAdminHasRole(t *testing.T) {
+	cases := []struct {
+		role     string
+		required string
+		want     bool
+	}{
+		{AdminRoleViewer, AdminRoleViewer, true},
+		{AdminRoleViewer, AdminRoleOperator, false},
+		{AdminRoleOperator, AdminRoleViewer, true},
+		{AdminRoleOperator, AdminRoleAdmin, false},
+		{AdminRoleAdmin, AdminRoleOperator, true},
+		{AdminRoleAdmin, AdminRoleAdmin, true},
+		{"", AdminRoleViewer, false},
+		{"root", AdminRoleViewer, false},
+		// 未知的角色要求不被任何账号满足
+		{AdminRoleAdmin, "root", false},
+		{AdminRoleAdmin, "", false},
+	}
+	for _, c := range cases {
+		admin := &Admin{Role: c.role}
+		if got := admin.HasRole(c.required); got != c.want {
+			t.Fatalf("role %q HasRole(%q) = %v, want %v", c.role, c.required, got, c.want)
+		}
+	}
+}
+
+func TestAdminCanAccessService(t *testing.T) {
+	cases := []struct {
+		role    string
+		scope   string
+		service string
+		want    bool
+	}{
+		{AdminRoleOperator, "", "pay_api", true},
+		{AdminRoleOperator, " ", "pay_api", true},
+		{AdminRoleAdmin, "order_*", "pay_api", true},
+		{AdminRoleOperator, "order_*", "order_api", true},
+		{AdminRoleOperator, "order_*", "order_", true},
+		{AdminRoleOperator, "order_*", "orderXapi", false},
+		{AdminRoleOperator, "order_*", "pay_api", false},
+		{AdminRoleOperator, "order_api", "order_api", true},
+		{AdminRoleOperator, "order_api", "order_api2", false},
+		{AdminRoleViewer, "order_*, pay_api", "pay_api", true},
+		{AdminRoleViewer, "order_*, pay_api", "pay_job", false},
+		{AdminRoleOperator, "order_%", "order_api", false},
+	}
+	for _, c := range cases {
+		admin := &Admin{Role: c.role, ServiceScope: c.scope}
+		if got := admin.CanAccessService(c.service); got != c.want {
+			t.Fatalf("role %s scope %q CanAccessService(%q) = %v, want %v", c.role, c.scope, c.service, got, c.want)
+		}
+	}
+}
+
+func TestAdminCoversScope(t *testing.T) {
+	cases := []struct {
+		role  string
+		scope string
+		item  string
+		want  bool
+	}{
+		{AdminRoleOperator, "", "*", true},
+		{AdminRoleAdmin, "order_*", "pay_*", true},
+		{AdminRoleOperator, "order_*", "order_api", true},
+		{AdminRoleOperator, "order_*", "order_*", true},
+		{AdminRoleOperator, "order_*", "order_v2_*", true},
+		// 更宽的前缀会包含范围外的服务
+		{AdminRoleOperator, "order_*", "order*", false},
+		{AdminRoleOperator, "order_*", "*", false},
+		{AdminRoleOperator, "order_*", "pay_*", false},
+		// 单个服务名不能覆盖前缀
+		{AdminRoleOperator, "order_api", "order_*", false},
+		{AdminRoleOperator, "order_api", "order_api", true},
+		{AdminRoleOperator, "order_api,pay_*", "pay_job_*", true},
+	}
+	for _, c := range cases {
+		admin := &Admin{Role: c.role, ServiceScope: c.scope}
+		if got := admin.CoversScope(c.item); got != c.want {
+			t.Fatalf("role %s scope %q CoversScope(%q) = %v, want %v", c.role, c.scope, c.item, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package enity
+
+import (
+	"strings"
+	"time"
+)
+
+// ApiToken 管理员签发的接口令牌, 供 CI 等自动化工具调用后台接口
+// 数据库只保存令牌的 sha256 摘要, 明文只在创建时返回一次
+type ApiToken struct {
+	ID           int64     `json:"id" gorm:"primary_key" description:"主键"`
+	AdminID      int       `json:"admin_id" gorm:"column:admin_id" description:"签发人id"`
+	Name         string    `json:"name" gorm:"column:name" description:"令牌名称"`
+	TokenPrefix  string    `json:"token_prefix" gorm:"column:token_prefix" description:"令牌前缀, 用于识别令牌"`
+	TokenHash    string    `json:"token_hash" gorm:"column:token_hash" description:"令牌sha256摘要"`
+	Role         string    `json:"role" gorm:"column:role" description:"令牌角色 viewer/operator, 不高于签发人角色"`
+	ServiceScope string    `json:"service_scope" gorm:"column:service_scope" description:"服务范围, 为空表示沿用签发人的服务范围"`
+	ExpireAt     time.Time `json:"expire_at" gorm:"column:expire_at" description:"过期时间"`
+	LastUsedAt   time.Time `json:"last_used_at" gorm:"column:last_used_at" description:"最后使用时间"`
+	LastUsedIP   string    `json:"last_used_ip" gorm:"column:last_used_ip" description:"最后使用的客户端ip"`
+	Revoked      int       `json:"revoked" gorm:"column:revoked" description:"是否已吊销"`
+	CreateAt     time.Time `json:"create_at" gorm:"column:create_at" description:"创建时间"`
+	UpdateAt     time.Time `json:"update_at" gorm:"column:update_at" description:"更新时间"`
+}
+
+func (ApiToken) TableName() string {
+	return "gateway_api_token"
+}
+
+// Valid 判断令牌在 now 时刻是否可用
+func (t *ApiToken) Valid(now time.Time) bool {
+	return t.Revoked == 0 && now.Before(t.ExpireAt)
+}
+
+// EffectiveAdmin 返回使用令牌时生效的账号: 角色取令牌角色, 服务范围取令牌与签发人范围的交集
+func (t *ApiToken) EffectiveAdmin(admin *Admin) *Admin {
+	effective := *admin
+	if !admin.HasRole(t.Role) {
+		effective.Role = AdminRoleViewer
+	} else {
+		effective.Role = t.Role
+	}
+	if strings.TrimSpace(t.ServiceScope) == "" {
+		return &effective
+	}
+	scopes := []string{}
+	for _, item := range strings.Split(t.ServiceScope, ",") {
+		if item = strings.TrimSpace(item); item != "" && admin.CoversScope(item) {
+			scopes = append(scopes, item)
+		}
+	}
+	// 交集为空时不能访问任何服务, 用一个不可能的服务名表示
+	if len(scopes) == 0 {
+		scopes = append(scopes, ",")
+	}
+	effective.ServiceScope = strings.Join(scopes, ",")
+	return &effective
+}
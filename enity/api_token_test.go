@@ -0,0 +1,80 @@
+package enity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestApiTokenValid(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name  string
+		token ApiToken
+		want  bool
+	}{
+		{"active", ApiToken{ExpireAt: now.Add(time.Hour)}, true},
+		{"expired", ApiToken{ExpireAt: now.Add(-time.Second)}, false},
+		{"expires now", ApiToken{ExpireAt: now}, false},
+		{"revoked", ApiToken{ExpireAt: now.Add(time.Hour), Revoked: 1}, false},
+	}
+	for _, c := range cases {
+		if got := c.token.Valid(now); got != c.want {
+			t.Fatalf("%s: Valid = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestApiTokenEffectiveAdmin(t *testing.T) {
+	cases := []struct {
+		name       string
+		admin      Admin
+		token      ApiToken
+		role       string
+		scope      string
+		allowed    []string
+		notAllowed []string
+	}{
+		{"token role below issuer",
+			Admin{Role: AdminRoleAdmin}, ApiToken{Role: AdminRoleViewer},
+			AdminRoleViewer, "", []string{"pay_api"}, nil},
+		{"issuer demoted below token role",
+			Admin{Role: AdminRoleViewer}, ApiToken{Role: AdminRoleOperator},
+			AdminRoleViewer, "", nil, nil},
+		{"empty token scope keeps issuer scope",
+			Admin{Role: AdminRoleOperator, ServiceScope: "order_*"}, ApiToken{Role: AdminRoleOperator},
+			AdminRoleOperator, "order_*", []string{"order_api"}, []string{"pay_api"}},
+		{"token scope narrows issuer scope",
+			Admin{Role: AdminRoleOperator, ServiceScope: "order_*"}, ApiToken{Role: AdminRoleOperator, ServiceScope: "order_api"},
+			AdminRoleOperator, "order_api", []string{"order_api"}, []string{"order_job"}},
+		{"items outside issuer scope are dropped",
+			Admin{Role: AdminRoleOperator, ServiceScope: "order_*"}, ApiToken{Role: AdminRoleViewer, ServiceScope: "order_api, pay_*, *"},
+			AdminRoleViewer, "order_api", []string{"order_api"}, []string{"pay_api", "order_job"}},
+		{"issuer without scope limit",
+			Admin{Role: AdminRoleOperator}, ApiToken{Role: AdminRoleOperator, ServiceScope: "pay_*"},
+			AdminRoleOperator, "pay_*", []string{"pay_api"}, []string{"order_api"}},
+		// 交集为空时使用不可能的服务名, 不能退化为空范围(即全部服务)
+		{"empty intersection",
+			Admin{Role: AdminRoleOperator, ServiceScope: "order_*"}, ApiToken{Role: AdminRoleOperator, ServiceScope: "pay_*"},
+			AdminRoleOperator, ",", nil, []string{"order_api", "pay_api", ""}},
+	}
+	for _, c := range cases {
+		admin := c.admin
+		effective := c.token.EffectiveAdmin(&admin)
+		if effective.Role != c.role || effective.ServiceScope != c.scope {
+			t.Fatalf("%s: effective role %q scope %q, want role %q scope %q", c.name, effective.Role, effective.ServiceScope, c.role, c.scope)
+		}
+		if admin != c.admin {
+			t.Fatalf("%s: issuer was modified", c.name)
+		}
+		for _, service := range c.allowed {
+			if !effective.CanAccessService(service) {
+				t.Fatalf("%s: service %s should be accessible", c.name, service)
+			}
+		}
+		for _, service := range c.notAllowed {
+			if effective.CanAccessService(service) {
+				t.Fatalf("%s: service %s should not be accessible", c.name, service)
+			}
+		}
+	}
+}
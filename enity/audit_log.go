@@ -0,0 +1,39 @@
+package enity
+
+import "time"
+
+// 审计日志的资源类型
+const (
+	AuditResourceService  = "service"
+	AuditResourceApp      = "app"
+	AuditResourceAdmin    = "admin"
+	AuditResourceApiToken = "api_token"
+	AuditResourceUpstream = "upstream"
+)
+
+// 审计日志的操作类型
+const (
+	AuditActionAdd    = "add"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLog 控制面变更审计日志, 只追加不修改
+type AuditLog struct {
+	ID        int64     `json:"id" gorm:"primary_key"`
+	AdminID   int       `json:"admin_id" gorm:"column:admin_id" description:"操作人id"`
+	AdminName string    `json:"admin_name" gorm:"column:admin_name" description:"操作人用户名"`
+	Resource  string    `json:"resource" gorm:"column:resource" description:"资源类型 service/app/admin"`
+	Action    string    `json:"action" gorm:"column:action" description:"操作类型 add/update/delete"`
+	Target    string    `json:"target" gorm:"column:target" description:"服务名称/租户id/管理员用户名"`
+	TraceID   string    `json:"trace_id" gorm:"column:trace_id" description:"请求trace id"`
+	ClientIP  string    `json:"client_ip" gorm:"column:client_ip" description:"客户端ip"`
+	Before    string    `json:"before" gorm:"column:before_data" description:"变更前的数据, json"`
+	After     string    `json:"after" gorm:"column:after_data" description:"变更后的数据, json"`
+	Diff      string    `json:"diff" gorm:"column:diff" description:"变更的字段, json"`
+	CreatedAt time.Time `json:"create_at" gorm:"column:create_at" description:"操作时间"`
+}
+
+func (AuditLog) TableName() string {
+	return "gateway_audit_log"
+}
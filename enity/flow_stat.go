@@ -0,0 +1,19 @@
+package enity
+
+import "time"
+
+// FlowStat 按小时持久化的流量统计, 由 redis 小时桶汇总而来
+type FlowStat struct {
+	ID           int64     `json:"id" gorm:"primary_key"`
+	Dimension    string    `json:"dimension" gorm:"column:dimension" description:"统计维度 total/service/app"`
+	Name         string    `json:"name" gorm:"column:name" description:"服务名称或租户id"`
+	StatDay      int       `json:"stat_day" gorm:"column:stat_day" description:"统计日期 yyyymmdd"`
+	StatHour     int       `json:"stat_hour" gorm:"column:stat_hour" description:"统计小时 0-23"`
+	RequestCount int64     `json:"request_count" gorm:"column:request_count" description:"请求数"`
+	CreatedAt    time.Time `json:"create_at" gorm:"column:create_at" description:"添加时间"`
+	UpdatedAt    time.Time `json:"update_at" gorm:"column:update_at" description:"更新时间"`
+}
+
+func (FlowStat) TableName() string {
+	return "gateway_flow_stat"
+}
@@ -5,6 +5,8 @@ type ServiceDetail struct {
 	HTTPRule      *HttpRule      `json:"http_rule" description:"http_rule"`
 	TCPRule       *TcpRule       `json:"tcp_rule" description:"tcp_rule"`
 	GRPCRule      *GrpcRule      `json:"grpc_rule" description:"grpc_rule"`
+	UDPRule       *UdpRule       `json:"udp_rule" description:"udp_rule"`
 	LoadBalance   *LoadBalance   `json:"load_balance" description:"load_balance"`
 	AccessControl *AccessControl `json:"access_control" description:"access_control"`
+	Upstream      *Upstream      `json:"upstream,omitempty" description:"负载均衡配置引用的上游, 只在代理中填充"`
 }
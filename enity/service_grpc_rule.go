@@ -1,12 +1,34 @@
 package enity
 
+import "net/url"
+
 type GrpcRule struct {
 	ID             int64  `json:"id" gorm:"primary_key"`
 	ServiceID      int64  `json:"service_id" gorm:"column:service_id" description:"服务id	"`
 	Port           int    `json:"port" gorm:"column:port" description:"端口	"`
 	HeaderTransfor string `json:"header_transfor" gorm:"column:header_transfor" description:"header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue"`
+	MethodRoute    string `json:"method_route" gorm:"column:method_route" description:"按方法前缀路由到上游分组 格式: /package.Service/ ip:port ip:port 多个逗号间隔"`
+	MethodLimit    string `json:"method_limit" gorm:"column:method_limit" description:"按方法前缀限流 格式: /package.Service/Method qps 多个逗号间隔"`
+	MethodAllow    string `json:"method_allow" gorm:"column:method_allow" description:"允许调用的方法前缀, 多个逗号间隔, 为空表示全部允许"`
+	MethodDeny     string `json:"method_deny" gorm:"column:method_deny" description:"禁止调用的方法前缀, 多个逗号间隔, 优先级高于允许列表"`
+	NeedGrpcWeb    int    `json:"need_grpc_web" gorm:"column:need_grpc_web" description:"是否允许通过 http 代理以 grpc-web 或 json 方式访问 1=允许"`
+	GrpcWebOrigins string `json:"grpc_web_origins" gorm:"column:grpc_web_origins" description:"允许跨域访问 grpc-web 的来源, 多个逗号间隔, * 表示任意来源但不携带凭证, 为空时不允许跨域"`
 }
 
 func (GrpcRule) TableName() string {
 	return "gateway_service_grpc_rule"
 }
+
+// ValidOriginList 检查跨域来源列表, 每项为 * 或 scheme://host[:port]
+func ValidOriginList(list string) bool {
+	for _, item := range splitNodeList(list) {
+		if item == "*" {
+			continue
+		}
+		u, err := url.Parse(item)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -1,15 +1,60 @@
 package enity
 
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 上游节点状态
+const (
+	NodeStateEnabled  = "enable"  // 正常参与选择
+	NodeStateDraining = "drain"   // 排空中, 不再接收新请求, 处理中的请求继续完成
+	NodeStateDisabled = "disable" // 禁用, 记录在 forbid_list 中
+)
+
+// 上游节点来源, 为空表示使用 ip_list 中的静态节点
+const (
+	DiscoveryFile  = "file"  // 本地文件, discovery_target 为文件路径, 文件变化时重新读取
+	DiscoveryRedis = "redis" // redis 注册中心, discovery_target 为注册的服务名, 后端注册并按心跳续期
+	DiscoveryHTTP  = "http"  // 定期请求接口, discovery_target 为接口地址
+)
+
+// 一致性hash的key来源, 配置格式为 source 或 source:name, 为空时 HTTP 使用完整请求地址, 其他协议使用客户端ip
+const (
+	HashKeyURL      = "url"       // 完整请求地址
+	HashKeyPath     = "path"      // 请求路径, gRPC 为方法全名
+	HashKeyClientIP = "client_ip" // 客户端ip
+	HashKeyAppID    = "app_id"    // jwt 鉴权得到的租户id
+	HashKeyHeader   = "header"    // header:<name>, gRPC 取 metadata
+	HashKeyCookie   = "cookie"    // cookie:<name>
+	HashKeyQuery    = "query"     // query:<name>
+)
+
 type LoadBalance struct {
-	ID            int64  `json:"id" gorm:"primary_key"`
-	ServiceID     int64  `json:"service_id" gorm:"column:service_id" description:"服务id	"`
-	CheckMethod   int    `json:"check_method" gorm:"column:check_method" description:"检查方法 tcpchk=检测端口是否握手成功	"`
-	CheckTimeout  int    `json:"check_timeout" gorm:"column:check_timeout" description:"check超时时间	"`
-	CheckInterval int    `json:"check_interval" gorm:"column:check_interval" description:"检查间隔, 单位s		"`
-	RoundType     int    `json:"round_type" gorm:"column:round_type" description:"轮询方式 round/weight_round/random/ip_hash"`
-	IpList        string `json:"ip_list" gorm:"column:ip_list" description:"ip列表"`
-	WeightList    string `json:"weight_list" gorm:"column:weight_list" description:"权重列表"`
-	ForbidList    string `json:"forbid_list" gorm:"column:forbid_list" description:"禁用ip列表"`
+	ID                int64  `json:"id" gorm:"primary_key"`
+	ServiceID         int64  `json:"service_id" gorm:"column:service_id" description:"服务id	"`
+	CheckMethod       int    `json:"check_method" gorm:"column:check_method" description:"检查方法 tcpchk=检测端口是否握手成功	"`
+	CheckTimeout      int    `json:"check_timeout" gorm:"column:check_timeout" description:"check超时时间	"`
+	CheckInterval     int    `json:"check_interval" gorm:"column:check_interval" description:"检查间隔, 单位s		"`
+	RoundType         int    `json:"round_type" gorm:"column:round_type" description:"轮询方式 random/round/weight_round/ip_hash/least_conn/p2c/bounded_hash"`
+	IpList            string `json:"ip_list" gorm:"column:ip_list" description:"ip列表, 每项为 ip:port, host:port 或 srv:<name>, 域名按 TTL 定期解析"`
+	WeightList        string `json:"weight_list" gorm:"column:weight_list" description:"权重列表"`
+	PriorityList      string `json:"priority_list" gorm:"column:priority_list" description:"优先级列表, 与ip列表一一对应, 0为最高优先级, 为空时不做切换"`
+	FailoverThreshold int    `json:"failover_threshold" gorm:"column:failover_threshold" description:"某一优先级可用节点的权重低于该百分比时同时使用下一优先级的节点"`
+	ForbidList        string `json:"forbid_list" gorm:"column:forbid_list" description:"禁用ip列表"`
+	DrainList         string `json:"drain_list" gorm:"column:drain_list" description:"排空中的节点列表"`
+
+	DiscoveryType   string `json:"discovery_type" gorm:"column:discovery_type" description:"节点来源 空=ip_list file/redis/http, 使用服务发现时 ip_list 为可选的初始节点"`
+	DiscoveryTarget string `json:"discovery_target" gorm:"column:discovery_target" description:"服务发现的目标: 文件路径/注册的服务名/接口地址"`
+	UpstreamID      int64  `json:"upstream_id" gorm:"column:upstream_id" description:"引用的上游id, 不为0时使用上游的节点、探活、超时和TLS配置"`
+
+	HashKey        string `json:"hash_key" gorm:"column:hash_key" description:"一致性hash的key来源 url/path/client_ip/app_id/header:<name>/cookie:<name>/query:<name>"`
+	HashReplicas   int    `json:"hash_replicas" gorm:"column:hash_replicas" description:"一致性hash每个节点的虚拟节点数, 0表示默认值"`
+	HashLoadFactor int    `json:"hash_load_factor" gorm:"column:hash_load_factor" description:"有界负载一致性hash的负载上限, 平均负载的百分比, 0表示默认值"`
+	SlowStart      int    `json:"slow_start" gorm:"column:slow_start" description:"慢启动时长, 单位s, 新增或恢复的节点在该时间内权重从小值线性增长, 0表示不开启"`
 
 	UpstreamConnectTimeout int `json:"upstream_connect_timeout" gorm:"column:upstream_connect_timeout" description:"下游建立连接超时, 单位s"`
 	UpstreamHeaderTimeout  int `json:"upstream_header_timeout" gorm:"column:upstream_header_timeout" description:"下游获取header超时, 单位s	"`
@@ -20,3 +65,97 @@ type LoadBalance struct {
 func (LoadBalance) TableName() string {
 	return "gateway_service_load_balance"
 }
+
+// ParseHashKey 拆分 hash key 配置, 返回来源和 header/cookie/query 的名称
+func ParseHashKey(key string) (source, name string) {
+	source, name, _ = strings.Cut(strings.TrimSpace(key), ":")
+	return source, strings.TrimSpace(name)
+}
+
+// ValidHashKey 检查 hash key 配置, header/cookie/query 需要指定名称
+func ValidHashKey(key string) bool {
+	if key == "" {
+		return true
+	}
+	source, name := ParseHashKey(key)
+	switch source {
+	case HashKeyURL, HashKeyPath, HashKeyClientIP, HashKeyAppID:
+		return name == ""
+	case HashKeyHeader, HashKeyCookie, HashKeyQuery:
+		return name != ""
+	}
+	return false
+}
+
+// NodeState 返回节点的状态, forbid_list 中的条目可以是 ip:port 或只有 ip
+func (lb *LoadBalance) NodeState(node string) string {
+	host, _, err := net.SplitHostPort(node)
+	if err != nil {
+		host = node
+	}
+	for _, item := range splitNodeList(lb.ForbidList) {
+		if item == node || item == host {
+			return NodeStateDisabled
+		}
+	}
+	for _, item := range splitNodeList(lb.DrainList) {
+		if item == node {
+			return NodeStateDraining
+		}
+	}
+	return NodeStateEnabled
+}
+
+// ExcludedEntries 返回禁用和排空中的全部条目, 由负载均衡器按节点、域名条目或 ip 匹配
+func (lb *LoadBalance) ExcludedEntries() []string {
+	return append(splitNodeList(lb.ForbidList), splitNodeList(lb.DrainList)...)
+}
+
+// Priorities 返回条目到优先级的映射, 未设置优先级列表时返回空
+func (lb *LoadBalance) Priorities() map[string]int {
+	return parsePriorities(lb.IpList, lb.PriorityList)
+}
+
+// ValidDiscovery 检查服务发现配置, 使用服务发现时需要指定目标
+func ValidDiscovery(discoveryType, target string) error {
+	switch discoveryType {
+	case "":
+		return nil
+	case DiscoveryFile, DiscoveryRedis:
+	case DiscoveryHTTP:
+		if u, err := url.Parse(target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("discovery target must be an http or https url")
+		}
+	default:
+		return fmt.Errorf("unknown discovery type %s", discoveryType)
+	}
+	if strings.TrimSpace(target) == "" {
+		return fmt.Errorf("discovery target is required for discovery type %s", discoveryType)
+	}
+	return nil
+}
+
+func splitNodeList(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parsePriorities 按顺序对应 ip 列表和优先级列表, 优先级列表较短或无法解析的项优先级为 0
+func parsePriorities(ipList, priorityList string) map[string]int {
+	if strings.TrimSpace(priorityList) == "" {
+		return nil
+	}
+	priorities := map[string]int{}
+	items := strings.Split(priorityList, ",")
+	for i, ip := range splitNodeList(ipList) {
+		if i < len(items) {
+			priorities[ip], _ = strconv.Atoi(strings.TrimSpace(items[i]))
+		}
+	}
+	return priorities
+}
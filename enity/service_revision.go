@@ -0,0 +1,27 @@
+package enity
+
+import "time"
+
+// ServiceRevision 服务配置的历史版本, 每次保存服务时生成, 只追加不修改
+type ServiceRevision struct {
+	ID          int64     `json:"id" gorm:"primary_key"`
+	ServiceID   int64     `json:"service_id" gorm:"column:service_id" description:"服务id"`
+	ServiceName string    `json:"service_name" gorm:"column:service_name" description:"服务名称"`
+	Version     int       `json:"version" gorm:"column:version" description:"版本号, 同一服务内递增"`
+	Action      string    `json:"action" gorm:"column:action" description:"产生版本的操作 add/update/rollback"`
+	Detail      string    `json:"detail" gorm:"column:detail" description:"完整的服务配置 ServiceDetail, json"`
+	AdminName   string    `json:"admin_name" gorm:"column:admin_name" description:"操作人用户名"`
+	Remark      string    `json:"remark" gorm:"column:remark" description:"备注"`
+	CreatedAt   time.Time `json:"create_at" gorm:"column:create_at" description:"创建时间"`
+}
+
+func (ServiceRevision) TableName() string {
+	return "gateway_service_revision"
+}
+
+// 产生服务版本的操作
+const (
+	ServiceRevisionAdd      = "add"
+	ServiceRevisionUpdate   = "update"
+	ServiceRevisionRollback = "rollback"
+)
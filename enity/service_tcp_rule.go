@@ -1,9 +1,15 @@
 package enity
 
 type TcpRule struct {
-	ID        int64 `json:"id" gorm:"primary_key"`
-	ServiceID int64 `json:"service_id" gorm:"column:service_id" description:"服务id	"`
-	Port      int   `json:"port" gorm:"column:port" description:"端口	"`
+	ID          int64  `json:"id" gorm:"primary_key"`
+	ServiceID   int64  `json:"service_id" gorm:"column:service_id" description:"服务id	"`
+	Port        int    `json:"port" gorm:"column:port" description:"端口, 0表示只通过共享端口SNI接入"`
+	IdleTimeout int    `json:"idle_timeout" gorm:"column:idle_timeout" description:"连接空闲超时, 单位s, 0表示不限制"`
+	MaxConns    int    `json:"max_conns" gorm:"column:max_conns" description:"最大并发连接数, 0表示不限制"`
+	NeedTls     int    `json:"need_tls" gorm:"column:need_tls" description:"是否在网关终止TLS 1=是"`
+	CertFile    string `json:"cert_file" gorm:"column:cert_file" description:"TLS证书文件路径"`
+	KeyFile     string `json:"key_file" gorm:"column:key_file" description:"TLS私钥文件路径"`
+	SniHost     string `json:"sni_host" gorm:"column:sni_host" description:"共享端口SNI路由域名, 以逗号间隔, 支持*.example.com"`
 }
 
 func (TcpRule) TableName() string {
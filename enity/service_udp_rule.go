@@ -0,0 +1,12 @@
+package enity
+
+type UdpRule struct {
+	ID             int64 `json:"id" gorm:"primary_key"`
+	ServiceID      int64 `json:"service_id" gorm:"column:service_id" description:"服务id	"`
+	Port           int   `json:"port" gorm:"column:port" description:"端口	"`
+	SessionTimeout int   `json:"session_timeout" gorm:"column:session_timeout" description:"会话空闲超时, 单位s"`
+}
+
+func (UdpRule) TableName() string {
+	return "gateway_service_udp_rule"
+}
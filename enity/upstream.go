@@ -0,0 +1,79 @@
+package enity
+
+import (
+	"strings"
+	"time"
+)
+
+// 上游主动探活方式
+const (
+	UpstreamCheckTcp  = 0 // tcp 握手
+	UpstreamCheckNone = 1 // 不探活
+	UpstreamCheckHttp = 2 // 请求 check_path, 返回 2xx/3xx 视为健康
+)
+
+// Upstream 可被多个服务引用的上游集群, 节点、权重、探活策略、超时和 TLS 配置只维护一份
+// 服务的负载均衡配置中 upstream_id 不为 0 时使用上游的配置, 轮询方式和一致性hash配置仍按服务设置
+type Upstream struct {
+	ID          int64  `json:"id" gorm:"primary_key"`
+	Name        string `json:"name" gorm:"column:name" description:"上游名称"`
+	Description string `json:"description" gorm:"column:description" description:"描述"`
+
+	IpList            string `json:"ip_list" gorm:"column:ip_list" description:"ip列表, 每项为 ip:port, host:port 或 srv:<name>"`
+	WeightList        string `json:"weight_list" gorm:"column:weight_list" description:"权重列表"`
+	PriorityList      string `json:"priority_list" gorm:"column:priority_list" description:"优先级列表, 与ip列表一一对应, 0为最高优先级, 为空时不做切换"`
+	FailoverThreshold int    `json:"failover_threshold" gorm:"column:failover_threshold" description:"某一优先级可用节点的权重低于该百分比时同时使用下一优先级的节点"`
+	ForbidList        string `json:"forbid_list" gorm:"column:forbid_list" description:"禁用ip列表, 对所有引用的服务生效"`
+	DiscoveryType     string `json:"discovery_type" gorm:"column:discovery_type" description:"节点来源 空=ip_list file/redis/http"`
+	DiscoveryTarget   string `json:"discovery_target" gorm:"column:discovery_target" description:"服务发现的目标: 文件路径/注册的服务名/接口地址"`
+
+	CheckMethod    int    `json:"check_method" gorm:"column:check_method" description:"探活方式 0=tcp 1=不探活 2=http"`
+	CheckPath      string `json:"check_path" gorm:"column:check_path" description:"http 探活的请求路径"`
+	CheckTimeout   int    `json:"check_timeout" gorm:"column:check_timeout" description:"探活超时, 单位s, 0表示默认值"`
+	CheckInterval  int    `json:"check_interval" gorm:"column:check_interval" description:"探活间隔, 单位s, 0表示默认值"`
+	CheckMaxErrNum int    `json:"check_max_err_num" gorm:"column:check_max_err_num" description:"连续失败多少次后摘除节点, 0表示默认值"`
+	SlowStart      int    `json:"slow_start" gorm:"column:slow_start" description:"慢启动时长, 单位s, 新增或恢复的节点在该时间内权重从小值线性增长, 0表示不开启"`
+
+	ConnectTimeout int `json:"connect_timeout" gorm:"column:connect_timeout" description:"建立连接超时, 单位s"`
+	HeaderTimeout  int `json:"header_timeout" gorm:"column:header_timeout" description:"获取header超时, 单位s"`
+	IdleTimeout    int `json:"idle_timeout" gorm:"column:idle_timeout" description:"链接最大空闲时间, 单位s"`
+	MaxIdle        int `json:"max_idle" gorm:"column:max_idle" description:"最大空闲链接数"`
+
+	TLSEnable             int    `json:"tls_enable" gorm:"column:tls_enable" description:"是否使用 https 访问上游"`
+	TLSServerName         string `json:"tls_server_name" gorm:"column:tls_server_name" description:"校验证书使用的服务名, 为空时使用节点地址"`
+	TLSInsecureSkipVerify int    `json:"tls_insecure_skip_verify" gorm:"column:tls_insecure_skip_verify" description:"是否跳过证书校验"`
+	TLSCACert             string `json:"tls_ca_cert" gorm:"column:tls_ca_cert" description:"校验上游证书的 CA 证书, PEM 格式, 为空时使用系统证书"`
+
+	CreatedAt time.Time `json:"create_at" gorm:"column:create_at" description:"添加时间"`
+	UpdatedAt time.Time `json:"update_at" gorm:"column:update_at" description:"更新时间"`
+	IsDelete  int8      `json:"is_delete" gorm:"column:is_delete" description:"是否已删除:0:否,1:是"`
+}
+
+func (Upstream) TableName() string {
+	return "gateway_upstream"
+}
+
+// Nodes 返回上游条目到权重的映射, 权重列表较短时缺少的权重为 1
+func (u *Upstream) Nodes() map[string]string {
+	nodes := map[string]string{}
+	ipList := splitNodeList(u.IpList)
+	weightList := strings.Split(u.WeightList, ",")
+	for i, ip := range ipList {
+		if i < len(weightList) && strings.TrimSpace(weightList[i]) != "" {
+			nodes[ip] = strings.TrimSpace(weightList[i])
+		} else {
+			nodes[ip] = "1"
+		}
+	}
+	return nodes
+}
+
+// Priorities 返回上游条目到优先级的映射, 未设置优先级列表时返回空
+func (u *Upstream) Priorities() map[string]int {
+	return parsePriorities(u.IpList, u.PriorityList)
+}
+
+// ExcludedEntries 返回上游禁用的全部条目
+func (u *Upstream) ExcludedEntries() []string {
+	return splitNodeList(u.ForbidList)
+}
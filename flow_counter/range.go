@@ -0,0 +1,170 @@
+package flow_counter
+
+import (
+	"fmt"
+	"gateway/configs"
+	"gateway/pkg/database/redis"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 统计粒度
+const (
+	StepMinute = "minute"
+	StepHour   = "hour"
+	StepDay    = "day"
+)
+
+// MaxRangePoints 单次查询最多返回的点数
+const MaxRangePoints = 1440
+
+// 默认保留时间
+const (
+	defaultMinuteRetention = 48 * time.Hour
+	defaultHourRetention   = 35 * 24 * time.Hour
+	defaultDayRetention    = 400 * 24 * time.Hour
+)
+
+// FlowPoint 一个统计桶, Time 为桶的起始时间
+type FlowPoint struct {
+	Time  time.Time
+	Value int64
+}
+
+// NodeCounterName 服务下单个上游节点的计数器名称
+func NodeCounterName(serviceName, node string) string {
+	return serviceName + "#node#" + node
+}
+
+// NodeNames 返回服务在 [from, to] 区间内有请求记录的上游节点
+// 节点来自代理写入的天粒度计数器, 与上游组、服务发现和域名解析得到的实际地址一致
+func NodeNames(serviceName string, from, to time.Time) ([]string, error) {
+	prefix := "flow_day_count_"
+	suffix := "_" + NodeCounterName(serviceName, "")
+	keys, err := redis.ScanKeys(prefix + "????????" + globEscaper.Replace(suffix) + "*")
+	if err != nil {
+		return nil, err
+	}
+	fromDay := truncate(from, StepDay).Format("20060102")
+	toDay := truncate(to, StepDay).Format("20060102")
+	seen := map[string]bool{}
+	nodes := []string{}
+	for _, key := range keys {
+		rest := strings.TrimPrefix(key, prefix)
+		if len(rest) < 8 || rest[:8] < fromDay || rest[:8] > toDay {
+			continue
+		}
+		node, ok := strings.CutPrefix(rest[8:], suffix)
+		if !ok || node == "" || seen[node] {
+			continue
+		}
+		seen[node] = true
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+// globEscaper 转义 SCAN pattern 中的通配符
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// StatusCounterName 服务下单个状态分类的计数器名称
+func StatusCounterName(serviceName, statusClass string) string {
+	return serviceName + "#status#" + statusClass
+}
+
+func minuteKey(counterName string, t time.Time) string {
+	return fmt.Sprintf("%s_%s_%s", "flow_minute_count", t.Format("200601021504"), counterName)
+}
+
+func hourKey(counterName string, t time.Time) string {
+	return fmt.Sprintf("%s_%s_%s", "flow_hour_count", t.Format("2006010215"), counterName)
+}
+
+func dayKey(counterName string, t time.Time) string {
+	return fmt.Sprintf("%s_%s_%s", "flow_day_count", t.Format("20060102"), counterName)
+}
+
+func minuteRetention() time.Duration {
+	if h := configs.GetFlowStatConfig().MinuteRetention; h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	return defaultMinuteRetention
+}
+
+func hourRetention() time.Duration {
+	if d := configs.GetFlowStatConfig().HourRetention; d > 0 {
+		return time.Duration(d) * 24 * time.Hour
+	}
+	return defaultHourRetention
+}
+
+func dayRetention() time.Duration {
+	if d := configs.GetFlowStatConfig().DayRetention; d > 0 {
+		return time.Duration(d) * 24 * time.Hour
+	}
+	return defaultDayRetention
+}
+
+// truncate 按本地时间对齐到统计桶的起始时间
+func truncate(t time.Time, step string) time.Time {
+	t = t.Local()
+	switch step {
+	case StepMinute:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+	case StepHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, time.Local)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	}
+}
+
+func next(t time.Time, step string) time.Time {
+	switch step {
+	case StepMinute:
+		return t.Add(time.Minute)
+	case StepHour:
+		return t.Add(time.Hour)
+	default:
+		// 按日历天递增, 避免夏令时切换时错位
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// GetRangeData 查询 [from, to] 区间内各统计桶的请求数, 超过保留时间的桶返回 0
+// 写入时同时累加分钟、小时、天三个粒度的桶, 较粗的粒度即为降采样后的数据
+func GetRangeData(counterName string, from, to time.Time, step string) ([]FlowPoint, error) {
+	var keyFunc func(string, time.Time) string
+	switch step {
+	case StepMinute:
+		keyFunc = minuteKey
+	case StepHour:
+		keyFunc = hourKey
+	case StepDay:
+		keyFunc = dayKey
+	default:
+		return nil, fmt.Errorf("unsupported step %q", step)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("invalid range: to is before from")
+	}
+
+	points := []FlowPoint{}
+	keys := []string{}
+	for t := truncate(from, step); !t.After(to); t = next(t, step) {
+		if len(points) >= MaxRangePoints {
+			return nil, fmt.Errorf("too many points, at most %d points per query", MaxRangePoints)
+		}
+		points = append(points, FlowPoint{Time: t})
+		keys = append(keys, keyFunc(counterName, t))
+	}
+	values, err := redis.MGetInt64(keys...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range points {
+		points[i].Value = values[i]
+	}
+	return points, nil
+}
@@ -12,8 +12,11 @@ type RedisFlowCount interface {
 	Increase()
 	GetDayData(t time.Time) (int64, error)
 	GetHourData(t time.Time) (int64, error)
+	GetMinuteData(t time.Time) (int64, error)
 	GetDayKey(t time.Time) string
 	GetHourKey(t time.Time) string
+	GetMinuteKey(t time.Time) string
+	GetRangeData(from, to time.Time, step string) ([]FlowPoint, error)
 }
 
 type redisFlowCounter struct {
@@ -50,18 +53,25 @@ func NewRedisFlowCountService(CounterName string, interval time.Duration) *redis
 			currentTime := time.Now()
 			dayKey := reqCounter.GetDayKey(currentTime)
 			hourKey := reqCounter.GetHourKey(currentTime)
+			minuteKey := reqCounter.GetMinuteKey(currentTime)
 			if err := redis.Pipeline(
 				func(pipe redis.Pipeliner) error {
 					return pipe.IncrBy(ctx, dayKey, tickerCount).Err()
 				},
 				func(pipe redis.Pipeliner) error {
-					return pipe.Expire(ctx, dayKey, 86400*2*time.Second).Err()
+					return pipe.Expire(ctx, dayKey, dayRetention()).Err()
 				},
 				func(pipe redis.Pipeliner) error {
 					return pipe.IncrBy(ctx, hourKey, tickerCount).Err()
 				},
 				func(pipe redis.Pipeliner) error {
-					return pipe.Expire(ctx, hourKey, 86400*2*time.Second).Err()
+					return pipe.Expire(ctx, hourKey, hourRetention()).Err()
+				},
+				func(pipe redis.Pipeliner) error {
+					return pipe.IncrBy(ctx, minuteKey, tickerCount).Err()
+				},
+				func(pipe redis.Pipeliner) error {
+					return pipe.Expire(ctx, minuteKey, minuteRetention()).Err()
 				},
 			); err != nil {
 				fmt.Println("Pipeline error:", err)
@@ -90,13 +100,15 @@ func NewRedisFlowCountService(CounterName string, interval time.Duration) *redis
 }
 
 func (o *redisFlowCounter) GetDayKey(t time.Time) string {
-	dayStr := t.Format("20060102")
-	return fmt.Sprintf("%s_%s_%s", "flow_day_count", dayStr, o.CounterName)
+	return dayKey(o.CounterName, t)
 }
 
 func (o *redisFlowCounter) GetHourKey(t time.Time) string {
-	hourStr := t.Format("2006010215")
-	return fmt.Sprintf("%s_%s_%s", "flow_hour_count", hourStr, o.CounterName)
+	return hourKey(o.CounterName, t)
+}
+
+func (o *redisFlowCounter) GetMinuteKey(t time.Time) string {
+	return minuteKey(o.CounterName, t)
 }
 
 func (o *redisFlowCounter) GetHourData(t time.Time) (int64, error) {
@@ -107,6 +119,15 @@ func (o *redisFlowCounter) GetDayData(t time.Time) (int64, error) {
 	return redis.GetInt64(o.GetDayKey(t))
 }
 
+func (o *redisFlowCounter) GetMinuteData(t time.Time) (int64, error) {
+	return redis.GetInt64(o.GetMinuteKey(t))
+}
+
+// GetRangeData 按粒度查询区间内的请求数
+func (o *redisFlowCounter) GetRangeData(from, to time.Time, step string) ([]FlowPoint, error) {
+	return GetRangeData(o.CounterName, from, to, step)
+}
+
 // 原子增加
 func (o *redisFlowCounter) Increase() {
 	atomic.AddInt64(&o.TickerCount, 1)
@@ -23,7 +23,11 @@ SET time_zone = "+00:00";
 CREATE TABLE `gateway_admin` (
   `id` bigint(20) NOT NULL COMMENT '自增id',
   `user_name` varchar(255) NOT NULL DEFAULT '' COMMENT '用户名',
+  `salt` varchar(50) NOT NULL DEFAULT '' COMMENT '盐值',
   `password` varchar(255) NOT NULL DEFAULT '' COMMENT '密码',
+  `role` varchar(32) NOT NULL DEFAULT 'viewer' COMMENT '角色 viewer/operator/admin',
+  `status` tinyint(4) NOT NULL DEFAULT '1' COMMENT '状态 0=禁用 1=启用',
+  `service_scope` varchar(1000) NOT NULL DEFAULT '' COMMENT '可管理的服务, 逗号分隔, 以*结尾表示前缀, 为空表示全部服务',
   `create_at` datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '新增时间',
   `update_at` datetime NOT NULL DEFAULT '1971-01-01 00:00:00' COMMENT '更新时间',
   `is_delete` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否删除'
@@ -33,8 +37,8 @@ CREATE TABLE `gateway_admin` (
 -- 转存表中的数据 `gateway_admin`
 --
 
-INSERT INTO `gateway_admin` (`id`, `user_name`, `password`, `create_at`, `update_at`, `is_delete`) VALUES
-(1, 'admin',  '$2a$10$4oKTR0e.UKEdVVJwWwl/pu.njPTVem/bmNM0eb16FF34g9kcPK1rK', '2020-04-10 16:42:05', '2020-04-21 06:35:08', 0);
+INSERT INTO `gateway_admin` (`id`, `user_name`, `password`, `role`, `status`, `create_at`, `update_at`, `is_delete`) VALUES
+(1, 'admin',  '$2a$10$4oKTR0e.UKEdVVJwWwl/pu.njPTVem/bmNM0eb16FF34g9kcPK1rK', 'admin', 1, '2020-04-10 16:42:05', '2020-04-21 06:35:08', 0);
 
 -- $2a$10$1jFsmThr.6z6J9bUcvzFVerTqtf7vPLsE1i1Z5d9c1rODaeEj4J3O
 -- --------------------------------------------------------
@@ -124,7 +128,13 @@ CREATE TABLE `gateway_service_grpc_rule` (
   `id` bigint(20) NOT NULL COMMENT '自增主键',
   `service_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
   `port` int(5) NOT NULL DEFAULT '0' COMMENT '端口',
-  `header_transfor` varchar(5000) NOT NULL DEFAULT '' COMMENT 'header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue 多个逗号间隔'
+  `header_transfor` varchar(5000) NOT NULL DEFAULT '' COMMENT 'header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue 多个逗号间隔',
+  `method_route` varchar(5000) NOT NULL DEFAULT '' COMMENT '按方法前缀路由到上游分组 格式: /package.Service/ ip:port ip:port 多个逗号间隔',
+  `method_limit` varchar(2000) NOT NULL DEFAULT '' COMMENT '按方法前缀限流 格式: /package.Service/Method qps 多个逗号间隔',
+  `method_allow` varchar(2000) NOT NULL DEFAULT '' COMMENT '允许调用的方法前缀, 多个逗号间隔',
+  `method_deny` varchar(2000) NOT NULL DEFAULT '' COMMENT '禁止调用的方法前缀, 多个逗号间隔',
+  `need_grpc_web` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否允许通过 http 代理以 grpc-web 或 json 方式访问 1=允许',
+  `grpc_web_origins` varchar(2000) NOT NULL DEFAULT '' COMMENT '允许跨域访问 grpc-web 的来源, 多个逗号间隔, * 表示任意来源但不携带凭证, 为空时不允许跨域'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关路由匹配表';
 
 --
@@ -150,6 +160,7 @@ CREATE TABLE `gateway_service_http_rule` (
   `need_https` tinyint(4) NOT NULL DEFAULT '0' COMMENT '支持https 1=支持',
   `need_strip_uri` tinyint(4) NOT NULL DEFAULT '0' COMMENT '启用strip_uri 1=启用',
   `need_websocket` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否支持websocket 1=支持',
+  `need_log_body` tinyint(4) NOT NULL DEFAULT '0' COMMENT '访问日志记录请求和响应body 1=记录',
   `url_rewrite` varchar(5000) NOT NULL DEFAULT '' COMMENT 'url重写功能 格式：^/gatekeeper/test_service(.*) $1 多个逗号间隔',
   `header_transfor` varchar(5000) NOT NULL DEFAULT '' COMMENT 'header转换支持增加(add)、删除(del)、修改(edit) 格式: add headname headvalue 多个逗号间隔'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关路由匹配表';
@@ -232,10 +243,20 @@ CREATE TABLE `gateway_service_load_balance` (
   `check_method` tinyint(20) NOT NULL DEFAULT '0' COMMENT '检查方法 0=tcpchk,检测端口是否握手成功',
   `check_timeout` int(10) NOT NULL DEFAULT '0' COMMENT 'check超时时间,单位s',
   `check_interval` int(11) NOT NULL DEFAULT '0' COMMENT '检查间隔, 单位s',
-  `round_type` tinyint(4) NOT NULL DEFAULT '2' COMMENT '轮询方式 0=random 1=round-robin 2=weight_round-robin 3=ip_hash',
-  `ip_list` varchar(2000) NOT NULL DEFAULT '' COMMENT 'ip列表',
+  `round_type` tinyint(4) NOT NULL DEFAULT '2' COMMENT '轮询方式 0=random 1=round-robin 2=weight_round-robin 3=ip_hash 4=least_conn 5=p2c 6=bounded_hash',
+  `ip_list` varchar(2000) NOT NULL DEFAULT '' COMMENT 'ip列表, 每项为 ip:port, host:port 或 srv:<name>',
   `weight_list` varchar(2000) NOT NULL DEFAULT '' COMMENT '权重列表',
+  `priority_list` varchar(2000) NOT NULL DEFAULT '' COMMENT '优先级列表, 与ip列表一一对应, 0为最高优先级, 为空时不做切换',
+  `failover_threshold` int(11) NOT NULL DEFAULT '0' COMMENT '某一优先级可用节点的权重低于该百分比时同时使用下一优先级的节点',
   `forbid_list` varchar(2000) NOT NULL DEFAULT '' COMMENT '禁用ip列表',
+  `drain_list` varchar(2000) NOT NULL DEFAULT '' COMMENT '排空中的节点列表',
+  `discovery_type` varchar(32) NOT NULL DEFAULT '' COMMENT '节点来源 空=ip_list file=本地文件 redis=redis注册中心 http=接口轮询',
+  `discovery_target` varchar(255) NOT NULL DEFAULT '' COMMENT '服务发现的目标: 文件路径/注册的服务名/接口地址',
+  `upstream_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '引用的上游id, 不为0时使用上游的节点、探活、超时和TLS配置',
+  `hash_key` varchar(255) NOT NULL DEFAULT '' COMMENT '一致性hash的key来源 url/path/client_ip/app_id/header:<name>/cookie:<name>/query:<name>',
+  `hash_replicas` int(11) NOT NULL DEFAULT '0' COMMENT '一致性hash每个节点的虚拟节点数, 0表示默认值10',
+  `hash_load_factor` int(11) NOT NULL DEFAULT '0' COMMENT '有界负载一致性hash的负载上限, 平均负载的百分比, 0表示默认值125',
+  `slow_start` int(11) NOT NULL DEFAULT '0' COMMENT '慢启动时长, 单位s, 新增或恢复的节点在该时间内权重从小值线性增长, 0表示不开启',
   `upstream_connect_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '建立连接超时, 单位s',
   `upstream_header_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '获取header超时, 单位s',
   `upstream_idle_timeout` int(10) NOT NULL DEFAULT '0' COMMENT '链接最大空闲时间, 单位s',
@@ -282,7 +303,13 @@ INSERT INTO `gateway_service_load_balance` (`id`, `service_id`, `check_method`,
 CREATE TABLE `gateway_service_tcp_rule` (
   `id` bigint(20) NOT NULL COMMENT '自增主键',
   `service_id` bigint(20) NOT NULL COMMENT '服务id',
-  `port` int(5) NOT NULL DEFAULT '0' COMMENT '端口号'
+  `port` int(5) NOT NULL DEFAULT '0' COMMENT '端口号',
+  `idle_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '连接空闲超时, 单位s, 0表示不限制',
+  `max_conns` int(11) NOT NULL DEFAULT '0' COMMENT '最大并发连接数, 0表示不限制',
+  `need_tls` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否在网关终止TLS 1=是',
+  `cert_file` varchar(255) NOT NULL DEFAULT '' COMMENT 'TLS证书文件路径',
+  `key_file` varchar(255) NOT NULL DEFAULT '' COMMENT 'TLS私钥文件路径',
+  `sni_host` varchar(1000) NOT NULL DEFAULT '' COMMENT '共享端口SNI路由域名, 以逗号间隔'
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关路由匹配表';
 
 --
@@ -302,6 +329,125 @@ INSERT INTO `gateway_service_tcp_rule` (`id`, `service_id`, `port`) VALUES
 (180, 55, 8010),
 (181, 57, 8011);
 
+-- --------------------------------------------------------
+
+--
+-- 表的结构 `gateway_service_udp_rule`
+--
+
+CREATE TABLE `gateway_service_udp_rule` (
+  `id` bigint(20) NOT NULL COMMENT '自增主键',
+  `service_id` bigint(20) NOT NULL COMMENT '服务id',
+  `port` int(5) NOT NULL DEFAULT '0' COMMENT '端口号',
+  `session_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '会话空闲超时, 单位s'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关UDP路由匹配表';
+
+-- --------------------------------------------------------
+
+--
+-- 表的结构 `gateway_flow_stat`
+--
+
+CREATE TABLE `gateway_flow_stat` (
+  `id` bigint(20) NOT NULL COMMENT '自增主键',
+  `dimension` varchar(32) NOT NULL DEFAULT '' COMMENT '统计维度 total/service/app',
+  `name` varchar(255) NOT NULL DEFAULT '' COMMENT '服务名称或租户id',
+  `stat_day` int(11) NOT NULL DEFAULT '0' COMMENT '统计日期 yyyymmdd',
+  `stat_hour` tinyint(4) NOT NULL DEFAULT '0' COMMENT '统计小时 0-23',
+  `request_count` bigint(20) NOT NULL DEFAULT '0' COMMENT '请求数',
+  `create_at` datetime NOT NULL COMMENT '添加时间',
+  `update_at` datetime NOT NULL COMMENT '更新时间'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关流量统计表';
+
+--
+-- 表的结构 `gateway_audit_log`
+--
+
+CREATE TABLE `gateway_audit_log` (
+  `id` bigint(20) NOT NULL COMMENT '自增主键',
+  `admin_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '操作人id',
+  `admin_name` varchar(255) NOT NULL DEFAULT '' COMMENT '操作人用户名',
+  `resource` varchar(32) NOT NULL DEFAULT '' COMMENT '资源类型 service/app/admin',
+  `action` varchar(32) NOT NULL DEFAULT '' COMMENT '操作类型 add/update/delete',
+  `target` varchar(255) NOT NULL DEFAULT '' COMMENT '服务名称/租户id/管理员用户名',
+  `trace_id` varchar(64) NOT NULL DEFAULT '' COMMENT '请求trace id',
+  `client_ip` varchar(64) NOT NULL DEFAULT '' COMMENT '客户端ip',
+  `before_data` mediumtext NOT NULL COMMENT '变更前的数据, json',
+  `after_data` mediumtext NOT NULL COMMENT '变更后的数据, json',
+  `diff` mediumtext NOT NULL COMMENT '变更的字段, json',
+  `create_at` datetime NOT NULL COMMENT '操作时间'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关控制面审计日志表';
+
+--
+-- 表的结构 `gateway_service_revision`
+--
+
+CREATE TABLE `gateway_service_revision` (
+  `id` bigint(20) NOT NULL COMMENT '自增主键',
+  `service_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '服务id',
+  `service_name` varchar(255) NOT NULL DEFAULT '' COMMENT '服务名称',
+  `version` int(11) NOT NULL DEFAULT '0' COMMENT '版本号, 同一服务内递增',
+  `action` varchar(32) NOT NULL DEFAULT '' COMMENT '产生版本的操作 add/update/rollback',
+  `detail` mediumtext NOT NULL COMMENT '完整的服务配置, json',
+  `admin_name` varchar(255) NOT NULL DEFAULT '' COMMENT '操作人用户名',
+  `remark` varchar(255) NOT NULL DEFAULT '' COMMENT '备注',
+  `create_at` datetime NOT NULL COMMENT '创建时间'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关服务配置版本表';
+
+--
+-- 表的结构 `gateway_api_token`
+--
+
+CREATE TABLE `gateway_api_token` (
+  `id` bigint(20) NOT NULL COMMENT '自增主键',
+  `admin_id` bigint(20) NOT NULL DEFAULT '0' COMMENT '签发人id',
+  `name` varchar(255) NOT NULL DEFAULT '' COMMENT '令牌名称',
+  `token_prefix` varchar(32) NOT NULL DEFAULT '' COMMENT '令牌前缀, 用于识别令牌',
+  `token_hash` char(64) NOT NULL DEFAULT '' COMMENT '令牌sha256摘要',
+  `role` varchar(32) NOT NULL DEFAULT 'viewer' COMMENT '令牌角色 viewer/operator',
+  `service_scope` varchar(1000) NOT NULL DEFAULT '' COMMENT '服务范围, 为空表示沿用签发人的服务范围',
+  `expire_at` datetime NOT NULL COMMENT '过期时间',
+  `last_used_at` datetime NOT NULL DEFAULT '1970-01-01 08:00:00' COMMENT '最后使用时间',
+  `last_used_ip` varchar(64) NOT NULL DEFAULT '' COMMENT '最后使用的客户端ip',
+  `revoked` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已吊销',
+  `create_at` datetime NOT NULL COMMENT '创建时间',
+  `update_at` datetime NOT NULL COMMENT '更新时间'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关后台接口令牌表';
+
+--
+-- 表的结构 `gateway_upstream`
+--
+
+CREATE TABLE `gateway_upstream` (
+  `id` bigint(20) NOT NULL COMMENT '自增主键',
+  `name` varchar(255) NOT NULL DEFAULT '' COMMENT '上游名称',
+  `description` varchar(255) NOT NULL DEFAULT '' COMMENT '描述',
+  `ip_list` varchar(2000) NOT NULL DEFAULT '' COMMENT 'ip列表, 每项为 ip:port, host:port 或 srv:<name>',
+  `weight_list` varchar(2000) NOT NULL DEFAULT '' COMMENT '权重列表',
+  `priority_list` varchar(2000) NOT NULL DEFAULT '' COMMENT '优先级列表, 与ip列表一一对应, 0为最高优先级, 为空时不做切换',
+  `failover_threshold` int(11) NOT NULL DEFAULT '0' COMMENT '某一优先级可用节点的权重低于该百分比时同时使用下一优先级的节点',
+  `forbid_list` varchar(2000) NOT NULL DEFAULT '' COMMENT '禁用ip列表, 对所有引用的服务生效',
+  `discovery_type` varchar(32) NOT NULL DEFAULT '' COMMENT '节点来源 空=ip_list file=本地文件 redis=redis注册中心 http=接口轮询',
+  `discovery_target` varchar(255) NOT NULL DEFAULT '' COMMENT '服务发现的目标: 文件路径/注册的服务名/接口地址',
+  `check_method` tinyint(4) NOT NULL DEFAULT '0' COMMENT '探活方式 0=tcp 1=不探活 2=http',
+  `check_path` varchar(255) NOT NULL DEFAULT '' COMMENT 'http 探活的请求路径',
+  `check_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '探活超时, 单位s, 0表示默认值',
+  `check_interval` int(11) NOT NULL DEFAULT '0' COMMENT '探活间隔, 单位s, 0表示默认值',
+  `check_max_err_num` int(11) NOT NULL DEFAULT '0' COMMENT '连续失败多少次后摘除节点, 0表示默认值',
+  `slow_start` int(11) NOT NULL DEFAULT '0' COMMENT '慢启动时长, 单位s, 新增或恢复的节点在该时间内权重从小值线性增长, 0表示不开启',
+  `connect_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '建立连接超时, 单位s',
+  `header_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '获取header超时, 单位s',
+  `idle_timeout` int(11) NOT NULL DEFAULT '0' COMMENT '链接最大空闲时间, 单位s',
+  `max_idle` int(11) NOT NULL DEFAULT '0' COMMENT '最大空闲链接数',
+  `tls_enable` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否使用 https 访问上游',
+  `tls_server_name` varchar(255) NOT NULL DEFAULT '' COMMENT '校验证书使用的服务名',
+  `tls_insecure_skip_verify` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否跳过证书校验',
+  `tls_ca_cert` text NOT NULL COMMENT '校验上游证书的 CA 证书, PEM 格式',
+  `create_at` datetime NOT NULL COMMENT '添加时间',
+  `update_at` datetime NOT NULL COMMENT '更新时间',
+  `is_delete` tinyint(4) NOT NULL DEFAULT '0' COMMENT '是否已删除;0:否;1:是'
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COMMENT='网关上游集群表';
+
 --
 -- Indexes for dumped tables
 --
@@ -310,7 +456,8 @@ INSERT INTO `gateway_service_tcp_rule` (`id`, `service_id`, `port`) VALUES
 -- Indexes for table `gateway_admin`
 --
 ALTER TABLE `gateway_admin`
-  ADD PRIMARY KEY (`id`);
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `idx_user_name` (`user_name`);
 
 --
 -- Indexes for table `gateway_app`
@@ -354,6 +501,52 @@ ALTER TABLE `gateway_service_load_balance`
 ALTER TABLE `gateway_service_tcp_rule`
   ADD PRIMARY KEY (`id`);
 
+--
+-- Indexes for table `gateway_service_udp_rule`
+--
+ALTER TABLE `gateway_service_udp_rule`
+  ADD PRIMARY KEY (`id`);
+
+--
+-- Indexes for table `gateway_flow_stat`
+--
+ALTER TABLE `gateway_flow_stat`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `uniq_dimension_name_time` (`dimension`,`name`,`stat_day`,`stat_hour`),
+  ADD KEY `idx_stat_day` (`stat_day`);
+
+--
+-- Indexes for table `gateway_audit_log`
+--
+ALTER TABLE `gateway_audit_log`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `idx_create_at` (`create_at`),
+  ADD KEY `idx_resource_target` (`resource`,`target`),
+  ADD KEY `idx_admin_name` (`admin_name`),
+  ADD KEY `idx_trace_id` (`trace_id`);
+
+--
+-- Indexes for table `gateway_service_revision`
+--
+ALTER TABLE `gateway_service_revision`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `uniq_service_version` (`service_id`,`version`);
+
+--
+-- Indexes for table `gateway_api_token`
+--
+ALTER TABLE `gateway_api_token`
+  ADD PRIMARY KEY (`id`),
+  ADD UNIQUE KEY `uniq_token_hash` (`token_hash`),
+  ADD KEY `idx_admin_id` (`admin_id`);
+
+--
+-- Indexes for table `gateway_upstream`
+--
+ALTER TABLE `gateway_upstream`
+  ADD PRIMARY KEY (`id`),
+  ADD KEY `idx_name` (`name`);
+
 --
 -- 在导出的表使用AUTO_INCREMENT
 --
@@ -397,7 +590,37 @@ ALTER TABLE `gateway_service_load_balance`
 -- 使用表AUTO_INCREMENT `gateway_service_tcp_rule`
 --
 ALTER TABLE `gateway_service_tcp_rule`
-  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键', AUTO_INCREMENT=182;COMMIT;
+  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键', AUTO_INCREMENT=182;
+--
+-- 使用表AUTO_INCREMENT `gateway_service_udp_rule`
+--
+ALTER TABLE `gateway_service_udp_rule`
+  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键', AUTO_INCREMENT=1;
+--
+-- 使用表AUTO_INCREMENT `gateway_flow_stat`
+--
+ALTER TABLE `gateway_flow_stat`
+  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键', AUTO_INCREMENT=1;
+--
+-- 使用表AUTO_INCREMENT `gateway_audit_log`
+--
+ALTER TABLE `gateway_audit_log`
+  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键', AUTO_INCREMENT=1;
+--
+-- 使用表AUTO_INCREMENT `gateway_service_revision`
+--
+ALTER TABLE `gateway_service_revision`
+  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键', AUTO_INCREMENT=1;
+--
+-- 使用表AUTO_INCREMENT `gateway_api_token`
+--
+ALTER TABLE `gateway_api_token`
+  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键', AUTO_INCREMENT=1;
+--
+-- 使用表AUTO_INCREMENT `gateway_upstream`
+--
+ALTER TABLE `gateway_upstream`
+  MODIFY `id` bigint(20) NOT NULL AUTO_INCREMENT COMMENT '自增主键', AUTO_INCREMENT=1;COMMIT;
 
 /*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
 /*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS */;
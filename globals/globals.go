@@ -11,6 +11,7 @@ const (
 	LoadTypeHTTP = iota
 	LoadTypeTCP
 	LoadTypeGRPC
+	LoadTypeUDP
 
 	HTTPRuleTypePrefixURL = 0
 	HTTPRuleTypeDomain    = 1
@@ -18,6 +19,9 @@ const (
 	ValidatorKey               = "ValidatorKey"
 	TranslatorKey              = "TranslatorKey"
 	AdminSessionInfoKey string = "AdminSessionInfoKey"
+	AdminInfoKey        string = "AdminInfoKey"
+	ApiTokenKey         string = "ApiTokenKey"
+	ApiTokenPrefix      string = "gwt_"
 
 	DataChange = "data_change"
 
@@ -32,6 +36,7 @@ var (
 		LoadTypeHTTP: "HTTP",
 		LoadTypeTCP:  "TCP",
 		LoadTypeGRPC: "GRPC",
+		LoadTypeUDP:  "UDP",
 	}
 )
 
@@ -40,6 +45,7 @@ type DataChangeMessage struct {
 	Payload     string `json:"payload"`
 	ServiceType int    `json:"service_type"`
 	Operation   string `json:"operation"`
+	Node        string `json:"node,omitempty"` // Type 为 node 时表示被操作的节点, Operation 为节点的新状态
 }
 
 const (
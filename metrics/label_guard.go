@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+)
+
+// 标签取值的上限, 超过上限的新取值统一记为 other, 防止指标基数无限增长
+const (
+	maxServiceLabelValues = 1000
+	maxNodeLabelValues    = 2000
+	maxAppLabelValues     = 500
+
+	otherLabelValue = "other"
+)
+
+var (
+	serviceLabels = newLabelGuard(maxServiceLabelValues)
+	nodeLabels    = newLabelGuard(maxNodeLabelValues)
+	appLabels     = newLabelGuard(maxAppLabelValues)
+)
+
+// labelGuard 记录已出现的标签取值, 超过上限后不再接受新取值
+type labelGuard struct {
+	mu   sync.RWMutex
+	max  int
+	seen map[string]struct{}
+}
+
+func newLabelGuard(max int) *labelGuard {
+	return &labelGuard{max: max, seen: make(map[string]struct{})}
+}
+
+func (g *labelGuard) value(v string) string {
+	if v == "" {
+		return v
+	}
+	g.mu.RLock()
+	_, ok := g.seen[v]
+	g.mu.RUnlock()
+	if ok {
+		return v
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.seen[v]; ok {
+		return v
+	}
+	if len(g.seen) >= g.max {
+		return otherLabelValue
+	}
+	g.seen[v] = struct{}{}
+	return v
+}
+
+var knownMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "CONNECT": true, "OPTIONS": true, "TRACE": true,
+}
+
+// methodLabel 只保留标准 http 方法, 其他取值记为 OTHER
+func methodLabel(method string) string {
+	method = strings.ToUpper(method)
+	if method == "" || knownMethods[method] {
+		return method
+	}
+	return "OTHER"
+}
+
+// StatusClass 将 http 状态码归类为 2xx/3xx/4xx/5xx
+func StatusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return string(rune('0'+code/100)) + "xx"
+}
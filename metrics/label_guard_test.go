@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLabelGuard(t *testing.T) {
+	g := newLabelGuard(2)
+	cases := []struct {
+		value string
+		want  string
+	}{
+		{"order_api", "order_api"},
+		{"", ""},
+		{"pay_api", "pay_api"},
+		// 超过上限后新的取值记为 other, 已经出现过的取值不受影响
+		{"user_api", otherLabelValue},
+		{"order_api", "order_api"},
+		{"pay_api", "pay_api"},
+		{"stock_api", otherLabelValue},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if got := g.value(c.value); got != c.want {
+			t.Fatalf("value(%q) = %q, want %q", c.value, got, c.want)
+		}
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	cases := []struct {
+		code int
+		want string
+	}{
+		{100, "1xx"},
+		{200, "2xx"},
+		{204, "2xx"},
+		{302, "3xx"},
+		{404, "4xx"},
+		{499, "4xx"},
+		{500, "5xx"},
+		{599, "5xx"},
+		{0, "unknown"},
+		{99, "unknown"},
+		{600, "unknown"},
+		{-1, "unknown"},
+	}
+	for _, c := range cases {
+		if got := StatusClass(c.code); got != c.want {
+			t.Fatalf("StatusClass(%d) = %q, want %q", c.code, got, c.want)
+		}
+	}
+}
+
+func TestMethodLabel(t *testing.T) {
+	cases := map[string]string{
+		"":         "",
+		"get":      "GET",
+		"POST":     "POST",
+		"PROPFIND": "OTHER",
+	}
+	for method, want := range cases {
+		if got := methodLabel(method); got != want {
+			t.Fatalf("methodLabel(%q) = %q, want %q", method, got, want)
+		}
+	}
+}
+
+// TestIncActiveConnMetrics 多个服务的标签归并为 other 时活跃连接数累加, 不会互相覆盖
+func TestIncActiveConnMetrics(t *testing.T) {
+	saved := serviceLabels
+	serviceLabels = newLabelGuard(1)
+	defer func() { serviceLabels = saved }()
+
+	doneA := IncActiveConnMetrics("tcp", "guard_test_a")
+	doneB := IncActiveConnMetrics("tcp", "guard_test_b")
+	doneC := IncActiveConnMetrics("tcp", "guard_test_c")
+	other := gatewayActiveConns.WithLabelValues("tcp", otherLabelValue)
+	if got := testutil.ToFloat64(other); got != 2 {
+		t.Fatalf("other active connections = %v, want 2", got)
+	}
+	doneB()
+	if got := testutil.ToFloat64(other); got != 1 {
+		t.Fatalf("other active connections = %v, want 1", got)
+	}
+	doneC()
+	doneA()
+	if got := testutil.ToFloat64(gatewayActiveConns.WithLabelValues("tcp", "guard_test_a")); got != 0 {
+		t.Fatalf("guard_test_a active connections = %v, want 0", got)
+	}
+}
@@ -31,4 +31,62 @@ var (
 		Name: "limiter_count",
 		Help: "The total number of limiter events",
 	}, []string{"name", "node"})
+
+	tcpActiveConns = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tcp_active_connections",
+		Help: "The current number of active TCP connections",
+	}, []string{"name"})
+
+	tcpRejectedConns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tcp_rejected_connections_total",
+		Help: "The total number of TCP connections rejected by the max connections limit",
+	}, []string{"name"})
+
+	udpActiveSessions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "udp_active_sessions",
+		Help: "The current number of active UDP client sessions",
+	}, []string{"name"})
+
+	gatewayRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_requests_total",
+		Help: "The total number of requests (HTTP requests, gRPC calls, TCP connections)",
+	}, []string{"protocol", "service", "method", "status_class", "app"})
+
+	gatewayRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_request_duration_seconds",
+		Help:    "The total time spent handling a request, including the upstream",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"protocol", "service", "node"})
+
+	upstreamConnectDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_upstream_connect_seconds",
+		Help:    "The time spent establishing a new connection to the upstream node",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 12),
+	}, []string{"protocol", "service", "node"})
+
+	upstreamTTFB = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_upstream_ttfb_seconds",
+		Help:    "The time from sending the request to receiving the first response byte from the upstream node",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"protocol", "service", "node"})
+
+	gatewayBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_bytes_total",
+		Help: "The total number of bytes received from clients (in) and sent to clients (out)",
+	}, []string{"protocol", "service", "direction"})
+
+	gatewayActiveConns = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_active_connections",
+		Help: "The current number of in-flight HTTP requests, gRPC calls and TCP connections",
+	}, []string{"protocol", "service"})
+
+	gatewayRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_rejections_total",
+		Help: "The total number of requests rejected by the gateway, by reason",
+	}, []string{"protocol", "service", "reason"})
+
+	upstreamNodeHealth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_upstream_node_health",
+		Help: "The health of the upstream node reported by the active checker (1 = healthy, 0 = unhealthy)",
+	}, []string{"service", "node"})
 )
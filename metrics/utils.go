@@ -4,6 +4,7 @@ import (
 	"gateway/pkg/log"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/mem"
 	"go.uber.org/zap"
@@ -47,3 +48,82 @@ func RecordResponseTimeMetrics(serverName, nodeName string, responseTime float64
 func RecordLimiterMetrics(serverName, nodeName string) {
 	limiterCount.WithLabelValues(serverName, nodeName).Inc()
 }
+
+// RecordTcpActiveConnMetrics 服务的活跃连接数, gateway_active_connections 由 IncActiveConnMetrics 统计
+func RecordTcpActiveConnMetrics(serverName string, activeConns int) {
+	tcpActiveConns.WithLabelValues(serverName).Set(float64(activeConns))
+}
+
+func RecordTcpRejectedConnMetrics(serverName string) {
+	tcpRejectedConns.WithLabelValues(serverName).Inc()
+	RecordRejectionMetrics("tcp", serverName, RejectMaxConns)
+}
+
+func RecordUdpActiveSessionMetrics(serverName string, activeSessions int) {
+	udpActiveSessions.WithLabelValues(serverName).Set(float64(activeSessions))
+}
+
+// RecordRequestMetrics 记录一次请求, statusClass 对 http 为 2xx 等, 对 grpc 为状态码名称, 对 tcp 为 ok/error
+func RecordRequestMetrics(protocol, serviceName, method, statusClass, appID string) {
+	gatewayRequests.WithLabelValues(protocol, serviceLabels.value(serviceName), methodLabel(method), statusClass, appLabels.value(appID)).Inc()
+}
+
+func RecordRequestDurationMetrics(protocol, serviceName, nodeName string, seconds float64) {
+	gatewayRequestDuration.WithLabelValues(protocol, serviceLabels.value(serviceName), nodeLabels.value(nodeName)).Observe(seconds)
+}
+
+func RecordUpstreamConnectMetrics(protocol, serviceName, nodeName string, seconds float64) {
+	upstreamConnectDuration.WithLabelValues(protocol, serviceLabels.value(serviceName), nodeLabels.value(nodeName)).Observe(seconds)
+}
+
+func RecordUpstreamTTFBMetrics(protocol, serviceName, nodeName string, seconds float64) {
+	upstreamTTFB.WithLabelValues(protocol, serviceLabels.value(serviceName), nodeLabels.value(nodeName)).Observe(seconds)
+}
+
+func RecordBytesMetrics(protocol, serviceName string, bytesIn, bytesOut int64) {
+	serviceName = serviceLabels.value(serviceName)
+	if bytesIn > 0 {
+		gatewayBytes.WithLabelValues(protocol, serviceName, "in").Add(float64(bytesIn))
+	}
+	if bytesOut > 0 {
+		gatewayBytes.WithLabelValues(protocol, serviceName, "out").Add(float64(bytesOut))
+	}
+}
+
+// IncActiveConnMetrics 进行中的请求或连接数加一, 返回的函数用于减一
+func IncActiveConnMetrics(protocol, serviceName string) func() {
+	gauge := gatewayActiveConns.WithLabelValues(protocol, serviceLabels.value(serviceName))
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// 拒绝原因
+const (
+	RejectServiceLimit = "service_limit"
+	RejectClientLimit  = "client_limit"
+	RejectMethodLimit  = "method_limit"
+	RejectAppLimit     = "app_limit"
+	RejectAuth         = "auth"
+	RejectWhiteList    = "white_list"
+	RejectBlackList    = "black_list"
+	RejectMethodAccess = "method_access"
+	RejectMaxConns     = "max_conns"
+	RejectCircuitOpen  = "circuit_open"
+)
+
+func RecordRejectionMetrics(protocol, serviceName, reason string) {
+	gatewayRejections.WithLabelValues(protocol, serviceLabels.value(serviceName), reason).Inc()
+}
+
+func RecordNodeHealthMetrics(serviceName, nodeName string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	upstreamNodeHealth.WithLabelValues(serviceLabels.value(serviceName), nodeLabels.value(nodeName)).Set(value)
+}
+
+// RemoveNodeHealthMetrics 服务删除或重建负载均衡器时清除该服务的节点健康指标
+func RemoveNodeHealthMetrics(serviceName string) {
+	upstreamNodeHealth.DeletePartialMatch(prometheus.Labels{"service": serviceLabels.value(serviceName)})
+}
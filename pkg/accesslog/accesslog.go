@@ -0,0 +1,273 @@
+package accesslog
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"gateway/configs"
+	"gateway/pkg/trace"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const defaultMaxBodySize = 4096
+
+// 未配置 fields 时按以下顺序输出全部字段
+var defaultFields = []string{
+	"protocol", "service", "app_id", "client_ip", "method", "path", "upstream", "status",
+	"bytes_in", "bytes_out", "latency", "phases", "trace_id", "request_id", "error",
+	"request_body", "response_body",
+}
+
+var (
+	lock        sync.RWMutex
+	logger      *zap.Logger
+	closer      io.Closer
+	fields      []string
+	sampleRate  float64
+	maxBodySize int
+)
+
+// Entry 一条访问日志, http 对应一次请求, tcp 对应一个连接, grpc 对应一次调用
+type Entry struct {
+	mu sync.Mutex
+
+	Protocol     string
+	Service      string
+	AppID        string
+	ClientIP     string
+	Method       string
+	Path         string
+	Upstream     string
+	Status       int
+	BytesIn      int64
+	BytesOut     int64
+	Start        time.Time
+	Latency      time.Duration
+	Phases       []trace.PhaseTiming
+	TraceID      string
+	RequestID    string
+	Error        string
+	RequestBody  string
+	ResponseBody string
+}
+
+// NewEntry 创建访问日志记录, 开始时间为当前时间
+func NewEntry(protocol string) *Entry {
+	return &Entry{Protocol: protocol, Start: time.Now()}
+}
+
+// SetUpstream 记录实际转发的上游节点, 可在其他协程中调用
+func (e *Entry) SetUpstream(addr string) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Upstream = addr
+}
+
+// SetError 记录处理过程中的错误, 可在其他协程中调用
+func (e *Entry) SetError(err error) {
+	if e == nil || err == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Error = err.Error()
+}
+
+type entryKey struct{}
+
+// NewContext 将访问日志记录放入 context, 供下游处理环节补充字段
+func NewContext(ctx context.Context, e *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, e)
+}
+
+// FromContext 取出 context 中的访问日志记录, 不存在时返回 nil(方法可安全调用)
+func FromContext(ctx context.Context) *Entry {
+	e, _ := ctx.Value(entryKey{}).(*Entry)
+	return e
+}
+
+// Init 根据配置初始化访问日志输出
+func Init() {
+	Setup(configs.GetAccessLogConfig())
+}
+
+// Setup 使用指定配置初始化访问日志输出, 会先关闭之前的输出
+func Setup(conf *configs.AccessLogConfig) {
+	Close()
+	if conf == nil || !conf.Enable {
+		return
+	}
+
+	var writer zapcore.WriteSyncer
+	var c io.Closer
+	if conf.Output == "" || conf.Output == "stdout" {
+		writer = zapcore.AddSync(os.Stdout)
+	} else {
+		lj := &lumberjack.Logger{
+			Filename:   conf.Output,
+			MaxSize:    conf.MaxSize,
+			MaxBackups: conf.MaxBackups,
+			MaxAge:     conf.MaxAge,
+			Compress:   conf.Compress,
+		}
+		writer = zapcore.AddSync(lj)
+		c = lj
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "time",
+		MessageKey:     "msg",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeTime:     zapcore.TimeEncoderOfLayout(time.RFC3339Nano),
+		EncodeDuration: zapcore.MillisDurationEncoder,
+	}
+	var encoder zapcore.Encoder
+	if conf.Format == "text" {
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	} else {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	logger = zap.New(zapcore.NewCore(encoder, writer, zapcore.InfoLevel))
+	closer = c
+	fields = conf.Fields
+	if len(fields) == 0 {
+		fields = defaultFields
+	}
+	sampleRate = normalizeSampleRate(conf.SampleRate)
+	maxBodySize = conf.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+}
+
+// Close 刷新并关闭访问日志输出
+func Close() error {
+	lock.Lock()
+	defer lock.Unlock()
+	if logger == nil {
+		return nil
+	}
+	logger.Sync()
+	logger = nil
+	if closer != nil {
+		err := closer.Close()
+		closer = nil
+		return err
+	}
+	return nil
+}
+
+// Enabled 判断是否开启了访问日志
+func Enabled() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return logger != nil
+}
+
+// MaxBodySize 返回 body 最多记录的字节数
+func MaxBodySize() int {
+	lock.RLock()
+	defer lock.RUnlock()
+	return maxBodySize
+}
+
+// normalizeSampleRate 未配置时全部记录, 超出 0~1 的值取边界
+func normalizeSampleRate(rate *float64) float64 {
+	if rate == nil || *rate > 1 {
+		return 1
+	}
+	if *rate < 0 {
+		return 0
+	}
+	return *rate
+}
+
+// Log 输出一条访问日志, 成功的请求按 sample_rate 采样, 失败的请求总是输出
+func Log(e *Entry) {
+	lock.RLock()
+	l, fs, rate := logger, fields, sampleRate
+	lock.RUnlock()
+	if l == nil || e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.Latency == 0 {
+		e.Latency = time.Since(e.Start)
+	}
+	failed := e.Error != "" || e.Status >= 400 || (e.Protocol == "grpc" && e.Status != 0)
+	if !failed && rate < 1 && rand.Float64() >= rate {
+		return
+	}
+
+	zfs := make([]zap.Field, 0, len(fs))
+	for _, name := range fs {
+		if f, ok := e.field(name); ok {
+			zfs = append(zfs, f)
+		}
+	}
+	l.Info("access", zfs...)
+}
+
+func (e *Entry) field(name string) (zap.Field, bool) {
+	switch name {
+	case "protocol":
+		return zap.String(name, e.Protocol), true
+	case "service":
+		return zap.String(name, e.Service), true
+	case "app_id":
+		return zap.String(name, e.AppID), true
+	case "client_ip":
+		return zap.String(name, e.ClientIP), true
+	case "method":
+		return zap.String(name, e.Method), true
+	case "path":
+		return zap.String(name, e.Path), true
+	case "upstream":
+		return zap.String(name, e.Upstream), true
+	case "status":
+		return zap.Int(name, e.Status), true
+	case "bytes_in":
+		return zap.Int64(name, e.BytesIn), true
+	case "bytes_out":
+		return zap.Int64(name, e.BytesOut), true
+	case "latency":
+		return zap.Duration(name, e.Latency), true
+	case "phases":
+		return zap.Object(name, phaseMarshaler(e.Phases)), true
+	case "trace_id":
+		return zap.String(name, e.TraceID), true
+	case "request_id":
+		return zap.String(name, e.RequestID), true
+	case "error":
+		return zap.String(name, e.Error), true
+	case "request_body":
+		return zap.String(name, e.RequestBody), e.RequestBody != ""
+	case "response_body":
+		return zap.String(name, e.ResponseBody), e.ResponseBody != ""
+	}
+	return zap.Skip(), false
+}
+
+type phaseMarshaler []trace.PhaseTiming
+
+func (p phaseMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, phase := range p {
+		enc.AddDuration(phase.Name, phase.Duration)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"gateway/configs"
+)
+
+// setupFile 把访问日志输出到临时文件, 返回读取已输出记录的函数
+func setupFile(t *testing.T, conf configs.AccessLogConfig) func() []map[string]interface{} {
+	t.Helper()
+	conf.Enable = true
+	conf.Output = filepath.Join(t.TempDir(), "access.log")
+	Setup(&conf)
+	t.Cleanup(func() { Close() })
+	return func() []map[string]interface{} {
+		Close()
+		f, err := os.Open(conf.Output)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		records := []map[string]interface{}{}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			record := map[string]interface{}{}
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("invalid record %s: %v", scanner.Text(), err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	read := setupFile(t, configs.AccessLogConfig{Fields: []string{"service", "status", "request_body", "unknown"}})
+	e := NewEntry("http")
+	e.Service, e.Status, e.Path = "order_api", 200, "/order"
+	Log(e)
+	e = NewEntry("http")
+	e.Service, e.Status, e.RequestBody = "order_api", 201, `{"id":1}`
+	Log(e)
+
+	records := read()
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	// 只输出配置的字段, request_body 为空时不输出, 未知字段忽略
+	want := [][]string{
+		{"msg", "service", "status", "time"},
+		{"msg", "request_body", "service", "status", "time"},
+	}
+	for i, record := range records {
+		keys := []string{}
+		for k := range record {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, want[i]) {
+			t.Fatalf("record %d keys = %v, want %v", i, keys, want[i])
+		}
+	}
+	if records[1]["request_body"] != `{"id":1}` {
+		t.Fatalf("request_body = %v", records[1]["request_body"])
+	}
+}
+
+// TestLogSampling sample_rate 为 0 时成功的请求不输出, 失败的请求总是输出
+func TestLogSampling(t *testing.T) {
+	rate := 0.0
+	read := setupFile(t, configs.AccessLogConfig{Fields: []string{"protocol", "service"}, SampleRate: &rate})
+	cases := []struct {
+		protocol string
+		service  string
+		status   int
+		err      string
+	}{
+		{"http", "ok_http", 200, ""},
+		{"http", "redirect_http", 302, ""},
+		{"http", "bad_request_http", 400, ""},
+		{"http", "server_error_http", 502, ""},
+		{"tcp", "ok_tcp", 0, ""},
+		{"tcp", "reset_tcp", 0, "connection reset by peer"},
+		{"grpc", "ok_grpc", 0, ""},
+		{"grpc", "unavailable_grpc", 14, ""},
+	}
+	for _, c := range cases {
+		e := NewEntry(c.protocol)
+		e.Service, e.Status, e.Error = c.service, c.status, c.err
+		Log(e)
+	}
+
+	got := []string{}
+	for _, record := range read() {
+		got = append(got, record["service"].(string))
+	}
+	want := []string{"bad_request_http", "server_error_http", "reset_tcp", "unavailable_grpc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("logged %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeSampleRate(t *testing.T) {
+	rate := func(v float64) *float64 { return &v }
+	cases := []struct {
+		rate *float64
+		want float64
+	}{
+		{nil, 1},
+		{rate(0), 0},
+		{rate(0.25), 0.25},
+		{rate(1.5), 1},
+		{rate(-1), 0},
+	}
+	for _, c := range cases {
+		if got := normalizeSampleRate(c.rate); got != c.want {
+			t.Fatalf("normalizeSampleRate(%v) = %v, want %v", c.rate, got, c.want)
+		}
+	}
+}
+
+// TestCountingReader 统计全部读取的字节数, 只缓存前 limit 个字节
+func TestCountingReader(t *testing.T) {
+	body := "hello, access log"
+	cases := []struct {
+		name     string
+		limit    int
+		setLimit int
+		want     string
+	}{
+		{"no body", 0, 0, ""},
+		{"truncated", 5, 0, "hello"},
+		{"limit larger than body", 64, 0, body},
+		{"limit set before reading", 0, 8, "hello, a"},
+	}
+	for _, c := range cases {
+		r := NewCountingReader(io.NopCloser(strings.NewReader(body)), c.limit)
+		if c.setLimit > 0 {
+			r.SetLimit(c.setLimit)
+		}
+		// 每次读 3 个字节, 缓存跨多次读取累积
+		buf := make([]byte, 3)
+		read := []byte{}
+		for {
+			n, err := r.Read(buf)
+			read = append(read, buf[:n]...)
+			if err != nil {
+				break
+			}
+		}
+		if string(read) != body {
+			t.Fatalf("%s: read %q, want the whole body", c.name, read)
+		}
+		if r.Count() != int64(len(body)) {
+			t.Fatalf("%s: count = %d, want %d", c.name, r.Count(), len(body))
+		}
+		if r.Body() != c.want {
+			t.Fatalf("%s: body = %q, want %q", c.name, r.Body(), c.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package accesslog
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// CountingReader 统计读取的字节数, 可选地缓存前 limit 个字节用于记录 body
+type CountingReader struct {
+	io.ReadCloser
+	n     int64
+	buf   []byte
+	limit int
+}
+
+// NewCountingReader 包装 rc, limit 为 0 时不缓存内容
+func NewCountingReader(rc io.ReadCloser, limit int) *CountingReader {
+	return &CountingReader{ReadCloser: rc, limit: limit}
+}
+
+func (r *CountingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(&r.n, int64(n))
+	if remain := r.limit - len(r.buf); remain > 0 && n > 0 {
+		if n < remain {
+			remain = n
+		}
+		r.buf = append(r.buf, p[:remain]...)
+	}
+	return n, err
+}
+
+// SetLimit 开始缓存前 limit 个字节, 需要在读取 body 之前调用
+func (r *CountingReader) SetLimit(limit int) {
+	r.limit = limit
+}
+
+// Count 返回已读取的字节数
+func (r *CountingReader) Count() int64 {
+	return atomic.LoadInt64(&r.n)
+}
+
+// Body 返回缓存的内容
+func (r *CountingReader) Body() string {
+	return string(r.buf)
+}
@@ -130,6 +130,40 @@ func GetInt64(key string) (int64, error) {
 	return strconv.ParseInt(val, 10, 64)
 }
 
+// MGetInt64 批量获取 int64 类型的值, 不存在的 key 返回 0
+func MGetInt64(keys ...string) ([]int64, error) {
+	out := make([]int64, len(keys))
+	if len(keys) == 0 {
+		return out, nil
+	}
+	vals, err := redisClient.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, val := range vals {
+		str, ok := val.(string)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = n
+	}
+	return out, nil
+}
+
+// ScanKeys 使用 SCAN 遍历匹配 pattern 的全部 key, 不会像 KEYS 一样阻塞 redis
+func ScanKeys(pattern string) ([]string, error) {
+	keys := []string{}
+	iter := redisClient.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
 // IncrWithExpire 对指定的key执行自增操作，并设置过期时间
 // key: 需要自增的键
 // expiration: 过期时间
@@ -96,4 +96,55 @@ const (
 
 	// HttpAccessModeErrCode HTTP接入方式匹配失败
 	HTTPAccessModeErrCode
+
+	// AddUDPServiceErrCode 添加UDP服务失败
+	AddUDPServiceErrCode
+	// UpdateUDPServiceErrCode 更新UDP服务失败
+	UpdateUDPServiceErrCode
+
+	// FlowStatErrCode 获取流量统计数据失败
+	FlowStatErrCode
+	// FlowHistoryErrCode 获取历史流量统计失败
+	FlowHistoryErrCode
+
+	// PermissionDeniedErrCode 没有操作权限
+	PermissionDeniedErrCode
+	// AdminListErrCode 获取管理员列表失败
+	AdminListErrCode
+	// AdminAddErrCode 添加管理员失败
+	AdminAddErrCode
+	// AdminUpdateErrCode 更新管理员失败
+	AdminUpdateErrCode
+	// AdminDeleteErrCode 删除管理员失败
+	AdminDeleteErrCode
+	// AuditListErrCode 获取审计日志失败
+	AuditListErrCode
+	// ServiceRevisionListErrCode 获取服务版本列表失败
+	ServiceRevisionListErrCode
+	// ServiceRevisionDiffErrCode 服务版本对比失败
+	ServiceRevisionDiffErrCode
+	// ServiceRollbackErrCode 服务回滚失败
+	ServiceRollbackErrCode
+	// ConfigExportErrCode 导出配置失败
+	ConfigExportErrCode
+	// ConfigImportErrCode 导入配置失败
+	ConfigImportErrCode
+	// ApiTokenListErrCode 获取接口令牌列表失败
+	ApiTokenListErrCode
+	// ApiTokenAddErrCode 签发接口令牌失败
+	ApiTokenAddErrCode
+	// ApiTokenRevokeErrCode 吊销接口令牌失败
+	ApiTokenRevokeErrCode
+	// ServiceNodeStateErrCode 修改上游节点状态失败
+	ServiceNodeStateErrCode
+	// UpstreamListErrCode 获取上游列表失败
+	UpstreamListErrCode
+	// UpstreamDetailErrCode 获取上游详情失败
+	UpstreamDetailErrCode
+	// UpstreamAddErrCode 添加上游失败
+	UpstreamAddErrCode
+	// UpstreamUpdateErrCode 更新上游失败
+	UpstreamUpdateErrCode
+	// UpstreamDeleteErrCode 删除上游失败
+	UpstreamDeleteErrCode
 )
@@ -34,6 +34,8 @@ func ResponseError(c *gin.Context, code ResponseCode, err error) {
 		httpstatus = http.StatusServiceUnavailable
 	case ServiceNotFoundErrCode, AppNotFoundErrCode:
 		httpstatus = http.StatusNotFound
+	case PermissionDeniedErrCode:
+		httpstatus = http.StatusForbidden
 	// case HTTPAccessModeErrCode:
 	// 	httpstatus = http.StatusBadRequest
 	default:
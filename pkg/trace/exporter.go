@@ -0,0 +1,313 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gateway/configs"
+	"gateway/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultServiceName   = "gateway"
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+	defaultExportTimeout = 10 * time.Second
+	queueSize            = 4096
+)
+
+var (
+	exporterLock sync.RWMutex
+	exp          *exporter
+	sampleRatio  = 1.0
+)
+
+// Init 根据配置启动 span 上报, 未开启时只传播 trace 上下文不上报
+func Init() {
+	Setup(configs.GetTraceConfig())
+}
+
+// Setup 使用指定配置启动 span 上报, 会先关闭之前的上报协程
+func Setup(conf *configs.TraceConfig) {
+	Shutdown(context.Background())
+	if conf == nil || !conf.Enable || conf.Endpoint == "" {
+		return
+	}
+	e := &exporter{
+		endpoint:      conf.Endpoint,
+		serviceName:   conf.ServiceName,
+		batchSize:     conf.BatchSize,
+		flushInterval: time.Duration(conf.FlushInterval) * time.Second,
+		client:        &http.Client{Timeout: time.Duration(conf.Timeout) * time.Second},
+		queue:         make(chan *Span, queueSize),
+		flushReq:      make(chan chan struct{}),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	if e.serviceName == "" {
+		e.serviceName = defaultServiceName
+	}
+	if e.batchSize <= 0 {
+		e.batchSize = defaultBatchSize
+	}
+	if e.flushInterval <= 0 {
+		e.flushInterval = defaultFlushInterval
+	}
+	if e.client.Timeout <= 0 {
+		e.client.Timeout = defaultExportTimeout
+	}
+	go e.run()
+
+	exporterLock.Lock()
+	exp = e
+	if conf.SampleRatio >= 0 && conf.SampleRatio <= 1 {
+		sampleRatio = conf.SampleRatio
+	}
+	exporterLock.Unlock()
+}
+
+// Flush 立即上报已结束的 span
+func Flush(ctx context.Context) {
+	exporterLock.RLock()
+	e := exp
+	exporterLock.RUnlock()
+	if e == nil {
+		return
+	}
+	ack := make(chan struct{})
+	select {
+	case e.flushReq <- ack:
+	case <-e.stopped:
+		return
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case <-ack:
+	case <-ctx.Done():
+	}
+}
+
+// Shutdown 上报剩余的 span 并停止上报协程
+func Shutdown(ctx context.Context) error {
+	exporterLock.Lock()
+	e := exp
+	exp = nil
+	exporterLock.Unlock()
+	if e == nil {
+		return nil
+	}
+	close(e.done)
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func shouldSample() bool {
+	exporterLock.RLock()
+	enabled, ratio := exp != nil, sampleRatio
+	exporterLock.RUnlock()
+	if !enabled || ratio <= 0 {
+		return false
+	}
+	return ratio >= 1 || randFloat() < ratio
+}
+
+func export(span *Span) {
+	exporterLock.RLock()
+	e := exp
+	exporterLock.RUnlock()
+	if e == nil {
+		return
+	}
+	select {
+	case e.queue <- span:
+	default:
+		// 队列已满时丢弃, 不能阻塞请求处理
+		log.Warn("trace queue is full, span dropped", zap.String("span", span.Name))
+	}
+}
+
+type exporter struct {
+	endpoint      string
+	serviceName   string
+	batchSize     int
+	flushInterval time.Duration
+	client        *http.Client
+
+	queue    chan *Span
+	flushReq chan chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+func (e *exporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, e.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			log.Warn("export spans failed", zap.Int("count", len(batch)), zap.Error(err))
+		}
+		batch = batch[:0]
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-e.queue:
+				batch = append(batch, span)
+				if len(batch) >= e.batchSize {
+					flush()
+				}
+			default:
+				return
+			}
+		}
+	}
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= e.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case ack := <-e.flushReq:
+			drain()
+			flush()
+			close(ack)
+		case <-e.done:
+			drain()
+			flush()
+			return
+		}
+	}
+}
+
+func (e *exporter) send(spans []*Span) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector responded %s", resp.Status)
+	}
+	return nil
+}
+
+// 以下结构体对应 OTLP/HTTP 的 JSON 编码(ExportTraceServiceRequest)
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	TraceState        string         `json:"traceState,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (e *exporter) encode(spans []*Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		item := otlpSpan{
+			TraceID:           s.SpanContext.TraceID.String(),
+			SpanID:            s.SpanContext.SpanID.String(),
+			TraceState:        s.SpanContext.TraceState,
+			Name:              s.Name,
+			Kind:              int(s.Kind),
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+			Status:            otlpStatus{Code: int(s.status), Message: s.message},
+		}
+		if s.ParentSpanID.IsValid() {
+			item.ParentSpanID = s.ParentSpanID.String()
+		}
+		for _, attr := range s.attributes {
+			item.Attributes = append(item.Attributes, encodeAttribute(attr.Key, attr.Value))
+		}
+		s.mu.Unlock()
+		out = append(out, item)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{encodeAttribute("service.name", e.serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "gateway/pkg/trace"}, Spans: out}},
+	}}}
+}
+
+func encodeAttribute(key string, value interface{}) otlpKeyValue {
+	var v map[string]interface{}
+	switch val := value.(type) {
+	case string:
+		v = map[string]interface{}{"stringValue": val}
+	case bool:
+		v = map[string]interface{}{"boolValue": val}
+	case int:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": val}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(val)}
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
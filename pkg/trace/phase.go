@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PhaseTiming 一个处理阶段的耗时
+type PhaseTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// PhaseRecorder 记录一次请求各处理阶段的耗时, 供访问日志和指标使用
+type PhaseRecorder struct {
+	mu     sync.Mutex
+	phases []PhaseTiming
+}
+
+// Record 记录阶段耗时, 同名阶段多次出现时累加
+func (r *PhaseRecorder) Record(name string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.phases {
+		if r.phases[i].Name == name {
+			r.phases[i].Duration += d
+			return
+		}
+	}
+	r.phases = append(r.phases, PhaseTiming{Name: name, Duration: d})
+}
+
+// Phases 按记录顺序返回各阶段耗时
+func (r *PhaseRecorder) Phases() []PhaseTiming {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]PhaseTiming(nil), r.phases...)
+}
+
+type phaseKey struct{}
+
+// ContextWithPhaseRecorder 在 context 中创建阶段耗时记录器
+func ContextWithPhaseRecorder(ctx context.Context) (context.Context, *PhaseRecorder) {
+	r := &PhaseRecorder{}
+	return context.WithValue(ctx, phaseKey{}, r), r
+}
+
+// PhaseRecorderFromContext 取出 context 中的阶段耗时记录器, 不存在时返回 nil(方法可安全调用)
+func PhaseRecorderFromContext(ctx context.Context) *PhaseRecorder {
+	r, _ := ctx.Value(phaseKey{}).(*PhaseRecorder)
+	return r
+}
+
+// Phase 同时对应一个 span 和一条阶段耗时记录
+type Phase struct {
+	Span     *Span
+	recorder *PhaseRecorder
+	once     sync.Once
+}
+
+// StartPhase 创建阶段 span, End 时把耗时写入 context 中的记录器
+func StartPhase(ctx context.Context, name string) (context.Context, *Phase) {
+	return StartPhaseWithKind(ctx, name, SpanKindInternal)
+}
+
+// StartPhaseWithKind 同 StartPhase, 可以指定 span 类型
+func StartPhaseWithKind(ctx context.Context, name string, kind SpanKind) (context.Context, *Phase) {
+	ctx, span := StartSpanWithKind(ctx, name, kind)
+	return ctx, &Phase{Span: span, recorder: PhaseRecorderFromContext(ctx)}
+}
+
+// End 结束阶段, 重复调用只有第一次生效
+func (p *Phase) End() {
+	p.once.Do(func() {
+		p.Span.End()
+		p.recorder.Record(p.Span.Name, p.Span.Duration())
+	})
+}
+
+// Ended 判断阶段是否已经结束
+func (p *Phase) Ended() bool {
+	return p.Span.Ended()
+}
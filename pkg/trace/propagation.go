@@ -0,0 +1,83 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// W3C Trace Context 及请求 id 使用的 header, grpc metadata 中使用小写形式
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+	RequestIDHeader   = "X-Request-Id"
+)
+
+// ParseTraceparent 解析 traceparent header, 格式: version-traceid-parentid-flags
+func ParseTraceparent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	// version 00 必须恰好 4 段, 更高版本允许追加字段
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return sc, false
+	}
+	return sc, true
+}
+
+// FormatTraceparent 生成 version 00 的 traceparent
+func FormatTraceparent(sc SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags&flagSampled)
+}
+
+// Extract 从请求头中解析上游的 SpanContext 放入 context, get 用于按 key 读取 header 或 metadata
+func Extract(ctx context.Context, get func(key string) string) context.Context {
+	sc, ok := ParseTraceparent(get(TraceparentHeader))
+	if !ok {
+		return ctx
+	}
+	sc.TraceState = get(TracestateHeader)
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject 将 context 中当前的 SpanContext 写入请求头, set 用于按 key 设置 header 或 metadata
+func Inject(ctx context.Context, set func(key, value string)) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	set(TraceparentHeader, FormatTraceparent(sc))
+	if sc.TraceState != "" {
+		set(TracestateHeader, sc.TraceState)
+	}
+}
+
+// RequestID 返回上游传入的请求 id, 没有时生成新的
+func RequestID(incoming string) string {
+	if incoming = strings.TrimSpace(incoming); incoming != "" && len(incoming) <= 128 {
+		return incoming
+	}
+	return uuid.New().String()
+}
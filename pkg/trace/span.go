@@ -0,0 +1,253 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	mrand "math/rand"
+	"sync"
+	"time"
+)
+
+// TraceID W3C trace-id, 16 字节
+type TraceID [16]byte
+
+// SpanID W3C parent-id, 8 字节
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+func (t TraceID) IsValid() bool  { return t != TraceID{} }
+func (s SpanID) String() string  { return hex.EncodeToString(s[:]) }
+func (s SpanID) IsValid() bool   { return s != SpanID{} }
+
+const flagSampled byte = 0x01
+
+// SpanContext 需要在进程间传递的 span 信息
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+}
+
+// IsValid 判断 trace-id 和 span-id 是否都已设置
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled 判断是否带有采样标记
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&flagSampled != 0
+}
+
+// SpanKind 与 OTLP 中 span.kind 的取值一致
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// StatusCode 与 OTLP 中 status.code 的取值一致
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+type attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span 表示一次操作的耗时区间, End 之后按采样结果上报
+type Span struct {
+	Name         string
+	Kind         SpanKind
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+
+	mu         sync.Mutex
+	attributes []attribute
+	status     StatusCode
+	message    string
+	ended      bool
+}
+
+// SetAttribute 设置 span 属性, 支持 string、bool、整数和浮点数
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attribute{Key: key, Value: value})
+}
+
+// SetStatus 设置 span 状态
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+	s.message = message
+}
+
+// SetError 在 err 不为空时将 span 标记为失败
+func (s *Span) SetError(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	}
+}
+
+// End 结束 span, 重复调用只有第一次生效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+	if s.SpanContext.IsSampled() {
+		export(s)
+	}
+}
+
+// Ended 判断 span 是否已经结束
+func (s *Span) Ended() bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ended
+}
+
+// Duration 返回 span 的耗时, 未结束时返回到当前的耗时
+func (s *Span) Duration() time.Duration {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return s.EndTime.Sub(s.StartTime)
+	}
+	return time.Since(s.StartTime)
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithSpan 将 span 放入 context, 之后创建的 span 以它为父节点
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext 从 context 中取出当前 span, 不存在时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext 将从上游请求中解析出的 SpanContext 放入 context
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext 返回 context 中当前 span 的 SpanContext, 没有本地 span 时返回上游的 SpanContext
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+// StartSpan 以 context 中的 span 或上游 SpanContext 为父节点创建 span, 都不存在时创建新的 trace
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	return StartSpanWithKind(ctx, name, SpanKindInternal)
+}
+
+// StartSpanWithKind 同 StartSpan, 可以指定 span 类型
+func StartSpanWithKind(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &Span{
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now(),
+	}
+	if parent.IsValid() {
+		span.SpanContext = SpanContext{
+			TraceID:    parent.TraceID,
+			SpanID:     newSpanID(),
+			Flags:      parent.Flags,
+			TraceState: parent.TraceState,
+		}
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.SpanContext = SpanContext{
+			TraceID: newTraceID(),
+			SpanID:  newSpanID(),
+		}
+		if shouldSample() {
+			span.SpanContext.Flags |= flagSampled
+		}
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+var (
+	randLock sync.Mutex
+	randSrc  = mrand.New(mrand.NewSource(seed()))
+)
+
+func seed() int64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	var n int64
+	for _, v := range b {
+		n = n<<8 | int64(v)
+	}
+	return n
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	randLock.Lock()
+	defer randLock.Unlock()
+	for !id.IsValid() {
+		randSrc.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	randLock.Lock()
+	defer randLock.Unlock()
+	for !id.IsValid() {
+		randSrc.Read(id[:])
+	}
+	return id
+}
+
+func randFloat() float64 {
+	randLock.Lock()
+	defer randLock.Unlock()
+	return randSrc.Float64()
+}
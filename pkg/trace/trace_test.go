@@ -0,0 +1,166 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gateway/configs"
+)
+
+type collector struct {
+	mu    sync.Mutex
+	spans []otlpSpan
+	svc   string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, attr := range rs.Resource.Attributes {
+			if attr.Key == "service.name" {
+				c.svc, _ = attr.Value["stringValue"].(string)
+			}
+		}
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func (c *collector) byName() map[string]otlpSpan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := map[string]otlpSpan{}
+	for _, s := range c.spans {
+		out[s.Name] = s
+	}
+	return out
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatal("valid traceparent rejected")
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.IsSampled() {
+		t.Fatalf("unexpected span context %+v", sc)
+	}
+	if got := FormatTraceparent(sc); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatalf("format mismatch: %s", got)
+	}
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceparent(bad); ok {
+			t.Errorf("invalid traceparent accepted: %q", bad)
+		}
+	}
+}
+
+func TestExportThroughCollector(t *testing.T) {
+	col := &collector{}
+	colSrv := httptest.NewServer(col)
+	defer colSrv.Close()
+
+	var upstreamTraceparent, upstreamTracestate string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get(TraceparentHeader)
+		upstreamTracestate = r.Header.Get(TracestateHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	Setup(&configs.TraceConfig{
+		Enable:        true,
+		Endpoint:      colSrv.URL + "/v1/traces",
+		ServiceName:   "gateway-test",
+		FlushInterval: 60,
+		SampleRatio:   0,
+	})
+	defer Shutdown(context.Background())
+
+	incoming := http.Header{}
+	incoming.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incoming.Set(TracestateHeader, "vendor=1")
+	ctx := Extract(context.Background(), incoming.Get)
+	ctx, root := StartSpanWithKind(ctx, "http.request", SpanKindServer)
+
+	_, phase := StartSpan(ctx, "auth")
+	phase.End()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, upstream.URL, nil)
+	resp, err := (&http.Client{Transport: NewTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	root.SetAttribute("http.status_code", resp.StatusCode)
+	root.End()
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	Flush(flushCtx)
+
+	spans := col.byName()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	if col.svc != "gateway-test" {
+		t.Errorf("unexpected service.name %q", col.svc)
+	}
+	rootSpan, authSpan, upSpan := spans["http.request"], spans["auth"], spans["upstream"]
+	for _, s := range spans {
+		if s.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("span %s not in incoming trace: %s", s.Name, s.TraceID)
+		}
+	}
+	if rootSpan.ParentSpanID != "00f067aa0ba902b7" || rootSpan.Kind != int(SpanKindServer) {
+		t.Errorf("root span parent/kind mismatch: %+v", rootSpan)
+	}
+	if authSpan.ParentSpanID != rootSpan.SpanID || upSpan.ParentSpanID != rootSpan.SpanID {
+		t.Errorf("phase spans should be children of root span")
+	}
+	if upstreamTraceparent != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+upSpan.SpanID+"-01" {
+		t.Errorf("upstream traceparent %q does not reference upstream span %s", upstreamTraceparent, upSpan.SpanID)
+	}
+	if upstreamTracestate != "vendor=1" {
+		t.Errorf("tracestate not propagated: %q", upstreamTracestate)
+	}
+}
+
+func TestUnsampledSpansAreNotExported(t *testing.T) {
+	col := &collector{}
+	colSrv := httptest.NewServer(col)
+	defer colSrv.Close()
+
+	Setup(&configs.TraceConfig{Enable: true, Endpoint: colSrv.URL, FlushInterval: 60, SampleRatio: 0})
+	defer Shutdown(context.Background())
+
+	incoming := http.Header{}
+	incoming.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := StartSpan(Extract(context.Background(), incoming.Get), "http.request")
+	span.End()
+	_, root := StartSpan(context.Background(), "root")
+	root.End()
+
+	Flush(context.Background())
+	if n := len(col.byName()); n != 0 {
+		t.Fatalf("expected no exported spans, got %d", n)
+	}
+}
@@ -0,0 +1,50 @@
+package trace
+
+import (
+	"net/http"
+)
+
+// Transport 包装 http.RoundTripper, 为每次上游请求创建 client span 并注入 traceparent
+type Transport struct {
+	Base http.RoundTripper
+	Name string
+}
+
+// NewTransport 创建带链路追踪的 RoundTripper, base 为空时使用 http.DefaultTransport
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{Base: base, Name: "upstream"}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := StartSpanWithKind(req.Context(), t.Name, SpanKindClient)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.String())
+	span.SetAttribute("net.peer.name", req.URL.Host)
+
+	// RoundTripper 不应修改传入的请求, 复制后再写入 header
+	outReq := req.Clone(ctx)
+	Inject(ctx, outReq.Header.Set)
+
+	resp, err := t.Base.RoundTrip(outReq)
+	if err != nil {
+		span.SetError(err)
+		span.End()
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(StatusError, resp.Status)
+	}
+	span.End()
+	return resp, nil
+}
+
+// CloseIdleConnections 透传给底层 Transport
+func (t *Transport) CloseIdleConnections() {
+	if c, ok := t.Base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,341 @@
+package grpcweb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	contentTypeGrpcWeb     = "application/grpc-web"
+	contentTypeGrpcWebText = "application/grpc-web-text"
+
+	// 消息帧标志位, 0x80 表示 trailer 帧, 0x01 表示压缩
+	frameFlagTrailer    byte = 0x80
+	frameFlagCompressed byte = 0x01
+
+	maxFrameSize = 4 << 20
+)
+
+// 这些 header 由 http/grpc 协议自身维护, 不作为 metadata 转发或回写
+var reservedHeaders = map[string]bool{
+	"content-type":      true,
+	"content-length":    true,
+	"te":                true,
+	"host":              true,
+	"connection":        true,
+	"keep-alive":        true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"user-agent":        true,
+	"accept-encoding":   true,
+}
+
+// IsGrpcWebRequest 判断请求是否为 grpc-web 请求(包括 -text 变体)
+func IsGrpcWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGrpcWeb)
+}
+
+// IsGrpcWebPreflight 判断是否为浏览器发起的 grpc-web 跨域预检请求
+func IsGrpcWebPreflight(r *http.Request) bool {
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		if strings.TrimSpace(strings.ToLower(h)) == "x-grpc-web" {
+			return true
+		}
+	}
+	return false
+}
+
+// Origins 允许跨域访问的来源, "*" 表示允许任意来源但不携带凭证
+type Origins []string
+
+// ParseOrigins 解析逗号间隔的来源列表
+func ParseOrigins(list string) Origins {
+	origins := Origins{}
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSuffix(strings.TrimSpace(item), "/"); item != "" {
+			origins = append(origins, item)
+		}
+	}
+	return origins
+}
+
+// match 返回来源是否允许跨域, 以及是否允许携带凭证; 只有明确列出的来源允许携带凭证
+func (o Origins) match(origin string) (allowed, credentials bool) {
+	for _, item := range o {
+		if strings.EqualFold(item, origin) {
+			return true, true
+		}
+		if item == "*" {
+			allowed = true
+		}
+	}
+	return allowed, false
+}
+
+// ServePreflight 响应跨域预检请求, 来源不在 origins 中时不返回跨域相关的 header, 由浏览器拒绝
+func ServePreflight(w http.ResponseWriter, r *http.Request, origins Origins) {
+	if !setCorsHeaders(w, r, origins) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+	w.Header().Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Serve 将 grpc-web 请求转换为一次 grpc 流调用, 经过 interceptor 后交给 handler(通常是 TransparentHandler) 处理,
+// 并以 grpc-web 格式写回响应消息和 trailer; origins 为允许跨域访问的来源
+func Serve(w http.ResponseWriter, r *http.Request, origins Origins, fullMethod string, interceptor grpc.StreamServerInterceptor, handler grpc.StreamHandler) {
+	isText := strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGrpcWebText)
+	var body io.Reader = r.Body
+	if isText {
+		body = &textDecoder{r: bufio.NewReader(r.Body)}
+	}
+	reader := bufio.NewReader(body)
+	recv := func() ([]byte, error) {
+		flag, payload, err := readFrame(reader)
+		if err != nil {
+			return nil, err
+		}
+		if flag&frameFlagCompressed != 0 {
+			return nil, status.Error(codes.Unimplemented, "compressed grpc-web message is not supported")
+		}
+		return payload, nil
+	}
+
+	writer := &webWriter{w: w, r: r, origins: origins, isText: isText}
+	ss := newServerStream(incomingContext(r), fullMethod, recv, writer)
+	err := invoke(ss, fullMethod, interceptor, handler)
+
+	header, headerSent, trailer := ss.finish()
+	if !headerSent {
+		writer.WriteHeader(header)
+	}
+	writer.WriteTrailer(status.Convert(err), trailer)
+}
+
+func invoke(ss *serverStream, fullMethod string, interceptor grpc.StreamServerInterceptor, handler grpc.StreamHandler) error {
+	if interceptor == nil {
+		return handler(nil, ss)
+	}
+	info := &grpc.StreamServerInfo{FullMethod: fullMethod, IsClientStream: false, IsServerStream: true}
+	return interceptor(nil, ss, info, handler)
+}
+
+// incomingContext 将 http header 转换为 grpc incoming metadata, 并写入客户端地址供黑白名单等拦截器使用
+func incomingContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for key, values := range r.Header {
+		key = strings.ToLower(key)
+		if reservedHeaders[key] || strings.HasPrefix(key, "access-control-") {
+			continue
+		}
+		md.Append(key, values...)
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	return peer.NewContext(ctx, &peer.Peer{Addr: remoteAddr(r.RemoteAddr)})
+}
+
+type remoteAddr string
+
+func (a remoteAddr) Network() string { return "tcp" }
+func (a remoteAddr) String() string  { return string(a) }
+
+var _ net.Addr = remoteAddr("")
+
+func readFrame(r io.Reader) (byte, []byte, error) {
+	var head [5]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, status.Error(codes.InvalidArgument, "incomplete grpc-web frame header")
+		}
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(head[1:])
+	if length > maxFrameSize {
+		return 0, nil, status.Errorf(codes.ResourceExhausted, "grpc-web frame too large: %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, status.Error(codes.InvalidArgument, "incomplete grpc-web frame")
+	}
+	return head[0], payload, nil
+}
+
+func encodeFrame(flag byte, payload []byte) []byte {
+	buf := make([]byte, 5+len(payload))
+	buf[0] = flag
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(payload)))
+	copy(buf[5:], payload)
+	return buf
+}
+
+// webWriter 以 grpc-web 格式写出响应
+type webWriter struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	origins Origins
+	isText  bool
+}
+
+func (ww *webWriter) WriteHeader(md metadata.MD) {
+	h := ww.w.Header()
+	setCorsHeaders(ww.w, ww.r, ww.origins)
+	exposed := []string{"grpc-status", "grpc-message"}
+	for key, values := range md {
+		if reservedHeaders[key] || strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		for _, v := range values {
+			h.Add(key, v)
+		}
+		exposed = append(exposed, key)
+	}
+	h.Set("Access-Control-Expose-Headers", strings.Join(exposed, ","))
+	if ww.isText {
+		h.Set("Content-Type", contentTypeGrpcWebText+"+proto")
+	} else {
+		h.Set("Content-Type", contentTypeGrpcWeb+"+proto")
+	}
+	ww.w.WriteHeader(http.StatusOK)
+}
+
+func (ww *webWriter) WriteMessage(payload []byte) error {
+	return ww.write(encodeFrame(0, payload))
+}
+
+// WriteTrailer 写出 trailer 帧, 内容与 http/2 trailer 的格式一致
+func (ww *webWriter) WriteTrailer(st *status.Status, md metadata.MD) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "grpc-status: %d\r\n", st.Code())
+	if st.Message() != "" {
+		fmt.Fprintf(&buf, "grpc-message: %s\r\n", url.PathEscape(st.Message()))
+	}
+	for key, values := range md {
+		if strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		for _, v := range values {
+			fmt.Fprintf(&buf, "%s: %s\r\n", key, v)
+		}
+	}
+	ww.write(encodeFrame(frameFlagTrailer, buf.Bytes()))
+}
+
+func (ww *webWriter) write(frame []byte) error {
+	if ww.isText {
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+	if _, err := ww.w.Write(frame); err != nil {
+		return err
+	}
+	if f, ok := ww.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// setCorsHeaders 来源允许跨域时写入跨域 header 并返回 true; "*" 匹配的来源不允许携带凭证
+func setCorsHeaders(w http.ResponseWriter, r *http.Request, origins Origins) bool {
+	w.Header().Add("Vary", "Origin")
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	allowed, credentials := origins.match(origin)
+	if !allowed {
+		return false
+	}
+	if credentials {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+	return true
+}
+
+// textDecoder 解码 grpc-web-text 请求体, 客户端可能把多段各自带 '=' 填充的 base64 拼接发送,
+// 标准解码器遇到中间的填充会报错, 因此按段切分后分别解码
+type textDecoder struct {
+	r       *bufio.Reader
+	pending []byte
+}
+
+func (d *textDecoder) Read(b []byte) (int, error) {
+	for len(d.pending) == 0 {
+		decoded, err := d.readSegment()
+		if len(decoded) > 0 {
+			d.pending = decoded
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+// readSegment 读取到填充字符、EOF 或缓冲上限为止, 返回解码后的字节
+func (d *textDecoder) readSegment() ([]byte, error) {
+	var seg []byte
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			decoded, decodeErr := decodeRawBase64(seg)
+			if decodeErr != nil {
+				return nil, decodeErr
+			}
+			return decoded, err
+		}
+		switch {
+		case c == '\r' || c == '\n' || c == ' ' || c == '\t':
+			continue
+		case c == '=':
+			// 吞掉本段剩余的填充字符
+			for {
+				next, err := d.r.Peek(1)
+				if err != nil || next[0] != '=' {
+					break
+				}
+				d.r.ReadByte()
+			}
+			return decodeRawBase64(seg)
+		default:
+			seg = append(seg, c)
+			// 4 的整数倍, 可以直接按无填充编码解码
+			if len(seg) == 4096 {
+				return decodeRawBase64(seg)
+			}
+		}
+	}
+}
+
+func decodeRawBase64(seg []byte) ([]byte, error) {
+	decoded, err := base64.RawStdEncoding.DecodeString(string(seg))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid grpc-web-text body")
+	}
+	return decoded, nil
+}
@@ -0,0 +1,143 @@
+package grpcweb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadFrame(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(encodeFrame(0, []byte("hello")))
+	buf.Write(encodeFrame(frameFlagTrailer, []byte("grpc-status: 0\r\n")))
+
+	flag, payload, err := readFrame(&buf)
+	if err != nil || flag != 0 || string(payload) != "hello" {
+		t.Fatalf("first frame = %x %q %v", flag, payload, err)
+	}
+	flag, payload, err = readFrame(&buf)
+	if err != nil || flag != frameFlagTrailer || string(payload) != "grpc-status: 0\r\n" {
+		t.Fatalf("second frame = %x %q %v", flag, payload, err)
+	}
+	if _, _, err = readFrame(&buf); err != io.EOF {
+		t.Fatalf("read after last frame err = %v, want EOF", err)
+	}
+
+	// 不完整的帧头和消息体
+	if _, _, err = readFrame(bytes.NewReader([]byte{0, 0, 0})); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("incomplete header err = %v", err)
+	}
+	if _, _, err = readFrame(bytes.NewReader(encodeFrame(0, []byte("hello"))[:7])); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("incomplete payload err = %v", err)
+	}
+
+	// 超过上限的长度在分配内存之前拒绝
+	head := []byte{0, 0xff, 0xff, 0xff, 0xff}
+	if _, _, err = readFrame(bytes.NewReader(head)); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("oversized frame err = %v", err)
+	}
+}
+
+// TestTextDecoder 多段各自带填充的 base64 拼接发送, 中间夹杂换行, 解码后与原始帧一致
+func TestTextDecoder(t *testing.T) {
+	first := encodeFrame(0, []byte("ab"))
+	second := encodeFrame(0, []byte("message two"))
+	body := base64.StdEncoding.EncodeToString(first) + "\r\n" + base64.StdEncoding.EncodeToString(second)
+	if !strings.Contains(base64.StdEncoding.EncodeToString(first), "=") {
+		t.Fatal("first segment should be padded")
+	}
+
+	decoded, err := io.ReadAll(&textDecoder{r: bufio.NewReader(strings.NewReader(body))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(append([]byte{}, first...), second...); !bytes.Equal(decoded, want) {
+		t.Fatalf("decoded = %q, want %q", decoded, want)
+	}
+
+	// 超过单段缓冲上限的长消息
+	long := encodeFrame(0, bytes.Repeat([]byte("x"), 10000))
+	decoded, err = io.ReadAll(&textDecoder{r: bufio.NewReader(strings.NewReader(base64.StdEncoding.EncodeToString(long)))})
+	if err != nil || !bytes.Equal(decoded, long) {
+		t.Fatalf("long message decoded %d bytes, err %v", len(decoded), err)
+	}
+
+	if _, err = io.ReadAll(&textDecoder{r: bufio.NewReader(strings.NewReader("!!!!"))}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("invalid base64 err = %v", err)
+	}
+}
+
+func TestWriteTrailer(t *testing.T) {
+	for _, isText := range []bool{false, true} {
+		rec := httptest.NewRecorder()
+		ww := &webWriter{w: rec, r: httptest.NewRequest(http.MethodPost, "/", nil), isText: isText}
+		ww.WriteTrailer(status.New(codes.NotFound, "no such user"), metadata.Pairs("x-request-id", "abc", "grpc-status", "5"))
+
+		body := rec.Body.Bytes()
+		if isText {
+			var err error
+			if body, err = base64.StdEncoding.DecodeString(string(body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		flag, payload, err := readFrame(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if flag != frameFlagTrailer {
+			t.Fatalf("trailer flag = %x", flag)
+		}
+		want := "grpc-status: 5\r\ngrpc-message: no%20such%20user\r\nx-request-id: abc\r\n"
+		if string(payload) != want {
+			t.Fatalf("trailer = %q, want %q", payload, want)
+		}
+	}
+}
+
+// TestCorsOrigins 只有明确列出的来源允许携带凭证, * 允许任意来源但不携带凭证, 其他来源不返回跨域 header
+func TestCorsOrigins(t *testing.T) {
+	cases := []struct {
+		origins     string
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{"https://app.example.com", "https://app.example.com", "https://app.example.com", "true"},
+		{"https://app.example.com/", "https://APP.example.com", "https://APP.example.com", "true"},
+		{"https://app.example.com", "https://evil.example.com", "", ""},
+		{"", "https://app.example.com", "", ""},
+		{"*", "https://evil.example.com", "*", ""},
+		{"*,https://app.example.com", "https://app.example.com", "https://app.example.com", "true"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodOptions, "/svc/pkg.Service/Method", nil)
+		r.Header.Set("Origin", c.origin)
+		r.Header.Set("Access-Control-Request-Method", "POST")
+		r.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+		if !IsGrpcWebPreflight(r) {
+			t.Fatal("request should be a grpc-web preflight")
+		}
+		rec := httptest.NewRecorder()
+		ServePreflight(rec, r, ParseOrigins(c.origins))
+
+		h := rec.Header()
+		if got := h.Get("Access-Control-Allow-Origin"); got != c.allowOrigin {
+			t.Fatalf("origins %q origin %q: allow origin = %q, want %q", c.origins, c.origin, got, c.allowOrigin)
+		}
+		if got := h.Get("Access-Control-Allow-Credentials"); got != c.credentials {
+			t.Fatalf("origins %q origin %q: allow credentials = %q, want %q", c.origins, c.origin, got, c.credentials)
+		}
+		if allowed := c.allowOrigin != ""; allowed != (h.Get("Access-Control-Allow-Methods") != "") {
+			t.Fatalf("origins %q origin %q: allow methods = %q", c.origins, c.origin, h.Get("Access-Control-Allow-Methods"))
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package grpcweb
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"gateway/proxy/grpc_proxy/proxy"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var errStreamClosed = errors.New("grpc-web stream closed")
+
+// responseWriter 负责把 grpc 响应写回 http 客户端, grpc-web 和 json 转码各有一种实现
+type responseWriter interface {
+	WriteHeader(md metadata.MD)
+	WriteMessage(payload []byte) error
+}
+
+// serverStream 将一次 http 请求适配为 grpc.ServerStream, 供 TransparentHandler 和 grpc 拦截器使用
+// 消息编解码沿用 proxy.Codec(), 因此透传时不需要知道消息的具体类型
+type serverStream struct {
+	ctx    context.Context
+	method string
+	codec  grpc.Codec
+	recv   func() ([]byte, error)
+	writer responseWriter
+
+	mu         sync.Mutex
+	header     metadata.MD
+	trailer    metadata.MD
+	headerSent bool
+	closed     bool
+}
+
+func newServerStream(ctx context.Context, method string, recv func() ([]byte, error), writer responseWriter) *serverStream {
+	s := &serverStream{
+		method:  method,
+		codec:   proxy.Codec(),
+		recv:    recv,
+		writer:  writer,
+		header:  metadata.MD{},
+		trailer: metadata.MD{},
+	}
+	s.ctx = grpc.NewContextWithServerTransportStream(ctx, &transportStream{s})
+	return s
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.headerSent {
+		return errors.New("grpc-web header already sent")
+	}
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *serverStream) SendHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.headerSent {
+		return errors.New("grpc-web header already sent")
+	}
+	s.header = metadata.Join(s.header, md)
+	return s.flushHeaderLocked()
+}
+
+func (s *serverStream) SetTrailer(md metadata.MD) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	payload, err := s.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.headerSent {
+		if err := s.flushHeaderLocked(); err != nil {
+			return err
+		}
+	}
+	if s.closed {
+		return errStreamClosed
+	}
+	return s.writer.WriteMessage(payload)
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	payload, err := s.recv()
+	if err != nil {
+		return err
+	}
+	return s.codec.Unmarshal(payload, m)
+}
+
+// finish 在处理结束后调用, 返回最终的 header 和 trailer, 之后的写入都会被拒绝
+func (s *serverStream) finish() (header metadata.MD, headerSent bool, trailer metadata.MD) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.header, s.headerSent, s.trailer
+}
+
+func (s *serverStream) flushHeaderLocked() error {
+	if s.closed {
+		return errStreamClosed
+	}
+	s.writer.WriteHeader(s.header)
+	s.headerSent = true
+	return nil
+}
+
+// transportStream 实现 grpc.ServerTransportStream, 使 grpc.MethodFromServerStream 和 grpc.SetHeader 等函数可用
+type transportStream struct {
+	s *serverStream
+}
+
+func (t *transportStream) Method() string {
+	return t.s.method
+}
+
+func (t *transportStream) SetHeader(md metadata.MD) error {
+	return t.s.SetHeader(md)
+}
+
+func (t *transportStream) SendHeader(md metadata.MD) error {
+	return t.s.SendHeader(md)
+}
+
+func (t *transportStream) SetTrailer(md metadata.MD) error {
+	t.s.SetTrailer(md)
+	return nil
+}
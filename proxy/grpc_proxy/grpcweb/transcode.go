@@ -0,0 +1,209 @@
+package grpcweb
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	registryLock sync.RWMutex
+	registries   []*protoregistry.Files
+)
+
+// LoadDescriptorSets 加载 protoc --include_imports --descriptor_set_out 生成的描述文件, 用于 json 转码
+func LoadDescriptorSets(paths []string) error {
+	loaded := make([]*protoregistry.Files, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read descriptor set %s: %w", path, err)
+		}
+		set := &descriptorpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(data, set); err != nil {
+			return fmt.Errorf("unmarshal descriptor set %s: %w", path, err)
+		}
+		files, err := protodesc.NewFiles(set)
+		if err != nil {
+			return fmt.Errorf("build descriptor set %s: %w", path, err)
+		}
+		loaded = append(loaded, files)
+	}
+	registryLock.Lock()
+	registries = loaded
+	registryLock.Unlock()
+	return nil
+}
+
+// FindUnaryMethod 根据 /package.Service/Method 查找已注册的一元方法描述
+func FindUnaryMethod(fullMethod string) (protoreflect.MethodDescriptor, bool) {
+	pos := strings.LastIndex(fullMethod, "/")
+	if pos <= 1 {
+		return nil, false
+	}
+	serviceName := protoreflect.FullName(fullMethod[1:pos])
+	methodName := protoreflect.Name(fullMethod[pos+1:])
+
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for _, files := range registries {
+		desc, err := files.FindDescriptorByName(serviceName)
+		if err != nil {
+			continue
+		}
+		service, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			continue
+		}
+		method := service.Methods().ByName(methodName)
+		if method == nil || method.IsStreamingClient() || method.IsStreamingServer() {
+			return nil, false
+		}
+		return method, true
+	}
+	return nil, false
+}
+
+// IsJsonRequest 判断请求是否为需要转码的 json 请求
+func IsJsonRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+// ServeJSON 将 json 请求按方法描述转码为 protobuf 后发起一元调用, 再把响应转码回 json
+func ServeJSON(w http.ResponseWriter, r *http.Request, origins Origins, method protoreflect.MethodDescriptor, fullMethod string, interceptor grpc.StreamServerInterceptor, handler grpc.StreamHandler) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxFrameSize))
+	if err != nil {
+		writeJsonError(w, r, origins, metadata.MD{}, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+	in := dynamicpb.NewMessage(method.Input())
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := protojson.Unmarshal(body, in); err != nil {
+			writeJsonError(w, r, origins, metadata.MD{}, status.Error(codes.InvalidArgument, err.Error()))
+			return
+		}
+	}
+	reqPayload, err := proto.Marshal(in)
+	if err != nil {
+		writeJsonError(w, r, origins, metadata.MD{}, status.Error(codes.Internal, err.Error()))
+		return
+	}
+
+	sent := false
+	recv := func() ([]byte, error) {
+		if sent {
+			return nil, io.EOF
+		}
+		sent = true
+		return reqPayload, nil
+	}
+	writer := &jsonWriter{}
+	ss := newServerStream(incomingContext(r), fullMethod, recv, writer)
+	err = invoke(ss, fullMethod, interceptor, handler)
+	header, _, _ := ss.finish()
+	if err != nil {
+		writeJsonError(w, r, origins, header, err)
+		return
+	}
+	if writer.payload == nil {
+		writeJsonError(w, r, origins, header, status.Error(codes.Internal, "no response message"))
+		return
+	}
+
+	out := dynamicpb.NewMessage(method.Output())
+	if err := proto.Unmarshal(writer.payload, out); err != nil {
+		writeJsonError(w, r, origins, header, status.Error(codes.Internal, err.Error()))
+		return
+	}
+	respBody, err := protojson.Marshal(out)
+	if err != nil {
+		writeJsonError(w, r, origins, header, status.Error(codes.Internal, err.Error()))
+		return
+	}
+	writeJsonHeader(w, r, origins, header, http.StatusOK)
+	w.Write(respBody)
+}
+
+// jsonWriter 只保留一元调用的第一条响应消息
+type jsonWriter struct {
+	payload []byte
+}
+
+func (jw *jsonWriter) WriteHeader(md metadata.MD) {}
+
+func (jw *jsonWriter) WriteMessage(payload []byte) error {
+	if jw.payload != nil {
+		return status.Error(codes.Internal, "unary method returned more than one message")
+	}
+	jw.payload = payload
+	return nil
+}
+
+func writeJsonHeader(w http.ResponseWriter, r *http.Request, origins Origins, md metadata.MD, code int) {
+	setCorsHeaders(w, r, origins)
+	for key, values := range md {
+		if reservedHeaders[key] || strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") {
+			continue
+		}
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+}
+
+func writeJsonError(w http.ResponseWriter, r *http.Request, origins Origins, md metadata.MD, err error) {
+	st := status.Convert(err)
+	writeJsonHeader(w, r, origins, md, HTTPStatusFromCode(st.Code()))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":    st.Code(),
+		"message": st.Message(),
+	})
+}
+
+// HTTPStatusFromCode 将 grpc 状态码映射为 http 状态码
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"context"
+	"gateway/enity"
+	"gateway/pkg/accesslog"
+	"gateway/pkg/trace"
+	"gateway/proxy/grpc_proxy/proxy"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcAccessLogMiddleware 每次调用输出一条访问日志, 需要放在 GrpcTraceMiddleware 之后
+func GrpcAccessLogMiddleware(serviceDetail *enity.ServiceDetail) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !accesslog.Enabled() {
+			return handler(srv, ss)
+		}
+		entry := accesslog.NewEntry("grpc")
+		entry.Service = serviceDetail.Info.ServiceName
+		entry.Method = "POST"
+		entry.Path = info.FullMethod
+		if peerCtx, ok := peer.FromContext(ss.Context()); ok {
+			peerAddr := peerCtx.Addr.String()
+			if addrPos := strings.LastIndex(peerAddr, ":"); addrPos > 0 {
+				peerAddr = peerAddr[0:addrPos]
+			}
+			entry.ClientIP = peerAddr
+		}
+		if md, ok := metadata.FromIncomingContext(ss.Context()); ok {
+			if values := md.Get(trace.RequestIDHeader); len(values) > 0 {
+				entry.RequestID = values[0]
+			}
+		}
+		entry.TraceID = trace.SpanContextFromContext(ss.Context()).TraceID.String()
+
+		stream := &countingServerStream{
+			ServerStream: ss,
+			ctx:          accesslog.NewContext(ss.Context(), entry),
+		}
+		err := handler(srv, stream)
+
+		entry.AppID = grpcAppID(ss.Context())
+		entry.Status = int(status.Code(err))
+		entry.SetError(err)
+		entry.BytesIn = atomic.LoadInt64(&stream.bytesIn)
+		entry.BytesOut = atomic.LoadInt64(&stream.bytesOut)
+		entry.Phases = trace.PhaseRecorderFromContext(ss.Context()).Phases()
+		accesslog.Log(entry)
+		return err
+	}
+}
+
+// countingServerStream 统计收发的消息字节数, 访问日志和指标共用
+type countingServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	bytesIn  int64
+	bytesOut int64
+}
+
+func (s *countingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *countingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.bytesOut, int64(proxy.FrameSize(m)))
+	}
+	return err
+}
+
+func (s *countingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.bytesIn, int64(proxy.FrameSize(m)))
+	}
+	return err
+}
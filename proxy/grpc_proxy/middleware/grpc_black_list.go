@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"gateway/enity"
+	"gateway/metrics"
 	"gateway/pkg/log"
 	"gateway/utils"
 
@@ -32,6 +33,7 @@ func GrpcBlackListMiddleware(serviceDetail *enity.ServiceDetail) func(srv interf
 		}
 		if serviceDetail.AccessControl.OpenAuth == 1 && len(whileIpList) == 0 && len(blackIpList) > 0 {
 			if utils.InStringSlice(blackIpList, clientIP) {
+				metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectBlackList)
 				return fmt.Errorf(fmt.Sprintf("%s in black ip list", clientIP))
 			}
 		}
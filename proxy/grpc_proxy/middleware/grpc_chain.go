@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"gateway/enity"
+
+	"google.golang.org/grpc"
+)
+
+// GrpcStreamInterceptors 返回 grpc 服务的拦截器链, grpc 代理端口和 http 代理的 grpc-web 入口共用
+func GrpcStreamInterceptors(serviceDetail *enity.ServiceDetail) []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		GrpcTraceMiddleware(serviceDetail),
+		GrpcMetricsMiddleware(serviceDetail),
+		GrpcAccessLogMiddleware(serviceDetail),
+		// GrpcFlowCountMiddleware(serviceDetail),
+		GrpcPhaseMiddleware("access_mode",
+			GrpcMethodAccessMiddleware(serviceDetail),
+		),
+		GrpcPhaseMiddleware("limit",
+			GrpcFlowLimitMiddleware(serviceDetail),
+			GrpcMethodFlowLimitMiddleware(serviceDetail),
+		),
+		GrpcPhaseMiddleware("auth",
+			GrpcJwtAuthTokenMiddleware(serviceDetail),
+			// GrpcJwtFlowCountMiddleware(serviceDetail),
+			GrpcJwtFlowLimitMiddleware(serviceDetail),
+			GrpcWhiteListMiddleware(serviceDetail),
+			GrpcBlackListMiddleware(serviceDetail),
+		),
+		GrpcHeaderTransferMiddleware(serviceDetail),
+	}
+}
+
+// ChainStreamInterceptor 将多个拦截器串联为一个, 执行顺序与 grpc.ChainStreamInterceptor 一致
+func ChainStreamInterceptor(interceptors ...grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return chainHandler(interceptors, 0, info, handler)(srv, ss)
+	}
+}
+
+func chainHandler(interceptors []grpc.StreamServerInterceptor, curr int, info *grpc.StreamServerInfo, finalHandler grpc.StreamHandler) grpc.StreamHandler {
+	if curr == len(interceptors) {
+		return finalHandler
+	}
+	return func(srv interface{}, ss grpc.ServerStream) error {
+		return interceptors[curr](srv, ss, info, chainHandler(interceptors, curr+1, info, finalHandler))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"gateway/enity"
+	"gateway/metrics"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -23,6 +24,7 @@ func GrpcFlowLimitMiddleware(serviceDetail *enity.ServiceDetail) func(srv interf
 				return err
 			}
 			if !serviceLimiter.Allow() {
+				metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectServiceLimit)
 				return fmt.Errorf(fmt.Sprintf("service flow limit %v", serviceDetail.AccessControl.ServiceFlowLimit))
 			}
 		}
@@ -41,6 +43,7 @@ func GrpcFlowLimitMiddleware(serviceDetail *enity.ServiceDetail) func(srv interf
 				return err
 			}
 			if !clientLimiter.Allow() {
+				metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectClientLimit)
 				return fmt.Errorf(fmt.Sprintf("%v flow limit %v", clientIP, serviceDetail.AccessControl.ClientIPFlowLimit))
 			}
 		}
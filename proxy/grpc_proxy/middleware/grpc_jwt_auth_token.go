@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"gateway/enity"
+	"gateway/metrics"
 	"gateway/pkg/log"
 	"gateway/proxy/pkg"
 	"gateway/utils"
@@ -31,15 +32,18 @@ func GrpcJwtAuthTokenMiddleware(serviceDetail *enity.ServiceDetail) func(srv int
 		if token != "" {
 			claims, err := utils.JwtDecode(token)
 			if err != nil {
+				metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectAuth)
 				return fmt.Errorf("JwtDecode %v", err)
 			}
 			appInfo, err := pkg.Cache.GetApp(claims.Issuer)
 			if err == nil {
 				md.Set("app", utils.Obj2Json(appInfo))
+				setGrpcAppID(ss.Context(), appInfo.AppID)
 				appMatched = true
 			}
 		}
 		if serviceDetail.AccessControl.OpenAuth == 1 && !appMatched {
+			metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectAuth)
 			return fmt.Errorf("not match valid app")
 		}
 		if err := handler(srv, ss); err != nil {
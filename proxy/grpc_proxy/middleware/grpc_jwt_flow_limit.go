@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"gateway/enity"
+	"gateway/metrics"
 	"gateway/pkg/log"
 	"gateway/proxy/pkg"
 	"strings"
@@ -48,6 +49,7 @@ func GrpcJwtFlowLimitMiddleware(serviceDetail *enity.ServiceDetail) func(srv int
 				return err
 			}
 			if !clientLimiter.Allow() {
+				metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectAppLimit)
 				return fmt.Errorf("%v flow limit %v", clientIP, appInfo.Qps)
 			}
 		}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"gateway/enity"
+	"gateway/metrics"
+	"gateway/pkg/log"
+	"gateway/utils"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcMethodAccessMiddleware 按方法前缀做访问控制, 禁止列表优先, 允许列表为空时放行全部方法
+func GrpcMethodAccessMiddleware(serviceDetail *enity.ServiceDetail) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	allowList := []string{}
+	denyList := []string{}
+	if serviceDetail.GRPCRule.MethodAllow != "" {
+		allowList = strings.Split(serviceDetail.GRPCRule.MethodAllow, ",")
+	}
+	if serviceDetail.GRPCRule.MethodDeny != "" {
+		denyList = strings.Split(serviceDetail.GRPCRule.MethodDeny, ",")
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if _, ok := utils.MatchLongestPrefix(info.FullMethod, denyList); ok {
+			metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectMethodAccess)
+			return status.Errorf(codes.PermissionDenied, "method %s is denied", info.FullMethod)
+		}
+		if len(allowList) > 0 {
+			if _, ok := utils.MatchLongestPrefix(info.FullMethod, allowList); !ok {
+				metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectMethodAccess)
+				return status.Errorf(codes.PermissionDenied, "method %s is not allowed", info.FullMethod)
+			}
+		}
+		if err := handler(srv, ss); err != nil {
+			log.Error("grpcMethodAccessMiddleware failed ", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"gateway/enity"
+	"gateway/metrics"
+	"gateway/pkg/log"
+	"gateway/proxy/pkg"
+	"gateway/utils"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcMethodFlowLimitMiddleware 按方法前缀限流, 命中最长前缀的配置生效
+func GrpcMethodFlowLimitMiddleware(serviceDetail *enity.ServiceDetail) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	limits := map[string]float64{}
+	prefixes := []string{}
+	if serviceDetail.GRPCRule.MethodLimit != "" {
+		for _, item := range strings.Split(serviceDetail.GRPCRule.MethodLimit, ",") {
+			fields := strings.Fields(item)
+			if len(fields) != 2 {
+				continue
+			}
+			qps, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || qps <= 0 {
+				continue
+			}
+			limits[fields[0]] = qps
+			prefixes = append(prefixes, fields[0])
+		}
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if prefix, ok := utils.MatchLongestPrefix(info.FullMethod, prefixes); ok {
+			methodLimiter, err := pkg.FlowLimiter.GetLimiter(
+				pkg.MethodLimiterKey(serviceDetail.Info.ServiceName, prefix),
+				limits[prefix])
+			if err != nil {
+				return err
+			}
+			if !methodLimiter.Allow() {
+				metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectMethodLimit)
+				return status.Errorf(codes.ResourceExhausted, "method %s flow limit %v", info.FullMethod, limits[prefix])
+			}
+		}
+		if err := handler(srv, ss); err != nil {
+			log.Error("grpcMethodFlowLimitMiddleware failed ", zap.Error(err))
+			return err
+		}
+		return nil
+	}
+}
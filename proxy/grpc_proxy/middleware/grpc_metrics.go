@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"gateway/enity"
+	"gateway/flow_counter"
+	"gateway/globals"
+	"gateway/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcMetricsMiddleware 记录调用数、进行中的调用、耗时和收发字节数, 需要放在 GrpcTraceMiddleware 之后
+func GrpcMetricsMiddleware(serviceDetail *enity.ServiceDetail) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	serviceName := serviceDetail.Info.ServiceName
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done := metrics.IncActiveConnMetrics("grpc", serviceName)
+		defer done()
+
+		labels := &grpcLabels{}
+		stream := &countingServerStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ss.Context(), grpcLabelsKey{}, labels),
+		}
+		start := time.Now()
+		err := handler(srv, stream)
+
+		statusClass := status.Code(err).String()
+		metrics.RecordRequestMetrics("grpc", serviceName, "POST", statusClass, labels.appID())
+		if counter, err := globals.FlowCounter.GetCounter(flow_counter.StatusCounterName(serviceName, statusClass)); err == nil {
+			counter.Increase()
+		}
+		metrics.RecordRequestDurationMetrics("grpc", serviceName, "", time.Since(start).Seconds())
+		metrics.RecordBytesMetrics("grpc", serviceName, atomic.LoadInt64(&stream.bytesIn), atomic.LoadInt64(&stream.bytesOut))
+		return err
+	}
+}
+
+type grpcLabelsKey struct{}
+
+// grpcLabels 由后续拦截器补充的指标标签
+type grpcLabels struct {
+	mu  sync.Mutex
+	app string
+}
+
+func (l *grpcLabels) appID() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.app
+}
+
+// setGrpcAppID 记录鉴权得到的租户 id, 未经过 GrpcMetricsMiddleware 时忽略
+func setGrpcAppID(ctx context.Context, appID string) {
+	if l, ok := ctx.Value(grpcLabelsKey{}).(*grpcLabels); ok {
+		l.mu.Lock()
+		l.app = appID
+		l.mu.Unlock()
+	}
+}
+
+// grpcAppID 取出鉴权得到的租户 id
+func grpcAppID(ctx context.Context) string {
+	if l, ok := ctx.Value(grpcLabelsKey{}).(*grpcLabels); ok {
+		return l.appID()
+	}
+	return ""
+}
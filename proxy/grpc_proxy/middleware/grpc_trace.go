@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"gateway/enity"
+	"gateway/pkg/trace"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcTraceMiddleware 解析 metadata 中的 traceparent/tracestate 和 x-request-id, 创建本次调用的 server span
+func GrpcTraceMiddleware(serviceDetail *enity.ServiceDetail) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, ok := metadata.FromIncomingContext(ss.Context())
+		if !ok {
+			md = metadata.MD{}
+		}
+		get := func(key string) string {
+			if values := md.Get(key); len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+		ctx := trace.Extract(ss.Context(), get)
+		ctx, span := trace.StartSpanWithKind(ctx, "grpc.request", trace.SpanKindServer)
+		ctx, _ = trace.ContextWithPhaseRecorder(ctx)
+		span.SetAttribute("rpc.system", "grpc")
+		span.SetAttribute("rpc.method", info.FullMethod)
+		span.SetAttribute("gateway.service", serviceDetail.Info.ServiceName)
+
+		// x-request-id 写回 incoming metadata, 转发时随 metadata 一起透传给上游
+		requestID := trace.RequestID(get(trace.RequestIDHeader))
+		md.Set(trace.RequestIDHeader, requestID)
+		ctx = metadata.NewIncomingContext(ctx, md)
+		ss.SetHeader(metadata.Pairs(trace.RequestIDHeader, requestID))
+		span.SetAttribute("request.id", requestID)
+
+		err := handler(srv, &tracedServerStream{ServerStream: ss, ctx: ctx})
+		span.SetAttribute("rpc.grpc.status_code", int(status.Code(err)))
+		span.SetError(err)
+		span.End()
+		return err
+	}
+}
+
+// GrpcPhaseMiddleware 为一组拦截器创建阶段 span 并记录阶段耗时, 进入最终处理器或被拦截时结束
+func GrpcPhaseMiddleware(phase string, interceptors ...grpc.StreamServerInterceptor) func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	chained := ChainStreamInterceptor(interceptors...)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		_, p := trace.StartPhase(ss.Context(), phase)
+		err := chained(srv, ss, info, func(srv interface{}, ss grpc.ServerStream) error {
+			p.End()
+			return handler(srv, ss)
+		})
+		if !p.Ended() {
+			p.Span.SetError(err)
+			p.End()
+		}
+		return err
+	}
+}
+
+// tracedServerStream 替换 ServerStream 的 context, 使后续拦截器和处理器能取到当前 span
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"gateway/enity"
+	"gateway/metrics"
 	"gateway/pkg/log"
 	"gateway/utils"
 	"strings"
@@ -28,6 +29,7 @@ func GrpcWhiteListMiddleware(serviceDetail *enity.ServiceDetail) func(srv interf
 		clientIP := peerAddr[0:addrPos]
 		if serviceDetail.AccessControl.OpenAuth == 1 && len(iplist) > 0 {
 			if !utils.InStringSlice(iplist, clientIP) {
+				metrics.RecordRejectionMetrics("grpc", serviceDetail.Info.ServiceName, metrics.RejectWhiteList)
 				return fmt.Errorf(fmt.Sprintf("%s not in white ip list", clientIP))
 			}
 		}
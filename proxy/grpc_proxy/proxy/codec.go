@@ -71,3 +71,11 @@ func (protoCodec) Unmarshal(data []byte, v interface{}) error {
 func (protoCodec) String() string {
 	return "proto"
 }
+
+// FrameSize 返回透传消息的字节数, 非透传消息返回 0
+func FrameSize(m interface{}) int {
+	if f, ok := m.(*frame); ok {
+		return len(f.payload)
+	}
+	return 0
+}
@@ -2,28 +2,144 @@ package reverse_proxy
 
 import (
 	"context"
+	"gateway/flow_counter"
+	"gateway/globals"
+	"gateway/metrics"
+	"gateway/pkg/accesslog"
+	"gateway/pkg/trace"
 	"gateway/proxy/load_balance"
-	"log"
+	"gateway/utils"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"gateway/proxy/grpc_proxy/proxy"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-func NewGrpcLoadBalanceHandler(lb load_balance.LoadBalance) grpc.StreamHandler {
-	return func() grpc.StreamHandler {
-		nextAddr, err := lb.Get("")
+// Balancers 返回服务当前的默认负载均衡器和以方法前缀为 key 的方法路由负载均衡器
+type Balancers func() (load_balance.LoadBalance, map[string]load_balance.LoadBalance, error)
+
+// NewGrpcLoadBalanceHandler 创建透明代理处理器, 每次调用时按方法名选择上游
+// 每次调用通过 balancers 获取负载均衡器, 命中最长方法前缀时使用对应分组的负载均衡器, 否则使用默认负载均衡器
+// hashRule 为服务配置的一致性hash key 来源
+func NewGrpcLoadBalanceHandler(serviceName, hashRule string, balancers Balancers) grpc.StreamHandler {
+	director := func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error) {
+		lb, methodLbs, err := balancers()
+		if err != nil {
+			return nil, nil, status.Errorf(codes.Unavailable, "get load balancer fail: %v", err)
+		}
+		targetLb := lb
+		if prefix, ok := utils.MatchLongestPrefix(fullMethodName, methodPrefixes(methodLbs)); ok {
+			targetLb = methodLbs[prefix]
+		}
+		nextAddr, err := targetLb.Get(grpcHashKey(ctx, fullMethodName, hashRule))
 		if err != nil {
-			log.Fatal("get next addr fail")
+			return nil, nil, status.Errorf(codes.Unavailable, "get next addr fail: %v", err)
+		}
+		trace.SpanFromContext(ctx).SetAttribute("net.peer.name", nextAddr)
+		accesslog.FromContext(ctx).SetUpstream(nextAddr)
+		if call, ok := ctx.Value(upstreamCallKey{}).(*upstreamCall); ok {
+			call.node = nextAddr
+			call.done = load_balance.Track(targetLb, nextAddr)
+		}
+		if counter, err := globals.FlowCounter.GetCounter(flow_counter.NodeCounterName(serviceName, nextAddr)); err == nil {
+			counter.Increase()
 		}
-		director := func(ctx context.Context, fullMethodName string) (context.Context, *grpc.ClientConn, error) {
-			c, err := grpc.DialContext(ctx, nextAddr, grpc.WithCodec(proxy.Codec()), grpc.WithInsecure())
-			md, _ := metadata.FromIncomingContext(ctx)
-			outCtx, _ := context.WithCancel(ctx)
-			outCtx = metadata.NewOutgoingContext(outCtx, md.Copy())
-			return outCtx, c, err
+		c, err := grpc.DialContext(ctx, nextAddr, grpc.WithCodec(proxy.Codec()), grpc.WithInsecure(),
+			grpc.WithContextDialer(metricsDialer(serviceName, nextAddr)))
+		md, _ := metadata.FromIncomingContext(ctx)
+		outMd := md.Copy()
+		trace.Inject(ctx, func(key, value string) { outMd.Set(key, value) })
+		outCtx := metadata.NewOutgoingContext(ctx, outMd)
+		return outCtx, c, err
+	}
+	return tracedHandler(serviceName, proxy.TransparentHandler(director))
+}
+
+func methodPrefixes(methodLbs map[string]load_balance.LoadBalance) []string {
+	prefixes := make([]string, 0, len(methodLbs))
+	for prefix := range methodLbs {
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// metricsDialer 记录与上游节点建立连接的耗时
+func metricsDialer(serviceName, node string) func(ctx context.Context, addr string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		start := time.Now()
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+		if err == nil {
+			metrics.RecordUpstreamConnectMetrics("grpc", serviceName, node, time.Since(start).Seconds())
+		}
+		return conn, err
+	}
+}
+
+type upstreamCallKey struct{}
+
+// upstreamCall 记录一次转发选中的上游节点, 用于首个响应消息的耗时统计和负载均衡反馈
+type upstreamCall struct {
+	start time.Time
+	node  string
+	once  sync.Once
+	ttfb  int64 // 纳秒, 原子操作
+	done  func(latency time.Duration, err error)
+}
+
+// tracedHandler 为转发到上游的调用创建 client span, director 会把它注入到上游请求的 metadata 中
+func tracedHandler(serviceName string, handler grpc.StreamHandler) grpc.StreamHandler {
+	return func(srv interface{}, ss grpc.ServerStream) error {
+		ctx, p := trace.StartPhaseWithKind(ss.Context(), "upstream", trace.SpanKindClient)
+		call := &upstreamCall{start: time.Now()}
+		ctx = context.WithValue(ctx, upstreamCallKey{}, call)
+		err := handler(srv, &upstreamServerStream{ServerStream: ss, ctx: ctx, serviceName: serviceName, call: call})
+		if call.done != nil {
+			latency := time.Duration(atomic.LoadInt64(&call.ttfb))
+			if latency == 0 {
+				latency = time.Since(call.start)
+			}
+			call.done(latency, upstreamFailure(err))
 		}
-		return proxy.TransparentHandler(director)
-	}()
+		p.Span.SetAttribute("rpc.grpc.status_code", int(status.Code(err)))
+		p.Span.SetError(err)
+		p.End()
+		return err
+	}
+}
+
+type upstreamServerStream struct {
+	grpc.ServerStream
+	ctx         context.Context
+	serviceName string
+	call        *upstreamCall
+}
+
+func (s *upstreamServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// SendMsg 转发给客户端的第一个消息即上游返回的首个响应
+func (s *upstreamServerStream) SendMsg(m interface{}) error {
+	s.call.once.Do(func() {
+		ttfb := time.Since(s.call.start)
+		atomic.StoreInt64(&s.call.ttfb, int64(ttfb))
+		metrics.RecordUpstreamTTFBMetrics("grpc", s.serviceName, s.call.node, ttfb.Seconds())
+	})
+	return s.ServerStream.SendMsg(m)
+}
+
+// upstreamFailure 只把上游不可用、超时这类错误反馈给负载均衡器, 业务错误码不影响节点选择
+func upstreamFailure(err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return err
+	}
+	return nil
 }
@@ -0,0 +1,50 @@
+package reverse_proxy
+
+import (
+	"context"
+	"encoding/json"
+	"gateway/enity"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// grpcHashKey 按服务配置的 hash key 来源取出本次调用用于一致性hash的 key
+// 未配置或调用中缺少配置的 metadata/租户时使用客户端ip; url 和 path 均取方法全名, grpc 没有 query 参数
+func grpcHashKey(ctx context.Context, fullMethodName, rule string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := ""
+	source, name := enity.ParseHashKey(rule)
+	switch source {
+	case enity.HashKeyURL, enity.HashKeyPath:
+		key = fullMethodName
+	case enity.HashKeyAppID:
+		// grpc 鉴权中间件将租户信息写入 metadata
+		if apps := md.Get("app"); len(apps) > 0 {
+			app := &enity.App{}
+			if err := json.Unmarshal([]byte(apps[0]), app); err == nil {
+				key = app.AppID
+			}
+		}
+	case enity.HashKeyHeader:
+		if values := md.Get(name); len(values) > 0 {
+			key = values[0]
+		}
+	case enity.HashKeyCookie:
+		req := &http.Request{Header: http.Header{"Cookie": md.Get("cookie")}}
+		if cookie, err := req.Cookie(name); err == nil {
+			key = cookie.Value
+		}
+	}
+	if key == "" {
+		if p, ok := peer.FromContext(ctx); ok {
+			key = p.Addr.String()
+			if host, _, err := net.SplitHostPort(key); err == nil {
+				key = host
+			}
+		}
+	}
+	return key
+}
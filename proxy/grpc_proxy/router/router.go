@@ -5,6 +5,7 @@ import (
 	"net"
 
 	"gateway/proxy/grpc_proxy/reverse_proxy"
+	"gateway/proxy/load_balance"
 	"gateway/proxy/pkg"
 
 	"gateway/proxy/grpc_proxy/middleware"
@@ -30,27 +31,21 @@ func GrpcProxyServerRun() {
 		tempItem := serviceItem
 		go func(serviceDetail *enity.ServiceDetail) {
 			addr := fmt.Sprintf(":%d", serviceDetail.GRPCRule.Port)
-			rb, err := pkg.LoadBalanceTransport.GetLoadBalancer(serviceDetail)
-			if err != nil {
+			if _, err := pkg.LoadBalanceTransport.GetLoadBalancer(serviceDetail); err != nil {
 				log.Fatal("get tcpLoadBalancer failed", zap.String("addr", addr), zap.Error(err))
 				return
 			}
+			if _, err := pkg.LoadBalanceTransport.GetMethodLoadBalancers(serviceDetail); err != nil {
+				log.Fatal("get grpc method loadBalancer failed", zap.String("addr", addr), zap.Error(err))
+				return
+			}
 			lis, err := net.Listen("tcp", addr)
 			if err != nil {
 				log.Fatal(" grpcProxy listen failed", zap.String("addr", addr), zap.Error(err))
 			}
-			grpcHandler := reverse_proxy.NewGrpcLoadBalanceHandler(rb)
+			grpcHandler := reverse_proxy.NewGrpcLoadBalanceHandler(serviceDetail.Info.ServiceName, serviceDetail.LoadBalance.HashKey, currentBalancers(serviceDetail))
 			s := grpc.NewServer(
-				grpc.ChainStreamInterceptor(
-					// middleware.GrpcFlowCountMiddleware(serviceDetail),
-					middleware.GrpcFlowLimitMiddleware(serviceDetail),
-					middleware.GrpcJwtAuthTokenMiddleware(serviceDetail),
-					// middleware.GrpcJwtFlowCountMiddleware(serviceDetail),
-					middleware.GrpcJwtFlowLimitMiddleware(serviceDetail),
-					middleware.GrpcWhiteListMiddleware(serviceDetail),
-					middleware.GrpcBlackListMiddleware(serviceDetail),
-					middleware.GrpcHeaderTransferMiddleware(serviceDetail),
-				),
+				grpc.ChainStreamInterceptor(middleware.GrpcStreamInterceptors(serviceDetail)...),
 				grpc.CustomCodec(proxy.Codec()),
 				grpc.UnknownServiceHandler(grpcHandler))
 
@@ -66,6 +61,25 @@ func GrpcProxyServerRun() {
 	}
 }
 
+// currentBalancers 每次调用按服务最新的详情获取负载均衡器, 服务修改后新的调用使用重建的负载均衡器
+func currentBalancers(serviceDetail *enity.ServiceDetail) reverse_proxy.Balancers {
+	return func() (load_balance.LoadBalance, map[string]load_balance.LoadBalance, error) {
+		current, err := pkg.CurrentService(serviceDetail)
+		if err != nil {
+			return nil, nil, err
+		}
+		lb, err := pkg.LoadBalanceTransport.GetLoadBalancer(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		methodLbs, err := pkg.LoadBalanceTransport.GetMethodLoadBalancers(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		return lb, methodLbs, nil
+	}
+}
+
 func GrpcProxyServerStop() {
 	for _, grpcServer := range grpcServerList {
 		grpcServer.GracefulStop()
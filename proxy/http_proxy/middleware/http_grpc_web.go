@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"strings"
+
+	"gateway/enity"
+	"gateway/proxy/grpc_proxy/grpcweb"
+	grpcMiddleware "gateway/proxy/grpc_proxy/middleware"
+	"gateway/proxy/grpc_proxy/reverse_proxy"
+	"gateway/proxy/load_balance"
+	"gateway/proxy/pkg"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HTTPGrpcWebMiddleware 处理 /<service_name>/package.Service/Method 形式的 grpc-web 及 json 请求,
+// 请求会经过与 grpc 代理相同的拦截器链, 再由透明代理转发到上游; 不匹配的请求交给后续的 http 中间件
+func HTTPGrpcWebMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceName, fullMethod, ok := splitGrpcWebPath(c.Request.URL.Path)
+		if !ok {
+			c.Next()
+			return
+		}
+		serviceDetail, ok := pkg.Cache.GetGrpcService(serviceName)
+		if !ok || serviceDetail.GRPCRule == nil || serviceDetail.GRPCRule.NeedGrpcWeb != 1 {
+			c.Next()
+			return
+		}
+
+		origins := grpcweb.ParseOrigins(serviceDetail.GRPCRule.GrpcWebOrigins)
+		if grpcweb.IsGrpcWebPreflight(c.Request) {
+			grpcweb.ServePreflight(c.Writer, c.Request, origins)
+			c.Abort()
+			return
+		}
+
+		isGrpcWeb := grpcweb.IsGrpcWebRequest(c.Request)
+		method, isJson := grpcweb.FindUnaryMethod(fullMethod)
+		isJson = isJson && grpcweb.IsJsonRequest(c.Request)
+		if !isGrpcWeb && !isJson {
+			c.Next()
+			return
+		}
+
+		c.Set("service", serviceDetail)
+		handler := newGrpcWebHandler(serviceDetail)
+		interceptor := grpcMiddleware.ChainStreamInterceptor(grpcMiddleware.GrpcStreamInterceptors(serviceDetail)...)
+		if isGrpcWeb {
+			grpcweb.Serve(c.Writer, c.Request, origins, fullMethod, interceptor, handler)
+		} else {
+			grpcweb.ServeJSON(c.Writer, c.Request, origins, method, fullMethod, interceptor, handler)
+		}
+		c.Abort()
+	}
+}
+
+// newGrpcWebHandler 复用 grpc 代理的负载均衡器构建透明代理处理器
+func newGrpcWebHandler(serviceDetail *enity.ServiceDetail) grpc.StreamHandler {
+	lb, err := pkg.LoadBalanceTransport.GetLoadBalancer(serviceDetail)
+	if err != nil {
+		return unavailableHandler(err)
+	}
+	methodLbs, err := pkg.LoadBalanceTransport.GetMethodLoadBalancers(serviceDetail)
+	if err != nil {
+		return unavailableHandler(err)
+	}
+	return reverse_proxy.NewGrpcLoadBalanceHandler(serviceDetail.Info.ServiceName, serviceDetail.LoadBalance.HashKey,
+		func() (load_balance.LoadBalance, map[string]load_balance.LoadBalance, error) {
+			return lb, methodLbs, nil
+		})
+}
+
+func unavailableHandler(err error) grpc.StreamHandler {
+	return func(srv interface{}, ss grpc.ServerStream) error {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+}
+
+// splitGrpcWebPath 将 /<service_name>/package.Service/Method 拆分为服务名和 grpc 方法全名
+func splitGrpcWebPath(path string) (string, string, bool) {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(segments) != 3 || segments[0] == "" || segments[1] == "" || segments[2] == "" {
+		return "", "", false
+	}
+	return segments[0], "/" + segments[1] + "/" + segments[2], true
+}
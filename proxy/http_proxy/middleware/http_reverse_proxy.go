@@ -5,8 +5,10 @@ import (
 	"gateway/enity"
 
 	"gateway/pkg/response"
+	"gateway/pkg/trace"
 	proxy "gateway/proxy/http_proxy/reverse_proxy"
 	"gateway/proxy/pkg"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -57,7 +59,9 @@ func HTTPReverseProxyMiddleware() gin.HandlerFunc {
 		//创建 reverseproxy
 		//使用 reverseproxy.ServerHTTP(c.Request,c.Response)
 		proxy := proxy.NewLoadBalanceReverseProxy(c, lb, trans)
+		start := time.Now()
 		proxy.ServeHTTP(c.Writer, c.Request)
+		trace.PhaseRecorderFromContext(c.Request.Context()).Record("upstream", time.Since(start))
 		c.Abort()
 
 		return
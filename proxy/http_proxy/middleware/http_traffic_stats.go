@@ -3,8 +3,10 @@ package middleware
 import (
 	"fmt"
 	"gateway/enity"
+	"gateway/flow_counter"
 	"gateway/globals"
 	"gateway/metrics"
+	"gateway/pkg/accesslog"
 	"gateway/pkg/log"
 	"gateway/pkg/response"
 	"time"
@@ -46,12 +48,17 @@ func HTTPTrafficStats() gin.HandlerFunc {
 func TrafficStats() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		startTime := time.Now()
+		serviceName := ""
+		if serverInAny, ok := c.Get("service"); ok {
+			serviceName = serverInAny.(*enity.ServiceDetail).Info.ServiceName
+		}
+		done := metrics.IncActiveConnMetrics("http", serviceName)
+		defer done()
 
 		// 处理请求
 		c.Next()
 
-		serverInAny, ok := c.Get("service")
-		if !ok {
+		if serviceName == "" {
 			response.ResponseError(c, response.ServiceNotFoundErrCode, fmt.Errorf("service not found"))
 			c.Abort()
 			return
@@ -61,13 +68,43 @@ func TrafficStats() gin.HandlerFunc {
 
 		statusCode := c.GetInt("ErrorCode")
 		responseTime := time.Since(startTime).Seconds()
-		serviceName := serverInAny.(*enity.ServiceDetail).Info.ServiceName
 
 		if statusCode == response.ServerLimiterAllowErrCode {
 			metrics.RecordLimiterMetrics(serviceName, loadBalanceAddr)
 		} else if statusCode == response.ClientIPLimiterAllowErrCode {
 			metrics.RecordLimiterMetrics(serviceName+"_client", loadBalanceAddr)
 		}
+		if reason, ok := rejectReasons[statusCode]; ok {
+			metrics.RecordRejectionMetrics("http", serviceName, reason)
+		}
+
+		appID := ""
+		if appInterface, ok := c.Get("app"); ok {
+			if app, ok := appInterface.(*enity.App); ok {
+				appID = app.AppID
+			}
+		}
+		statusClass := metrics.StatusClass(c.Writer.Status())
+		metrics.RecordRequestMetrics("http", serviceName, c.Request.Method, statusClass, appID)
+		// 按节点和状态分类计数, 供统计接口按维度查询
+		if loadBalanceAddr != "" {
+			if counter, err := globals.FlowCounter.GetCounter(flow_counter.NodeCounterName(serviceName, loadBalanceAddr)); err == nil {
+				counter.Increase()
+			}
+		}
+		if counter, err := globals.FlowCounter.GetCounter(flow_counter.StatusCounterName(serviceName, statusClass)); err == nil {
+			counter.Increase()
+		}
+		metrics.RecordRequestDurationMetrics("http", serviceName, loadBalanceAddr, responseTime)
+
+		// 请求 body 由 RequestLog 包装为 CountingReader, 未包装时退回到 Content-Length
+		var bytesIn int64
+		if body, ok := c.Request.Body.(*accesslog.CountingReader); ok {
+			bytesIn = body.Count()
+		} else if c.Request.ContentLength > 0 {
+			bytesIn = c.Request.ContentLength
+		}
+		metrics.RecordBytesMetrics("http", serviceName, bytesIn, int64(c.Writer.Size()))
 
 		// 更新service负载均衡器请求总数
 		metrics.RecordRequestTotalMetrics(serviceName, loadBalanceAddr)
@@ -77,3 +114,16 @@ func TrafficStats() gin.HandlerFunc {
 		log.Debug("更新service响应时间指标", zap.String("serviceName", serviceName), zap.String("loadBalanceAddr", loadBalanceAddr), zap.Float64("responseTime", responseTime))
 	}
 }
+
+// rejectReasons 网关主动拒绝请求的错误码与拒绝原因的对应关系
+var rejectReasons = map[int]string{
+	int(response.ServerLimiterAllowErrCode):   metrics.RejectServiceLimit,
+	int(response.ClientIPLimiterAllowErrCode): metrics.RejectClientLimit,
+	int(response.APPLimiterAllowErrCode):      metrics.RejectAppLimit,
+	int(response.TokensErrCode):               metrics.RejectAuth,
+	int(response.JwtDecodeErrCode):            metrics.RejectAuth,
+	int(response.ValidAppErrCode):             metrics.RejectAuth,
+	int(response.ClientIPNotInWhiteListCode):  metrics.RejectWhiteList,
+	int(response.ClientIPInBlackListErrCode):  metrics.RejectBlackList,
+	int(response.CircuitBreakerOpenErrCode):   metrics.RejectCircuitOpen,
+}
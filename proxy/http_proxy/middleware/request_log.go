@@ -1,74 +1,121 @@
 package middleware
 
 import (
-	"bytes"
+	"gateway/enity"
+	"gateway/pkg/accesslog"
 	"gateway/pkg/log"
-
-	"io/ioutil"
+	"gateway/pkg/trace"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-func RequestOutLog(c *gin.Context, responseTime time.Duration) {
-	// after request
-	// 记录响应信息
-	uri := c.Request.RequestURI
-	ip := c.ClientIP()
-	method := c.Request.Method
-	statusCode := c.Writer.Status()
-	args := c.Request.PostForm
-	response := c.GetString("response")
+// RequestLog 每个请求输出一条访问日志, 应用日志中只保留调试信息
+func RequestLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+		entry := accesslog.NewEntry("http")
+		c.Request = c.Request.WithContext(accesslog.NewContext(c.Request.Context(), entry))
 
-	log.Info("Response Info",
-		zap.String("uri", uri),
-		zap.String("client_ip", ip),
-		zap.String("method", method),
-		zap.Int("status_code", statusCode),
-		zap.Any("args", args),
-		zap.Any("response", response),
-		zap.Duration("response_time", responseTime),
-		zap.String("trace_id", c.GetString("TraceID")),
-	)
-}
+		// 确定服务之前不缓存 body, 由 HTTPAccessLogBody 按服务配置开启
+		var reqBody *accesslog.CountingReader
+		if c.Request.Body != nil {
+			reqBody = accesslog.NewCountingReader(c.Request.Body, 0)
+			c.Request.Body = reqBody
+		}
 
-func RequestInLog(c *gin.Context) {
-	// 获取请求 body
-	var requestBody []byte
-	if c.Request.Body != nil {
-		requestBody, _ = ioutil.ReadAll(c.Request.Body)
+		c.Next()
+
+		entry.Latency = time.Since(startTime)
+		entry.ClientIP = c.ClientIP()
+		entry.Method = c.Request.Method
+		entry.Path = c.Request.URL.Path
+		entry.Status = c.Writer.Status()
+		if size := c.Writer.Size(); size > 0 {
+			entry.BytesOut = int64(size)
+		}
+		if reqBody != nil {
+			entry.BytesIn = reqBody.Count()
+		}
+		entry.TraceID = c.GetString("TraceID")
+		entry.RequestID = c.GetString("RequestID")
+		entry.Phases = trace.PhaseRecorderFromContext(c.Request.Context()).Phases()
+		if serverInterface, ok := c.Get("service"); ok {
+			entry.Service = serverInterface.(*enity.ServiceDetail).Info.ServiceName
+		}
+		if appInterface, ok := c.Get("app"); ok {
+			if app, ok := appInterface.(*enity.App); ok {
+				entry.AppID = app.AppID
+			}
+		}
+		// service_addr 由反向代理在选择上游节点后写入
+		if upstream := c.GetString("service_addr"); upstream != "" {
+			entry.SetUpstream(upstream)
+		}
+		if len(c.Errors) > 0 {
+			entry.Error = c.Errors.String()
+		}
+		if respWriter, ok := c.Get(bodyLogWriterKey); ok {
+			if reqBody != nil {
+				entry.RequestBody = reqBody.Body()
+			}
+			entry.ResponseBody = string(respWriter.(*bodyLogWriter).buf)
+		}
+		accesslog.Log(entry)
+
+		log.Debug("request done",
+			zap.String("uri", c.Request.RequestURI),
+			zap.Int("status_code", entry.Status),
+			zap.Duration("response_time", entry.Latency),
+			zap.String("trace_id", entry.TraceID),
+		)
 	}
+}
 
-	// 重新生成 request body
-	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(requestBody))
+const bodyLogWriterKey = "access_log_body_writer"
 
-	// 记录请求信息
-	uri := c.Request.RequestURI
-	clientIP := c.ClientIP()
-	method := c.Request.Method
-	requestBodyStr := string(requestBody)
+// HTTPAccessLogBody 服务开启 need_log_body 时缓存请求和响应 body 写入访问日志, 需要放在 HTTPAccessModeMiddleware 之后
+func HTTPAccessLogBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceDetail, ok := c.Value("service").(*enity.ServiceDetail)
+		if !ok || serviceDetail.HTTPRule == nil || serviceDetail.HTTPRule.NeedLogBody != 1 || !accesslog.Enabled() {
+			c.Next()
+			return
+		}
+		limit := accesslog.MaxBodySize()
+		if reqBody, ok := c.Request.Body.(*accesslog.CountingReader); ok {
+			reqBody.SetLimit(limit)
+		}
+		respWriter := &bodyLogWriter{ResponseWriter: c.Writer, limit: limit}
+		c.Writer = respWriter
+		c.Set(bodyLogWriterKey, respWriter)
+		c.Next()
+	}
+}
 
-	log.Info("Request Info",
-		zap.String("uri", uri),
-		zap.String("client_ip", clientIP),
-		zap.String("method", method),
-		zap.String("reques_body", requestBodyStr),
-		zap.String("trace_id", c.GetString("TraceID")),
-	)
+// bodyLogWriter 在写出响应的同时缓存前 limit 个字节
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	buf   []byte
+	limit int
 }
 
-func RequestLog() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 处理请求前记录请求信息和请求进入时间
-		startTime := time.Now()
-		RequestInLog(c)
+func (w *bodyLogWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
 
-		// 处理请求
-		c.Next()
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
 
-		responseTime := time.Since(startTime)
-		// 处理请求后记录响应信息和请求执行时间
-		RequestOutLog(c, responseTime)
+func (w *bodyLogWriter) capture(b []byte) {
+	if remain := w.limit - len(w.buf); remain > 0 {
+		if len(b) < remain {
+			remain = len(b)
+		}
+		w.buf = append(w.buf, b[:remain]...)
 	}
 }
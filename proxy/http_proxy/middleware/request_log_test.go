@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gateway/configs"
+	"gateway/enity"
+	"gateway/pkg/accesslog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestHTTPAccessLogBody 开启 need_log_body 的服务缓存请求和响应 body 的前 max_body_size 个字节, 响应本身不受影响
+func TestHTTPAccessLogBody(t *testing.T) {
+	accesslog.Setup(&configs.AccessLogConfig{Enable: true, Output: filepath.Join(t.TempDir(), "access.log"), MaxBodySize: 10})
+	t.Cleanup(func() { accesslog.Close() })
+
+	cases := []struct {
+		name        string
+		needLogBody int
+		reqBody     string
+		respBody    string
+	}{
+		{"disabled", 0, "", ""},
+		{"truncated", 1, "request bo", "response b"},
+	}
+	for _, tc := range cases {
+		var reqBody *accesslog.CountingReader
+		var respWriter *bodyLogWriter
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			reqBody = accesslog.NewCountingReader(c.Request.Body, 0)
+			c.Request.Body = reqBody
+			c.Set("service", &enity.ServiceDetail{HTTPRule: &enity.HttpRule{NeedLogBody: tc.needLogBody}})
+			c.Next()
+			if w, ok := c.Get(bodyLogWriterKey); ok {
+				respWriter = w.(*bodyLogWriter)
+			}
+		}, HTTPAccessLogBody())
+		r.POST("/echo", func(c *gin.Context) {
+			body := make([]byte, 64)
+			n, _ := c.Request.Body.Read(body)
+			c.Writer.WriteString("response ")
+			c.Writer.Write([]byte("body: "))
+			c.Writer.Write(body[:n])
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("request body")))
+		if w.Body.String() != "response body: request body" {
+			t.Fatalf("%s: response = %q", tc.name, w.Body.String())
+		}
+		if reqBody.Body() != tc.reqBody {
+			t.Fatalf("%s: request body = %q, want %q", tc.name, reqBody.Body(), tc.reqBody)
+		}
+		if tc.needLogBody == 0 {
+			if respWriter != nil {
+				t.Fatalf("%s: response body should not be captured", tc.name)
+			}
+			continue
+		}
+		if respWriter == nil || string(respWriter.buf) != tc.respBody {
+			t.Fatalf("%s: response body = %v, want %q", tc.name, respWriter, tc.respBody)
+		}
+	}
+}
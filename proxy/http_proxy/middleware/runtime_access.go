@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"gateway/pkg/response"
+	"gateway/utils"
+	"net"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RuntimeAccessMiddleware 运行时信息接口只允许指定的客户端访问, allowIPs 为空时只允许本机
+// 按连接的对端地址判断, 不信任 X-Forwarded-For 等可以伪造的 header
+func RuntimeAccessMiddleware(allowIPs []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		remoteIP := c.RemoteIP()
+		allowed := utils.InStringSlice(allowIPs, remoteIP)
+		if len(allowIPs) == 0 {
+			ip := net.ParseIP(remoteIP)
+			allowed = ip != nil && ip.IsLoopback()
+		}
+		if !allowed {
+			response.ResponseError(c, response.ClientIPNotInWhiteListCode, fmt.Errorf("%s not in runtime allow ip list", remoteIP))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,14 +1,72 @@
 package middleware
 
 import (
+	"gateway/enity"
+	"gateway/pkg/trace"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
+// SetTraceID 解析上游的 traceparent/tracestate 和 X-Request-Id, 创建本次请求的 server span,
+// 后续阶段及上游请求的 span 都以它为父节点
 func SetTraceID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		traceID := uuid.New().String()
-		c.Set("TraceID", traceID)
+		ctx := trace.Extract(c.Request.Context(), c.Request.Header.Get)
+		ctx, span := trace.StartSpanWithKind(ctx, "http.request", trace.SpanKindServer)
+		ctx, _ = trace.ContextWithPhaseRecorder(ctx)
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.target", c.Request.URL.Path)
+		span.SetAttribute("net.peer.ip", c.ClientIP())
+
+		requestID := trace.RequestID(c.GetHeader(trace.RequestIDHeader))
+		// 写回请求头, 反向代理转发时会透传给上游
+		c.Request.Header.Set(trace.RequestIDHeader, requestID)
+		c.Writer.Header().Set(trace.RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Set("TraceID", span.SpanContext.TraceID.String())
+		c.Set("RequestID", requestID)
+		c.Next()
+
+		span.SetAttribute("http.status_code", c.Writer.Status())
+		span.SetAttribute("request.id", requestID)
+		if serverInterface, ok := c.Get("service"); ok {
+			span.SetAttribute("gateway.service", serverInterface.(*enity.ServiceDetail).Info.ServiceName)
+		}
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			span.SetStatus(trace.StatusError, http.StatusText(c.Writer.Status()))
+		}
+		span.End()
+	}
+}
+
+// HTTPPhaseStart 与 HTTPPhaseEnd 成对使用, 为两者之间的中间件创建一个阶段 span 并记录阶段耗时
+// 中间的中间件中断请求时 HTTPPhaseEnd 不会执行, 此时在 HTTPPhaseStart 返回前结束阶段
+func HTTPPhaseStart(phase string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, p := trace.StartPhase(c.Request.Context(), phase)
+		c.Set(phaseKey(phase), p)
+		c.Next()
+		if !p.Ended() {
+			if c.IsAborted() {
+				p.Span.SetStatus(trace.StatusError, "aborted")
+			}
+			p.End()
+		}
+	}
+}
+
+// HTTPPhaseEnd 结束 HTTPPhaseStart 创建的阶段
+func HTTPPhaseEnd(phase string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value, ok := c.Get(phaseKey(phase)); ok {
+			value.(*trace.Phase).End()
+		}
 		c.Next()
 	}
 }
+
+func phaseKey(phase string) string {
+	return "phase_" + phase
+}
@@ -0,0 +1,58 @@
+package reverse_proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// feedbackTransport 将上游的响应耗时和结果反馈给负载均衡器
+// 耗时取到收到响应头为止, 处理中的请求在响应体读完关闭后才结束
+type feedbackTransport struct {
+	base http.RoundTripper
+	done func(latency time.Duration, err error) // 当前请求选中节点的反馈函数
+}
+
+func (t *feedbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	done := t.done
+	if done == nil {
+		return resp, err
+	}
+	latency := time.Since(start)
+	if err != nil {
+		done(latency, err)
+		return resp, err
+	}
+	var result error
+	if resp.StatusCode >= http.StatusInternalServerError {
+		result = fmt.Errorf("upstream status %d", resp.StatusCode)
+	}
+	// 协议升级后 ReverseProxy 需要 Body 实现 io.ReadWriteCloser, 不能包装
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		done(latency, result)
+		return resp, nil
+	}
+	resp.Body = &feedbackBody{ReadCloser: resp.Body, done: func() { done(latency, result) }}
+	return resp, nil
+}
+
+// CloseIdleConnections 透传给底层 Transport
+func (t *feedbackTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+type feedbackBody struct {
+	io.ReadCloser
+	done func()
+}
+
+func (b *feedbackBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
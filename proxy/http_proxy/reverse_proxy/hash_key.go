@@ -0,0 +1,44 @@
+package reverse_proxy
+
+import (
+	"gateway/enity"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hashKey 按服务配置的 hash key 来源取出本次请求用于一致性hash的 key
+// 未配置时使用完整请求地址; 请求中缺少配置的 header/cookie/query/租户时退回客户端ip, 避免所有请求落到同一节点
+func hashKey(c *gin.Context, req *http.Request, rule string) string {
+	if rule == "" {
+		return req.URL.String()
+	}
+	key := ""
+	source, name := enity.ParseHashKey(rule)
+	switch source {
+	case enity.HashKeyURL:
+		key = req.URL.String()
+	case enity.HashKeyPath:
+		key = req.URL.Path
+	case enity.HashKeyClientIP:
+		key = c.ClientIP()
+	case enity.HashKeyAppID:
+		if appInterface, ok := c.Get("app"); ok {
+			if app, ok := appInterface.(*enity.App); ok {
+				key = app.AppID
+			}
+		}
+	case enity.HashKeyHeader:
+		key = req.Header.Get(name)
+	case enity.HashKeyCookie:
+		if cookie, err := req.Cookie(name); err == nil {
+			key = cookie.Value
+		}
+	case enity.HashKeyQuery:
+		key = req.URL.Query().Get(name)
+	}
+	if key == "" {
+		key = c.ClientIP()
+	}
+	return key
+}
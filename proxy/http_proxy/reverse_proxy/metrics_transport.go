@@ -0,0 +1,51 @@
+package reverse_proxy
+
+import (
+	"gateway/metrics"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// metricsTransport 通过 httptrace 记录上游的建连耗时和首字节耗时
+type metricsTransport struct {
+	base        http.RoundTripper
+	serviceName string
+}
+
+func newMetricsTransport(base http.RoundTripper, serviceName string) *metricsTransport {
+	return &metricsTransport{base: base, serviceName: serviceName}
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	node := req.URL.Host
+	var connectStart, wroteRequest time.Time
+	clientTrace := &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			connectStart = time.Now()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			// 复用连接时不会触发, 只统计新建连接
+			if err == nil && !connectStart.IsZero() {
+				metrics.RecordUpstreamConnectMetrics("http", t.serviceName, node, time.Since(connectStart).Seconds())
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			if !wroteRequest.IsZero() {
+				metrics.RecordUpstreamTTFBMetrics("http", t.serviceName, node, time.Since(wroteRequest).Seconds())
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), clientTrace))
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections 透传给底层 Transport
+func (t *metricsTransport) CloseIdleConnections() {
+	if c, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		c.CloseIdleConnections()
+	}
+}
@@ -1,7 +1,9 @@
 package reverse_proxy
 
 import (
+	"gateway/enity"
 	"gateway/pkg/response"
+	"gateway/pkg/trace"
 	"gateway/proxy/load_balance"
 	"net/http"
 	"net/http/httputil"
@@ -13,12 +15,23 @@ import (
 
 // NewLoadBalanceReverseProxy 创建负载均衡反向代理
 func NewLoadBalanceReverseProxy(c *gin.Context, lb load_balance.LoadBalance, trans *http.Transport) *httputil.ReverseProxy {
+	serviceName, hashRule := "", ""
+	if serverInterface, ok := c.Get("service"); ok {
+		serviceDetail := serverInterface.(*enity.ServiceDetail)
+		serviceName = serviceDetail.Info.ServiceName
+		if serviceDetail.LoadBalance != nil {
+			hashRule = serviceDetail.LoadBalance.HashKey
+		}
+	}
+	// 每个请求单独创建代理, director 选中节点后设置本次请求的反馈函数
+	transport := &feedbackTransport{base: trace.NewTransport(newMetricsTransport(trans, serviceName))}
 	//请求协调者
 	director := func(req *http.Request) {
-		nextAddr, err := lb.Get(req.URL.String())
+		nextAddr, err := lb.Get(hashKey(c, req, hashRule))
 		if err != nil || nextAddr == "" {
 			panic("get next addr fail")
 		}
+		transport.done = load_balance.Track(lb, nextAddr)
 
 		target, err := url.Parse(nextAddr)
 		if err != nil {
@@ -64,7 +77,12 @@ func NewLoadBalanceReverseProxy(c *gin.Context, lb load_balance.LoadBalance, tra
 			response.ResponseError(c, response.ReverseProxyErrCode, err)
 		}
 	}
-	return &httputil.ReverseProxy{Director: director, ModifyResponse: modifyFunc, ErrorHandler: errFunc}
+	return &httputil.ReverseProxy{
+		Director:       director,
+		Transport:      transport,
+		ModifyResponse: modifyFunc,
+		ErrorHandler:   errFunc,
+	}
 }
 
 func singleJoiningSlash(a, b string) string {
@@ -6,6 +6,7 @@ import (
 	"gateway/pkg/log"
 	"gateway/proxy/http_proxy/controller"
 	"gateway/proxy/http_proxy/middleware"
+	"gateway/proxy/pkg"
 	"net/http"
 	"time"
 
@@ -41,16 +42,33 @@ func InitRouter() *gin.Engine {
 		})
 	})
 
+	// 注册运行时路由, 查看当前的负载均衡器、连接池和探活协程
+	// 数据面端口对外开放, 需要在配置中开启并且只允许指定的客户端访问
+	if runtimeConfig := configs.GetRuntimeConfig(); runtimeConfig.Enable {
+		router.GET("/runtime/balancers", middleware.RuntimeAccessMiddleware(runtimeConfig.AllowIPs), func(c *gin.Context) {
+			c.JSON(200, pkg.LoadBalanceTransport.Stats())
+		})
+	}
+
 	router.Use(
+		// grpc-web 请求命中的是 grpc 服务, 需要在 http 接入方式匹配之前处理
+		middleware.HTTPGrpcWebMiddleware(),
+		middleware.HTTPPhaseStart("access_mode"),
 		middleware.HTTPAccessModeMiddleware(),
+		middleware.HTTPPhaseEnd("access_mode"),
+		middleware.HTTPAccessLogBody(),
 		middleware.HTTPTrafficStats(),
 		middleware.TrafficStats(),
+		middleware.HTTPPhaseStart("limit"),
 		middleware.HTTPFlowLimitMiddleware(),
+		middleware.HTTPPhaseEnd("limit"),
+		middleware.HTTPPhaseStart("auth"),
 		middleware.HTTPJwtAuthTokenMiddleware(),
 		middleware.HTTPJwtFlowCountMiddleware(),
 		middleware.HTTPJwtFlowLimitMiddleware(),
 		middleware.HTTPWhiteListMiddleware(),
 		middleware.HTTPBlackListMiddleware(),
+		middleware.HTTPPhaseEnd("auth"),
 		middleware.HTTPHeaderTransferMiddleware(),
 		middleware.HTTPStripUriMiddleware(),
 		middleware.HTTPUrlRewriteMiddleware(),
@@ -0,0 +1,75 @@
+package load_balance
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// BoundedHashBalance 有界负载一致性hash
+// 按一致性hash选择节点, 节点处理中的请求数达到平均值的 loadFactor 倍时沿哈希环顺延到下一个节点
+// 负载正常时同一个 key 总是落到同一节点, 热点 key 不会压垮单个节点; 处理中的请求数由代理通过 Feedback 反馈
+type BoundedHashBalance struct {
+	*ConsistentHashBanlance
+	tracked    trackedNodes
+	loadFactor float64
+}
+
+func NewBoundedHashBalance(replicas int, loadFactor float64) *BoundedHashBalance {
+	if loadFactor < 1 {
+		loadFactor = DefaultHashLoadFactor
+	}
+	return &BoundedHashBalance{
+		ConsistentHashBanlance: NewConsistentHashBanlance(replicas, nil),
+		loadFactor:             loadFactor,
+	}
+}
+
+func (b *BoundedHashBalance) Add(params ...string) error {
+	if err := b.ConsistentHashBanlance.Add(params...); err != nil {
+		return err
+	}
+	return b.tracked.Add(params[0])
+}
+
+func (b *BoundedHashBalance) Get(key string) (string, error) {
+	ring := b.ring.Load()
+	if len(ring.keys) == 0 {
+		return "", fmt.Errorf("node is empty")
+	}
+	idx := b.search(ring, key)
+	set := b.tracked.set.Load()
+	if set == nil || len(set.nodes) == 0 {
+		return ring.hashMap[ring.keys[idx]], nil
+	}
+
+	// 负载上限 = ceil(loadFactor * (处理中请求总数 + 1) / 节点数), 至少为 1
+	total := int64(0)
+	for _, node := range set.nodes {
+		total += node.pending()
+	}
+	limit := int64(math.Ceil(b.loadFactor * float64(total+1) / float64(len(set.nodes))))
+	for i := 0; i < len(ring.keys); i++ {
+		addr := ring.hashMap[ring.keys[(idx+i)%len(ring.keys)]]
+		node, ok := set.index[addr]
+		if !ok || node.pending() < limit {
+			return addr, nil
+		}
+	}
+	return ring.hashMap[ring.keys[idx]], nil
+}
+
+// Acquire 实现 Feedback
+func (b *BoundedHashBalance) Acquire(addr string) func(latency time.Duration, err error) {
+	return b.tracked.Acquire(addr)
+}
+
+func (b *BoundedHashBalance) SetConf(conf LoadBalanceConf) {
+	b.ConsistentHashBanlance.SetConf(conf)
+	b.tracked.SetConf(conf)
+}
+
+func (b *BoundedHashBalance) Update() {
+	b.ConsistentHashBanlance.Update()
+	b.tracked.Update()
+}
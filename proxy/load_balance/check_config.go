@@ -1,10 +1,14 @@
 package load_balance
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
+	"net/http"
 	"reflect"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -14,33 +18,127 @@ const (
 	DefaultCheckTimeout   = 5
 	DefaultCheckMaxErrNum = 2
 	DefaultCheckInterval  = 5
+
+	CheckMethodTcp  = 0 // tcp 握手探活
+	CheckMethodNone = 1 // 不做主动探活，例如 UDP 上游
+	CheckMethodHttp = 2 // 请求 CheckPolicy.Path, 返回 2xx/3xx 视为健康
 )
 
+// CheckPolicy 主动探活策略, 为零的字段使用默认值
+type CheckPolicy struct {
+	Method    int
+	Path      string      // http 探活的请求路径
+	TLS       *tls.Config // 不为空时 http 探活使用 https
+	Timeout   time.Duration
+	Interval  time.Duration
+	MaxErrNum int // 连续失败达到该次数时摘除节点
+}
+
+func (p CheckPolicy) withDefaults() CheckPolicy {
+	if p.Timeout <= 0 {
+		p.Timeout = time.Duration(DefaultCheckTimeout) * time.Second
+	}
+	if p.Interval <= 0 {
+		p.Interval = time.Duration(DefaultCheckInterval) * time.Second
+	}
+	if p.MaxErrNum <= 0 {
+		p.MaxErrNum = DefaultCheckMaxErrNum
+	}
+	if p.Path == "" {
+		p.Path = "/"
+	} else if !strings.HasPrefix(p.Path, "/") {
+		p.Path = "/" + p.Path
+	}
+	return p
+}
+
 type LoadBalanceCheckConf struct {
 	observers    []Observer
 	confIpWeight map[string]string
 	activeList   []string
 	format       string
+	policy       CheckPolicy
+	statusHook   StatusHook
+	checkClient  *http.Client // http 探活使用, 不复用连接
+
+	mu       sync.RWMutex      // 保护 observers, confIpWeight, activeList, excluded, origins 和 priorities, 探活协程、域名解析和请求协程会同时访问
+	excluded map[string]bool   // 禁用或排空中的条目, 探活照常进行但不参与选择
+	origins  map[string]string // 域名解析出的节点对应的上游条目, 条目被禁用或排空时其下所有节点都不参与选择
+
+	priorities        map[string]int // 条目的优先级, 为空时所有节点都参与选择
+	failoverThreshold float64        // 可用权重低于该比例时同时使用下一优先级的节点
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// StatusHook 节点探活状态变化时回调, 用于上报节点健康指标
+type StatusHook func(node string, healthy bool)
+
+func (s *LoadBalanceCheckConf) reportStatus(node string, healthy bool) {
+	if s.statusHook != nil {
+		s.statusHook(node, healthy)
+	}
 }
 
 func (s *LoadBalanceCheckConf) Attach(o Observer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.observers = append(s.observers, o)
 }
 
-func (s *LoadBalanceCheckConf) NotifyAllObservers() {
+// Detach 移除监听者, 用于共享配置的视图被关闭
+func (s *LoadBalanceCheckConf) Detach(o Observer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	observers := make([]Observer, 0, len(s.observers))
 	for _, obs := range s.observers {
+		if obs != o {
+			observers = append(observers, obs)
+		}
+	}
+	s.observers = observers
+}
+
+func (s *LoadBalanceCheckConf) NotifyAllObservers() {
+	s.mu.RLock()
+	observers := s.observers
+	s.mu.RUnlock()
+	for _, obs := range observers {
 		obs.Update()
 	}
 }
 
 func (s *LoadBalanceCheckConf) GetConf() []string {
-	confList := []string{}
+	return s.confList(s.format, nil)
+}
+
+// confList 按 format 格式化可选节点, 除自身不参与选择的条目外还排除 excluded 中的条目
+// 设置了优先级时只返回参与选择的优先级中的节点
+func (s *LoadBalanceCheckConf) confList(format string, excluded map[string]bool) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	available := []string{}
 	for _, ip := range s.activeList {
+		if s.isExcluded(ip) || matchExcluded(excluded, ip, s.origins[ip]) {
+			continue
+		}
+		available = append(available, ip)
+	}
+	var tiers map[int]bool
+	if len(s.priorities) > 0 {
+		tiers = s.activeTiers(available)
+	}
+	confList := []string{}
+	for _, ip := range available {
+		if tiers != nil && !tiers[s.priorityOf(ip)] {
+			continue
+		}
 		weight, ok := s.confIpWeight[ip]
 		if !ok {
 			weight = "50" //默认weight
 		}
-		confList = append(confList, fmt.Sprintf(s.format, ip)+","+weight)
+		confList = append(confList, fmt.Sprintf(format, ip)+","+weight)
 	}
 	return confList
 }
@@ -48,15 +146,24 @@ func (s *LoadBalanceCheckConf) GetConf() []string {
 // 更新配置时，通知监听者也更新
 func (s *LoadBalanceCheckConf) WatchConf() {
 	//fmt.Println("watchConf")
+	if s.policy.Method == CheckMethodNone {
+		return
+	}
+	activeCheckers.Add(1)
 	go func() {
+		defer activeCheckers.Add(-1)
 		confIpErrNum := map[string]int{}
+		// 初始时所有节点都视为健康, 域名解析新增的节点同样如此
+		healthy := map[string]bool{}
 		for {
 			changedList := []string{}
-			for item, _ := range s.confIpWeight {
-				conn, err := net.DialTimeout("tcp", item, time.Duration(DefaultCheckTimeout)*time.Second)
-				//todo http statuscode
+			probed := s.nodes()
+			for _, item := range probed {
+				if _, ok := healthy[item]; !ok {
+					healthy[item] = true
+				}
+				err := s.probe(item)
 				if err == nil {
-					conn.Close()
 					if _, ok := confIpErrNum[item]; ok {
 						confIpErrNum[item] = 0
 					}
@@ -68,36 +175,213 @@ func (s *LoadBalanceCheckConf) WatchConf() {
 						confIpErrNum[item] = 1
 					}
 				}
-				if confIpErrNum[item] < DefaultCheckMaxErrNum {
+				up := confIpErrNum[item] < s.policy.MaxErrNum
+				if up {
 					changedList = append(changedList, item)
 				}
+				if healthy[item] != up {
+					healthy[item] = up
+					s.reportStatus(item, up)
+				}
 			}
+			changedList = s.mergeProbed(probed, changedList)
 			sort.Strings(changedList)
-			sort.Strings(s.activeList)
-			if !reflect.DeepEqual(changedList, s.activeList) {
+			if !reflect.DeepEqual(changedList, s.sortedActiveList()) {
 				s.UpdateConf(changedList)
 			}
-			time.Sleep(time.Duration(DefaultCheckInterval) * time.Second)
+			timer := time.NewTimer(s.policy.Interval)
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
 		}
 	}()
 }
 
+// probe 按探活策略检查一个节点
+func (s *LoadBalanceCheckConf) probe(node string) error {
+	if s.policy.Method != CheckMethodHttp {
+		conn, err := net.DialTimeout("tcp", node, s.policy.Timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	scheme := "http://"
+	if s.policy.TLS != nil {
+		scheme = "https://"
+	}
+	resp, err := s.checkClient.Get(scheme + node + s.policy.Path)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("health check %s returned %s", node, resp.Status)
+	}
+	return nil
+}
+
+// Close 停止探活, 已经发布的节点列表保持不变
+func (s *LoadBalanceCheckConf) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
 // 更新配置时，通知监听者也更新
 func (s *LoadBalanceCheckConf) UpdateConf(conf []string) {
 	//fmt.Println("UpdateConf", conf)
-	s.activeList = conf
-	for _, obs := range s.observers {
-		obs.Update()
+	activeList := append([]string{}, conf...)
+	s.mu.Lock()
+	s.activeList = activeList
+	s.mu.Unlock()
+	s.NotifyAllObservers()
+}
+
+// sortedActiveList 返回排序后的存活节点副本, 不修改正在被读取的 activeList
+func (s *LoadBalanceCheckConf) sortedActiveList() []string {
+	s.mu.RLock()
+	activeList := append([]string{}, s.activeList...)
+	s.mu.RUnlock()
+	sort.Strings(activeList)
+	return activeList
+}
+
+// mergeProbed 合并一轮探活的结果: 探活期间被移除的节点不再加入, 探活期间新增的节点保持当前状态
+func (s *LoadBalanceCheckConf) mergeProbed(probed, up []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	seen := make(map[string]bool, len(probed))
+	for _, item := range probed {
+		seen[item] = true
+	}
+	activeList := []string{}
+	for _, item := range up {
+		if _, ok := s.confIpWeight[item]; ok {
+			activeList = append(activeList, item)
+		}
+	}
+	for _, item := range s.activeList {
+		if !seen[item] {
+			activeList = append(activeList, item)
+		}
 	}
+	return activeList
+}
+
+// nodes 返回当前配置的全部节点
+func (s *LoadBalanceCheckConf) nodes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	nodes := make([]string, 0, len(s.confIpWeight))
+	for item := range s.confIpWeight {
+		nodes = append(nodes, item)
+	}
+	return nodes
+}
+
+// SetNodes 替换全部节点及权重并通知监听者, 用于域名解析或服务发现的结果变化
+// origins 记录节点来自哪个上游条目; 仍然存在的节点保持原来的探活状态, 新增节点视为健康
+func (s *LoadBalanceCheckConf) SetNodes(conf map[string]string, origins map[string]string) {
+	added := []string{}
+	s.mu.Lock()
+	active := make(map[string]bool, len(s.activeList))
+	for _, item := range s.activeList {
+		active[item] = true
+	}
+	activeList := []string{}
+	for item := range conf {
+		if _, ok := s.confIpWeight[item]; !ok {
+			added = append(added, item)
+			active[item] = true
+		}
+		if active[item] {
+			activeList = append(activeList, item)
+		}
+	}
+	s.confIpWeight, s.origins, s.activeList = conf, origins, activeList
+	s.mu.Unlock()
+
+	for _, item := range added {
+		s.reportStatus(item, true)
+	}
+	s.NotifyAllObservers()
+}
+
+// ExcludeNodes 设置不参与选择的条目并通知监听者, 已经建立的连接和处理中的请求不受影响
+// 条目与节点本身、节点所属的域名条目或节点的 ip 相同时该节点不参与选择, 服务发现新增的节点同样适用
+func (s *LoadBalanceCheckConf) ExcludeNodes(entries []string) {
+	excluded := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		excluded[entry] = true
+	}
+	s.mu.Lock()
+	s.excluded = excluded
+	s.mu.Unlock()
+	s.NotifyAllObservers()
+}
+
+// isExcluded 调用方需持有读锁
+func (s *LoadBalanceCheckConf) isExcluded(node string) bool {
+	return matchExcluded(s.excluded, node, s.origins[node])
+}
+
+// matchExcluded 节点本身、节点所属的域名条目 origin 或节点的 ip 在 excluded 中时返回 true
+func matchExcluded(excluded map[string]bool, node, origin string) bool {
+	if len(excluded) == 0 {
+		return false
+	}
+	if excluded[node] || (origin != "" && excluded[origin]) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(node)
+	return err == nil && excluded[host]
 }
 
 func NewLoadBalanceCheckConf(format string, conf map[string]string) (LoadBalanceConf, error) {
+	return NewLoadBalanceCheckConfWithMethod(format, conf, DefaultCheckMethod)
+}
+
+// NewLoadBalanceCheckConfWithMethod 指定探活方式创建配置
+func NewLoadBalanceCheckConfWithMethod(format string, conf map[string]string, checkMethod int) (LoadBalanceConf, error) {
+	return NewLoadBalanceCheckConfWithHook(format, conf, checkMethod, nil)
+}
+
+// NewLoadBalanceCheckConfWithHook 指定探活方式创建配置, 创建时所有节点上报为健康, 之后探活状态变化时回调 hook
+func NewLoadBalanceCheckConfWithHook(format string, conf map[string]string, checkMethod int, hook StatusHook) (LoadBalanceConf, error) {
+	return NewLoadBalanceCheckConfWithPolicy(format, conf, CheckPolicy{Method: checkMethod}, hook)
+}
+
+// NewLoadBalanceCheckConfWithPolicy 按探活策略创建配置, 多个服务共享同一个上游时只创建一份, 各服务通过 LoadBalanceConfView 使用
+func NewLoadBalanceCheckConfWithPolicy(format string, conf map[string]string, policy CheckPolicy, hook StatusHook) (*LoadBalanceCheckConf, error) {
 	aList := []string{}
 	//默认初始化
-	for item, _ := range conf {
+	for item := range conf {
 		aList = append(aList, item)
 	}
-	mConf := &LoadBalanceCheckConf{format: format, activeList: aList, confIpWeight: conf}
+	policy = policy.withDefaults()
+	mConf := &LoadBalanceCheckConf{
+		format:       format,
+		activeList:   aList,
+		confIpWeight: conf,
+		policy:       policy,
+		statusHook:   hook,
+		stop:         make(chan struct{}),
+	}
+	if policy.Method == CheckMethodHttp {
+		mConf.checkClient = &http.Client{
+			Timeout:   policy.Timeout,
+			Transport: &http.Transport{TLSClientConfig: policy.TLS, DisableKeepAlives: true},
+			// 重定向视为健康, 不跟随
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+	}
+	for _, item := range aList {
+		mConf.reportStatus(item, true)
+	}
 	mConf.WatchConf()
 	return mConf, nil
 }
@@ -0,0 +1,72 @@
+package load_balance
+
+import "sync"
+
+// LoadBalanceConfView 共享节点配置的视图, 多个服务引用同一个上游时共用一份节点和探活结果
+// 每个视图有自己的地址格式和不参与选择的条目; 节点和权重的修改作用于共享配置, 所有视图都会收到通知
+type LoadBalanceConfView struct {
+	parent *LoadBalanceCheckConf
+	format string
+
+	mu        sync.RWMutex
+	excluded  map[string]bool
+	observers []Observer
+}
+
+// NewLoadBalanceConfView 创建共享配置的视图, 不再使用时调用 Close
+func NewLoadBalanceConfView(parent *LoadBalanceCheckConf, format string) *LoadBalanceConfView {
+	v := &LoadBalanceConfView{parent: parent, format: format}
+	parent.Attach(v)
+	return v
+}
+
+func (v *LoadBalanceConfView) Attach(o Observer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.observers = append(v.observers, o)
+}
+
+// Update 共享配置变化时通知视图的监听者
+func (v *LoadBalanceConfView) Update() {
+	v.mu.RLock()
+	observers := v.observers
+	v.mu.RUnlock()
+	for _, obs := range observers {
+		obs.Update()
+	}
+}
+
+func (v *LoadBalanceConfView) GetConf() []string {
+	v.mu.RLock()
+	excluded := v.excluded
+	v.mu.RUnlock()
+	return v.parent.confList(v.format, excluded)
+}
+
+// WatchConf 探活由共享配置负责
+func (v *LoadBalanceConfView) WatchConf() {}
+
+func (v *LoadBalanceConfView) UpdateConf(conf []string) {
+	v.parent.UpdateConf(conf)
+}
+
+func (v *LoadBalanceConfView) SetNodes(conf map[string]string, origins map[string]string) {
+	v.parent.SetNodes(conf, origins)
+}
+
+// ExcludeNodes 只影响当前视图, 其他引用同一个上游的服务不受影响
+func (v *LoadBalanceConfView) ExcludeNodes(entries []string) {
+	excluded := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		excluded[entry] = true
+	}
+	v.mu.Lock()
+	v.excluded = excluded
+	v.mu.Unlock()
+	v.Update()
+}
+
+// Close 解除与共享配置的关联, 共享配置的探活继续进行
+func (v *LoadBalanceConfView) Close() {
+	v.parent.Detach(v)
+}
@@ -0,0 +1,74 @@
+package load_balance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitView(t *testing.T, view *LoadBalanceConfView, want []string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		got := view.GetConf()
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("view conf = %v, want %v", got, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestConfViewSharesHealthCheck 两个服务引用同一个上游时共用一份 http 探活结果, 禁用节点只影响各自的视图
+func TestConfViewSharesHealthCheck(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer healthy.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+	healthyAddr := strings.TrimPrefix(healthy.URL, "http://")
+	brokenAddr := strings.TrimPrefix(broken.URL, "http://")
+
+	parent, err := NewLoadBalanceCheckConfWithPolicy("%s", map[string]string{healthyAddr: "1", brokenAddr: "1"}, CheckPolicy{
+		Method:    CheckMethodHttp,
+		Path:      "health",
+		Interval:  20 * time.Millisecond,
+		MaxErrNum: 1,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parent.Close()
+
+	httpView := NewLoadBalanceConfView(parent, "http://%s")
+	tcpView := NewLoadBalanceConfView(parent, "%s")
+	lb := LoadBanlanceFactorWithConf(LbRoundRobin, httpView)
+	waitView(t, httpView, []string{"http://" + healthyAddr + ",1"})
+	waitView(t, tcpView, []string{healthyAddr + ",1"})
+	if node, err := lb.Get(""); err != nil || node != "http://"+healthyAddr {
+		t.Fatalf("lb.Get = %q, %v", node, err)
+	}
+
+	tcpView.ExcludeNodes([]string{healthyAddr})
+	waitView(t, tcpView, []string{})
+	waitView(t, httpView, []string{"http://" + healthyAddr + ",1"})
+
+	httpView.Close()
+	tcpView.Close()
+	parent.mu.RLock()
+	observers := len(parent.observers)
+	parent.mu.RUnlock()
+	if observers != 0 {
+		t.Fatalf("parent still has %d observers after views are closed", observers)
+	}
+}
@@ -6,6 +6,12 @@ type LoadBalanceConf interface {
 	GetConf() []string
 	WatchConf()
 	UpdateConf(conf []string)
+	// SetNodes 替换全部节点及权重, 用于域名解析和服务发现的结果变化
+	SetNodes(conf map[string]string, origins map[string]string)
+	// ExcludeNodes 设置不参与选择的条目(禁用或排空中), 条目可以是节点、域名条目或只有 ip, 不影响探活
+	ExcludeNodes(entries []string)
+	// Close 停止探活; 共享配置的视图只解除与共享配置的关联
+	Close()
 }
 
 // Observer 观察者接口，用于实现观察者模式
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type Hash func(data []byte) uint32
@@ -25,33 +26,52 @@ func (s UInt32Slice) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 
+// ConsistentHashBanlance 一致性哈希
+// 哈希环以不可变快照的形式整体替换, Get 只读取当前快照, 不需要加锁
 type ConsistentHashBanlance struct {
-	mux      sync.RWMutex
+	mux      sync.Mutex // 只用于串行化写入
 	hash     Hash
-	replicas int               //复制因子
-	keys     UInt32Slice       //已排序的节点hash切片
-	hashMap  map[uint32]string //节点哈希和Key的map,键是hash值，值是节点key
+	replicas int //复制因子
+	ring     atomic.Pointer[hashRing]
 
 	//观察主体
 	conf LoadBalanceConf
 }
 
+// hashRing 哈希环快照, 发布后不再修改
+type hashRing struct {
+	keys    UInt32Slice       //已排序的节点hash切片
+	hashMap map[uint32]string //节点哈希和Key的map,键是hash值，值是节点key
+}
+
 func NewConsistentHashBanlance(replicas int, fn Hash) *ConsistentHashBanlance {
+	if replicas <= 0 {
+		replicas = DefaultHashReplicas
+	}
 	m := &ConsistentHashBanlance{
 		replicas: replicas,
 		hash:     fn,
-		hashMap:  make(map[uint32]string),
 	}
 	if m.hash == nil {
 		//最多32位,保证是一个2^32-1环
 		m.hash = crc32.ChecksumIEEE
 	}
+	m.ring.Store(&hashRing{hashMap: map[uint32]string{}})
 	return m
 }
 
 // 验证是否为空
 func (c *ConsistentHashBanlance) IsEmpty() bool {
-	return len(c.keys) == 0
+	return len(c.ring.Load().keys) == 0
+}
+
+// add 结合复制因子计算所有虚拟节点的hash值，并存入 ring.keys 中，同时在 ring.hashMap 中保存哈希值和key的映射
+func (c *ConsistentHashBanlance) add(ring *hashRing, addr string) {
+	for i := 0; i < c.replicas; i++ {
+		hash := c.hash([]byte(strconv.Itoa(i) + addr))
+		ring.keys = append(ring.keys, hash)
+		ring.hashMap[hash] = addr
+	}
 }
 
 // Add 方法用来添加缓存节点，参数为节点key，比如使用IP
@@ -59,37 +79,44 @@ func (c *ConsistentHashBanlance) Add(params ...string) error {
 	if len(params) == 0 {
 		return fmt.Errorf("param len 1 at least")
 	}
-	addr := params[0]
 	c.mux.Lock()
 	defer c.mux.Unlock()
-	// 结合复制因子计算所有虚拟节点的hash值，并存入m.keys中，同时在m.hashMap中保存哈希值和key的映射
-	for i := 0; i < c.replicas; i++ {
-		hash := c.hash([]byte(strconv.Itoa(i) + addr))
-		c.keys = append(c.keys, hash)
-		c.hashMap[hash] = addr
+	old := c.ring.Load()
+	ring := &hashRing{
+		keys:    append(UInt32Slice{}, old.keys...),
+		hashMap: make(map[uint32]string, len(old.hashMap)+c.replicas),
 	}
+	for hash, addr := range old.hashMap {
+		ring.hashMap[hash] = addr
+	}
+	c.add(ring, params[0])
 	// 对所有虚拟节点的哈希值进行排序，方便之后进行二分查找
-	sort.Sort(c.keys)
+	sort.Sort(ring.keys)
+	c.ring.Store(ring)
 	return nil
 }
 
 // Get 方法根据给定的对象获取最靠近它的那个节点
 func (c *ConsistentHashBanlance) Get(key string) (string, error) {
-	if c.IsEmpty() {
+	ring := c.ring.Load()
+	if len(ring.keys) == 0 {
 		return "", fmt.Errorf("node is empty")
 	}
+	return ring.hashMap[ring.keys[c.search(ring, key)]], nil
+}
+
+// search 返回 key 在哈希环上对应的虚拟节点下标, 调用方需保证环不为空
+func (c *ConsistentHashBanlance) search(ring *hashRing, key string) int {
 	hash := c.hash([]byte(key))
 
 	// 通过二分查找获取最优节点，第一个"服务器hash"值大于"数据hash"值的就是最优"服务器节点"
-	idx := sort.Search(len(c.keys), func(i int) bool { return c.keys[i] >= hash })
+	idx := sort.Search(len(ring.keys), func(i int) bool { return ring.keys[i] >= hash })
 
 	// 如果查找结果 大于 服务器节点哈希数组的最大索引，表示此时该对象哈希值位于最后一个节点之后，那么放入第一个节点中
-	if idx == len(c.keys) {
+	if idx == len(ring.keys) {
 		idx = 0
 	}
-	c.mux.RLock()
-	defer c.mux.RUnlock()
-	return c.hashMap[c.keys[idx]], nil
+	return idx
 }
 
 func (c *ConsistentHashBanlance) SetConf(conf LoadBalanceConf) {
@@ -97,11 +124,14 @@ func (c *ConsistentHashBanlance) SetConf(conf LoadBalanceConf) {
 }
 
 func (c *ConsistentHashBanlance) Update() {
-	if conf, ok := c.conf.(*LoadBalanceCheckConf); ok {
-		c.keys = nil
-		c.hashMap = map[uint32]string{}
+	if conf := c.conf; conf != nil {
+		ring := &hashRing{hashMap: map[uint32]string{}}
 		for _, ip := range conf.GetConf() {
-			c.Add(strings.Split(ip, ",")...)
+			c.add(ring, strings.Split(ip, ",")[0])
 		}
+		sort.Sort(ring.keys)
+		c.mux.Lock()
+		c.ring.Store(ring)
+		c.mux.Unlock()
 	}
 }
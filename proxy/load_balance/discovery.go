@@ -0,0 +1,165 @@
+package load_balance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gateway/pkg/log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultDiscoveryInterval = 5 * time.Second // 轮询类服务发现的间隔
+	DefaultDiscoveryTimeout  = 3 * time.Second // 单次获取节点的超时
+	DefaultDiscoveryWeight   = 50              // 节点未指定权重时的默认值
+)
+
+// Discovery 服务发现, 从文件、注册中心或接口获取上游节点, 节点列表以 ip:port -> 权重 表示
+type Discovery interface {
+	// Nodes 获取一次当前的全部节点
+	Nodes(ctx context.Context) (map[string]string, error)
+	// Watch 持续监听节点变化, 每次获取到节点列表时回调 update, 直到 ctx 取消
+	// 获取失败时不回调, 调用方保留上一次的结果
+	Watch(ctx context.Context, update func(nodes map[string]string))
+}
+
+// DiscoveryWatcher 将服务发现的结果写入 LoadBalanceConf, 节点列表整体替换, 仍然存在的节点保持探活状态
+type DiscoveryWatcher struct {
+	discovery Discovery
+	conf      LoadBalanceConf
+
+	mu   sync.Mutex
+	last map[string]string
+
+	cancel context.CancelFunc
+}
+
+// NewDiscoveryWatcher 创建服务发现的监听器, 调用 Start 后开始监听
+func NewDiscoveryWatcher(discovery Discovery, conf LoadBalanceConf) *DiscoveryWatcher {
+	return &DiscoveryWatcher{discovery: discovery, conf: conf, cancel: func() {}}
+}
+
+// Refresh 同步获取一次节点, 用于创建负载均衡器前拿到初始节点
+func (w *DiscoveryWatcher) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultDiscoveryTimeout)
+	defer cancel()
+	nodes, err := w.discovery.Nodes(ctx)
+	if err != nil {
+		return err
+	}
+	w.apply(nodes)
+	return nil
+}
+
+// Start 在后台监听节点变化, 直到 Close
+func (w *DiscoveryWatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.mu.Lock()
+	w.cancel = cancel
+	w.mu.Unlock()
+	activeWatchers.Add(1)
+	go func() {
+		defer activeWatchers.Add(-1)
+		w.discovery.Watch(ctx, w.apply)
+	}()
+}
+
+// Close 停止监听
+func (w *DiscoveryWatcher) Close() {
+	w.mu.Lock()
+	cancel := w.cancel
+	w.mu.Unlock()
+	cancel()
+}
+
+// apply 节点列表有变化时写入配置
+func (w *DiscoveryWatcher) apply(nodes map[string]string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.last != nil && sameNodes(w.last, nodes) {
+		return
+	}
+	w.last = nodes
+	w.conf.SetNodes(nodes, nil)
+}
+
+// pollNodes 按 interval 轮询 fetch, 获取成功时回调 update, 用于注册中心和接口类的服务发现
+func pollNodes(ctx context.Context, interval time.Duration, source string, fetch func(ctx context.Context) (map[string]string, error), update func(map[string]string)) {
+	if interval <= 0 {
+		interval = DefaultDiscoveryInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, DefaultDiscoveryTimeout)
+		nodes, err := fetch(fetchCtx)
+		cancel()
+		if err == nil {
+			update(nodes)
+		} else if ctx.Err() == nil {
+			log.Warn("discover upstream nodes failed", zap.String("source", source), zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// discoveryDocument 文件和接口返回的节点列表格式, 例如 {"nodes": [{"addr": "10.0.0.1:8080", "weight": 50}]}
+type discoveryDocument struct {
+	Nodes []struct {
+		Addr   string `json:"addr" yaml:"addr"`
+		Weight int    `json:"weight" yaml:"weight"`
+	} `json:"nodes" yaml:"nodes"`
+}
+
+// parseDiscoveryDocument 解析 JSON 或 YAML 格式的节点列表, 有任何一个节点不合法时整体失败, 避免写入残缺的列表
+func parseDiscoveryDocument(data []byte, isYAML bool) (map[string]string, error) {
+	doc := discoveryDocument{}
+	var err error
+	if isYAML {
+		err = yaml.Unmarshal(data, &doc)
+	} else {
+		err = json.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]string, len(doc.Nodes))
+	for _, node := range doc.Nodes {
+		weight, err := discoveryNode(node.Addr, node.Weight)
+		if err != nil {
+			return nil, err
+		}
+		nodes[node.Addr] = weight
+	}
+	return nodes, nil
+}
+
+// discoveryNode 检查节点地址, 返回节点权重, 未指定权重时使用默认值
+func discoveryNode(addr string, weight int) (string, error) {
+	if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+		return "", fmt.Errorf("invalid node address %q", addr)
+	}
+	if weight < 0 {
+		return "", fmt.Errorf("invalid weight %d of node %s", weight, addr)
+	}
+	if weight == 0 {
+		weight = DefaultDiscoveryWeight
+	}
+	return strconv.Itoa(weight), nil
+}
+
+func isYAMLName(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") || strings.Contains(name, "yaml")
+}
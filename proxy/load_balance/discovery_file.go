@@ -0,0 +1,81 @@
+package load_balance
+
+import (
+	"context"
+	"gateway/pkg/log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// fileDebounce 文件变化后等待的时间, 编辑器保存和 ConfigMap 更新会连续产生多个事件
+const fileDebounce = 200 * time.Millisecond
+
+// FileDiscovery 从本地文件读取节点列表, 文件变化时重新读取
+// 文件格式为 {"nodes": [{"addr": "10.0.0.1:8080", "weight": 50}]}, 扩展名为 .yaml/.yml 时按 YAML 解析
+type FileDiscovery struct {
+	Path string
+}
+
+func NewFileDiscovery(path string) *FileDiscovery {
+	return &FileDiscovery{Path: path}
+}
+
+func (d *FileDiscovery) Nodes(ctx context.Context) (map[string]string, error) {
+	data, err := os.ReadFile(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	return parseDiscoveryDocument(data, isYAMLName(d.Path))
+}
+
+// Watch 监听文件所在目录, 文件被替换(重命名覆盖、符号链接切换)时同样能收到事件; 无法监听时退化为轮询
+func (d *FileDiscovery) Watch(ctx context.Context, update func(nodes map[string]string)) {
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = watcher.Add(filepath.Dir(d.Path)); err != nil {
+			watcher.Close()
+		}
+	}
+	if err != nil {
+		log.Warn("watch discovery file failed, fall back to polling", zap.String("path", d.Path), zap.Error(err))
+		pollNodes(ctx, DefaultDiscoveryInterval, d.Path, d.Nodes, update)
+		return
+	}
+	defer watcher.Close()
+
+	reload := func() {
+		nodes, err := d.Nodes(ctx)
+		if err != nil {
+			log.Warn("read discovery file failed", zap.String("path", d.Path), zap.Error(err))
+			return
+		}
+		update(nodes)
+	}
+	reload()
+	timer := time.NewTimer(fileDebounce)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// 目录中任何变化都重新读取, 内容不变时由调用方忽略
+			timer.Reset(fileDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warn("watch discovery file error", zap.String("path", d.Path), zap.Error(err))
+		case <-timer.C:
+			reload()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package load_balance
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxDiscoveryBody 接口返回的节点列表大小上限
+const maxDiscoveryBody = 4 << 20
+
+// HTTPDiscovery 定期请求接口获取节点列表, 返回格式与 FileDiscovery 相同, Content-Type 含 yaml 时按 YAML 解析
+// 接口返回 ETag 时下次请求带上 If-None-Match, 返回 304 表示节点没有变化
+type HTTPDiscovery struct {
+	URL      string
+	Interval time.Duration
+	Client   *http.Client
+
+	etag  string
+	nodes map[string]string
+}
+
+func NewHTTPDiscovery(url string, interval time.Duration) *HTTPDiscovery {
+	return &HTTPDiscovery{URL: url, Interval: interval, Client: &http.Client{Timeout: DefaultDiscoveryTimeout}}
+}
+
+func (d *HTTPDiscovery) Nodes(ctx context.Context) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.etag != "" {
+		req.Header.Set("If-None-Match", d.etag)
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && d.nodes != nil:
+		return d.nodes, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("discovery endpoint returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBody))
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := parseDiscoveryDocument(data, isYAMLName(resp.Header.Get("Content-Type")))
+	if err != nil {
+		return nil, err
+	}
+	d.etag, d.nodes = resp.Header.Get("ETag"), nodes
+	return nodes, nil
+}
+
+// Watch 按 Interval 轮询接口, Nodes 和 Watch 不能并发调用
+func (d *HTTPDiscovery) Watch(ctx context.Context, update func(nodes map[string]string)) {
+	pollNodes(ctx, d.Interval, d.URL, d.Nodes, update)
+}
@@ -0,0 +1,100 @@
+package load_balance
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// DiscoveryRedisKeyPrefix 注册中心在 redis 中的 key 前缀
+// <prefix><name> 为有序集合, 成员是节点地址, 分数是心跳过期的 unix 时间戳(秒)
+// <prefix><name>:weight 为哈希, 记录节点地址对应的权重
+const DiscoveryRedisKeyPrefix = "gateway:discovery:"
+
+// RedisDiscovery 基于 redis 的注册中心, 后端启动后调用 RegisterNode 注册并按心跳续期, 超过 TTL 没有心跳的节点自动摘除
+type RedisDiscovery struct {
+	Client   redis.Cmdable
+	Name     string // 注册中心中的服务名
+	Interval time.Duration
+}
+
+func NewRedisDiscovery(client redis.Cmdable, name string, interval time.Duration) *RedisDiscovery {
+	return &RedisDiscovery{Client: client, Name: name, Interval: interval}
+}
+
+func discoveryRedisKeys(name string) (nodesKey, weightKey string) {
+	return DiscoveryRedisKeyPrefix + name, DiscoveryRedisKeyPrefix + name + ":weight"
+}
+
+// RegisterNode 注册节点或续期心跳, 后端应以小于 ttl 的间隔重复调用, 建议 ttl/3
+func RegisterNode(ctx context.Context, client redis.Cmdable, name, addr string, weight int, ttl time.Duration) error {
+	if _, err := discoveryNode(addr, weight); err != nil {
+		return err
+	}
+	nodesKey, weightKey := discoveryRedisKeys(name)
+	pipe := client.TxPipeline()
+	pipe.ZAdd(ctx, nodesKey, &redis.Z{Score: float64(time.Now().Add(ttl).Unix()), Member: addr})
+	pipe.HSet(ctx, weightKey, addr, weight)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// DeregisterNode 主动注销节点, 后端正常退出时调用, 不必等待心跳过期
+func DeregisterNode(ctx context.Context, client redis.Cmdable, name, addr string) error {
+	nodesKey, weightKey := discoveryRedisKeys(name)
+	pipe := client.TxPipeline()
+	pipe.ZRem(ctx, nodesKey, addr)
+	pipe.HDel(ctx, weightKey, addr)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Nodes 返回心跳未过期的节点, 同时清理已经过期的节点
+func (d *RedisDiscovery) Nodes(ctx context.Context) (map[string]string, error) {
+	nodesKey, weightKey := discoveryRedisKeys(d.Name)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	expired, err := d.Client.ZRangeByScore(ctx, nodesKey, &redis.ZRangeBy{Min: "-inf", Max: "(" + now}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(expired) > 0 {
+		pipe := d.Client.TxPipeline()
+		pipe.ZRemRangeByScore(ctx, nodesKey, "-inf", "("+now)
+		pipe.HDel(ctx, weightKey, expired...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	addrs, err := d.Client.ZRangeByScore(ctx, nodesKey, &redis.ZRangeBy{Min: now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]string, len(addrs))
+	if len(addrs) == 0 {
+		return nodes, nil
+	}
+	weights, err := d.Client.HMGet(ctx, weightKey, addrs...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, addr := range addrs {
+		weight := 0
+		if str, ok := weights[i].(string); ok {
+			weight, _ = strconv.Atoi(str)
+		}
+		// 注册时已经检查过地址, 这里跳过被直接写入 redis 的非法节点
+		w, err := discoveryNode(addr, weight)
+		if err != nil {
+			continue
+		}
+		nodes[addr] = w
+	}
+	return nodes, nil
+}
+
+func (d *RedisDiscovery) Watch(ctx context.Context, update func(nodes map[string]string)) {
+	pollNodes(ctx, d.Interval, DiscoveryRedisKeyPrefix+d.Name, d.Nodes, update)
+}
@@ -0,0 +1,106 @@
+package load_balance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeFileAtomic 先写临时文件再重命名, 避免监听方读到写了一半的内容
+func writeFileAtomic(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitConf(t *testing.T, conf *LoadBalanceCheckConf, want []string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		got := sortedConf(conf)
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("conf = %v, want %v", got, want)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestFileDiscoveryWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.yaml")
+	writeFileAtomic(t, path, "nodes:\n  - addr: 10.0.0.1:80\n    weight: 20\n  - addr: 10.0.0.2:80\n")
+
+	// ip_list 中的初始节点在第一次获取成功后被替换
+	conf := newTestConf(t, map[string]string{"10.0.0.9:80": "1"})
+	watcher := NewDiscoveryWatcher(NewFileDiscovery(path), conf)
+	if err := watcher.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	waitConf(t, conf, []string{"10.0.0.1:80,20", "10.0.0.2:80,50"})
+
+	watcher.Start()
+	defer watcher.Close()
+	writeFileAtomic(t, path, "nodes:\n  - addr: 10.0.0.2:80\n    weight: 10\n  - addr: 10.0.0.3:80\n    weight: 10\n")
+	waitConf(t, conf, []string{"10.0.0.2:80,10", "10.0.0.3:80,10"})
+
+	// 按 ip 禁用同样作用于服务发现的节点
+	conf.ExcludeNodes([]string{"10.0.0.3"})
+	waitConf(t, conf, []string{"10.0.0.2:80,10"})
+}
+
+func TestHTTPDiscoveryETag(t *testing.T) {
+	var mu sync.Mutex
+	body, etag, requests := `{"nodes":[{"addr":"10.0.1.1:8080","weight":5}]}`, `"v1"`, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	d := NewHTTPDiscovery(server.URL, 10*time.Millisecond)
+	want := map[string]string{"10.0.1.1:8080": "5"}
+	for i := 0; i < 2; i++ {
+		nodes, err := d.Nodes(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(nodes, want) {
+			t.Fatalf("nodes = %v, want %v", nodes, want)
+		}
+	}
+
+	conf := newTestConf(t, nil)
+	watcher := NewDiscoveryWatcher(d, conf)
+	watcher.Start()
+	defer watcher.Close()
+	mu.Lock()
+	body, etag = `{"nodes":[{"addr":"10.0.1.1:8080","weight":5},{"addr":"10.0.1.2:8080"}]}`, `"v2"`
+	mu.Unlock()
+	waitConf(t, conf, []string{"10.0.1.1:8080,5", "10.0.1.2:8080,50"})
+}
+
+func TestParseDiscoveryDocumentRejectsInvalidNode(t *testing.T) {
+	if _, err := parseDiscoveryDocument([]byte(`{"nodes":[{"addr":"10.0.0.1:80"},{"addr":"10.0.0.2"}]}`), false); err == nil {
+		t.Fatal("expected error for node without port")
+	}
+}
@@ -0,0 +1,334 @@
+package load_balance
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// SRVPrefix 上游条目以该前缀开头时按 SRV 记录解析, 例如 srv:_http._tcp.backend.internal
+const SRVPrefix = "srv:"
+
+const (
+	DefaultDNSTimeout = 2 * time.Second
+	DefaultDNSMinTTL  = 5 * time.Second
+	DefaultDNSMaxTTL  = 5 * time.Minute
+	DefaultDNSTTL     = 30 * time.Second
+)
+
+// IsDNSEntry 判断上游条目是否需要解析: SRV 条目或 host 不是 ip 的 host:port
+func IsDNSEntry(entry string) bool {
+	if strings.HasPrefix(entry, SRVPrefix) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(entry)
+	if err != nil {
+		return false
+	}
+	return net.ParseIP(host) == nil
+}
+
+// DNSResolver 解析上游条目中的域名和 SRV 记录, 返回解析出的节点和下次刷新的间隔
+type DNSResolver struct {
+	Server     string        // 域名服务器地址 host:port, 为空时使用系统解析, 此时拿不到 TTL, 按 DefaultTTL 刷新
+	Timeout    time.Duration // 单次查询超时
+	MinTTL     time.Duration // 刷新间隔下限, 避免 TTL 过小时频繁查询
+	MaxTTL     time.Duration // 刷新间隔上限
+	DefaultTTL time.Duration // 系统解析或解析失败时的刷新间隔
+}
+
+func (r *DNSResolver) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return DefaultDNSTimeout
+}
+
+func (r *DNSResolver) defaultTTL() time.Duration {
+	if r.DefaultTTL > 0 {
+		return r.DefaultTTL
+	}
+	return DefaultDNSTTL
+}
+
+// clampTTL 将记录的 TTL 限制在 [MinTTL, MaxTTL] 之间
+func (r *DNSResolver) clampTTL(ttl time.Duration) time.Duration {
+	minTTL, maxTTL := r.MinTTL, r.MaxTTL
+	if minTTL <= 0 {
+		minTTL = DefaultDNSMinTTL
+	}
+	if maxTTL <= 0 {
+		maxTTL = DefaultDNSMaxTTL
+	}
+	if ttl < minTTL {
+		return minTTL
+	}
+	if ttl > maxTTL {
+		return maxTTL
+	}
+	return ttl
+}
+
+// ResolveEntry 解析一个上游条目, 返回 ip:port 到权重的映射和下次刷新的间隔
+// host:port 条目的每个地址沿用条目的权重; SRV 条目只取优先级最高(数值最小)的一组记录, 权重取记录的 weight, 为 0 时沿用条目的权重
+func (r *DNSResolver) ResolveEntry(ctx context.Context, entry, weight string) (map[string]string, time.Duration, error) {
+	if strings.HasPrefix(entry, SRVPrefix) {
+		return r.resolveSRV(ctx, strings.TrimPrefix(entry, SRVPrefix), weight)
+	}
+	host, port, err := net.SplitHostPort(entry)
+	if err != nil {
+		return nil, 0, err
+	}
+	ips, ttl, err := r.lookupHost(ctx, host, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	nodes := make(map[string]string, len(ips))
+	for _, ip := range ips {
+		nodes[net.JoinHostPort(ip, port)] = weight
+	}
+	return nodes, ttl, nil
+}
+
+func (r *DNSResolver) resolveSRV(ctx context.Context, name, weight string) (map[string]string, time.Duration, error) {
+	if r.Server == "" {
+		_, srvs, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, 0, err
+		}
+		records := make([]srvRecord, 0, len(srvs))
+		for _, srv := range srvs {
+			records = append(records, srvRecord{target: srv.Target, port: srv.Port, priority: srv.Priority, weight: srv.Weight})
+		}
+		return r.srvNodes(ctx, records, nil, r.defaultTTL(), weight)
+	}
+
+	answers, err := r.exchange(ctx, name, dnsmessage.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	ttl := time.Duration(0)
+	records := []srvRecord{}
+	for _, answer := range answers {
+		if srv, ok := answer.Body.(*dnsmessage.SRVResource); ok {
+			records = append(records, srvRecord{
+				target:   srv.Target.String(),
+				port:     srv.Port,
+				priority: srv.Priority,
+				weight:   srv.Weight,
+			})
+			ttl = minTTL(ttl, answer.Header.TTL)
+		}
+	}
+	if len(records) == 0 {
+		return nil, 0, fmt.Errorf("no SRV record for %s", name)
+	}
+	return r.srvNodes(ctx, records, answers, ttl, weight)
+}
+
+type srvRecord struct {
+	target   string
+	port     uint16
+	priority uint16
+	weight   uint16
+}
+
+// srvNodes 解析 SRV 记录的目标主机, 优先使用应答附加段中的地址记录
+func (r *DNSResolver) srvNodes(ctx context.Context, records []srvRecord, extra []dnsmessage.Resource, ttl time.Duration, weight string) (map[string]string, time.Duration, error) {
+	priority := records[0].priority
+	for _, record := range records {
+		if record.priority < priority {
+			priority = record.priority
+		}
+	}
+	nodes := map[string]string{}
+	for _, record := range records {
+		if record.priority != priority {
+			continue
+		}
+		ips, hostTTL, err := r.lookupHost(ctx, record.target, extra)
+		if err != nil {
+			return nil, 0, err
+		}
+		if hostTTL < ttl {
+			ttl = hostTTL
+		}
+		nodeWeight := weight
+		if record.weight > 0 {
+			nodeWeight = strconv.Itoa(int(record.weight))
+		}
+		for _, ip := range ips {
+			nodes[net.JoinHostPort(ip, strconv.Itoa(int(record.port)))] = nodeWeight
+		}
+	}
+	return nodes, r.clampTTL(ttl), nil
+}
+
+// lookupHost 解析主机的 A/AAAA 记录, extra 中已有该主机的地址记录时不再查询
+func (r *DNSResolver) lookupHost(ctx context.Context, host string, extra []dnsmessage.Resource) ([]string, time.Duration, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{ip.String()}, r.clampTTL(r.defaultTTL()), nil
+	}
+	if r.Server == "" {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, 0, err
+		}
+		ips := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP.String())
+		}
+		return ips, r.clampTTL(r.defaultTTL()), nil
+	}
+
+	if ips, ttl := addressRecords(extra, host); len(ips) > 0 {
+		return ips, r.clampTTL(ttl), nil
+	}
+	ips, ttl := []string{}, time.Duration(0)
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		answers, err := r.exchange(ctx, host, qtype)
+		if err != nil {
+			// 只有 A 记录的主机查询 AAAA 失败时不影响结果
+			if len(ips) > 0 {
+				break
+			}
+			return nil, 0, err
+		}
+		// 应答中可能包含 CNAME 链, 只取地址记录
+		found, foundTTL := addressRecords(answers, "")
+		if len(found) > 0 {
+			ips = append(ips, found...)
+			ttl = minTTL(ttl, uint32(foundTTL/time.Second))
+		}
+	}
+	if len(ips) == 0 {
+		return nil, 0, fmt.Errorf("no address record for %s", host)
+	}
+	return ips, r.clampTTL(ttl), nil
+}
+
+// addressRecords 取出资源记录中的 A/AAAA 地址及最小 TTL, host 不为空时只取该主机的记录
+func addressRecords(resources []dnsmessage.Resource, host string) ([]string, time.Duration) {
+	ips, ttl := []string{}, time.Duration(0)
+	for _, res := range resources {
+		if host != "" && !strings.EqualFold(strings.TrimSuffix(res.Header.Name.String(), "."), strings.TrimSuffix(host, ".")) {
+			continue
+		}
+		switch body := res.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, net.IP(body.AAAA[:]).String())
+		default:
+			continue
+		}
+		ttl = minTTL(ttl, res.Header.TTL)
+	}
+	return ips, ttl
+}
+
+// minTTL 返回 cur 与 ttl 秒中较小的值, cur 为 0 表示尚未取值
+func minTTL(cur time.Duration, ttl uint32) time.Duration {
+	d := time.Duration(ttl) * time.Second
+	if cur == 0 || d < cur {
+		return d
+	}
+	return cur
+}
+
+// exchange 向 Server 发起一次查询, 返回应答段和附加段的记录; 应答被截断时改用 TCP 重新查询
+func (r *DNSResolver) exchange(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, err
+	}
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout())
+	defer cancel()
+	resp, err := r.roundTrip(ctx, "udp", packed, id)
+	if err == nil && resp.Truncated {
+		resp, err = r.roundTrip(ctx, "tcp", packed, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch resp.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return nil, fmt.Errorf("%s: no such host", strings.TrimSuffix(name, "."))
+	default:
+		return nil, fmt.Errorf("lookup %s: %s", strings.TrimSuffix(name, "."), resp.RCode)
+	}
+	return append(resp.Answers, resp.Additionals...), nil
+}
+
+func (r *DNSResolver) roundTrip(ctx context.Context, network string, packed []byte, id uint16) (*dnsmessage.Message, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, r.Server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		// TCP 查询以 2 字节长度开头
+		buf := make([]byte, 2+len(packed))
+		binary.BigEndian.PutUint16(buf, uint16(len(packed)))
+		copy(buf[2:], packed)
+		if _, err := conn.Write(buf); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		body := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return nil, err
+		}
+		resp := &dnsmessage.Message{}
+		if err := resp.Unpack(body); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		resp := &dnsmessage.Message{}
+		// 忽略无法解析或 id 不匹配的报文, 继续等待直到超时
+		if err := resp.Unpack(buf[:n]); err != nil || resp.ID != id {
+			continue
+		}
+		return resp, nil
+	}
+}
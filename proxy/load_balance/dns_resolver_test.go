@@ -0,0 +1,200 @@
+package load_balance
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsStub 本地 UDP 域名服务器, 按 名称+类型 返回预设的记录, 没有预设时返回 NXDOMAIN
+type dnsStub struct {
+	conn    net.PacketConn
+	mu      sync.Mutex
+	records map[string][]dnsmessage.Resource
+	extra   map[string][]dnsmessage.Resource
+}
+
+func newDNSStub(t *testing.T) *dnsStub {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &dnsStub{conn: conn, records: map[string][]dnsmessage.Resource{}, extra: map[string][]dnsmessage.Resource{}}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *dnsStub) addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+func stubKey(name string, qtype dnsmessage.Type) string {
+	return strings.ToLower(name) + "/" + qtype.String()
+}
+
+func (s *dnsStub) set(name string, qtype dnsmessage.Type, answers []dnsmessage.Resource, extra ...dnsmessage.Resource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[stubKey(name, qtype)] = answers
+	s.extra[stubKey(name, qtype)] = extra
+}
+
+func (s *dnsStub) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var req dnsmessage.Message
+		if err := req.Unpack(buf[:n]); err != nil || len(req.Questions) != 1 {
+			continue
+		}
+		q := req.Questions[0]
+		resp := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: req.ID, Response: true, RecursionAvailable: true},
+			Questions: req.Questions,
+		}
+		s.mu.Lock()
+		answers, ok := s.records[stubKey(q.Name.String(), q.Type)]
+		_, hasA := s.records[stubKey(q.Name.String(), dnsmessage.TypeA)]
+		resp.Additionals = s.extra[stubKey(q.Name.String(), q.Type)]
+		s.mu.Unlock()
+		if ok {
+			resp.Answers = answers
+		} else if !hasA {
+			resp.RCode = dnsmessage.RCodeNameError
+		}
+		packed, err := resp.Pack()
+		if err != nil {
+			continue
+		}
+		s.conn.WriteTo(packed, addr)
+	}
+}
+
+func aRecord(name, ip string, ttl uint32) dnsmessage.Resource {
+	var a [4]byte
+	copy(a[:], net.ParseIP(ip).To4())
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.AResource{A: a},
+	}
+}
+
+func srvRecordOf(name, target string, priority, weight, port uint16, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body:   &dnsmessage.SRVResource{Target: dnsmessage.MustNewName(target), Priority: priority, Weight: weight, Port: port},
+	}
+}
+
+func TestResolveHostHonorsTTL(t *testing.T) {
+	stub := newDNSStub(t)
+	stub.set("api.internal.", dnsmessage.TypeA, []dnsmessage.Resource{
+		aRecord("api.internal.", "10.0.0.1", 7),
+		aRecord("api.internal.", "10.0.0.2", 9),
+	})
+	resolver := &DNSResolver{Server: stub.addr(), MinTTL: time.Second, MaxTTL: time.Minute}
+
+	nodes, ttl, err := resolver.ResolveEntry(context.Background(), "api.internal:8080", "20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"10.0.0.1:8080": "20", "10.0.0.2:8080": "20"}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Fatalf("nodes = %v, want %v", nodes, want)
+	}
+	if ttl != 7*time.Second {
+		t.Fatalf("ttl = %s, want 7s", ttl)
+	}
+
+	if _, _, err := resolver.ResolveEntry(context.Background(), "missing.internal:80", "1"); err == nil {
+		t.Fatal("expected error for missing host")
+	}
+}
+
+func TestResolveSRV(t *testing.T) {
+	stub := newDNSStub(t)
+	name := "_http._tcp.svc.internal."
+	stub.set(name, dnsmessage.TypeSRV, []dnsmessage.Resource{
+		srvRecordOf(name, "a.svc.internal.", 10, 30, 9001, 60),
+		srvRecordOf(name, "b.svc.internal.", 10, 0, 9002, 60),
+		srvRecordOf(name, "backup.svc.internal.", 20, 50, 9003, 60),
+	}, aRecord("a.svc.internal.", "10.0.1.1", 30))
+	// b 不在附加段中, 需要单独查询
+	stub.set("b.svc.internal.", dnsmessage.TypeA, []dnsmessage.Resource{aRecord("b.svc.internal.", "10.0.1.2", 15)})
+	resolver := &DNSResolver{Server: stub.addr(), MinTTL: time.Second, MaxTTL: time.Minute}
+
+	nodes, ttl, err := resolver.ResolveEntry(context.Background(), SRVPrefix+"_http._tcp.svc.internal", "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只取优先级最高的一组, weight 为 0 时沿用条目的权重
+	want := map[string]string{"10.0.1.1:9001": "30", "10.0.1.2:9002": "5"}
+	if !reflect.DeepEqual(nodes, want) {
+		t.Fatalf("nodes = %v, want %v", nodes, want)
+	}
+	if ttl != 15*time.Second {
+		t.Fatalf("ttl = %s, want 15s", ttl)
+	}
+}
+
+func TestDNSWatcherRefresh(t *testing.T) {
+	stub := newDNSStub(t)
+	stub.set("api.internal.", dnsmessage.TypeA, []dnsmessage.Resource{aRecord("api.internal.", "10.0.0.1", 0)})
+	resolver := &DNSResolver{Server: stub.addr(), MinTTL: 10 * time.Millisecond, MaxTTL: time.Second}
+
+	entries := map[string]string{"api.internal:80": "50", "10.0.0.9:80": "10"}
+	conf := newTestConf(t, map[string]string{"10.0.0.9:80": "10"})
+	watcher, err := NewDNSWatcher(resolver, conf, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lb := LoadBanlanceFactorWithConf(LbRoundRobin, conf)
+
+	watcher.Refresh(context.Background())
+	if got := sortedConf(conf); !reflect.DeepEqual(got, []string{"10.0.0.1:80,50", "10.0.0.9:80,10"}) {
+		t.Fatalf("conf = %v", got)
+	}
+
+	// 重新部署后地址变化, TTL 到期后刷新
+	stub.set("api.internal.", dnsmessage.TypeA, []dnsmessage.Resource{
+		aRecord("api.internal.", "10.0.0.2", 0),
+		aRecord("api.internal.", "10.0.0.3", 0),
+	})
+	time.Sleep(20 * time.Millisecond)
+	watcher.Refresh(context.Background())
+	if got := sortedConf(conf); !reflect.DeepEqual(got, []string{"10.0.0.2:80,50", "10.0.0.3:80,50", "10.0.0.9:80,10"}) {
+		t.Fatalf("conf after refresh = %v", got)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 6; i++ {
+		addr, _ := lb.Get("")
+		seen[addr] = true
+	}
+	if seen["10.0.0.1:80"] || !seen["10.0.0.2:80"] || !seen["10.0.0.3:80"] {
+		t.Fatalf("balancer not updated: %v", seen)
+	}
+
+	// 排空域名条目时其下所有地址都不参与选择
+	conf.ExcludeNodes([]string{"api.internal:80"})
+	if got := sortedConf(conf); !reflect.DeepEqual(got, []string{"10.0.0.9:80,10"}) {
+		t.Fatalf("conf after drain = %v", got)
+	}
+}
+
+func sortedConf(conf *LoadBalanceCheckConf) []string {
+	list := conf.GetConf()
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,143 @@
+package load_balance
+
+import (
+	"context"
+	"gateway/pkg/log"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DNSWatcher 按 TTL 定期解析上游条目中的域名和 SRV 记录, 解析出的每个地址作为一个节点写入 LoadBalanceConf
+// 解析失败时保留上一次的结果, 按 DefaultTTL 重试
+type DNSWatcher struct {
+	resolver *DNSResolver
+	conf     LoadBalanceConf
+	static   map[string]string // 不需要解析的 ip:port 条目及权重
+	entries  map[string]string // 需要解析的条目及权重
+
+	mu       sync.Mutex
+	resolved map[string]map[string]string // 条目 -> 解析出的 ip:port -> 权重
+	next     map[string]time.Time         // 条目下次解析的时间
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDNSWatcher 创建解析器, entries 为全部上游条目及权重, 其中的 ip:port 条目原样作为节点
+func NewDNSWatcher(resolver *DNSResolver, conf LoadBalanceConf, entries map[string]string) (*DNSWatcher, error) {
+	w := &DNSWatcher{
+		resolver: resolver,
+		conf:     conf,
+		static:   map[string]string{},
+		entries:  map[string]string{},
+		resolved: map[string]map[string]string{},
+		next:     map[string]time.Time{},
+		stop:     make(chan struct{}),
+	}
+	for entry, weight := range entries {
+		if IsDNSEntry(entry) {
+			w.entries[entry] = weight
+		} else {
+			w.static[entry] = weight
+		}
+	}
+	return w, nil
+}
+
+// Refresh 解析已经到期的条目, 结果有变化时更新节点, 返回距离下次到期的时间
+func (w *DNSWatcher) Refresh(ctx context.Context) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	changed := false
+	for entry, weight := range w.entries {
+		if due, ok := w.next[entry]; ok && now.Before(due) {
+			continue
+		}
+		nodes, ttl, err := w.resolver.ResolveEntry(ctx, entry, weight)
+		if err != nil {
+			log.Warn("resolve upstream failed", zap.String("entry", entry), zap.Error(err))
+			w.next[entry] = now.Add(w.resolver.defaultTTL())
+			continue
+		}
+		w.next[entry] = now.Add(ttl)
+		if !sameNodes(w.resolved[entry], nodes) {
+			w.resolved[entry] = nodes
+			changed = true
+		}
+	}
+	if changed {
+		w.apply()
+	}
+
+	wait := w.resolver.defaultTTL()
+	for _, due := range w.next {
+		if d := due.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	if wait <= 0 {
+		wait = time.Second
+	}
+	return wait
+}
+
+// apply 合并静态节点和解析结果写入配置, 同一地址出现在多个条目中时以先出现的为准
+func (w *DNSWatcher) apply() {
+	nodes := make(map[string]string, len(w.static))
+	origins := map[string]string{}
+	for node, weight := range w.static {
+		nodes[node] = weight
+	}
+	for entry, resolved := range w.resolved {
+		for node, weight := range resolved {
+			if _, ok := nodes[node]; ok {
+				continue
+			}
+			nodes[node] = weight
+			origins[node] = entry
+		}
+	}
+	w.conf.SetNodes(nodes, origins)
+}
+
+// Start 在后台按 TTL 持续刷新, 直到 Close
+func (w *DNSWatcher) Start() {
+	activeWatchers.Add(1)
+	go func() {
+		defer activeWatchers.Add(-1)
+		for {
+			ctx, cancel := context.WithCancel(context.Background())
+			wait := w.Refresh(ctx)
+			cancel()
+			timer := time.NewTimer(wait)
+			select {
+			case <-w.stop:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// Close 停止后台刷新
+func (w *DNSWatcher) Close() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+func sameNodes(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for node, weight := range a {
+		if w, ok := b[node]; !ok || w != weight {
+			return false
+		}
+	}
+	return true
+}
@@ -1,5 +1,7 @@
 package load_balance
 
+import "time"
+
 type LbType int
 
 const (
@@ -7,24 +9,50 @@ const (
 	LbRoundRobin
 	LbWeightRoundRobin
 	LbConsistentHash
+	LbLeastConn   // 最少处理中请求
+	LbP2C         // 随机两选一, 按响应耗时 ewma 和处理中请求数比较
+	LbBoundedHash // 有界负载一致性hash
 )
 
+const (
+	DefaultHashReplicas   = 10   // 一致性hash每个节点默认的虚拟节点数
+	DefaultHashLoadFactor = 1.25 // 有界负载一致性hash默认的负载上限, 平均负载的倍数
+)
+
+// Options 负载均衡器的可选配置, 零值使用默认值
+type Options struct {
+	HashReplicas   int           // 一致性hash每个节点的虚拟节点数
+	HashLoadFactor float64       // 有界负载一致性hash的负载上限, 平均负载的倍数
+	SlowStart      time.Duration // 慢启动时长, 只对加权轮询、最少连接和 p2c 生效, 0 表示不开启
+	PreviousNodes  []string      // 重建负载均衡器时重建前的节点, 不在其中的节点做慢启动; 为空时创建时的节点都不做慢启动
+}
+
 func LoadBanlanceFactory(lbType LbType) LoadBalance {
 	switch lbType {
 	case LbRandom:
 		return &RandomBalance{}
 	case LbConsistentHash:
-		return NewConsistentHashBanlance(10, nil)
+		return NewConsistentHashBanlance(DefaultHashReplicas, nil)
+	case LbBoundedHash:
+		return NewBoundedHashBalance(DefaultHashReplicas, DefaultHashLoadFactor)
 	case LbRoundRobin:
 		return &RoundRobinBalance{}
 	case LbWeightRoundRobin:
 		return &WeightRoundRobinBalance{}
+	case LbLeastConn:
+		return &LeastConnBalance{}
+	case LbP2C:
+		return &P2CBalance{}
 	default:
 		return &RandomBalance{}
 	}
 }
 
 func LoadBanlanceFactorWithConf(lbType LbType, mConf LoadBalanceConf) LoadBalance {
+	return LoadBanlanceFactorWithOptions(lbType, mConf, Options{})
+}
+
+func LoadBanlanceFactorWithOptions(lbType LbType, mConf LoadBalanceConf, opts Options) LoadBalance {
 	//观察者模式
 	switch lbType {
 	case LbRandom:
@@ -34,7 +62,13 @@ func LoadBanlanceFactorWithConf(lbType LbType, mConf LoadBalanceConf) LoadBalanc
 		lb.Update()
 		return lb
 	case LbConsistentHash:
-		lb := NewConsistentHashBanlance(10, nil)
+		lb := NewConsistentHashBanlance(opts.HashReplicas, nil)
+		lb.SetConf(mConf)
+		mConf.Attach(lb)
+		lb.Update()
+		return lb
+	case LbBoundedHash:
+		lb := NewBoundedHashBalance(opts.HashReplicas, opts.HashLoadFactor)
 		lb.SetConf(mConf)
 		mConf.Attach(lb)
 		lb.Update()
@@ -46,7 +80,24 @@ func LoadBanlanceFactorWithConf(lbType LbType, mConf LoadBalanceConf) LoadBalanc
 		lb.Update()
 		return lb
 	case LbWeightRoundRobin:
-		lb := &WeightRoundRobinBalance{}
+		lb := &WeightRoundRobinBalance{slowStart: opts.SlowStart}
+		lb.seed(opts.PreviousNodes)
+		lb.SetConf(mConf)
+		mConf.Attach(lb)
+		lb.Update()
+		return lb
+	case LbLeastConn:
+		lb := &LeastConnBalance{}
+		lb.slowStart = opts.SlowStart
+		lb.seed(opts.PreviousNodes)
+		lb.SetConf(mConf)
+		mConf.Attach(lb)
+		lb.Update()
+		return lb
+	case LbP2C:
+		lb := &P2CBalance{}
+		lb.slowStart = opts.SlowStart
+		lb.seed(opts.PreviousNodes)
 		lb.SetConf(mConf)
 		mConf.Attach(lb)
 		lb.Update()
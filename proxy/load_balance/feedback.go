@@ -0,0 +1,215 @@
+package load_balance
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Feedback 需要感知请求执行情况的负载均衡器实现该接口
+// 代理选中节点后调用 Acquire, 请求结束时调用返回的函数, 传入上游响应耗时和是否失败
+type Feedback interface {
+	Acquire(addr string) func(latency time.Duration, err error)
+}
+
+// Track 通知负载均衡器请求开始, 返回请求结束时调用的函数; 负载均衡器不需要反馈时返回空函数
+func Track(lb LoadBalance, addr string) func(latency time.Duration, err error) {
+	if fb, ok := lb.(Feedback); ok && addr != "" {
+		return fb.Acquire(addr)
+	}
+	return func(time.Duration, error) {}
+}
+
+const (
+	// ewmaDecay 响应耗时 ewma 的衰减时间常数, 越大越平滑
+	ewmaDecay = 10 * time.Second
+	// failurePenalty 请求失败时按不低于该值的耗时计入 ewma, 使失败节点在一段时间内少被选中
+	failurePenalty = time.Second
+)
+
+// trackedNode 记录节点的处理中请求数和响应耗时 ewma
+type trackedNode struct {
+	addr      string
+	weight    int64     // 原子操作
+	inflight  int64     // 原子操作
+	warmSince time.Time // 节点加入的时间, 用于慢启动, 创建后不再修改
+
+	mu       sync.Mutex
+	ewma     float64 // 纳秒
+	lastSeen time.Time
+}
+
+func (n *trackedNode) acquire() func(latency time.Duration, err error) {
+	atomic.AddInt64(&n.inflight, 1)
+	var once sync.Once
+	return func(latency time.Duration, err error) {
+		once.Do(func() {
+			atomic.AddInt64(&n.inflight, -1)
+			if err != nil && latency < failurePenalty {
+				latency = failurePenalty
+			}
+			n.observe(latency)
+		})
+	}
+}
+
+// observe 按距离上次采样的时间衰减旧值, 长时间没有请求的节点很快回到新的耗时水平
+func (n *trackedNode) observe(latency time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	if n.lastSeen.IsZero() {
+		n.ewma = float64(latency)
+	} else {
+		w := decayWeight(now.Sub(n.lastSeen))
+		n.ewma = n.ewma*w + float64(latency)*(1-w)
+	}
+	n.lastSeen = now
+}
+
+func (n *trackedNode) latency() float64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ewma
+}
+
+func (n *trackedNode) pending() int64 {
+	return atomic.LoadInt64(&n.inflight)
+}
+
+func (n *trackedNode) loadWeight() int64 {
+	return atomic.LoadInt64(&n.weight)
+}
+
+// decayWeight 旧值保留的比例, 间隔越长保留越少
+func decayWeight(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 1
+	}
+	return math.Exp(-float64(elapsed) / float64(ewmaDecay))
+}
+
+// trackedNodes 需要反馈的负载均衡器共用的节点列表, Update 时保留已有节点的统计数据
+// 节点列表以快照形式整体替换, 节点本身的统计数据是原子或加锁的, 新旧快照可以共用同一个节点
+type trackedNodes struct {
+	mu  sync.Mutex // 只用于串行化写入
+	set atomic.Pointer[trackedSet]
+
+	//观察主体
+	conf LoadBalanceConf
+
+	slowStart time.Duration // 新增或恢复的节点参与选择的比例从小值线性增长到 1 的时长, 0 表示不开启
+}
+
+// trackedSet 一份节点快照, 发布后不再修改
+type trackedSet struct {
+	nodes []*trackedNode
+	index map[string]*trackedNode
+}
+
+func (t *trackedNodes) Add(params ...string) error {
+	if len(params) == 0 {
+		return fmt.Errorf("param len 1 at least")
+	}
+	weight := int64(1)
+	if len(params) > 1 {
+		w, err := strconv.ParseInt(params[1], 10, 64)
+		if err != nil {
+			return err
+		}
+		weight = w
+	}
+	if weight <= 0 {
+		weight = 1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old := t.set.Load()
+	set := &trackedSet{index: map[string]*trackedNode{}}
+	if old != nil {
+		for _, node := range old.nodes {
+			set.add(node.addr, node.loadWeight(), old.index)
+		}
+	}
+	set.add(params[0], weight, nil)
+	t.set.Store(set)
+	return nil
+}
+
+// add 添加节点, old 中已有的节点沿用原来的统计数据
+// old 中没有的节点记录加入时间, old 为 nil 时是负载均衡器创建时的节点, 不做慢启动
+func (s *trackedSet) add(addr string, weight int64, old map[string]*trackedNode) {
+	if node, ok := s.index[addr]; ok {
+		atomic.StoreInt64(&node.weight, weight)
+		return
+	}
+	node, ok := old[addr]
+	if !ok {
+		node = &trackedNode{addr: addr}
+		if old != nil {
+			node.warmSince = time.Now()
+		}
+	}
+	atomic.StoreInt64(&node.weight, weight)
+	s.index[addr] = node
+	s.nodes = append(s.nodes, node)
+}
+
+func (t *trackedNodes) SetConf(conf LoadBalanceConf) {
+	t.conf = conf
+}
+
+func (t *trackedNodes) Update() {
+	conf := t.conf
+	if conf == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var old map[string]*trackedNode
+	if prev := t.set.Load(); prev != nil {
+		old = prev.index
+	}
+	set := &trackedSet{index: map[string]*trackedNode{}}
+	for _, item := range conf.GetConf() {
+		params := strings.Split(item, ",")
+		weight := int64(1)
+		if len(params) > 1 {
+			if w, err := strconv.ParseInt(params[1], 10, 64); err == nil && w > 0 {
+				weight = w
+			}
+		}
+		set.add(params[0], weight, old)
+	}
+	t.set.Store(set)
+}
+
+// Acquire 实现 Feedback, 节点已经被移除时不再记录
+func (t *trackedNodes) Acquire(addr string) func(latency time.Duration, err error) {
+	set := t.set.Load()
+	if set == nil {
+		return func(time.Duration, error) {}
+	}
+	node, ok := set.index[addr]
+	if !ok {
+		return func(time.Duration, error) {}
+	}
+	return node.acquire()
+}
+
+// admit 慢启动期间的节点按比例参与选择
+func (t *trackedNodes) admit(node *trackedNode, now time.Time) bool {
+	return slowStartAdmit(slowStartFactor(node.warmSince, t.slowStart, now))
+}
+
+// snapshot 返回当前节点列表, 调用方不能修改
+func (t *trackedNodes) snapshot() []*trackedNode {
+	if set := t.set.Load(); set != nil {
+		return set.nodes
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package load_balance
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// LeastConnBalance 选择处理中请求数与权重之比最小的节点, 比例相同时从轮转的起点开始取第一个
+// 处理中的请求数由代理通过 Feedback 反馈, 没有反馈时退化为轮询
+// 慢启动期间的节点按比例跳过, 所有节点都被跳过时选择轮转的起点
+type LeastConnBalance struct {
+	trackedNodes
+	next uint64
+}
+
+func (r *LeastConnBalance) Next() (string, error) {
+	nodes := r.snapshot()
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("node is empty")
+	}
+	start := atomic.AddUint64(&r.next, 1)
+	now := time.Now()
+	var best *trackedNode
+	var bestPending, bestWeight int64
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[(start+uint64(i))%uint64(len(nodes))]
+		if !r.admit(node, now) {
+			continue
+		}
+		pending, weight := node.pending(), node.loadWeight()
+		// pending/weight < bestPending/bestWeight, 交叉相乘避免浮点运算
+		if best == nil || pending*bestWeight < bestPending*weight {
+			best, bestPending, bestWeight = node, pending, weight
+		}
+	}
+	if best == nil {
+		best = nodes[start%uint64(len(nodes))]
+	}
+	return best.addr, nil
+}
+
+func (r *LeastConnBalance) Get(key string) (string, error) {
+	return r.Next()
+}
@@ -0,0 +1,290 @@
+package load_balance
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var allLbTypes = []LbType{LbRandom, LbRoundRobin, LbWeightRoundRobin, LbConsistentHash, LbLeastConn, LbP2C, LbBoundedHash}
+
+func newTestConf(t *testing.T, weights map[string]string) *LoadBalanceCheckConf {
+	t.Helper()
+	conf, err := NewLoadBalanceCheckConfWithMethod("%s", weights, CheckMethodNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conf.(*LoadBalanceCheckConf)
+}
+
+// TestConcurrentGetUpdate 在 go test -race 下检查选择节点与更新节点列表之间没有数据竞争
+func TestConcurrentGetUpdate(t *testing.T) {
+	nodes := []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.4:80"}
+	for _, lbType := range allLbTypes {
+		t.Run(strconv.Itoa(int(lbType)), func(t *testing.T) {
+			weights := map[string]string{}
+			for i, node := range nodes {
+				weights[node] = strconv.Itoa(i + 1)
+			}
+			conf := newTestConf(t, weights)
+			lb := LoadBanlanceFactorWithConf(lbType, conf)
+
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for n := 0; ; n++ {
+						select {
+						case <-stop:
+							return
+						default:
+						}
+						addr, err := lb.Get(fmt.Sprintf("client-%d-%d", i, n))
+						if err == nil && addr != "" {
+							done := Track(lb, addr)
+							var reqErr error
+							if n%5 == 0 {
+								reqErr = errors.New("upstream failed")
+							}
+							done(time.Millisecond, reqErr)
+						}
+					}
+				}(i)
+			}
+
+			for n := 0; n < 200; n++ {
+				switch n % 3 {
+				case 0:
+					conf.UpdateConf(nodes[:1+n%len(nodes)])
+				case 1:
+					conf.ExcludeNodes(nodes[n%len(nodes) : n%len(nodes)+1])
+				default:
+					conf.UpdateConf(nodes)
+					conf.ExcludeNodes(nil)
+				}
+			}
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
+
+// TestWeightRoundRobinDistribution 权重 5:1:1 时每 7 次选择的分布与权重一致
+func TestWeightRoundRobinDistribution(t *testing.T) {
+	conf := newTestConf(t, map[string]string{"a:80": "5", "b:80": "1", "c:80": "1"})
+	lb := LoadBanlanceFactorWithConf(LbWeightRoundRobin, conf)
+
+	count := map[string]int{}
+	for i := 0; i < 70; i++ {
+		addr, err := lb.Get("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		count[addr]++
+	}
+	if count["a:80"] != 50 || count["b:80"] != 10 || count["c:80"] != 10 {
+		t.Fatalf("unexpected distribution %v", count)
+	}
+}
+
+// TestWeightRoundRobinFeedback 失败反馈降低有效权重, 之后逐步恢复
+func TestWeightRoundRobinFeedback(t *testing.T) {
+	conf := newTestConf(t, map[string]string{"a:80": "10", "b:80": "10"})
+	lb := LoadBanlanceFactorWithConf(LbWeightRoundRobin, conf).(*WeightRoundRobinBalance)
+
+	Track(lb, "a:80")(time.Millisecond, errors.New("connection refused"))
+	if w := lb.effectiveWeight("a:80"); w != 5 {
+		t.Fatalf("effective weight after failure = %d, want 5", w)
+	}
+	for i := 0; i < 10; i++ {
+		Track(lb, "a:80")(time.Millisecond, errors.New("connection refused"))
+	}
+	if w := lb.effectiveWeight("a:80"); w != 1 {
+		t.Fatalf("effective weight after repeated failures = %d, want 1", w)
+	}
+
+	// 有效权重降低后, 失败节点被选中的次数明显少于正常节点
+	count := map[string]int{}
+	for i := 0; i < 4; i++ {
+		addr, _ := lb.Get("")
+		count[addr]++
+	}
+	if count["a:80"] >= count["b:80"] {
+		t.Fatalf("failed node still preferred: %v", count)
+	}
+
+	for i := 0; i < 20; i++ {
+		lb.ReportSuccess("a:80")
+	}
+	if w := lb.effectiveWeight("a:80"); w != 10 {
+		t.Fatalf("effective weight after recovery = %d, want 10", w)
+	}
+
+	// 更新节点列表时保留有效权重
+	lb.ReportFailure("a:80")
+	conf.UpdateConf([]string{"a:80", "b:80"})
+	if w := lb.effectiveWeight("a:80"); w != 5 {
+		t.Fatalf("effective weight lost on update = %d, want 5", w)
+	}
+}
+
+// TestLeastConnPrefersIdleNode 处理中请求较少的节点优先
+func TestLeastConnPrefersIdleNode(t *testing.T) {
+	conf := newTestConf(t, map[string]string{"a:80": "1", "b:80": "1"})
+	lb := LoadBanlanceFactorWithConf(LbLeastConn, conf)
+
+	done := Track(lb, "a:80")
+	for i := 0; i < 10; i++ {
+		if addr, _ := lb.Get(""); addr != "b:80" {
+			t.Fatalf("got %s, want b:80 while a:80 is busy", addr)
+		}
+	}
+	done(time.Millisecond, nil)
+}
+
+// TestConsistentHashStable 节点列表不变时同一个 key 总是落到同一个节点
+func TestConsistentHashStable(t *testing.T) {
+	conf := newTestConf(t, map[string]string{"a:80": "1", "b:80": "1", "c:80": "1"})
+	lb := LoadBanlanceFactorWithConf(LbConsistentHash, conf)
+
+	want, err := lb.Get("user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.UpdateConf([]string{"c:80", "b:80", "a:80"})
+	if got, _ := lb.Get("user-1"); got != want {
+		t.Fatalf("got %s after update, want %s", got, want)
+	}
+}
+
+// TestBoundedHashSpillsOver 同一个 key 的处理中请求超过负载上限后顺延到其他节点, 请求结束后回到原节点
+func TestBoundedHashSpillsOver(t *testing.T) {
+	conf := newTestConf(t, map[string]string{"a:80": "1", "b:80": "1", "c:80": "1"})
+	lb := LoadBanlanceFactorWithOptions(LbBoundedHash, conf, Options{HashReplicas: 50, HashLoadFactor: 1.25})
+
+	home, err := lb.Get("user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dones := []func(time.Duration, error){}
+	count := map[string]int{}
+	for i := 0; i < 30; i++ {
+		addr, _ := lb.Get("user-1")
+		count[addr]++
+		dones = append(dones, Track(lb, addr))
+	}
+	// 负载上限 ceil(1.25*(n+1)/3), 原节点最多承担约 42% 的处理中请求
+	if count[home] > 13 || len(count) < 2 {
+		t.Fatalf("hot key not spread: %v", count)
+	}
+	for _, done := range dones {
+		done(time.Millisecond, nil)
+	}
+	if got, _ := lb.Get("user-1"); got != home {
+		t.Fatalf("got %s after load drained, want %s", got, home)
+	}
+}
+
+// TestCloseStopsChecker 服务重建时关闭旧配置, 探活协程随之退出
+func TestCloseStopsChecker(t *testing.T) {
+	waitCheckers := func(want int64) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for ActiveCheckers() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("active checkers = %d, want %d", ActiveCheckers(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	// 其他用例关闭的配置可能还没有退出
+	waitCheckers(0)
+	confs := []*LoadBalanceCheckConf{}
+	for i := 0; i < 5; i++ {
+		conf, err := NewLoadBalanceCheckConfWithPolicy("%s", map[string]string{"127.0.0.1:1": "1"}, CheckPolicy{
+			Timeout:  10 * time.Millisecond,
+			Interval: 10 * time.Millisecond,
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		confs = append(confs, conf)
+	}
+	if got := ActiveCheckers(); got != 5 {
+		t.Fatalf("active checkers = %d, want 5", got)
+	}
+	for _, conf := range confs {
+		conf.Close()
+		conf.Close()
+	}
+	waitCheckers(0)
+}
+
+// TestSlowStartRecoveredNode 节点恢复或新增后在慢启动窗口内只承担少量请求, 负载均衡器创建时已有的节点不做慢启动
+func TestSlowStartRecoveredNode(t *testing.T) {
+	for _, lbType := range []LbType{LbWeightRoundRobin, LbLeastConn, LbP2C} {
+		t.Run(strconv.Itoa(int(lbType)), func(t *testing.T) {
+			conf := newTestConf(t, map[string]string{"a:80": "10", "b:80": "10"})
+			lb := LoadBanlanceFactorWithOptions(lbType, conf, Options{SlowStart: time.Minute})
+
+			pick := func() map[string]int {
+				count := map[string]int{}
+				for i := 0; i < 1000; i++ {
+					addr, err := lb.Get("")
+					if err != nil {
+						t.Fatal(err)
+					}
+					count[addr]++
+				}
+				return count
+			}
+			if count := pick(); count["b:80"] < 300 {
+				t.Fatalf("initial node b is warming up: %v", count)
+			}
+
+			conf.UpdateConf([]string{"a:80"})
+			conf.UpdateConf([]string{"a:80", "b:80"})
+			if count := pick(); count["b:80"] > 300 {
+				t.Fatalf("recovered node b got too many requests: %v", count)
+			}
+
+			// 重建负载均衡器时, 重建前没有的节点同样做慢启动
+			lb = LoadBanlanceFactorWithOptions(lbType, newTestConf(t, map[string]string{"a:80": "10", "b:80": "10"}), Options{
+				SlowStart:     time.Minute,
+				PreviousNodes: []string{"a:80,10"},
+			})
+			if count := pick(); count["b:80"] > 300 {
+				t.Fatalf("node b added on rebuild got too many requests: %v", count)
+			}
+		})
+	}
+}
+
+func TestSlowStartFactor(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		since  time.Time
+		window time.Duration
+		want   float64
+	}{
+		{time.Time{}, time.Minute, 1},
+		{now, 0, 1},
+		{now, time.Minute, slowStartMinFactor},
+		{now.Add(-30 * time.Second), time.Minute, 0.55},
+		{now.Add(-2 * time.Minute), time.Minute, 1},
+	}
+	for _, c := range cases {
+		if got := slowStartFactor(c.since, c.window, now); math.Abs(got-c.want) > 1e-9 {
+			t.Fatalf("slowStartFactor(%v, %v) = %v, want %v", now.Sub(c.since), c.window, got, c.want)
+		}
+	}
+	if w := slowStartWeight(10, slowStartMinFactor); w != 1 {
+		t.Fatalf("slowStartWeight(10, min) = %d, want 1", w)
+	}
+}
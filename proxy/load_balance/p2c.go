@@ -0,0 +1,50 @@
+package load_balance
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// P2CBalance 随机取两个节点, 选择负载较低的一个
+// 负载 = 响应耗时 ewma * (处理中请求数 + 1) / 权重, 还没有耗时数据的节点负载为 0, 会优先获得请求
+// 慢启动期间的节点按比例让给另一个节点, 避免新节点因为没有耗时数据立即获得大量请求
+type P2CBalance struct {
+	trackedNodes
+}
+
+func (r *P2CBalance) Next() (string, error) {
+	nodes := r.snapshot()
+	switch len(nodes) {
+	case 0:
+		return "", fmt.Errorf("node is empty")
+	case 1:
+		return nodes[0].addr, nil
+	}
+	i := rand.Intn(len(nodes))
+	j := rand.Intn(len(nodes) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := nodes[i], nodes[j]
+	now := time.Now()
+	if admitA, admitB := r.admit(a, now), r.admit(b, now); admitA != admitB {
+		if admitA {
+			return a.addr, nil
+		}
+		return b.addr, nil
+	}
+	costA, costB := p2cCost(a), p2cCost(b)
+	if costB < costA || (costB == costA && b.pending() < a.pending()) {
+		return b.addr, nil
+	}
+	return a.addr, nil
+}
+
+func (r *P2CBalance) Get(key string) (string, error) {
+	return r.Next()
+}
+
+func p2cCost(n *trackedNode) float64 {
+	return n.latency() * float64(n.pending()+1) / float64(n.loadWeight())
+}
@@ -0,0 +1,63 @@
+package load_balance
+
+import (
+	"sort"
+	"strconv"
+)
+
+// SetPriorities 设置条目的优先级和切换阈值并通知监听者, 数值越小优先级越高
+// 默认只使用最高优先级的节点, 某一级可用节点的权重低于该级全部节点权重的 threshold 时同时使用下一级的节点
+// priorities 的 key 为上游条目, 域名解析出的节点使用所属条目的优先级, 未设置的节点优先级为 0; priorities 为空时不做切换
+func (s *LoadBalanceCheckConf) SetPriorities(priorities map[string]int, threshold float64) {
+	s.mu.Lock()
+	s.priorities, s.failoverThreshold = priorities, threshold
+	s.mu.Unlock()
+	s.NotifyAllObservers()
+}
+
+// priorityOf 调用方需持有读锁
+func (s *LoadBalanceCheckConf) priorityOf(node string) int {
+	if priority, ok := s.priorities[node]; ok {
+		return priority
+	}
+	return s.priorities[s.origins[node]]
+}
+
+// activeTiers 返回参与选择的优先级, 调用方需持有读锁
+// 从高到低依次加入, 某一级可用节点的权重不低于该级全部节点权重的 failoverThreshold 时停止; 所有级别都不满足时全部参与选择
+func (s *LoadBalanceCheckConf) activeTiers(available []string) map[int]bool {
+	total, up := map[int]int{}, map[int]int{}
+	for node, weight := range s.confIpWeight {
+		total[s.priorityOf(node)] += tierWeight(weight)
+	}
+	for _, node := range available {
+		priority := s.priorityOf(node)
+		weight, ok := s.confIpWeight[node]
+		if !ok {
+			// 配置中已经没有的节点同时计入该级别的全部权重, 保证所在的级别参与比较
+			total[priority] += tierWeight(weight)
+		}
+		up[priority] += tierWeight(weight)
+	}
+	tiers := make([]int, 0, len(total))
+	for priority := range total {
+		tiers = append(tiers, priority)
+	}
+	sort.Ints(tiers)
+	active := map[int]bool{}
+	for _, priority := range tiers {
+		active[priority] = true
+		if up[priority] > 0 && float64(up[priority]) >= s.failoverThreshold*float64(total[priority]) {
+			break
+		}
+	}
+	return active
+}
+
+// tierWeight 计算可用容量使用的权重, 无法解析时按 1 计算
+func tierWeight(weight string) int {
+	if w, err := strconv.Atoi(weight); err == nil && w > 0 {
+		return w
+	}
+	return 1
+}
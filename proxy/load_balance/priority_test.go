@@ -0,0 +1,65 @@
+package load_balance
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestPriorityFailover 高优先级可用权重低于阈值时才使用低优先级的节点, 恢复后切回
+func TestPriorityFailover(t *testing.T) {
+	conf := newTestConf(t, map[string]string{"a:80": "1", "b:80": "1", "c:80": "1", "d:80": "1", "backup:80": "1"})
+	conf.SetPriorities(map[string]int{"a:80": 0, "b:80": 0, "c:80": 0, "d:80": 0, "backup:80": 1}, 0.5)
+
+	primary := []string{"a:80,1", "b:80,1", "c:80,1", "d:80,1"}
+	if got := sortedConf(conf); !reflect.DeepEqual(got, primary) {
+		t.Fatalf("conf = %v, want primary tier only", got)
+	}
+
+	// 4 个节点中 2 个可用, 等于阈值, 不切换
+	conf.UpdateConf([]string{"a:80", "b:80", "backup:80"})
+	if got, want := sortedConf(conf), []string{"a:80,1", "b:80,1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("conf = %v, want %v", got, want)
+	}
+
+	// 只剩 1 个可用, 低于阈值, 同时使用备用节点
+	conf.UpdateConf([]string{"a:80", "backup:80"})
+	if got, want := sortedConf(conf), []string{"a:80,1", "backup:80,1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("conf = %v, want %v", got, want)
+	}
+
+	// 禁用的节点同样不计入可用权重
+	conf.UpdateConf([]string{"a:80", "b:80", "c:80", "d:80", "backup:80"})
+	conf.ExcludeNodes([]string{"a:80", "b:80", "c:80"})
+	if got, want := sortedConf(conf), []string{"backup:80,1", "d:80,1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("conf = %v, want %v", got, want)
+	}
+
+	conf.ExcludeNodes(nil)
+	if got := sortedConf(conf); !reflect.DeepEqual(got, primary) {
+		t.Fatalf("conf = %v, want primary tier after recovery", got)
+	}
+
+	// 不设置优先级时所有节点都参与选择
+	conf.SetPriorities(nil, 0)
+	if got := conf.GetConf(); len(got) != 5 {
+		t.Fatalf("conf = %v, want all nodes", got)
+	}
+}
+
+// TestPriorityFailoverView 服务视图禁用的节点只影响该服务的切换
+func TestPriorityFailoverView(t *testing.T) {
+	parent := newTestConf(t, map[string]string{"a:80": "1", "backup:80": "1"})
+	parent.SetPriorities(map[string]int{"backup:80": 1}, 0)
+	view := NewLoadBalanceConfView(parent, "http://%s")
+	defer view.Close()
+	other := NewLoadBalanceConfView(parent, "%s")
+	defer other.Close()
+
+	view.ExcludeNodes([]string{"a:80"})
+	if got, want := view.GetConf(), []string{"http://backup:80,1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("view conf = %v, want %v", got, want)
+	}
+	if got, want := other.GetConf(), []string{"a:80,1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("other view conf = %v, want %v", got, want)
+	}
+}
@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// RandomBalance 随机选择节点
+// 节点列表以快照形式整体替换, Get 只读取当前快照, 不需要加锁
 type RandomBalance struct {
-	curIndex int
-	rss      []string
+	mu  sync.Mutex // 只用于串行化写入
+	rss atomic.Pointer[[]string]
 	//观察主体
 	conf LoadBalanceConf
 }
@@ -17,17 +21,18 @@ func (r *RandomBalance) Add(params ...string) error {
 	if len(params) == 0 {
 		return fmt.Errorf("param len 1 at least")
 	}
-	addr := params[0]
-	r.rss = append(r.rss, addr)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rss.Store(appendAddr(r.rss.Load(), params[0]))
 	return nil
 }
 
 func (r *RandomBalance) Next() string {
-	if len(r.rss) == 0 {
+	rss := r.rss.Load()
+	if rss == nil || len(*rss) == 0 {
 		return ""
 	}
-	r.curIndex = rand.Intn(len(r.rss))
-	return r.rss[r.curIndex]
+	return (*rss)[rand.Intn(len(*rss))]
 }
 
 func (r *RandomBalance) Get(key string) (string, error) {
@@ -39,10 +44,23 @@ func (r *RandomBalance) SetConf(conf LoadBalanceConf) {
 }
 
 func (r *RandomBalance) Update() {
-	if conf, ok := r.conf.(*LoadBalanceCheckConf); ok {
-		r.rss = nil
+	if conf := r.conf; conf != nil {
+		rss := []string{}
 		for _, ip := range conf.GetConf() {
-			r.Add(strings.Split(ip, ",")...)
+			rss = append(rss, strings.Split(ip, ",")[0])
 		}
+		r.mu.Lock()
+		r.rss.Store(&rss)
+		r.mu.Unlock()
 	}
 }
+
+// appendAddr 复制一份节点列表并追加 addr, 不修改已经发布的快照
+func appendAddr(rss *[]string, addr string) *[]string {
+	out := []string{}
+	if rss != nil {
+		out = append(out, *rss...)
+	}
+	out = append(out, addr)
+	return &out
+}
@@ -3,11 +3,16 @@ package load_balance
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// RoundRobinBalance 依次选择节点
+// 节点列表以快照形式整体替换, 轮转位置使用原子计数, Get 不需要加锁
 type RoundRobinBalance struct {
-	curIndex int
-	rss      []string
+	mu       sync.Mutex // 只用于串行化写入
+	curIndex uint64
+	rss      atomic.Pointer[[]string]
 	//观察主体
 	conf LoadBalanceConf
 }
@@ -16,22 +21,19 @@ func (r *RoundRobinBalance) Add(params ...string) error {
 	if len(params) == 0 {
 		return fmt.Errorf("param len 1 at least")
 	}
-	addr := params[0]
-	r.rss = append(r.rss, addr)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rss.Store(appendAddr(r.rss.Load(), params[0]))
 	return nil
 }
 
 func (r *RoundRobinBalance) Next() string {
-	if len(r.rss) == 0 {
+	rss := r.rss.Load()
+	if rss == nil || len(*rss) == 0 {
 		return ""
 	}
-	lens := len(r.rss) //5
-	if r.curIndex >= lens {
-		r.curIndex = 0
-	}
-	curAddr := r.rss[r.curIndex]
-	r.curIndex = (r.curIndex + 1) % lens
-	return curAddr
+	index := atomic.AddUint64(&r.curIndex, 1) - 1
+	return (*rss)[index%uint64(len(*rss))]
 }
 
 func (r *RoundRobinBalance) Get(key string) (string, error) {
@@ -43,10 +45,13 @@ func (r *RoundRobinBalance) SetConf(conf LoadBalanceConf) {
 }
 
 func (r *RoundRobinBalance) Update() {
-	if conf, ok := r.conf.(*LoadBalanceCheckConf); ok {
-		r.rss = nil
+	if conf := r.conf; conf != nil {
+		rss := []string{}
 		for _, ip := range conf.GetConf() {
-			r.Add(strings.Split(ip, ",")...)
+			rss = append(rss, strings.Split(ip, ",")[0])
 		}
+		r.mu.Lock()
+		r.rss.Store(&rss)
+		r.mu.Unlock()
 	}
 }
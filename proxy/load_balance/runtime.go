@@ -0,0 +1,19 @@
+package load_balance
+
+import "sync/atomic"
+
+// 正在运行的后台协程数, 服务重建后应当回落, 持续增长说明有配置没有被 Close
+var (
+	activeCheckers atomic.Int64 // 主动探活协程
+	activeWatchers atomic.Int64 // 域名解析和服务发现协程
+)
+
+// ActiveCheckers 返回正在运行的主动探活协程数
+func ActiveCheckers() int64 {
+	return activeCheckers.Load()
+}
+
+// ActiveWatchers 返回正在运行的域名解析和服务发现协程数
+func ActiveWatchers() int64 {
+	return activeWatchers.Load()
+}
@@ -0,0 +1,74 @@
+package load_balance
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slowStartMinFactor 慢启动开始时节点承担的流量比例, 之后线性增长到 1
+const slowStartMinFactor = 0.1
+
+// slowStartFactor 返回节点当前可以承担的流量比例
+// since 为节点加入(新增或探活恢复)的时间, 为零表示负载均衡器创建时已经存在的节点, 不做慢启动
+func slowStartFactor(since time.Time, window time.Duration, now time.Time) float64 {
+	if window <= 0 || since.IsZero() {
+		return 1
+	}
+	elapsed := now.Sub(since)
+	if elapsed >= window {
+		return 1
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return slowStartMinFactor + (1-slowStartMinFactor)*float64(elapsed)/float64(window)
+}
+
+// slowStartWeight 按慢启动比例缩小权重, 不低于 1
+func slowStartWeight(weight int, factor float64) int {
+	if factor >= 1 {
+		return weight
+	}
+	if w := int(math.Ceil(float64(weight) * factor)); w > 1 {
+		return w
+	}
+	return 1
+}
+
+// slowStartAdmit 慢启动期间的节点按比例参与选择, 用于按处理中请求数和耗时选择的负载均衡器
+// 新节点没有处理中的请求和耗时数据, 只缩小权重仍会让它立即获得大量请求
+func slowStartAdmit(factor float64) bool {
+	return factor >= 1 || rand.Float64() < factor
+}
+
+// seed 负载均衡器重建时用重建前的节点作为上一份快照, 第一次 Update 时不在其中的节点做慢启动
+// previous 为重建前节点配置 GetConf 的结果, 为 nil 或没有开启慢启动时不处理
+func (r *WeightRoundRobinBalance) seed(previous []string) {
+	if previous == nil || r.slowStart <= 0 {
+		return
+	}
+	rss := newWeightNodes()
+	for _, item := range previous {
+		params := strings.Split(item, ",")
+		weight := 1
+		if len(params) > 1 {
+			weight, _ = strconv.Atoi(params[1])
+		}
+		rss.add(params[0], weight, nil)
+	}
+	r.rss.Store(rss)
+}
+
+func (t *trackedNodes) seed(previous []string) {
+	if previous == nil || t.slowStart <= 0 {
+		return
+	}
+	set := &trackedSet{index: map[string]*trackedNode{}}
+	for _, item := range previous {
+		set.add(strings.Split(item, ",")[0], 1, nil)
+	}
+	t.set.Store(set)
+}
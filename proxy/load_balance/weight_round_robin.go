@@ -4,21 +4,66 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// WeightRoundRobinBalance 平滑加权轮询
+// 节点列表以快照形式整体替换, Get 取得快照后只锁定该快照计算, Update 不会阻塞处理中的选择
 type WeightRoundRobinBalance struct {
-	curIndex int
-	rss      []*WeightNode
-	rsw      []int
+	mu  sync.Mutex // 只用于串行化写入
+	rss atomic.Pointer[weightNodes]
 	//观察主体
 	conf LoadBalanceConf
+
+	slowStart time.Duration // 新增或恢复的节点权重从小值线性增长到 weight 的时长, 0 表示不开启
 }
 
 type WeightNode struct {
 	addr            string
-	weight          int //权重值
-	currentWeight   int //节点当前权重
-	effectiveWeight int //有效权重
+	weight          int       //权重值
+	currentWeight   int       //节点当前权重
+	effectiveWeight int       //有效权重
+	warmSince       time.Time // 节点加入的时间, 用于慢启动
+}
+
+// weightNodes 一份节点快照, 各节点的临时权重和有效权重由 mu 保护
+type weightNodes struct {
+	mu    sync.Mutex
+	nodes []*WeightNode
+	index map[string]*WeightNode
+}
+
+func newWeightNodes() *weightNodes {
+	return &weightNodes{index: map[string]*WeightNode{}}
+}
+
+// add 添加节点, old 中已有的节点沿用原来的临时权重和有效权重
+// old 中没有的节点记录加入时间, old 为空时是负载均衡器创建时的节点, 不做慢启动
+func (w *weightNodes) add(addr string, weight int, old *weightNodes) {
+	if weight <= 0 {
+		weight = 1
+	}
+	node := &WeightNode{addr: addr, weight: weight, effectiveWeight: weight}
+	if old != nil {
+		old.mu.Lock()
+		if prev, ok := old.index[addr]; ok {
+			node.currentWeight = prev.currentWeight
+			node.warmSince = prev.warmSince
+			if prev.effectiveWeight < weight {
+				node.effectiveWeight = prev.effectiveWeight
+			}
+		} else {
+			node.warmSince = time.Now()
+		}
+		old.mu.Unlock()
+	}
+	if _, ok := w.index[addr]; ok {
+		return
+	}
+	w.index[addr] = node
+	w.nodes = append(w.nodes, node)
 }
 
 func (r *WeightRoundRobinBalance) Add(params ...string) error {
@@ -29,24 +74,44 @@ func (r *WeightRoundRobinBalance) Add(params ...string) error {
 	if err != nil {
 		return err
 	}
-	node := &WeightNode{addr: params[0], weight: int(parInt)}
-	node.effectiveWeight = node.weight
-	r.rss = append(r.rss, node)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.rss.Load()
+	rss := newWeightNodes()
+	if old != nil {
+		for _, node := range old.nodes {
+			rss.add(node.addr, node.weight, old)
+		}
+	}
+	rss.add(params[0], int(parInt), old)
+	r.rss.Store(rss)
 	return nil
 }
 
 func (r *WeightRoundRobinBalance) Next() string {
+	rss := r.rss.Load()
+	if rss == nil {
+		return ""
+	}
+	rss.mu.Lock()
+	defer rss.mu.Unlock()
+	now := time.Now()
 	total := 0
 	var best *WeightNode
-	for i := 0; i < len(r.rss); i++ {
-		w := r.rss[i]
+	for i := 0; i < len(rss.nodes); i++ {
+		w := rss.nodes[i]
+		// 慢启动期间的节点按比例缩小权重
+		weight := w.effectiveWeight
+		if ramp := slowStartWeight(w.weight, slowStartFactor(w.warmSince, r.slowStart, now)); ramp < weight {
+			weight = ramp
+		}
 		//step 1 统计所有有效权重之和
-		total += w.effectiveWeight
+		total += weight
 
 		//step 2 变更节点临时权重为的节点临时权重+节点有效权重
-		w.currentWeight += w.effectiveWeight
+		w.currentWeight += weight
 
-		//step 3 有效权重默认与权重相同，通讯异常时-1, 通讯成功+1，直到恢复到weight大小
+		//step 3 有效权重默认与权重相同，通讯异常时降低(见 Acquire), 之后每轮+1，直到恢复到weight大小
 		if w.effectiveWeight < w.weight {
 			w.effectiveWeight++
 		}
@@ -67,15 +132,91 @@ func (r *WeightRoundRobinBalance) Get(key string) (string, error) {
 	return r.Next(), nil
 }
 
+// Acquire 实现 Feedback, 请求失败时有效权重减半(至少减 1, 不低于 1), 成功时 +1 直到恢复到 weight
+func (r *WeightRoundRobinBalance) Acquire(addr string) func(latency time.Duration, err error) {
+	var once sync.Once
+	return func(latency time.Duration, err error) {
+		once.Do(func() {
+			if err != nil {
+				r.ReportFailure(addr)
+			} else {
+				r.ReportSuccess(addr)
+			}
+		})
+	}
+}
+
+// ReportFailure 节点通讯异常, 降低有效权重
+func (r *WeightRoundRobinBalance) ReportFailure(addr string) {
+	r.adjust(addr, func(w *WeightNode) {
+		step := w.weight / 2
+		if step < 1 {
+			step = 1
+		}
+		w.effectiveWeight -= step
+		if w.effectiveWeight < 1 {
+			w.effectiveWeight = 1
+		}
+	})
+}
+
+// ReportSuccess 节点通讯成功, 有效权重 +1 直到恢复到 weight
+func (r *WeightRoundRobinBalance) ReportSuccess(addr string) {
+	r.adjust(addr, func(w *WeightNode) {
+		if w.effectiveWeight < w.weight {
+			w.effectiveWeight++
+		}
+	})
+}
+
+// adjust 在当前快照上修改节点权重, 节点已经被移除时忽略
+func (r *WeightRoundRobinBalance) adjust(addr string, fn func(w *WeightNode)) {
+	rss := r.rss.Load()
+	if rss == nil {
+		return
+	}
+	rss.mu.Lock()
+	defer rss.mu.Unlock()
+	if node, ok := rss.index[addr]; ok {
+		fn(node)
+	}
+}
+
+// effectiveWeight 返回节点当前的有效权重, 节点不存在时返回 0
+func (r *WeightRoundRobinBalance) effectiveWeight(addr string) int {
+	rss := r.rss.Load()
+	if rss == nil {
+		return 0
+	}
+	rss.mu.Lock()
+	defer rss.mu.Unlock()
+	if node, ok := rss.index[addr]; ok {
+		return node.effectiveWeight
+	}
+	return 0
+}
+
 func (r *WeightRoundRobinBalance) SetConf(conf LoadBalanceConf) {
 	r.conf = conf
 }
 
 func (r *WeightRoundRobinBalance) Update() {
-	if conf, ok := r.conf.(*LoadBalanceCheckConf); ok {
-		r.rss = nil
+	if conf := r.conf; conf != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		old := r.rss.Load()
+		rss := newWeightNodes()
 		for _, ip := range conf.GetConf() {
-			r.Add(strings.Split(ip, ",")...)
+			params := strings.Split(ip, ",")
+			if len(params) != 2 {
+				continue
+			}
+			weight, err := strconv.Atoi(params[1])
+			if err != nil {
+				continue
+			}
+			rss.add(params[0], weight, old)
 		}
+		r.rss.Store(rss)
 	}
 }
@@ -12,7 +12,8 @@ import (
 
 type Model interface {
 	enity.Admin | enity.ServiceInfo | enity.AccessControl | enity.GrpcRule |
-		enity.HttpRule | enity.TcpRule | enity.LoadBalance | enity.App
+		enity.HttpRule | enity.TcpRule | enity.UdpRule | enity.LoadBalance | enity.App |
+		enity.Upstream
 }
 
 // PageList 分页查询
@@ -120,6 +121,16 @@ func get[T Model](db *gorm.DB, search *T) (*T, error) {
 	return &out, nil
 }
 
+// getUpstream 查询服务引用的上游, 上游不存在或已删除时返回空, 服务的负载均衡器无法创建
+func getUpstream(db *gorm.DB, upstreamID int64) (*enity.Upstream, error) {
+	upstream, err := get(db, &enity.Upstream{ID: upstreamID})
+	if err == gorm.ErrRecordNotFound || (err == nil && upstream.IsDelete == 1) {
+		log.Warn("upstream not found", zap.Int64("upstream_id", upstreamID))
+		return nil, nil
+	}
+	return upstream, err
+}
+
 func getServiceDetail(db *gorm.DB, search *enity.ServiceInfo) (*enity.ServiceDetail, error) {
 	// log记录查询信息
 	log.Info("start getting service detail")
@@ -132,7 +143,7 @@ func getServiceDetail(db *gorm.DB, search *enity.ServiceInfo) (*enity.ServiceDet
 		search = info
 	}
 
-	var httpRule, tcpRule, grpcRule interface{}
+	var httpRule, tcpRule, grpcRule, udpRule interface{}
 	var err error
 
 	// 优化后的查询代码
@@ -158,6 +169,13 @@ func getServiceDetail(db *gorm.DB, search *enity.ServiceInfo) (*enity.ServiceDet
 			return nil, err
 		}
 		log.Info("get grpc rule successful", zap.Any("grpcRule", grpcRule))
+	case globals.LoadTypeUDP:
+		udpRule, err = get(db, &enity.UdpRule{ServiceID: search.ID})
+		if err != nil && err != gorm.ErrRecordNotFound {
+			log.Error("error retrieving udp rule", zap.Error(err))
+			return nil, err
+		}
+		log.Info("get udp rule successful", zap.Any("udpRule", udpRule))
 	}
 
 	accessControl, err := get(db, &enity.AccessControl{ServiceID: search.ID})
@@ -179,6 +197,11 @@ func getServiceDetail(db *gorm.DB, search *enity.ServiceInfo) (*enity.ServiceDet
 		LoadBalance:   loadBalance,
 		AccessControl: accessControl,
 	}
+	if loadBalance != nil && loadBalance.UpstreamID > 0 {
+		if detail.Upstream, err = getUpstream(db, loadBalance.UpstreamID); err != nil {
+			return nil, err
+		}
+	}
 
 	if httpRule != nil {
 		if rule, ok := httpRule.(*enity.HttpRule); ok {
@@ -204,6 +227,14 @@ func getServiceDetail(db *gorm.DB, search *enity.ServiceInfo) (*enity.ServiceDet
 			return nil, fmt.Errorf("unexpected type for TCP rule: %T", tcpRule)
 		}
 	}
+	if udpRule != nil {
+		if rule, ok := udpRule.(*enity.UdpRule); ok {
+			detail.UDPRule = rule
+		} else {
+			log.Error("error retrieving udp rule: unexpected type", zap.Any("udpRule", udpRule))
+			return nil, fmt.Errorf("unexpected type for UDP rule: %T", udpRule)
+		}
+	}
 
 	// log记录成功取到信息
 	log.Info("get service detail successful", zap.Any("detail", detail))
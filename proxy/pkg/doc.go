@@ -18,6 +18,8 @@
 //
 // # GetLoadBalancer 获取LoadBalancer实例，如果不存在则创建一个新的实例并添加到映射中
 //
+// # CurrentLoadBalancer 按服务最新的详情获取LoadBalancer实例，tcp，udp，grpc监听器每个连接、会话或调用时使用
+//
 // # GetTransportor 获取Transportor实例，如果不存在则创建一个新的实例并添加到映射中
 //
 // GetApp 通过 appID 返回 app。
@@ -2,6 +2,7 @@ package pkg
 
 import (
 	"gateway/pkg/log"
+	"strings"
 	"sync"
 
 	"golang.org/x/time/rate"
@@ -40,7 +41,20 @@ func (fl *flowLimiter) GetLimiter(serviceName string, qps float64) (*rate.Limite
 	return newLimiter, nil
 }
 
+// Remove 删除服务级、客户端级以及按方法划分的限流器
 func (fl *flowLimiter) Remove(serviceName string) {
 	log.Debug("删除limiter")
 	fl.flowLimiterMap.Delete(serviceName)
+	fl.flowLimiterMap.Delete(serviceName + "_client")
+	fl.flowLimiterMap.Range(func(key, _ any) bool {
+		if strings.HasPrefix(key.(string), MethodLimiterKey(serviceName, "")) {
+			fl.flowLimiterMap.Delete(key)
+		}
+		return true
+	})
+}
+
+// MethodLimiterKey 返回按方法前缀限流时使用的限流器 key
+func MethodLimiterKey(serviceName, methodPrefix string) string {
+	return serviceName + "#method#" + methodPrefix
 }
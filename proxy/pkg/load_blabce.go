@@ -1,22 +1,68 @@
 package pkg
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"gateway/configs"
 	"gateway/enity"
 	"gateway/globals"
+	"gateway/metrics"
+	"gateway/pkg/database/redis"
+	"gateway/pkg/log"
 	"gateway/proxy/load_balance"
 	"gateway/utils"
 	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 // LoadBalanceAndTransport 接口组合了 GetLoadBalancer 和 GetTransportor 两个接口
 type LoadBalanceAndTransport interface {
 	GetLoadBalancer(service *enity.ServiceDetail) (load_balance.LoadBalance, error)
+	GetMethodLoadBalancers(service *enity.ServiceDetail) (map[string]load_balance.LoadBalance, error)
 	GetTransportor(service *enity.ServiceDetail) (*http.Transport, error)
+	UpdateNodes(service *enity.ServiceDetail)
+	Reload(current, updated *enity.ServiceDetail)
 	Remove(serviceName string)
+	RemoveUpstream(upstreamID int64)
+	Stats() RuntimeStats
+}
+
+// CurrentService 返回监听器所属服务在缓存中的最新详情, 服务已经删除时返回错误
+// TCP/UDP/gRPC 监听器启动后不会重建, 服务或上游修改后原来的负载均衡器已经关闭,
+// 每个连接、会话或调用都按最新详情重新获取负载均衡器, 与 HTTP 每个请求获取一次相同
+func CurrentService(detail *enity.ServiceDetail) (*enity.ServiceDetail, error) {
+	var current *enity.ServiceDetail
+	var ok bool
+	switch detail.Info.LoadType {
+	case globals.LoadTypeTCP:
+		current, ok = Cache.GetTcpService(detail.Info.ServiceName)
+	case globals.LoadTypeUDP:
+		current, ok = Cache.GetUdpService(detail.Info.ServiceName)
+	case globals.LoadTypeGRPC:
+		current, ok = Cache.GetGrpcService(detail.Info.ServiceName)
+	default:
+		return detail, nil
+	}
+	if !ok {
+		return nil, fmt.Errorf("service %s is deleted", detail.Info.ServiceName)
+	}
+	return current, nil
+}
+
+// CurrentLoadBalancer 按监听器所属服务的最新详情获取负载均衡器
+func CurrentLoadBalancer(detail *enity.ServiceDetail) (load_balance.LoadBalance, error) {
+	current, err := CurrentService(detail)
+	if err != nil {
+		return nil, err
+	}
+	return LoadBalanceTransport.GetLoadBalancer(current)
 }
 
 const (
@@ -28,8 +74,13 @@ const (
 )
 
 type loadBalanceAndTransport struct {
-	loadBalanceMap sync.Map // 存储LoadBalancerItem的同步映射
+	loadBalanceMap sync.Map // 存储 *balancer 的同步映射
 	transportMap   sync.Map // 存储TransportItem的同步映射
+
+	upstreamMu  sync.Mutex // 保证同一个上游只创建一份节点配置
+	upstreamMap sync.Map   // 存储被服务引用的上游的共享节点配置, key 为上游 id
+
+	previousNodes sync.Map // 被删除的负载均衡器的节点, 重建时不在其中的节点做慢启动
 }
 
 // NewLoadBalancer 返回一个新的 LoadBalancer 实例
@@ -37,12 +88,163 @@ func NewLoadBalancerAndTransport() *loadBalanceAndTransport {
 	return &loadBalanceAndTransport{
 		loadBalanceMap: sync.Map{},
 		transportMap:   sync.Map{},
+		upstreamMap:    sync.Map{},
 	}
 }
 
+// Remove 删除服务的负载均衡器和连接池, 停止探活等后台协程并关闭空闲连接, 处理中的请求不受影响
 func (lbr *loadBalanceAndTransport) Remove(serviceName string) {
-	lbr.loadBalanceMap.Delete(serviceName)
-	lbr.transportMap.Delete(serviceName)
+	lbr.removeBalancers(serviceName)
+	lbr.removeTransport(serviceName)
+}
+
+// Reload 服务配置修改后删除旧的负载均衡器, 下次请求、连接或会话时按新配置重建
+// 连接池的配置没有变化时(例如只修改了权重)保留连接池, 新的负载均衡器继续使用已经建立的连接
+func (lbr *loadBalanceAndTransport) Reload(current, updated *enity.ServiceDetail) {
+	serviceName := updated.Info.ServiceName
+	lbr.removeBalancers(serviceName)
+	if current == nil || transportSettingsOf(current) != transportSettingsOf(updated) {
+		lbr.removeTransport(serviceName)
+	}
+}
+
+// removeBalancers 删除服务及其方法路由的负载均衡器并停止探活、域名解析和服务发现
+func (lbr *loadBalanceAndTransport) removeBalancers(serviceName string) {
+	lbr.removeBalancer(serviceName)
+	metrics.RemoveNodeHealthMetrics(serviceName)
+	lbr.loadBalanceMap.Range(func(key, _ any) bool {
+		if strings.HasPrefix(key.(string), methodLoadBalancerKey(serviceName, "")) {
+			lbr.removeBalancer(key.(string))
+		}
+		return true
+	})
+}
+
+// removeBalancer 引用上游的服务只解除与共享配置的关联, 上游的探活继续进行
+func (lbr *loadBalanceAndTransport) removeBalancer(key string) {
+	if value, ok := lbr.loadBalanceMap.LoadAndDelete(key); ok {
+		item := value.(*balancer)
+		lbr.previousNodes.Store(key, item.nodes.conf.GetConf())
+		item.nodes.close()
+	}
+}
+
+// takePreviousNodes 返回并清除 key 上一个负载均衡器的节点, 没有时返回 nil
+func (lbr *loadBalanceAndTransport) takePreviousNodes(key string) []string {
+	if value, ok := lbr.previousNodes.LoadAndDelete(key); ok {
+		return value.([]string)
+	}
+	return nil
+}
+
+// removeTransport 删除连接池并关闭空闲连接, 处理中的请求结束后连接随 Transport 一起回收
+func (lbr *loadBalanceAndTransport) removeTransport(key string) {
+	if value, ok := lbr.transportMap.LoadAndDelete(key); ok {
+		value.(*http.Transport).CloseIdleConnections()
+	}
+}
+
+// RemoveUpstream 删除上游的共享节点配置和连接池, 引用该上游的服务需要另外调用 Remove 重建负载均衡器
+// 旧的共享配置停止探活, TCP/UDP/gRPC 监听器通过 CurrentLoadBalancer 在新的连接上挂到重建的共享配置
+func (lbr *loadBalanceAndTransport) RemoveUpstream(upstreamID int64) {
+	lbr.upstreamMu.Lock()
+	defer lbr.upstreamMu.Unlock()
+	if value, ok := lbr.upstreamMap.LoadAndDelete(upstreamID); ok {
+		item := value.(*sharedUpstream)
+		item.nodes.close()
+		metrics.RemoveNodeHealthMetrics(upstreamMetricsName(item.upstream.Name))
+	}
+	lbr.removeTransport(upstreamTransportKey(upstreamID))
+}
+
+// UpdateNodes 按服务最新的 forbid_list / drain_list 更新已创建的负载均衡器, 不重建负载均衡器和连接池
+func (lbr *loadBalanceAndTransport) UpdateNodes(service *enity.ServiceDetail) {
+	if service == nil || service.Info == nil || service.LoadBalance == nil {
+		return
+	}
+	serviceName := service.Info.ServiceName
+	lbr.loadBalanceMap.Range(func(key, value any) bool {
+		k := key.(string)
+		if k == serviceName || strings.HasPrefix(k, methodLoadBalancerKey(serviceName, "")) {
+			value.(*balancer).nodes.conf.ExcludeNodes(service.LoadBalance.ExcludedEntries())
+		}
+		return true
+	})
+}
+
+// balancer 负载均衡器及其节点配置, 从映射中删除时关闭节点配置
+type balancer struct {
+	lb         load_balance.LoadBalance
+	nodes      *nodeConf
+	roundType  int
+	upstreamID int64
+	createdAt  time.Time
+}
+
+func newBalancerItem(service *enity.ServiceDetail, lb load_balance.LoadBalance, nodes *nodeConf) *balancer {
+	return &balancer{
+		lb:         lb,
+		nodes:      nodes,
+		roundType:  service.LoadBalance.RoundType,
+		upstreamID: service.LoadBalance.UpstreamID,
+		createdAt:  time.Now(),
+	}
+}
+
+// storeBalancer 保存新建的负载均衡器, 并发请求已经保存了同一个 key 时关闭新建的, 避免探活协程泄漏
+func (lbr *loadBalanceAndTransport) storeBalancer(key string, item *balancer) load_balance.LoadBalance {
+	if actual, loaded := lbr.loadBalanceMap.LoadOrStore(key, item); loaded {
+		item.nodes.close()
+		return actual.(*balancer).lb
+	}
+	return item.lb
+}
+
+// nodeConf 负载均衡器的节点配置, 用于运行时禁用/排空节点
+type nodeConf struct {
+	conf      load_balance.LoadBalanceConf
+	dns       *load_balance.DNSWatcher       // 上游条目中有域名或 SRV 记录时定期解析, 否则为空
+	discovery *load_balance.DiscoveryWatcher // 配置了服务发现时监听节点变化, 否则为空
+}
+
+// start 负载均衡器创建后开始域名解析和服务发现
+func (n *nodeConf) start() {
+	if n.dns != nil {
+		n.dns.Start()
+	}
+	if n.discovery != nil {
+		n.discovery.Start()
+	}
+}
+
+func (n *nodeConf) close() {
+	n.conf.Close()
+	if n.dns != nil {
+		n.dns.Close()
+	}
+	if n.discovery != nil {
+		n.discovery.Close()
+	}
+}
+
+// sharedUpstream 被多个服务引用的上游, 节点配置和探活只有一份, 各服务通过 LoadBalanceConfView 使用
+type sharedUpstream struct {
+	upstream *enity.Upstream
+	conf     *load_balance.LoadBalanceCheckConf
+	nodes    *nodeConf
+}
+
+func upstreamTransportKey(upstreamID int64) string {
+	return fmt.Sprintf("upstream#%d", upstreamID)
+}
+
+// upstreamMetricsName 上游节点健康指标使用的服务名, 与服务本身的指标区分
+func upstreamMetricsName(name string) string {
+	return "upstream:" + name
+}
+
+func methodLoadBalancerKey(serviceName, methodPrefix string) string {
+	return serviceName + "#method#" + methodPrefix
 }
 
 // GetLoadBalancer 获取LoadBalancer实例，如果不存在则创建一个新的实例并添加到映射中
@@ -50,28 +252,45 @@ func (lbr *loadBalanceAndTransport) GetLoadBalancer(service *enity.ServiceDetail
 	if service == nil || service.Info == nil || service.LoadBalance == nil {
 		return nil, fmt.Errorf("service or service info or load balance is nil")
 	}
-	if service.GRPCRule == nil && service.HTTPRule == nil && service.TCPRule == nil {
-		return nil, fmt.Errorf("grpc rule, http rule and tcp rule are all nil")
+	if service.GRPCRule == nil && service.HTTPRule == nil && service.TCPRule == nil && service.UDPRule == nil {
+		return nil, fmt.Errorf("grpc rule, http rule, tcp rule and udp rule are all nil")
 	}
-	if ipList := utils.SplitStringByComma(service.LoadBalance.IpList); ipList == nil {
-		return nil, fmt.Errorf("ip list is nil")
+	if service.LoadBalance.UpstreamID > 0 && service.Upstream == nil {
+		return nil, fmt.Errorf("upstream %d of service %s is not loaded", service.LoadBalance.UpstreamID, service.Info.ServiceName)
 	}
-	if weightList := utils.SplitStringByComma(service.LoadBalance.WeightList); weightList == nil {
-		return nil, fmt.Errorf("weight list is nil")
+	// 使用服务发现时 ip_list 只是可选的初始节点, 引用上游时节点来自上游
+	if service.LoadBalance.DiscoveryType == "" && service.Upstream == nil {
+		if ipList := utils.SplitStringByComma(service.LoadBalance.IpList); ipList == nil {
+			return nil, fmt.Errorf("ip list is nil")
+		}
+		if weightList := utils.SplitStringByComma(service.LoadBalance.WeightList); weightList == nil {
+			return nil, fmt.Errorf("weight list is nil")
+		}
 	}
 
 	if lbrItem, ok := lbr.loadBalanceMap.Load(service.Info.ServiceName); ok {
-		return lbrItem.(load_balance.LoadBalance), nil
+		return lbrItem.(*balancer).lb, nil
 	}
 
 	schema := "http://"
 	if service.HTTPRule != nil && service.HTTPRule.NeedHttps == 1 {
 		schema = "https://"
 	}
-	if service.Info.LoadType == globals.LoadTypeTCP || service.Info.LoadType == globals.LoadTypeGRPC {
+	if service.Upstream != nil && service.Upstream.TLSEnable == 1 {
+		schema = "https://"
+	}
+	if service.Info.LoadType == globals.LoadTypeTCP || service.Info.LoadType == globals.LoadTypeGRPC || service.Info.LoadType == globals.LoadTypeUDP {
 		schema = ""
 	}
 
+	if service.Upstream != nil {
+		lb, conf, err := lbr.newUpstreamLoadBalancer(service, schema, lbr.takePreviousNodes(service.Info.ServiceName))
+		if err != nil {
+			return nil, err
+		}
+		return lbr.storeBalancer(service.Info.ServiceName, newBalancerItem(service, lb, conf)), nil
+	}
+
 	ipConf := make(map[string]string)
 	if ipList := utils.SplitStringByComma(service.LoadBalance.IpList); ipList != nil {
 		weightList := utils.SplitStringByComma(service.LoadBalance.WeightList)
@@ -84,52 +303,300 @@ func (lbr *loadBalanceAndTransport) GetLoadBalancer(service *enity.ServiceDetail
 		}
 	}
 
-	mConf, err := load_balance.NewLoadBalanceCheckConf(fmt.Sprintf("%s%s", schema, "%s"), ipConf)
+	discovery, err := newDiscovery(service.LoadBalance.DiscoveryType, service.LoadBalance.DiscoveryTarget)
 	if err != nil {
 		return nil, err
 	}
+	lb, conf, err := newLoadBalancer(service, schema, ipConf, discovery, lbr.takePreviousNodes(service.Info.ServiceName))
+	if err != nil {
+		return nil, err
+	}
+	return lbr.storeBalancer(service.Info.ServiceName, newBalancerItem(service, lb, conf)), nil
+}
 
-	lb := load_balance.LoadBanlanceFactorWithConf(load_balance.LbType(service.LoadBalance.RoundType), mConf)
-	lbr.loadBalanceMap.Store(service.Info.ServiceName, lb)
+// GetMethodLoadBalancers 根据 grpc 规则中的 method_route 为每个方法前缀创建独立的负载均衡器
+// 返回以方法前缀为 key 的映射, 未配置方法路由时返回空映射
+func (lbr *loadBalanceAndTransport) GetMethodLoadBalancers(service *enity.ServiceDetail) (map[string]load_balance.LoadBalance, error) {
+	if service == nil || service.Info == nil || service.LoadBalance == nil {
+		return nil, fmt.Errorf("service or service info or load balance is nil")
+	}
+	lbs := make(map[string]load_balance.LoadBalance)
+	if service.GRPCRule == nil || service.GRPCRule.MethodRoute == "" {
+		return lbs, nil
+	}
+	for _, route := range utils.SplitStringByComma(service.GRPCRule.MethodRoute) {
+		items := strings.Fields(route)
+		if len(items) < 2 {
+			return nil, fmt.Errorf("invalid method route: %s", route)
+		}
+		prefix := items[0]
+		key := methodLoadBalancerKey(service.Info.ServiceName, prefix)
+		if lbrItem, ok := lbr.loadBalanceMap.Load(key); ok {
+			lbs[prefix] = lbrItem.(*balancer).lb
+			continue
+		}
+		ipConf := make(map[string]string)
+		for _, addr := range items[1:] {
+			ipConf[addr] = "1"
+		}
+		lb, conf, err := newLoadBalancer(service, "", ipConf, nil, lbr.takePreviousNodes(key))
+		if err != nil {
+			return nil, err
+		}
+		lbs[prefix] = lbr.storeBalancer(key, newBalancerItem(service, lb, conf))
+	}
+	return lbs, nil
+}
+
+// newLoadBalancer 创建负载均衡器, forbid_list / drain_list 中的节点不参与选择, 设置了优先级列表时按可用权重在优先级之间切换
+// discovery 不为空时节点由服务发现提供, ipConf 中的节点只在第一次获取成功之前使用
+// previous 为重建前的节点, 不在其中的节点做慢启动
+func newLoadBalancer(service *enity.ServiceDetail, schema string, ipConf map[string]string, discovery load_balance.Discovery, previous []string) (load_balance.LoadBalance, *nodeConf, error) {
+	// UDP 上游无法通过 tcp 握手探活
+	checkMethod := load_balance.CheckMethodTcp
+	if service.Info.LoadType == globals.LoadTypeUDP {
+		checkMethod = load_balance.CheckMethodNone
+	}
+	nodes, conf, err := newNodeConf(service.Info.ServiceName, fmt.Sprintf("%s%s", schema, "%s"), ipConf, discovery, load_balance.CheckPolicy{Method: checkMethod})
+	if err != nil {
+		return nil, nil, err
+	}
+	conf.SetPriorities(service.LoadBalance.Priorities(), float64(service.LoadBalance.FailoverThreshold)/100)
+	conf.ExcludeNodes(service.LoadBalance.ExcludedEntries())
+	lb := newBalancer(service, nodes.conf, previous)
+	nodes.start()
+	return lb, nodes, nil
+}
+
+// newUpstreamLoadBalancer 为引用上游的服务创建负载均衡器, 节点和探活结果来自上游的共享配置
+// 服务自己的 forbid_list / drain_list 只作用于当前服务
+func (lbr *loadBalanceAndTransport) newUpstreamLoadBalancer(service *enity.ServiceDetail, schema string, previous []string) (load_balance.LoadBalance, *nodeConf, error) {
+	shared, err := lbr.getSharedUpstream(service.Upstream)
+	if err != nil {
+		return nil, nil, err
+	}
+	view := load_balance.NewLoadBalanceConfView(shared.conf, fmt.Sprintf("%s%s", schema, "%s"))
+	view.ExcludeNodes(service.LoadBalance.ExcludedEntries())
+	return newBalancer(service, view, previous), &nodeConf{conf: view}, nil
+}
+
+// getSharedUpstream 返回上游的共享节点配置, 第一个引用该上游的服务创建负载均衡器时创建
+func (lbr *loadBalanceAndTransport) getSharedUpstream(upstream *enity.Upstream) (*sharedUpstream, error) {
+	lbr.upstreamMu.Lock()
+	defer lbr.upstreamMu.Unlock()
+	if value, ok := lbr.upstreamMap.Load(upstream.ID); ok {
+		return value.(*sharedUpstream), nil
+	}
+
+	policy := load_balance.CheckPolicy{
+		Method:    upstream.CheckMethod,
+		Path:      upstream.CheckPath,
+		Timeout:   time.Duration(upstream.CheckTimeout) * time.Second,
+		Interval:  time.Duration(upstream.CheckInterval) * time.Second,
+		MaxErrNum: upstream.CheckMaxErrNum,
+	}
+	tlsConfig, err := upstreamTLSConfig(upstream)
+	if err != nil {
+		return nil, err
+	}
+	policy.TLS = tlsConfig
+	discovery, err := newDiscovery(upstream.DiscoveryType, upstream.DiscoveryTarget)
+	if err != nil {
+		return nil, err
+	}
+	// 共享配置只保存节点地址, 各服务的视图再加上自己的 schema
+	nodes, conf, err := newNodeConf(upstreamMetricsName(upstream.Name), "%s", upstream.Nodes(), discovery, policy)
+	if err != nil {
+		return nil, err
+	}
+	conf.SetPriorities(upstream.Priorities(), float64(upstream.FailoverThreshold)/100)
+	conf.ExcludeNodes(upstream.ExcludedEntries())
+	nodes.start()
+	shared := &sharedUpstream{upstream: upstream, conf: conf, nodes: nodes}
+	lbr.upstreamMap.Store(upstream.ID, shared)
+	return shared, nil
+}
 
-	return lb, nil
+// newNodeConf 创建节点配置并按探活策略开始探活, name 为节点健康指标使用的服务名
+// 域名和 SRV 条目先同步解析一次, 服务发现先同步获取一次, 创建后即有可用节点; 调用方创建负载均衡器后调用 start
+func newNodeConf(name, format string, ipConf map[string]string, discovery load_balance.Discovery, policy load_balance.CheckPolicy) (*nodeConf, *load_balance.LoadBalanceCheckConf, error) {
+	staticConf := make(map[string]string, len(ipConf))
+	hasDNS := false
+	for node, weight := range ipConf {
+		if load_balance.IsDNSEntry(node) {
+			hasDNS = true
+			continue
+		}
+		staticConf[node] = weight
+	}
+	mConf, err := load_balance.NewLoadBalanceCheckConfWithPolicy(format, staticConf, policy,
+		func(node string, healthy bool) {
+			metrics.RecordNodeHealthMetrics(name, node, healthy)
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes := &nodeConf{conf: mConf}
+	if discovery != nil {
+		nodes.discovery = load_balance.NewDiscoveryWatcher(discovery, mConf)
+		if err := nodes.discovery.Refresh(context.Background()); err != nil {
+			log.Warn("discover upstream nodes failed", zap.String("service", name), zap.Error(err))
+		}
+	} else if hasDNS {
+		if nodes.dns, err = load_balance.NewDNSWatcher(dnsResolver(), mConf, ipConf); err != nil {
+			mConf.Close()
+			return nil, nil, err
+		}
+		nodes.dns.Refresh(context.Background())
+	}
+	return nodes, mConf, nil
+}
+
+// newBalancer 按服务的轮询方式创建负载均衡器, 引用上游时使用上游的慢启动时长
+func newBalancer(service *enity.ServiceDetail, conf load_balance.LoadBalanceConf, previous []string) load_balance.LoadBalance {
+	slowStart := service.LoadBalance.SlowStart
+	if service.Upstream != nil {
+		slowStart = service.Upstream.SlowStart
+	}
+	return load_balance.LoadBanlanceFactorWithOptions(load_balance.LbType(service.LoadBalance.RoundType), conf, load_balance.Options{
+		HashReplicas:   service.LoadBalance.HashReplicas,
+		HashLoadFactor: float64(service.LoadBalance.HashLoadFactor) / 100,
+		SlowStart:      time.Duration(slowStart) * time.Second,
+		PreviousNodes:  previous,
+	})
+}
+
+// upstreamTLSConfig 按上游的 TLS 配置创建 tls.Config, 未启用 TLS 时返回空
+func upstreamTLSConfig(upstream *enity.Upstream) (*tls.Config, error) {
+	if upstream.TLSEnable != 1 {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         upstream.TLSServerName,
+		InsecureSkipVerify: upstream.TLSInsecureSkipVerify == 1,
+	}
+	if upstream.TLSCACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(upstream.TLSCACert)) {
+			return nil, fmt.Errorf("invalid CA certificate of upstream %s", upstream.Name)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// newDiscovery 按服务或上游的服务发现配置创建服务发现, 使用 ip_list 中的静态节点时返回空
+func newDiscovery(discoveryType, target string) (load_balance.Discovery, error) {
+	interval := time.Duration(configs.GetDiscoveryConfig().Interval) * time.Second
+	switch discoveryType {
+	case "":
+		return nil, nil
+	case enity.DiscoveryFile:
+		return load_balance.NewFileDiscovery(target), nil
+	case enity.DiscoveryRedis:
+		client := redis.GetRedisConnection()
+		if client == nil {
+			return nil, fmt.Errorf("redis is not initialized")
+		}
+		return load_balance.NewRedisDiscovery(client, target, interval), nil
+	case enity.DiscoveryHTTP:
+		return load_balance.NewHTTPDiscovery(target, interval), nil
+	}
+	return nil, fmt.Errorf("unknown discovery type %s", discoveryType)
+}
+
+// dnsResolver 按配置创建上游域名解析器
+func dnsResolver() *load_balance.DNSResolver {
+	conf := configs.GetDNSConfig()
+	return &load_balance.DNSResolver{
+		Server:     conf.Server,
+		Timeout:    time.Duration(conf.Timeout) * time.Second,
+		MinTTL:     time.Duration(conf.MinTTL) * time.Second,
+		MaxTTL:     time.Duration(conf.MaxTTL) * time.Second,
+		DefaultTTL: time.Duration(conf.DefaultTTL) * time.Second,
+	}
+}
+
+// transportSettings 创建连接池使用的配置, 相同时服务重建可以继续使用原来的连接池
+type transportSettings struct {
+	upstreamID     int64
+	connectTimeout int
+	maxIdle        int
+	idleTimeout    int
+	headerTimeout  int
+}
+
+func transportSettingsOf(service *enity.ServiceDetail) transportSettings {
+	lb := service.LoadBalance
+	if lb == nil {
+		return transportSettings{}
+	}
+	return transportSettings{
+		upstreamID:     lb.UpstreamID,
+		connectTimeout: orDefault(lb.UpstreamConnectTimeout, defaultUpstreamConnectTimeout),
+		maxIdle:        orDefault(lb.UpstreamMaxIdle, defaultUpstreamMaxIdle),
+		idleTimeout:    orDefault(lb.UpstreamIdleTimeout, defaultUpstreamIdleTimeout),
+		headerTimeout:  orDefault(lb.UpstreamHeaderTimeout, defaultUpstreamHeaderTimeout),
+	}
+}
+
+func orDefault(value, def int) int {
+	if value == 0 {
+		return def
+	}
+	return value
 }
 
 // GetTransportor 根据服务详情获取Transportor实例，如果映射中不存在则创建一个新的实例并添加到映射中
+// 引用上游的服务使用上游的超时和 TLS 配置, 同一个上游的服务共用一个连接池
 func (t *loadBalanceAndTransport) GetTransportor(service *enity.ServiceDetail) (*http.Transport, error) {
+	if service.Upstream != nil {
+		return t.getUpstreamTransportor(service.Upstream)
+	}
 	// 如果已经存在该服务的 TransportItem，则直接返回
 	if transItem, ok := t.transportMap.Load(service.Info.ServiceName); ok {
 		return transItem.(*http.Transport), nil
 	}
 
 	// 如果不存在该服务的 TransportItem，则创建一个新的实例并添加到映射中
-	if service.LoadBalance.UpstreamConnectTimeout == 0 {
-		service.LoadBalance.UpstreamConnectTimeout = defaultUpstreamConnectTimeout
-	}
-	if service.LoadBalance.UpstreamMaxIdle == 0 {
-		service.LoadBalance.UpstreamMaxIdle = defaultUpstreamMaxIdle
-	}
-	if service.LoadBalance.UpstreamIdleTimeout == 0 {
-		service.LoadBalance.UpstreamIdleTimeout = defaultUpstreamIdleTimeout
+	settings := transportSettingsOf(service)
+	trans := newTransport(settings.connectTimeout, settings.maxIdle, settings.idleTimeout, settings.headerTimeout, nil)
+
+	// 将 TransportItem 添加到映射中并返回
+	actual, _ := t.transportMap.LoadOrStore(service.Info.ServiceName, trans)
+	return actual.(*http.Transport), nil
+}
+
+// getUpstreamTransportor 返回上游共用的连接池, 上游修改后由 RemoveUpstream 删除
+func (t *loadBalanceAndTransport) getUpstreamTransportor(upstream *enity.Upstream) (*http.Transport, error) {
+	key := upstreamTransportKey(upstream.ID)
+	if transItem, ok := t.transportMap.Load(key); ok {
+		return transItem.(*http.Transport), nil
 	}
-	if service.LoadBalance.UpstreamHeaderTimeout == 0 {
-		service.LoadBalance.UpstreamHeaderTimeout = defaultUpstreamHeaderTimeout
+	tlsConfig, err := upstreamTLSConfig(upstream)
+	if err != nil {
+		return nil, err
 	}
-	trans := &http.Transport{
+	trans := newTransport(orDefault(upstream.ConnectTimeout, defaultUpstreamConnectTimeout), orDefault(upstream.MaxIdle, defaultUpstreamMaxIdle),
+		orDefault(upstream.IdleTimeout, defaultUpstreamIdleTimeout), orDefault(upstream.HeaderTimeout, defaultUpstreamHeaderTimeout), tlsConfig)
+	actual, _ := t.transportMap.LoadOrStore(key, trans)
+	return actual.(*http.Transport), nil
+}
+
+// newTransport 创建访问上游的 Transport, 超时单位为秒, tlsConfig 为空时使用默认配置
+func newTransport(connectTimeout, maxIdle, idleTimeout, headerTimeout int, tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
 		Proxy: http.ProxyFromEnvironment, // 从系统环境中获取代理信息（如果存在），并将其应用于请求
 		DialContext: (&net.Dialer{ // 初始化 Dialer，控制如何建立与上游服务的连接，并绑定到 Transport 的 DialContext 字段上
-			Timeout:   time.Duration(service.LoadBalance.UpstreamConnectTimeout) * time.Second, // 建立连接的超时时间
-			KeepAlive: defaultKeepAliveDuration,                                                // 连接保持的时间
-			DualStack: true,                                                                    // 是否启用 IPv6 和 IPv4
+			Timeout:   time.Duration(connectTimeout) * time.Second, // 建立连接的超时时间
+			KeepAlive: defaultKeepAliveDuration,                    // 连接保持的时间
+			DualStack: true,                                        // 是否启用 IPv6 和 IPv4
 		}).DialContext,
-		ForceAttemptHTTP2:     true,                                                                   // 启用 HTTP/2 支持
-		MaxIdleConns:          service.LoadBalance.UpstreamMaxIdle,                                    // 最大空闲连接数，最多允许保持多少个空闲的连接
-		IdleConnTimeout:       time.Duration(service.LoadBalance.UpstreamIdleTimeout) * time.Second,   // 空闲连接的超时时间
-		TLSHandshakeTimeout:   10 * time.Second,                                                       // TLS 握手超时时间
-		ResponseHeaderTimeout: time.Duration(service.LoadBalance.UpstreamHeaderTimeout) * time.Second, // 响应头部超时时间
+		ForceAttemptHTTP2:     true,                                       // 启用 HTTP/2 支持
+		MaxIdleConns:          maxIdle,                                    // 最大空闲连接数，最多允许保持多少个空闲的连接
+		IdleConnTimeout:       time.Duration(idleTimeout) * time.Second,   // 空闲连接的超时时间
+		TLSHandshakeTimeout:   10 * time.Second,                           // TLS 握手超时时间
+		ResponseHeaderTimeout: time.Duration(headerTimeout) * time.Second, // 响应头部超时时间
+		TLSClientConfig:       tlsConfig,                                  // 上游证书校验配置
 	}
-
-	// 将 TransportItem 添加到映射中并返回
-	t.transportMap.Store(service.Info.ServiceName, trans)
-	return trans, nil
 }
@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"gateway/enity"
+	"gateway/globals"
+	"gateway/proxy/load_balance"
+	"net"
+	"testing"
+	"time"
+)
+
+// listenNode 启动一个只接受连接的本地节点, 用于 tcp 探活
+func listenNode(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// setupListener 使用只包含给定 TCP 服务的缓存和新的负载均衡器映射, 与监听器启动时一样先创建一次负载均衡器
+func setupListener(t *testing.T, detail *enity.ServiceDetail) *serviceCache {
+	t.Helper()
+	services := NewServiceCache()
+	services.TCPServices.Store(detail.Info.ServiceName, detail)
+	Cache = &appCacheAndServiceCache{ServiceCache: services}
+	LoadBalanceTransport = NewLoadBalancerAndTransport()
+	t.Cleanup(func() {
+		LoadBalanceTransport.Remove(detail.Info.ServiceName)
+		Cache, LoadBalanceTransport = nil, nil
+	})
+	if _, err := LoadBalanceTransport.GetLoadBalancer(detail); err != nil {
+		t.Fatal(err)
+	}
+	return services
+}
+
+func tcpService(ipList, weightList string) *enity.ServiceDetail {
+	return &enity.ServiceDetail{
+		Info:    &enity.ServiceInfo{ServiceName: "redis_tcp", LoadType: globals.LoadTypeTCP},
+		TCPRule: &enity.TcpRule{Port: 8001},
+		LoadBalance: &enity.LoadBalance{
+			RoundType:  int(load_balance.LbRoundRobin),
+			IpList:     ipList,
+			WeightList: weightList,
+		},
+	}
+}
+
+// editService 与 UpdateServiceCache 一样保存新的详情并重建负载均衡器
+func editService(services *serviceCache, current, updated *enity.ServiceDetail) {
+	services.TCPServices.Store(updated.Info.ServiceName, updated)
+	LoadBalanceTransport.Reload(current, updated)
+}
+
+// selected 多次选择节点, 返回选中过的节点
+func selected(t *testing.T, lb load_balance.LoadBalance) map[string]bool {
+	t.Helper()
+	nodes := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		addr, err := lb.Get("127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[addr] = true
+	}
+	return nodes
+}
+
+// TestCurrentLoadBalancerAfterEdit 服务修改后监听器获取的负载均衡器仍然接收探活结果, 下线的节点不再被选择
+func TestCurrentLoadBalancerAfterEdit(t *testing.T) {
+	a, b := listenNode(t).Addr().String(), listenNode(t)
+	startup := tcpService(a+","+b.Addr().String(), "50,50")
+	services := setupListener(t, startup)
+
+	edited := tcpService(startup.LoadBalance.IpList, "60,50")
+	editService(services, startup, edited)
+	b.Close()
+
+	deadline := time.Now().Add(3 * load_balance.DefaultCheckInterval * time.Second)
+	for {
+		lb, err := CurrentLoadBalancer(startup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes := selected(t, lb)
+		if !nodes[b.Addr().String()] {
+			if !nodes[a] {
+				t.Fatalf("selected %v, want %s", nodes, a)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("node %s is still selected after it stopped accepting connections", b.Addr())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// TestUpdateNodesAfterEdit 服务修改后排空节点, 监听器获取的负载均衡器不再选择该节点
+func TestUpdateNodesAfterEdit(t *testing.T) {
+	a, b := listenNode(t).Addr().String(), listenNode(t).Addr().String()
+	startup := tcpService(a+","+b, "50,50")
+	services := setupListener(t, startup)
+
+	edited := tcpService(startup.LoadBalance.IpList, "60,50")
+	editService(services, startup, edited)
+	lb, err := CurrentLoadBalancer(startup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	drained := tcpService(edited.LoadBalance.IpList, edited.LoadBalance.WeightList)
+	drained.LoadBalance.DrainList = b
+	services.TCPServices.Store(drained.Info.ServiceName, drained)
+	LoadBalanceTransport.UpdateNodes(drained)
+
+	current, err := CurrentLoadBalancer(startup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != lb {
+		t.Fatal("draining a node should not rebuild the load balancer")
+	}
+	if nodes := selected(t, current); nodes[b] || !nodes[a] {
+		t.Fatalf("selected %v after draining %s", nodes, b)
+	}
+
+	// 服务删除后监听器不再获取负载均衡器
+	services.TCPServices.Delete(startup.Info.ServiceName)
+	if _, err := CurrentLoadBalancer(startup); err == nil {
+		t.Fatal("a deleted service should not get a load balancer")
+	}
+}
+
+// TestCurrentLoadBalancerAfterUpstreamEdit 上游修改后监听器获取的负载均衡器使用新的共享配置, 继续接收节点变化
+func TestCurrentLoadBalancerAfterUpstreamEdit(t *testing.T) {
+	a, b, c := listenNode(t).Addr().String(), listenNode(t).Addr().String(), listenNode(t).Addr().String()
+	upstream := &enity.Upstream{ID: 1, Name: "redis", IpList: a, WeightList: "50"}
+	startup := tcpService("", "")
+	startup.LoadBalance.UpstreamID = upstream.ID
+	startup.Upstream = upstream
+	services := setupListener(t, startup)
+	t.Cleanup(func() { LoadBalanceTransport.RemoveUpstream(upstream.ID) })
+
+	// 与 UpdateUpstream 相同: 先替换服务详情, 再删除共享配置和服务的负载均衡器
+	edited := *upstream
+	edited.IpList = b
+	detail := *startup
+	detail.Upstream = &edited
+	services.TCPServices.Store(detail.Info.ServiceName, &detail)
+	LoadBalanceTransport.RemoveUpstream(upstream.ID)
+	LoadBalanceTransport.Remove(detail.Info.ServiceName)
+
+	lb, err := CurrentLoadBalancer(startup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodes := selected(t, lb); len(nodes) != 1 || !nodes[b] {
+		t.Fatalf("selected %v after editing the upstream, want %s", nodes, b)
+	}
+
+	// 服务发现或域名解析更新共享配置的节点后, 监听器使用的负载均衡器随之更新
+	value, ok := LoadBalanceTransport.(*loadBalanceAndTransport).upstreamMap.Load(upstream.ID)
+	if !ok {
+		t.Fatal("shared upstream is not rebuilt")
+	}
+	value.(*sharedUpstream).conf.SetNodes(map[string]string{c: "50"}, nil)
+	if nodes := selected(t, lb); len(nodes) != 1 || !nodes[c] {
+		t.Fatalf("selected %v after the upstream nodes changed, want %s", nodes, c)
+	}
+}
@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"gateway/proxy/load_balance"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// RuntimeStats 运行时的负载均衡器、共享上游、连接池和后台协程, 用于排查服务重建后资源是否被回收
+type RuntimeStats struct {
+	Balancers  []BalancerStats `json:"balancers"`
+	Upstreams  []UpstreamStats `json:"upstreams"`
+	Transports []string        `json:"transports"` // 连接池的 key, 服务名或 upstream#<id>
+	Checkers   int64           `json:"checkers"`   // 主动探活协程数
+	Watchers   int64           `json:"watchers"`   // 域名解析和服务发现协程数
+	Goroutines int             `json:"goroutines"` // 进程的全部协程数
+}
+
+// BalancerStats 一个负载均衡器, key 为服务名, grpc 方法路由为 <服务名>#method#<方法前缀>
+type BalancerStats struct {
+	Key        string    `json:"key"`
+	RoundType  int       `json:"round_type"`
+	UpstreamID int64     `json:"upstream_id,omitempty"`
+	Nodes      []string  `json:"nodes"` // 当前可选的节点及权重
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// UpstreamStats 被服务引用的上游的共享节点配置
+type UpstreamStats struct {
+	ID    int64    `json:"id"`
+	Name  string   `json:"name"`
+	Nodes []string `json:"nodes"`
+}
+
+func (lbr *loadBalanceAndTransport) Stats() RuntimeStats {
+	stats := RuntimeStats{
+		Balancers:  []BalancerStats{},
+		Upstreams:  []UpstreamStats{},
+		Transports: []string{},
+		Checkers:   load_balance.ActiveCheckers(),
+		Watchers:   load_balance.ActiveWatchers(),
+		Goroutines: runtime.NumGoroutine(),
+	}
+	lbr.loadBalanceMap.Range(func(key, value any) bool {
+		item := value.(*balancer)
+		stats.Balancers = append(stats.Balancers, BalancerStats{
+			Key:        key.(string),
+			RoundType:  item.roundType,
+			UpstreamID: item.upstreamID,
+			Nodes:      item.nodes.conf.GetConf(),
+			CreatedAt:  item.createdAt,
+		})
+		return true
+	})
+	lbr.upstreamMap.Range(func(_, value any) bool {
+		item := value.(*sharedUpstream)
+		stats.Upstreams = append(stats.Upstreams, UpstreamStats{
+			ID:    item.upstream.ID,
+			Name:  item.upstream.Name,
+			Nodes: item.conf.GetConf(),
+		})
+		return true
+	})
+	lbr.transportMap.Range(func(key, _ any) bool {
+		stats.Transports = append(stats.Transports, key.(string))
+		return true
+	})
+	sort.Slice(stats.Balancers, func(i, j int) bool { return stats.Balancers[i].Key < stats.Balancers[j].Key })
+	sort.Slice(stats.Upstreams, func(i, j int) bool { return stats.Upstreams[i].ID < stats.Upstreams[j].ID })
+	sort.Strings(stats.Transports)
+	return stats
+}
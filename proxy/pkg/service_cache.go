@@ -19,9 +19,15 @@ import (
 type ServiceCache interface {
 	LoadService() error
 	UpdateServiceCache(serviceName string, serviceType int, operation string) error
+	UpdateServiceNodes(serviceName string, serviceType int) error
+	UpdateUpstream(upstreamID int64) error
 	HTTPAccessMode(c *gin.Context) (*enity.ServiceDetail, error)
 	GetGrpcServiceList() []*enity.ServiceDetail
+	GetGrpcService(serviceName string) (*enity.ServiceDetail, bool)
 	GetTcpServiceList() []*enity.ServiceDetail
+	GetTcpService(serviceName string) (*enity.ServiceDetail, bool)
+	GetUdpServiceList() []*enity.ServiceDetail
+	GetUdpService(serviceName string) (*enity.ServiceDetail, bool)
 }
 
 type serviceCache struct {
@@ -29,6 +35,7 @@ type serviceCache struct {
 	HTTPServices *sync.Map
 	TCPServices  *sync.Map
 	GRPCServices *sync.Map
+	UDPServices  *sync.Map
 	sf           singleflight.Group
 }
 
@@ -39,6 +46,7 @@ func NewServiceCache() *serviceCache {
 		HTTPServices: &sync.Map{},
 		TCPServices:  &sync.Map{},
 		GRPCServices: &sync.Map{},
+		UDPServices:  &sync.Map{},
 		sf:           singleflight.Group{},
 	}
 }
@@ -74,6 +82,8 @@ func (s *serviceCache) LoadService() error {
 			s.TCPServices.Store(tmpItem.ServiceName, serviceDetail)
 		case globals.LoadTypeGRPC:
 			s.GRPCServices.Store(tmpItem.ServiceName, serviceDetail)
+		case globals.LoadTypeUDP:
+			s.UDPServices.Store(tmpItem.ServiceName, serviceDetail)
 		}
 	}
 
@@ -98,6 +108,8 @@ func (s *serviceCache) UpdateServiceCache(serviceName string, serviceType int, o
 		serviceMap = s.TCPServices
 	case globals.LoadTypeGRPC:
 		serviceMap = s.GRPCServices
+	case globals.LoadTypeUDP:
+		serviceMap = s.UDPServices
 	default:
 		return fmt.Errorf("invalid service type")
 	}
@@ -116,23 +128,94 @@ func (s *serviceCache) UpdateServiceCache(serviceName string, serviceType int, o
 		return err
 	}
 
-	// 移除负载均衡和传输层的缓存
-	LoadBalanceTransport.Remove(serviceName)
 	FlowLimiter.Remove(serviceName)
 
-	// 将新的服务详情设置到缓存
+	// 将新的服务详情设置到缓存, 并关闭旧的负载均衡器和连接池
 	switch operation {
 	case globals.DataInsert, globals.DataUpdate:
+		var current *enity.ServiceDetail
+		if cached, ok := serviceMap.Load(serviceName); ok {
+			current = cached.(*enity.ServiceDetail)
+		}
 		serviceMap.Store(serviceName, updatedServiceDetail)
+		LoadBalanceTransport.Reload(current, updatedServiceDetail)
 		return nil
 	case globals.DataDelete:
 		serviceMap.Delete(serviceName)
+		LoadBalanceTransport.Remove(serviceName)
 		return nil
 	default:
 		return fmt.Errorf("invalid operation")
 	}
+}
 
-	return err
+// UpdateServiceNodes 节点被禁用/排空/启用后重新读取负载均衡配置
+// 只更新负载均衡器的可选节点, 不重建负载均衡器和连接池, 处理中的请求不受影响
+func (s *serviceCache) UpdateServiceNodes(serviceName string, serviceType int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx := mysql.GetDB()
+
+	var serviceMap *sync.Map
+	switch serviceType {
+	case globals.LoadTypeHTTP:
+		serviceMap = s.HTTPServices
+	case globals.LoadTypeTCP:
+		serviceMap = s.TCPServices
+	case globals.LoadTypeGRPC:
+		serviceMap = s.GRPCServices
+	case globals.LoadTypeUDP:
+		serviceMap = s.UDPServices
+	default:
+		return fmt.Errorf("invalid service type")
+	}
+
+	cached, ok := serviceMap.Load(serviceName)
+	if !ok {
+		return fmt.Errorf("service %s not found in cache", serviceName)
+	}
+	loadBalance, err := get(tx, &enity.LoadBalance{ServiceID: cached.(*enity.ServiceDetail).Info.ID})
+	if err != nil {
+		return err
+	}
+
+	// 复制一份服务详情再替换, 避免与正在读取缓存的请求产生数据竞争
+	detail := *cached.(*enity.ServiceDetail)
+	detail.LoadBalance = loadBalance
+	serviceMap.Store(serviceName, &detail)
+	LoadBalanceTransport.UpdateNodes(&detail)
+	return nil
+}
+
+// UpdateUpstream 上游被修改或删除后重新读取上游, 引用该上游的服务重建负载均衡器和连接池
+func (s *serviceCache) UpdateUpstream(upstreamID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upstream, err := getUpstream(mysql.GetDB(), upstreamID)
+	if err != nil {
+		return err
+	}
+
+	// 先替换服务详情再删除共享配置, 避免请求或监听器的新连接用旧的上游重新创建共享配置
+	services := []string{}
+	for _, serviceMap := range []*sync.Map{s.HTTPServices, s.TCPServices, s.GRPCServices, s.UDPServices} {
+		serviceMap.Range(func(key, value any) bool {
+			cached := value.(*enity.ServiceDetail)
+			if cached.LoadBalance == nil || cached.LoadBalance.UpstreamID != upstreamID {
+				return true
+			}
+			detail := *cached
+			detail.Upstream = upstream
+			serviceMap.Store(key, &detail)
+			services = append(services, key.(string))
+			return true
+		})
+	}
+	LoadBalanceTransport.RemoveUpstream(upstreamID)
+	for _, serviceName := range services {
+		LoadBalanceTransport.Remove(serviceName)
+	}
+	return nil
 }
 
 // HTTPAccessMode 根据请求的host和path，从URL解析出服务名，通过服务名从缓存中获取对应的服务详情。
@@ -182,11 +265,42 @@ func (s *serviceCache) GetGrpcServiceList() []*enity.ServiceDetail {
 	return s.getServiceListFromMap(s.GRPCServices)
 }
 
+// GetGrpcService 根据服务名获取 gRPC 服务详情。
+func (s *serviceCache) GetGrpcService(serviceName string) (*enity.ServiceDetail, bool) {
+	return s.getServiceFromMap(s.GRPCServices, serviceName)
+}
+
 // GetTcpServiceList 遍历map获取所有的 TCP 服务列表。
 func (s *serviceCache) GetTcpServiceList() []*enity.ServiceDetail {
 	return s.getServiceListFromMap(s.TCPServices)
 }
 
+// GetTcpService 根据服务名获取 TCP 服务详情。
+func (s *serviceCache) GetTcpService(serviceName string) (*enity.ServiceDetail, bool) {
+	return s.getServiceFromMap(s.TCPServices, serviceName)
+}
+
+// GetUdpServiceList 遍历map获取所有的 UDP 服务列表。
+func (s *serviceCache) GetUdpServiceList() []*enity.ServiceDetail {
+	return s.getServiceListFromMap(s.UDPServices)
+}
+
+// GetUdpService 根据服务名获取 UDP 服务详情。
+func (s *serviceCache) GetUdpService(serviceName string) (*enity.ServiceDetail, bool) {
+	return s.getServiceFromMap(s.UDPServices, serviceName)
+}
+
+// getServiceFromMap 工具函数，从传入的map中按服务名获取服务详情。
+func (s *serviceCache) getServiceFromMap(serviceMap *sync.Map, serviceName string) (*enity.ServiceDetail, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	serviceDetail, ok := serviceMap.Load(serviceName)
+	if !ok {
+		return nil, false
+	}
+	return serviceDetail.(*enity.ServiceDetail), true
+}
+
 // getServiceListFromMap 工具函数，工具传入的map进行遍历，返回[]*enity.ServiceDetail。
 func (s *serviceCache) getServiceListFromMap(serviceMap *sync.Map) []*enity.ServiceDetail {
 	s.mu.Lock()
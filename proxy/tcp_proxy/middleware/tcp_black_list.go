@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"gateway/enity"
+	"gateway/metrics"
 	"gateway/utils"
 	"strings"
 )
@@ -35,6 +36,7 @@ func TCPBlackListMiddleware() func(c *TcpSliceRouterContext) {
 		}
 		if serviceDetail.AccessControl.OpenAuth == 1 && len(whileIpList) == 0 && len(blackIpList) > 0 {
 			if utils.InStringSlice(blackIpList, clientIP) {
+				metrics.RecordRejectionMetrics("tcp", serviceDetail.Info.ServiceName, metrics.RejectBlackList)
 				c.conn.Write([]byte(fmt.Sprintf("%s in black ip list", clientIP)))
 				c.Abort()
 				return
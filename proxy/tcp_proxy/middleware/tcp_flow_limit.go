@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"gateway/enity"
+	"gateway/metrics"
 	"gateway/proxy/pkg"
 	"strings"
 )
@@ -28,6 +29,7 @@ func TCPFlowLimitMiddleware() func(c *TcpSliceRouterContext) {
 				return
 			}
 			if !serviceLimiter.Allow() {
+				metrics.RecordRejectionMetrics("tcp", serviceDetail.Info.ServiceName, metrics.RejectServiceLimit)
 				c.conn.Write([]byte(fmt.Sprintf("service flow limit %v", serviceDetail.AccessControl.ServiceFlowLimit)))
 				c.Abort()
 				return
@@ -49,6 +51,7 @@ func TCPFlowLimitMiddleware() func(c *TcpSliceRouterContext) {
 				return
 			}
 			if !clientLimiter.Allow() {
+				metrics.RecordRejectionMetrics("tcp", serviceDetail.Info.ServiceName, metrics.RejectClientLimit)
 				c.conn.Write([]byte(fmt.Sprintf("%v flow limit %v", clientIP, serviceDetail.AccessControl.ClientIPFlowLimit)))
 				c.Abort()
 				return
@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"gateway/pkg/accesslog"
 	"gateway/proxy/tcp_proxy/server"
 	"math"
 	"net"
@@ -52,6 +54,15 @@ func (c *TcpSliceRouterContext) Set(key, val interface{}) {
 	c.Ctx = context.WithValue(c.Ctx, key, val)
 }
 
+// ClientIP 返回客户端ip
+func (c *TcpSliceRouterContext) ClientIP() string {
+	host, _, err := net.SplitHostPort(c.conn.RemoteAddr().String())
+	if err != nil {
+		return c.conn.RemoteAddr().String()
+	}
+	return host
+}
+
 // TcpSliceRouterHandler 结构体 用于回调
 type TcpSliceRouterHandler struct {
 	coreFunc func(*TcpSliceRouterContext) server.TCPHandler
@@ -66,8 +77,13 @@ func (w *TcpSliceRouterHandler) ServeTCP(ctx context.Context, conn net.Conn) {
 	})
 	c.Reset()
 	c.Next()
+	if c.IsAborted() {
+		accesslog.FromContext(ctx).SetError(errConnAborted)
+	}
 }
 
+var errConnAborted = errors.New("connection rejected by middleware")
+
 // NewTcpSliceRouterHangler 构造函数，返回TcpSliceRouterHandler
 func NewTcpSliceRouterHandler(coreFunc func(*TcpSliceRouterContext) server.TCPHandler, router *TcpSliceRouter) *TcpSliceRouterHandler {
 	return &TcpSliceRouterHandler{
@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"gateway/enity"
+	"gateway/metrics"
 	"gateway/utils"
 	"strings"
 )
@@ -29,6 +30,7 @@ func TCPWhiteListMiddleware() func(c *TcpSliceRouterContext) {
 		}
 		if serviceDetail.AccessControl.OpenAuth == 1 && len(iplist) > 0 {
 			if !utils.InStringSlice(iplist, clientIP) {
+				metrics.RecordRejectionMetrics("tcp", serviceDetail.Info.ServiceName, metrics.RejectWhiteList)
 				c.conn.Write([]byte(fmt.Sprintf("%s not in white ip list", clientIP)))
 				c.Abort()
 				return
@@ -3,8 +3,12 @@ package reverse_proxy
 
 import (
 	"context"
+	"gateway/enity"
+	"gateway/metrics"
+	"gateway/pkg/accesslog"
 	"gateway/proxy/load_balance"
 	"gateway/proxy/tcp_proxy/middleware"
+	"gateway/proxy/tcp_proxy/server"
 	"io"
 	"log"
 	"net"
@@ -14,15 +18,29 @@ import (
 // NewTcpLoadBalanceReverseProxy 构建一个新的反向代理
 func NewTcpLoadBalanceReverseProxy(c *middleware.TcpSliceRouterContext, lb load_balance.LoadBalance) *TcpReverseProxy {
 	return func() *TcpReverseProxy {
-		nextAddr, err := lb.Get("")
+		// TCP 连接没有请求内容可取, 一致性hash统一按客户端ip选择节点
+		nextAddr, err := lb.Get(c.ClientIP())
 		if err != nil {
-			log.Fatal("get next addr fail")
+			// 没有可用节点时地址为空, ServeTCP 拨号失败后关闭连接
+			log.Printf("tcpproxy: get next addr fail: %v", err)
+		}
+		accesslog.FromContext(c.Ctx).SetUpstream(nextAddr)
+		serviceName := ""
+		var idleTimeout time.Duration
+		if serviceDetail, ok := c.Get("service").(*enity.ServiceDetail); ok {
+			serviceName = serviceDetail.Info.ServiceName
+			if serviceDetail.TCPRule != nil {
+				idleTimeout = time.Duration(serviceDetail.TCPRule.IdleTimeout) * time.Second
+			}
 		}
 		return &TcpReverseProxy{
 			ctx:             c.Ctx,
+			serviceName:     serviceName,
 			Addr:            nextAddr,
+			done:            load_balance.Track(lb, nextAddr),
 			KeepAlivePeriod: time.Second,
 			DialTimeout:     time.Second,
+			IdleTimeout:     idleTimeout,
 		}
 	}()
 }
@@ -30,12 +48,16 @@ func NewTcpLoadBalanceReverseProxy(c *middleware.TcpSliceRouterContext, lb load_
 // TCP反向代理
 type TcpReverseProxy struct {
 	ctx                  context.Context //单次请求单独设置
+	serviceName          string
 	Addr                 string
 	KeepAlivePeriod      time.Duration //设置
 	DialTimeout          time.Duration //设置超时时间
+	IdleTimeout          time.Duration // 上游连接的空闲超时, 与客户端连接一致, 为0时不设置
 	DialContext          func(ctx context.Context, network, address string) (net.Conn, error)
 	OnDialError          func(src net.Conn, dstDialErr error)
 	ProxyProtocolVersion int
+
+	done func(latency time.Duration, err error) // 连接结束时反馈给负载均衡器, 耗时为建连耗时
 }
 
 func (dp *TcpReverseProxy) dialTimeout() time.Duration {
@@ -71,14 +93,23 @@ func (dp *TcpReverseProxy) ServeTCP(ctx context.Context, src net.Conn) {
 	if dp.DialTimeout >= 0 {
 		ctx, cancel = context.WithTimeout(ctx, dp.dialTimeout())
 	}
+	dialStart := time.Now()
 	dst, err := dp.dialContext()(ctx, "tcp", dp.Addr)
 	if cancel != nil {
 		cancel()
 	}
+	dialLatency := time.Since(dialStart)
+	if err == nil {
+		metrics.RecordUpstreamConnectMetrics("tcp", dp.serviceName, dp.Addr, dialLatency.Seconds())
+	}
 	if err != nil {
+		dp.feedback(dialLatency, err)
+		accesslog.FromContext(ctx).SetError(err)
 		dp.onDialError()(src, err)
 		return
 	}
+	// 连接存续期间计为处理中的请求
+	defer dp.feedback(dialLatency, nil)
 
 	defer func() { go dst.Close() }() //记得退出下游连接
 
@@ -89,10 +120,28 @@ func (dp *TcpReverseProxy) ServeTCP(ctx context.Context, src net.Conn) {
 			c.SetKeepAlivePeriod(ka)
 		}
 	}
-	errc := make(chan error, 1)
+	// 客户端半关闭后只剩上游方向在读, 上游连接同样需要空闲超时, 否则空闲的上游会让拷贝协程一直阻塞
+	if dp.IdleTimeout > 0 {
+		dst = server.NewIdleTimeoutConn(dst, dp.IdleTimeout)
+	}
+	// 两个方向各自拷贝，一方读到 EOF 时只半关闭对端写方向，
+	// 等待另一方向也结束后才退出；出现异常时直接关闭两端连接
+	errc := make(chan error, 2)
 	go dp.proxyCopy(errc, src, dst)
 	go dp.proxyCopy(errc, dst, src)
-	<-errc
+	for i := 0; i < 2; i++ {
+		if err := <-errc; err != nil {
+			accesslog.FromContext(ctx).SetError(err)
+			src.Close()
+			dst.Close()
+		}
+	}
+}
+
+func (dp *TcpReverseProxy) feedback(latency time.Duration, err error) {
+	if dp.done != nil {
+		dp.done(latency, err)
+	}
 }
 
 func (dp *TcpReverseProxy) onDialError() func(src net.Conn, dstDialErr error) {
@@ -105,7 +154,19 @@ func (dp *TcpReverseProxy) onDialError() func(src net.Conn, dstDialErr error) {
 	}
 }
 
+type closeWriter interface {
+	CloseWrite() error
+}
+
 func (dp *TcpReverseProxy) proxyCopy(errc chan<- error, dst, src net.Conn) {
 	_, err := io.Copy(dst, src)
+	if err == nil {
+		// src 已经读到 EOF，将半关闭传递给 dst
+		if cw, ok := dst.(closeWriter); ok {
+			err = cw.CloseWrite()
+		} else {
+			err = dst.Close()
+		}
+	}
 	errc <- err
 }
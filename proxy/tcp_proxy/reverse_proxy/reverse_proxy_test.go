@@ -0,0 +1,125 @@
+package reverse_proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// TestServeTCPHalfClose 客户端发送完请求后半关闭, 上游读到 EOF 后返回的响应仍然完整转发给客户端
+func TestServeTCPHalfClose(t *testing.T) {
+	upstream := listen(t)
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// 读到 EOF 才开始响应, 稍作等待确认客户端方向结束后另一方向仍在转发
+		req, _ := io.ReadAll(conn)
+		time.Sleep(50 * time.Millisecond)
+		conn.Write(append([]byte("echo: "), req...))
+	}()
+
+	proxy := listen(t)
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		src, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+		defer src.Close()
+		rp := &TcpReverseProxy{Addr: upstream.Addr().String(), DialTimeout: time.Second, IdleTimeout: time.Second}
+		rp.ServeTCP(context.Background(), src)
+	}()
+
+	client, err := net.Dial("tcp", proxy.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := client.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.(*net.TCPConn).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := io.ReadAll(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != "echo: ping" {
+		t.Fatalf("response = %q, want %q", resp, "echo: ping")
+	}
+
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeTCP did not return after both directions finished")
+	}
+}
+
+// TestServeTCPUpstreamFirst 上游先半关闭时客户端仍然可以继续发送
+func TestServeTCPUpstreamFirst(t *testing.T) {
+	upstream := listen(t)
+	received := make(chan string, 1)
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("hello"))
+		conn.(*net.TCPConn).CloseWrite()
+		req, _ := io.ReadAll(conn)
+		received <- string(req)
+	}()
+
+	proxy := listen(t)
+	go func() {
+		src, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+		defer src.Close()
+		rp := &TcpReverseProxy{Addr: upstream.Addr().String(), DialTimeout: time.Second}
+		rp.ServeTCP(context.Background(), src)
+	}()
+
+	client, err := net.Dial("tcp", proxy.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetDeadline(time.Now().Add(5 * time.Second))
+	greeting, err := io.ReadAll(client)
+	if err != nil || string(greeting) != "hello" {
+		t.Fatalf("greeting = %q, %v", greeting, err)
+	}
+	if _, err := client.Write([]byte("bye")); err != nil {
+		t.Fatalf("write after upstream half close: %v", err)
+	}
+	client.(*net.TCPConn).CloseWrite()
+	select {
+	case req := <-received:
+		if req != "bye" {
+			t.Fatalf("upstream received %q, want %q", req, "bye")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream did not receive the request")
+	}
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"context"
+	"gateway/enity"
+	"gateway/flow_counter"
+	"gateway/globals"
+	"gateway/metrics"
+	"gateway/pkg/accesslog"
+	"gateway/proxy/tcp_proxy/server"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// accessLogHandler 每个连接结束时输出一条访问日志并记录连接指标
+type accessLogHandler struct {
+	serviceDetail *enity.ServiceDetail
+	next          server.TCPHandler
+}
+
+func (h *accessLogHandler) ServeTCP(ctx context.Context, conn net.Conn) {
+	// 未开启访问日志时 Log 不会输出, 记录仍用于统计指标
+	entry := accesslog.NewEntry("tcp")
+	entry.Service = h.serviceDetail.Info.ServiceName
+	if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+		entry.ClientIP = host
+	}
+	cc := &countingConn{Conn: conn}
+	h.next.ServeTCP(accesslog.NewContext(ctx, entry), cc)
+
+	entry.BytesIn = atomic.LoadInt64(&cc.bytesIn)
+	entry.BytesOut = atomic.LoadInt64(&cc.bytesOut)
+	accesslog.Log(entry)
+
+	statusClass := "ok"
+	if entry.Error != "" {
+		statusClass = "error"
+	}
+	metrics.RecordRequestMetrics("tcp", entry.Service, "", statusClass, "")
+	if entry.Upstream != "" {
+		if counter, err := globals.FlowCounter.GetCounter(flow_counter.NodeCounterName(entry.Service, entry.Upstream)); err == nil {
+			counter.Increase()
+		}
+	}
+	if counter, err := globals.FlowCounter.GetCounter(flow_counter.StatusCounterName(entry.Service, statusClass)); err == nil {
+		counter.Increase()
+	}
+	metrics.RecordRequestDurationMetrics("tcp", entry.Service, entry.Upstream, time.Since(entry.Start).Seconds())
+	metrics.RecordBytesMetrics("tcp", entry.Service, entry.BytesIn, entry.BytesOut)
+}
+
+// countingConn 统计连接收发的字节数
+type countingConn struct {
+	net.Conn
+	bytesIn  int64
+	bytesOut int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.bytesIn, int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.bytesOut, int64(n))
+	return n, err
+}
+
+// CloseWrite 透传半关闭
+func (c *countingConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return c.Conn.Close()
+}
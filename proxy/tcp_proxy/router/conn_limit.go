@@ -0,0 +1,69 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"gateway/enity"
+	"gateway/metrics"
+	"gateway/pkg/accesslog"
+	"gateway/proxy/tcp_proxy/server"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+var errTooManyConns = errors.New("too many connections")
+
+// serviceConns 每个服务的活跃连接数 serviceName -> *int64
+// 计数器按服务名保存在回调之外, 服务配置变化重建回调时旧回调上仍未结束的连接继续计入
+var serviceConns sync.Map
+
+// serviceConnCounter 返回服务的活跃连接计数器, 同一个服务始终返回同一个计数器
+func serviceConnCounter(serviceName string) *int64 {
+	value, _ := serviceConns.LoadOrStore(serviceName, new(int64))
+	return value.(*int64)
+}
+
+// removeServiceConnCounter 服务被删除后移除计数器, 仍有连接未结束时保留, 避免同名服务重新创建后计数从 0 开始
+func removeServiceConnCounter(serviceName string) {
+	if value, ok := serviceConns.Load(serviceName); ok && atomic.LoadInt64(value.(*int64)) == 0 {
+		serviceConns.CompareAndDelete(serviceName, value)
+	}
+}
+
+// connLimitHandler 统计服务活跃连接数，超过服务最大连接数时直接关闭连接
+type connLimitHandler struct {
+	serviceDetail *enity.ServiceDetail
+	counter       *int64
+	next          server.TCPHandler
+}
+
+func newConnLimitHandler(serviceDetail *enity.ServiceDetail, next server.TCPHandler) *connLimitHandler {
+	return &connLimitHandler{
+		serviceDetail: serviceDetail,
+		counter:       serviceConnCounter(serviceDetail.Info.ServiceName),
+		next:          next,
+	}
+}
+
+func (h *connLimitHandler) ServeTCP(ctx context.Context, conn net.Conn) {
+	serviceName := h.serviceDetail.Info.ServiceName
+	counter := h.counter
+
+	active := atomic.AddInt64(counter, 1)
+	defer func() {
+		metrics.RecordTcpActiveConnMetrics(serviceName, int(atomic.AddInt64(counter, -1)))
+	}()
+	if maxConns := h.serviceDetail.TCPRule.MaxConns; maxConns > 0 && active > int64(maxConns) {
+		metrics.RecordTcpRejectedConnMetrics(serviceName)
+		log.Printf(" [WARN] tcp_proxy %v reject conn %v: too many connections\n", serviceName, conn.RemoteAddr())
+		accesslog.FromContext(ctx).SetError(errTooManyConns)
+		return
+	}
+	metrics.RecordTcpActiveConnMetrics(serviceName, int(active))
+	// 与 http / grpc 一样增减计数, 多个服务的标签归并为 other 时不会互相覆盖
+	done := metrics.IncActiveConnMetrics("tcp", serviceName)
+	defer done()
+	h.next.ServeTCP(ctx, conn)
+}
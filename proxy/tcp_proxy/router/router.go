@@ -2,17 +2,25 @@ package router
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"gateway/configs"
 	"gateway/enity"
+	"gateway/pkg/accesslog"
 	"gateway/proxy/pkg"
 	"gateway/proxy/tcp_proxy/middleware"
 	"gateway/proxy/tcp_proxy/reverse_proxy"
 	"gateway/proxy/tcp_proxy/server"
 	"log"
 	"net"
+	"sync"
+	"time"
 )
 
-var tcpServerList = []*server.TcpServer{}
+var (
+	tcpServerList = []*server.TcpServer{}
+	tcpListLock   sync.Mutex
+)
 
 type tcpHandler struct {
 }
@@ -25,47 +33,126 @@ func TcpProxyServerRun() {
 	serviceList := pkg.Cache.GetTcpServiceList()
 	for _, serviceItem := range serviceList {
 		tempItem := serviceItem
+		// 只通过共享端口SNI接入的服务不单独监听端口
+		if tempItem.TCPRule.Port == 0 {
+			continue
+		}
 		go func(serviceDetail *enity.ServiceDetail) {
 			addr := fmt.Sprintf(":%d", serviceDetail.TCPRule.Port)
-			rb, err := pkg.LoadBalanceTransport.GetLoadBalancer(serviceDetail)
+			routerHandler, err := newTcpServiceHandler(serviceDetail)
 			if err != nil {
 				log.Fatalf(" [INFO] GetTcpLoadBalancer %v err:%v\n", addr, err)
 				return
 			}
 
-			//构建路由及设置中间件
-			router := middleware.NewTcpSliceRouter()
-			router.Group("/").Use(
-				middleware.TCPFlowCountMiddleware(),
-				middleware.TCPFlowLimitMiddleware(),
-				middleware.TCPWhiteListMiddleware(),
-				middleware.TCPBlackListMiddleware(),
-			)
-
-			//构建回调handler
-			routerHandler := middleware.NewTcpSliceRouterHandler(
-				func(c *middleware.TcpSliceRouterContext) server.TCPHandler {
-					return reverse_proxy.NewTcpLoadBalanceReverseProxy(c, rb)
-				}, router)
+			var tlsConfig *tls.Config
+			if serviceDetail.TCPRule.NeedTls == 1 {
+				if tlsConfig, err = newTcpServiceTLSConfig(serviceDetail.TCPRule); err != nil {
+					log.Printf(" [ERROR] tcp_proxy_run %v load certificate err:%v\n", addr, err)
+					return
+				}
+			}
 
 			baseCtx := context.WithValue(context.Background(), "service", serviceDetail)
 			tcpServer := &server.TcpServer{
-				Addr:    addr,
-				Handler: routerHandler,
-				BaseCtx: baseCtx,
+				Addr:        addr,
+				Handler:     routerHandler,
+				BaseCtx:     baseCtx,
+				IdleTimeout: time.Duration(serviceDetail.TCPRule.IdleTimeout) * time.Second,
+				TLSConfig:   tlsConfig,
 			}
+			tcpListLock.Lock()
 			tcpServerList = append(tcpServerList, tcpServer)
+			tcpListLock.Unlock()
 			log.Printf(" [INFO] tcp_proxy_run %v\n", addr)
 			if err := tcpServer.ListenAndServe(); err != nil && err != server.ErrServerClosed {
 				log.Fatalf(" [INFO] tcp_proxy_run %v err:%v\n", addr, err)
 			}
 		}(tempItem)
 	}
+
+	// 共享端口，根据SNI路由到不同的TCP服务
+	if sniConf := configs.GetTcpSniConfig(); sniConf.Addr != "" {
+		go func() {
+			sniServer := &server.TcpServer{
+				Addr:    sniConf.Addr,
+				Handler: newSniHandler(time.Duration(sniConf.ReadTimeout) * time.Second),
+			}
+			tcpListLock.Lock()
+			tcpServerList = append(tcpServerList, sniServer)
+			tcpListLock.Unlock()
+			log.Printf(" [INFO] tcp_sni_proxy_run %v\n", sniConf.Addr)
+			if err := sniServer.ListenAndServe(); err != nil && err != server.ErrServerClosed {
+				log.Fatalf(" [INFO] tcp_sni_proxy_run %v err:%v\n", sniConf.Addr, err)
+			}
+		}()
+	}
+}
+
+// newTcpServiceHandler 构建单个TCP服务的中间件及反向代理回调
+// 启动时先创建一次负载均衡器校验配置, 之后每个连接按服务最新的详情获取, 服务修改后新连接使用重建的负载均衡器
+func newTcpServiceHandler(serviceDetail *enity.ServiceDetail) (server.TCPHandler, error) {
+	if _, err := pkg.LoadBalanceTransport.GetLoadBalancer(serviceDetail); err != nil {
+		return nil, err
+	}
+
+	//构建路由及设置中间件
+	router := middleware.NewTcpSliceRouter()
+	router.Group("/").Use(
+		middleware.TCPFlowCountMiddleware(),
+		middleware.TCPFlowLimitMiddleware(),
+		middleware.TCPWhiteListMiddleware(),
+		middleware.TCPBlackListMiddleware(),
+	)
+
+	//构建回调handler
+	routerHandler := middleware.NewTcpSliceRouterHandler(
+		func(c *middleware.TcpSliceRouterContext) server.TCPHandler {
+			rb, err := pkg.CurrentLoadBalancer(serviceDetail)
+			if err != nil {
+				log.Printf(" [WARN] tcp_proxy %v get load balancer err:%v\n", serviceDetail.Info.ServiceName, err)
+				return unavailableHandler{err: err}
+			}
+			return reverse_proxy.NewTcpLoadBalanceReverseProxy(c, rb)
+		}, router)
+
+	// 活跃连接数按服务统计，独立端口与共享端口的连接合并计算
+	limitHandler := newConnLimitHandler(serviceDetail, routerHandler)
+	return &accessLogHandler{serviceDetail: serviceDetail, next: limitHandler}, nil
+}
+
+// unavailableHandler 获取负载均衡器失败时只记录错误, 连接随后被关闭
+type unavailableHandler struct {
+	err error
+}
+
+func (h unavailableHandler) ServeTCP(ctx context.Context, conn net.Conn) {
+	accesslog.FromContext(ctx).SetError(h.err)
+}
+
+// newTcpServiceTLSConfig 加载服务配置的证书
+func newTcpServiceTLSConfig(rule *enity.TcpRule) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(rule.CertFile, rule.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
 }
 
 func TcpProxyServerStop() {
+	tcpListLock.Lock()
+	defer tcpListLock.Unlock()
+	// 并行关闭，每个服务各自等待存量连接结束
+	var wg sync.WaitGroup
 	for _, tcpServer := range tcpServerList {
-		tcpServer.Close()
-		log.Printf(" [INFO] tcp_proxy_stop %v stopped\n", tcpServer.Addr)
+		wg.Add(1)
+		go func(tcpServer *server.TcpServer) {
+			defer wg.Done()
+			if err := tcpServer.Close(); err != nil {
+				log.Printf(" [WARN] tcp_proxy_stop %v force closed: %v\n", tcpServer.Addr, err)
+			}
+			log.Printf(" [INFO] tcp_proxy_stop %v stopped\n", tcpServer.Addr)
+		}(tcpServer)
 	}
+	wg.Wait()
 }
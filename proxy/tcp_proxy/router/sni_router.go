@@ -0,0 +1,132 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"gateway/enity"
+	"gateway/proxy/pkg"
+	"gateway/proxy/tcp_proxy/server"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultSniPeekTimeout = 10 * time.Second
+
+// sniHandler 共享端口回调，读取 ClientHello 中的 SNI 后交给对应的 TCP 服务处理
+type sniHandler struct {
+	peekTimeout time.Duration
+	services    sync.Map // serviceName -> *sniService
+}
+
+// sniService 缓存服务的回调，服务详情变化后重新构建
+type sniService struct {
+	detail    *enity.ServiceDetail
+	handler   server.TCPHandler
+	tlsConfig *tls.Config
+}
+
+func newSniHandler(peekTimeout time.Duration) *sniHandler {
+	if peekTimeout <= 0 {
+		peekTimeout = defaultSniPeekTimeout
+	}
+	return &sniHandler{peekTimeout: peekTimeout}
+}
+
+func (h *sniHandler) ServeTCP(ctx context.Context, conn net.Conn) {
+	serverName, conn, err := server.PeekClientHelloServerName(conn, h.peekTimeout)
+	if err != nil {
+		log.Printf(" [WARN] tcp_sni_proxy peek client hello from %v err:%v\n", conn.RemoteAddr(), err)
+		return
+	}
+
+	serviceList := pkg.Cache.GetTcpServiceList()
+	h.evict(serviceList)
+	detail := matchSniService(serviceList, serverName)
+	if detail == nil {
+		log.Printf(" [WARN] tcp_sni_proxy no service matched server name %q\n", serverName)
+		return
+	}
+	svc, err := h.getService(detail)
+	if err != nil {
+		log.Printf(" [ERROR] tcp_sni_proxy build service %v err:%v\n", detail.Info.ServiceName, err)
+		return
+	}
+
+	if d := detail.TCPRule.IdleTimeout; d > 0 {
+		conn = server.NewIdleTimeoutConn(conn, time.Duration(d)*time.Second)
+	}
+	// 默认透传，服务开启了TLS终止时在网关完成握手
+	if svc.tlsConfig != nil {
+		conn = tls.Server(conn, svc.tlsConfig)
+	}
+	ctx = context.WithValue(ctx, "service", detail)
+	svc.handler.ServeTCP(ctx, conn)
+}
+
+func (h *sniHandler) getService(detail *enity.ServiceDetail) (*sniService, error) {
+	if value, ok := h.services.Load(detail.Info.ServiceName); ok {
+		if svc := value.(*sniService); svc.detail == detail {
+			return svc, nil
+		}
+	}
+
+	handler, err := newTcpServiceHandler(detail)
+	if err != nil {
+		return nil, err
+	}
+	svc := &sniService{detail: detail, handler: handler}
+	if detail.TCPRule.NeedTls == 1 {
+		if svc.tlsConfig, err = newTcpServiceTLSConfig(detail.TCPRule); err != nil {
+			return nil, err
+		}
+	}
+	h.services.Store(detail.Info.ServiceName, svc)
+	return svc, nil
+}
+
+// evict 移除已经删除的服务的回调, 正在处理的连接继续使用原来的回调
+func (h *sniHandler) evict(serviceList []*enity.ServiceDetail) {
+	names := make(map[string]bool, len(serviceList))
+	for _, detail := range serviceList {
+		names[detail.Info.ServiceName] = true
+	}
+	h.services.Range(func(key, _ any) bool {
+		if name := key.(string); !names[name] {
+			h.services.Delete(name)
+			removeServiceConnCounter(name)
+		}
+		return true
+	})
+}
+
+// matchSniService 精确匹配优先，其次匹配 *.example.com 形式的通配域名
+func matchSniService(serviceList []*enity.ServiceDetail, serverName string) *enity.ServiceDetail {
+	serverName = strings.ToLower(strings.TrimSuffix(serverName, "."))
+	sort.Slice(serviceList, func(i, j int) bool {
+		return serviceList[i].Info.ServiceName < serviceList[j].Info.ServiceName
+	})
+
+	var wildcard *enity.ServiceDetail
+	for _, detail := range serviceList {
+		if detail.TCPRule == nil || detail.TCPRule.SniHost == "" {
+			continue
+		}
+		for _, host := range strings.Split(detail.TCPRule.SniHost, ",") {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if host == serverName {
+				return detail
+			}
+			if wildcard == nil && strings.HasPrefix(host, "*.") {
+				suffix := host[1:]
+				if strings.HasSuffix(serverName, suffix) && !strings.Contains(strings.TrimSuffix(serverName, suffix), ".") {
+					wildcard = detail
+				}
+			}
+		}
+	}
+	return wildcard
+}
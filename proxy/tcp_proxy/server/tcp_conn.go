@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"time"
 )
 
 type tcpKeepAliveListener struct {
@@ -28,6 +29,47 @@ func (k *contextKey) String() string {
 	return "tcp_proxy context value " + k.name
 }
 
+// idleTimeoutConn 空闲超时连接，每次读写成功后刷新超时时间，
+// 只有在超过 idleTimeout 没有任何数据往来时才会触发超时
+type idleTimeoutConn struct {
+	net.Conn
+	idleTimeout time.Duration
+}
+
+// NewIdleTimeoutConn 包装连接，使其在空闲超过 idleTimeout 后超时
+func NewIdleTimeoutConn(conn net.Conn, idleTimeout time.Duration) net.Conn {
+	conn.SetDeadline(time.Now().Add(idleTimeout))
+	return &idleTimeoutConn{Conn: conn, idleTimeout: idleTimeout}
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.refresh()
+	}
+	return n, err
+}
+
+func (c *idleTimeoutConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.refresh()
+	}
+	return n, err
+}
+
+// CloseWrite 半关闭写方向，底层连接不支持时直接关闭
+func (c *idleTimeoutConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return c.Conn.Close()
+}
+
+func (c *idleTimeoutConn) refresh() {
+	c.Conn.SetDeadline(time.Now().Add(c.idleTimeout))
+}
+
 type conn struct {
 	server     *TcpServer
 	cancelCtx  context.CancelFunc
@@ -48,6 +90,7 @@ func (c *conn) serve(ctx context.Context) {
 			fmt.Printf("tcp: panic serving %v: %v\n%s", c.remoteAddr, err, buf)
 		}
 		c.close()
+		c.server.trackConn(c, false)
 	}()
 	c.remoteAddr = c.rwc.RemoteAddr().String()
 	ctx = context.WithValue(ctx, LocalAddrContextKey, c.rwc.LocalAddr())
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+const testIdleTimeout = 200 * time.Millisecond
+
+// tcpPair 返回一对本地 tcp 连接, 用于需要半关闭的用例
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := l.Accept()
+		accepted <- conn
+	}()
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := <-accepted
+	if server == nil {
+		t.Fatal("accept failed")
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+// TestIdleTimeoutConnRead 持续有数据读入时超过 idleTimeout 也不超时, 空闲后超时
+func TestIdleTimeoutConnRead(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	conn := NewIdleTimeoutConn(a, testIdleTimeout)
+
+	go func() {
+		for i := 0; i < 6; i++ {
+			time.Sleep(testIdleTimeout / 2)
+			b.Write([]byte{byte(i)})
+		}
+	}()
+	start := time.Now()
+	buf := make([]byte, 1)
+	for i := 0; i < 6; i++ {
+		if _, err := conn.Read(buf); err != nil {
+			t.Fatalf("read %d after %v: %v", i, time.Since(start), err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed <= testIdleTimeout {
+		t.Fatalf("reads finished in %v, want longer than the idle timeout", elapsed)
+	}
+
+	if _, err := conn.Read(buf); !isTimeout(err) {
+		t.Fatalf("read on an idle conn: err = %v, want timeout", err)
+	}
+}
+
+// TestIdleTimeoutConnWrite 写入成功同样刷新超时时间
+func TestIdleTimeoutConnWrite(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	conn := NewIdleTimeoutConn(a, testIdleTimeout)
+
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			time.Sleep(testIdleTimeout / 2)
+			if _, err := b.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	// net.Pipe 的写入等对端读取后才返回, 全部写入的耗时超过 idleTimeout
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if _, err := conn.Write([]byte{byte(i)}); err != nil {
+			t.Fatalf("write %d after %v: %v", i, time.Since(start), err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed <= testIdleTimeout {
+		t.Fatalf("writes finished in %v, want longer than the idle timeout", elapsed)
+	}
+
+	// 停止读写后连接超时
+	b.SetDeadline(time.Now())
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !isTimeout(err) {
+			t.Fatalf("read on an idle conn: err = %v, want timeout", err)
+		}
+	case <-time.After(2 * testIdleTimeout):
+		t.Fatal("idle conn did not time out")
+	}
+}
+
+// TestIdleTimeoutConnCloseWrite 半关闭写方向后对端读到 EOF, 反方向仍然可以继续传输
+func TestIdleTimeoutConnCloseWrite(t *testing.T) {
+	client, server := tcpPair(t)
+	conn := NewIdleTimeoutConn(client, time.Second)
+
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.(interface{ CloseWrite() error }).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(server)
+	if err != nil || string(got) != "ping" {
+		t.Fatalf("server read %q, %v", got, err)
+	}
+	if _, err := server.Write([]byte("pong")); err != nil {
+		t.Fatalf("server write after client half close: %v", err)
+	}
+	server.Close()
+	got, err = io.ReadAll(conn)
+	if err != nil || string(got) != "pong" {
+		t.Fatalf("client read %q, %v", got, err)
+	}
+
+	// 底层连接不支持半关闭时直接关闭
+	a, b := net.Pipe()
+	defer b.Close()
+	pipe := NewIdleTimeoutConn(a, time.Second)
+	if err := pipe.(interface{ CloseWrite() error }).CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("read from a closed pipe: err = %v, want EOF", err)
+	}
+}
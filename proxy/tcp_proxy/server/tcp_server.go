@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
@@ -17,6 +18,9 @@ var (
 	LocalAddrContextKey = &contextKey{"local-addr"}
 )
 
+// defaultDrainTimeout Close 时等待存量连接结束的默认时长
+const defaultDrainTimeout = 10 * time.Second
+
 type onceCloseListener struct {
 	net.Listener
 	once     sync.Once
@@ -42,14 +46,18 @@ type TcpServer struct {
 	err     error
 	BaseCtx context.Context
 
-	WriteTimeout     time.Duration
-	ReadTimeout      time.Duration
+	ReadTimeout      time.Duration // 读写共用一个空闲超时，不再单独设置写超时
 	KeepAliveTimeout time.Duration
+	IdleTimeout      time.Duration // 空闲超时，有数据往来时自动刷新，为0时使用 ReadTimeout
+	DrainTimeout     time.Duration // Close 时等待存量连接结束的最长时间
+
+	TLSConfig *tls.Config // 不为空时在网关终止TLS
 
 	mu         sync.Mutex
 	inShutdown int32
 	doneChan   chan struct{}
 	l          *onceCloseListener
+	activeConn map[*conn]struct{}
 }
 
 func (s *TcpServer) shuttingDown() bool {
@@ -60,9 +68,6 @@ func (srv *TcpServer) ListenAndServe() error {
 	if srv.shuttingDown() {
 		return ErrServerClosed
 	}
-	if srv.doneChan == nil {
-		srv.doneChan = make(chan struct{})
-	}
 	addr := srv.Addr
 	if addr == "" {
 		return errors.New("need addr")
@@ -71,19 +76,58 @@ func (srv *TcpServer) ListenAndServe() error {
 	if err != nil {
 		return err
 	}
-	return srv.Serve(tcpKeepAliveListener{
-		ln.(*net.TCPListener)})
+	var l net.Listener = tcpKeepAliveListener{ln.(*net.TCPListener)}
+	if srv.TLSConfig != nil {
+		l = tls.NewListener(l, srv.TLSConfig)
+	}
+	return srv.Serve(l)
 }
 
+// Close 停止接收新连接，并在 DrainTimeout 内等待存量连接结束，超时后强制关闭
 func (srv *TcpServer) Close() error {
-	atomic.StoreInt32(&srv.inShutdown, 1)
+	d := srv.DrainTimeout
+	if d <= 0 {
+		d = defaultDrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// Shutdown 优雅关闭：关闭监听后等待所有连接处理完成，ctx 结束时强制关闭剩余连接
+func (srv *TcpServer) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&srv.inShutdown, 0, 1) {
+		return ErrServerClosed
+	}
+	srv.mu.Lock()
+	if srv.doneChan == nil {
+		srv.doneChan = make(chan struct{})
+	}
 	close(srv.doneChan) //关闭channel
-	srv.l.Close()       //执行listener关闭
-	return nil
+	if srv.l != nil {
+		srv.l.Close() //执行listener关闭
+	}
+	srv.mu.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if srv.ActiveConns() == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			srv.closeActiveConns()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
 func (srv *TcpServer) Serve(l net.Listener) error {
+	srv.mu.Lock()
 	srv.l = &onceCloseListener{Listener: l}
+	srv.mu.Unlock()
 	defer srv.l.Close() //执行listener关闭
 	if srv.BaseCtx == nil {
 		srv.BaseCtx = context.Background()
@@ -102,30 +146,67 @@ func (srv *TcpServer) Serve(l net.Listener) error {
 			continue
 		}
 		c := srv.newConn(rw)
+		srv.trackConn(c, true)
 		go c.serve(ctx)
 	}
-	return nil
 }
 
 func (srv *TcpServer) newConn(rwc net.Conn) *conn {
-	c := &conn{
+	// 设置参数
+	if d := srv.KeepAliveTimeout; d != 0 {
+		netConn := rwc
+		if tlsConn, ok := rwc.(*tls.Conn); ok {
+			netConn = tlsConn.NetConn()
+		}
+		if tcpConn, ok := netConn.(*net.TCPConn); ok {
+			tcpConn.SetKeepAlive(true)
+			tcpConn.SetKeepAlivePeriod(d)
+		}
+	}
+	// 超时时间在每次读写时刷新，避免长连接在有流量的情况下被断开
+	if d := srv.idleTimeout(); d != 0 {
+		rwc = NewIdleTimeoutConn(rwc, d)
+	}
+	return &conn{
 		server: srv,
 		rwc:    rwc,
 	}
-	// 设置参数
-	if d := c.server.ReadTimeout; d != 0 {
-		c.rwc.SetReadDeadline(time.Now().Add(d))
+}
+
+func (srv *TcpServer) idleTimeout() time.Duration {
+	if srv.IdleTimeout != 0 {
+		return srv.IdleTimeout
 	}
-	if d := c.server.WriteTimeout; d != 0 {
-		c.rwc.SetWriteDeadline(time.Now().Add(d))
+	return srv.ReadTimeout
+}
+
+// trackConn 记录或移除活跃连接，服务的最大连接数由 router 中的 connLimitHandler 按服务统计
+func (srv *TcpServer) trackConn(c *conn, add bool) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.activeConn == nil {
+		srv.activeConn = make(map[*conn]struct{})
 	}
-	if d := c.server.KeepAliveTimeout; d != 0 {
-		if tcpConn, ok := c.rwc.(*net.TCPConn); ok {
-			tcpConn.SetKeepAlive(true)
-			tcpConn.SetKeepAlivePeriod(d)
-		}
+	if add {
+		srv.activeConn[c] = struct{}{}
+	} else {
+		delete(srv.activeConn, c)
+	}
+}
+
+// ActiveConns 当前活跃连接数
+func (srv *TcpServer) ActiveConns() int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return len(srv.activeConn)
+}
+
+func (srv *TcpServer) closeActiveConns() {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for c := range srv.activeConn {
+		c.close()
 	}
-	return c
 }
 
 func (s *TcpServer) getDoneChan() <-chan struct{} {
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+var errClientHelloPeeked = errors.New("tcp: client hello peeked")
+
+// PeekClientHelloServerName 读取 TLS ClientHello 并解析 SNI，不终止 TLS。
+// 返回的连接会先重放已经读取的字节，可以直接转发给上游或继续做 TLS 握手
+func PeekClientHelloServerName(conn net.Conn, timeout time.Duration) (string, net.Conn, error) {
+	if timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	peeked := &bytes.Buffer{}
+	serverName := ""
+	err := tls.Server(readOnlyConn{r: io.TeeReader(conn, peeked)}, &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, errClientHelloPeeked
+		},
+	}).Handshake()
+
+	replay := &prefixConn{Conn: conn, r: io.MultiReader(peeked, conn)}
+	if serverName == "" {
+		if err == nil || err == errClientHelloPeeked {
+			err = errors.New("tcp: client hello without server name")
+		}
+		return "", replay, err
+	}
+	return serverName, replay, nil
+}
+
+// readOnlyConn 只用于解析 ClientHello，拒绝任何写入
+type readOnlyConn struct {
+	r io.Reader
+}
+
+func (c readOnlyConn) Read(p []byte) (int, error)         { return c.r.Read(p) }
+func (c readOnlyConn) Write(p []byte) (int, error)        { return 0, io.ErrClosedPipe }
+func (c readOnlyConn) Close() error                       { return nil }
+func (c readOnlyConn) LocalAddr() net.Addr                { return nil }
+func (c readOnlyConn) RemoteAddr() net.Addr               { return nil }
+func (c readOnlyConn) SetDeadline(t time.Time) error      { return nil }
+func (c readOnlyConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c readOnlyConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// prefixConn 先读取已缓存的数据再读取原连接
+type prefixConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *prefixConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// CloseWrite 半关闭写方向，底层连接不支持时直接关闭
+func (c *prefixConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"gateway/enity"
+	"gateway/pkg/log"
+	"gateway/utils"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// UDPBlackListMiddleware 黑名单中间件
+func UDPBlackListMiddleware() func(c *UdpSliceRouterContext) {
+	return func(c *UdpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			log.Warn("get service empty")
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*enity.ServiceDetail)
+
+		whileIpList := []string{}
+		if serviceDetail.AccessControl.WhiteList != "" {
+			whileIpList = strings.Split(serviceDetail.AccessControl.WhiteList, ",")
+		}
+
+		blackIpList := []string{}
+		if serviceDetail.AccessControl.BlackList != "" {
+			blackIpList = strings.Split(serviceDetail.AccessControl.BlackList, ",")
+		}
+
+		clientIP := c.ClientIP()
+		if serviceDetail.AccessControl.OpenAuth == 1 && len(whileIpList) == 0 && len(blackIpList) > 0 {
+			if utils.InStringSlice(blackIpList, clientIP) {
+				log.Debug("in black ip list", zap.String("client_ip", clientIP))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"gateway/enity"
+	"gateway/globals"
+	"gateway/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// UDPFlowCountMiddleware 流量统计中间件，按数据包计数
+func UDPFlowCountMiddleware() func(c *UdpSliceRouterContext) {
+	return func(c *UdpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			log.Warn("get service empty")
+			c.Abort()
+			return
+		}
+
+		serviceDetail := serverInterface.(*enity.ServiceDetail)
+
+		totalCounter, err := globals.FlowCounter.GetCounter(globals.FlowTotal)
+		if err != nil {
+			log.Error("get flow counter failed", zap.Error(err))
+			c.Abort()
+			return
+		}
+		totalCounter.Increase()
+		serviceCounter, err := globals.FlowCounter.GetCounter(serviceDetail.Info.ServiceName)
+		if err != nil {
+			log.Error("get flow counter failed", zap.Error(err))
+			c.Abort()
+			return
+		}
+		serviceCounter.Increase()
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"gateway/enity"
+	"gateway/pkg/log"
+	"gateway/proxy/pkg"
+
+	"go.uber.org/zap"
+)
+
+// UDPFlowLimitMiddleware 流量控制中间件，超过限流的数据包直接丢弃
+func UDPFlowLimitMiddleware() func(c *UdpSliceRouterContext) {
+	return func(c *UdpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			log.Warn("get service empty")
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*enity.ServiceDetail)
+
+		if serviceDetail.AccessControl.ServiceFlowLimit != 0 {
+			serviceLimiter, err := pkg.FlowLimiter.GetLimiter(
+				serviceDetail.Info.ServiceName,
+				float64(serviceDetail.AccessControl.ServiceFlowLimit))
+			if err != nil {
+				log.Error("get limiter failed", zap.Error(err))
+				c.Abort()
+				return
+			}
+			if !serviceLimiter.Allow() {
+				log.Debug("service flow limit", zap.String("service", serviceDetail.Info.ServiceName), zap.Int("limit", serviceDetail.AccessControl.ServiceFlowLimit))
+				c.Abort()
+				return
+			}
+		}
+
+		if serviceDetail.AccessControl.ClientIPFlowLimit > 0 {
+			clientLimiter, err := pkg.FlowLimiter.GetLimiter(
+				serviceDetail.Info.ServiceName+"_client",
+				float64(serviceDetail.AccessControl.ClientIPFlowLimit))
+			if err != nil {
+				log.Error("get limiter failed", zap.Error(err))
+				c.Abort()
+				return
+			}
+			if !clientLimiter.Allow() {
+				log.Debug("client ip flow limit", zap.String("client_ip", c.ClientIP()), zap.Int("limit", serviceDetail.AccessControl.ClientIPFlowLimit))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
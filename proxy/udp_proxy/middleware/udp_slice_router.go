@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"gateway/proxy/udp_proxy/server"
+	"math"
+	"net"
+)
+
+// 中间件最大数量
+const abortIndex int8 = math.MaxInt8 / 2 //最多 63 个中间件
+
+// 中间件方法
+type UdpHandlerFunc func(*UdpSliceRouterContext)
+
+// router 结构体
+type UdpSliceRouter struct {
+	handlers []UdpHandlerFunc
+}
+
+// UdpSliceRouterContext 结构体，每个数据包创建一个
+type UdpSliceRouterContext struct {
+	Packet   *server.Packet
+	Ctx      context.Context
+	handlers []UdpHandlerFunc
+	index    int8
+}
+
+// Get 从ctx中获取值
+func (c *UdpSliceRouterContext) Get(key interface{}) interface{} {
+	return c.Ctx.Value(key)
+}
+
+// Set 设置值到ctx中
+func (c *UdpSliceRouterContext) Set(key, val interface{}) {
+	c.Ctx = context.WithValue(c.Ctx, key, val)
+}
+
+// ClientIP 客户端IP
+func (c *UdpSliceRouterContext) ClientIP() string {
+	if addr, ok := c.Packet.ClientAddr.(*net.UDPAddr); ok {
+		return addr.IP.String()
+	}
+	host, _, _ := net.SplitHostPort(c.Packet.ClientAddr.String())
+	return host
+}
+
+// 从最先加入中间件开始回调
+func (c *UdpSliceRouterContext) Next() {
+	c.index++
+	for c.index < int8(len(c.handlers)) {
+		c.handlers[c.index](c)
+		c.index++
+	}
+}
+
+// 跳出中间件方法，UDP 没有连接可以回写错误，被拦截的数据包直接丢弃
+func (c *UdpSliceRouterContext) Abort() {
+	c.index = abortIndex
+}
+
+// 是否跳过了回调
+func (c *UdpSliceRouterContext) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// UdpSliceRouterHandler 结构体 用于回调
+type UdpSliceRouterHandler struct {
+	coreFunc func(*UdpSliceRouterContext)
+	router   *UdpSliceRouter
+}
+
+// ServeUDP 回调方法
+func (w *UdpSliceRouterHandler) ServeUDP(ctx context.Context, packet *server.Packet) {
+	handlers := make([]UdpHandlerFunc, 0, len(w.router.handlers)+1)
+	handlers = append(handlers, w.router.handlers...)
+	handlers = append(handlers, w.coreFunc)
+	c := &UdpSliceRouterContext{Packet: packet, Ctx: ctx, handlers: handlers, index: -1}
+	c.Next()
+}
+
+// NewUdpSliceRouterHandler 构造函数，返回UdpSliceRouterHandler
+func NewUdpSliceRouterHandler(coreFunc func(*UdpSliceRouterContext), router *UdpSliceRouter) *UdpSliceRouterHandler {
+	return &UdpSliceRouterHandler{
+		coreFunc: coreFunc,
+		router:   router,
+	}
+}
+
+// 构造 router
+func NewUdpSliceRouter() *UdpSliceRouter {
+	return &UdpSliceRouter{}
+}
+
+// 添加中间件
+func (r *UdpSliceRouter) Use(middlewares ...UdpHandlerFunc) *UdpSliceRouter {
+	r.handlers = append(r.handlers, middlewares...)
+	return r
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"gateway/enity"
+	"gateway/pkg/log"
+	"gateway/utils"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// UDPWhiteListMiddleware 白名单中间件
+func UDPWhiteListMiddleware() func(c *UdpSliceRouterContext) {
+	return func(c *UdpSliceRouterContext) {
+		serverInterface := c.Get("service")
+		if serverInterface == nil {
+			log.Warn("get service empty")
+			c.Abort()
+			return
+		}
+		serviceDetail := serverInterface.(*enity.ServiceDetail)
+		clientIP := c.ClientIP()
+
+		iplist := []string{}
+		if serviceDetail.AccessControl.WhiteList != "" {
+			iplist = strings.Split(serviceDetail.AccessControl.WhiteList, ",")
+		}
+		if serviceDetail.AccessControl.OpenAuth == 1 && len(iplist) > 0 {
+			if !utils.InStringSlice(iplist, clientIP) {
+				log.Debug("not in white ip list", zap.String("client_ip", clientIP))
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
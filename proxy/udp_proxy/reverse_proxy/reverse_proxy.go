@@ -0,0 +1,189 @@
+// reverse_proxy 构建 UDP 反向代理
+package reverse_proxy
+
+import (
+	"gateway/proxy/load_balance"
+	"gateway/proxy/udp_proxy/server"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultSessionTimeout = 60 * time.Second
+	maxDatagramSize       = 64 * 1024
+)
+
+// session 客户端会话，同一个客户端地址的数据包固定转发到同一个上游
+type session struct {
+	clientAddr net.Addr
+	upstream   net.Conn
+	lastActive int64 // unix nano
+}
+
+func (s *session) touch() {
+	atomic.StoreInt64(&s.lastActive, time.Now().UnixNano())
+}
+
+func (s *session) idle() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&s.lastActive)))
+}
+
+// UDP反向代理，按客户端地址维护会话，会话空闲超时后自动清理
+type UdpReverseProxy struct {
+	balancer        func() (load_balance.LoadBalance, error) // 每个新会话调用一次, 返回服务当前的负载均衡器
+	SessionTimeout  time.Duration
+	DialTimeout     time.Duration
+	OnSessionChange func(activeSessions int)
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewUdpLoadBalanceReverseProxy 构建一个新的反向代理并启动会话清理
+// balancer 在新建会话时获取负载均衡器, 服务修改后新会话使用重建的负载均衡器, 已有会话不受影响
+func NewUdpLoadBalanceReverseProxy(balancer func() (load_balance.LoadBalance, error), sessionTimeout time.Duration) *UdpReverseProxy {
+	if sessionTimeout <= 0 {
+		sessionTimeout = defaultSessionTimeout
+	}
+	rp := &UdpReverseProxy{
+		balancer:       balancer,
+		SessionTimeout: sessionTimeout,
+		DialTimeout:    time.Second,
+		sessions:       map[string]*session{},
+		done:           make(chan struct{}),
+	}
+	go rp.expireLoop()
+	return rp
+}
+
+// Forward 将数据包转发到客户端对应会话的上游，不存在会话时新建
+func (rp *UdpReverseProxy) Forward(packet *server.Packet) {
+	sess, err := rp.getSession(packet)
+	if err != nil {
+		log.Printf("udpproxy: for client %v, error dialing upstream: %v", packet.ClientAddr, err)
+		return
+	}
+	sess.touch()
+	if _, err := sess.upstream.Write(packet.Data); err != nil {
+		log.Printf("udpproxy: for client %v, error writing upstream %v: %v", packet.ClientAddr, sess.upstream.RemoteAddr(), err)
+		rp.removeSession(packet.ClientAddr.String(), sess)
+	}
+}
+
+func (rp *UdpReverseProxy) getSession(packet *server.Packet) (*session, error) {
+	key := packet.ClientAddr.String()
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if sess, ok := rp.sessions[key]; ok {
+		return sess, nil
+	}
+
+	// 按客户端IP选择上游，保证一致性hash时同一客户端落到同一节点
+	clientIP, _, _ := net.SplitHostPort(key)
+	lb, err := rp.balancer()
+	if err != nil {
+		return nil, err
+	}
+	nextAddr, err := lb.Get(clientIP)
+	if err != nil {
+		return nil, err
+	}
+	upstream, err := net.DialTimeout("udp", nextAddr, rp.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	sess := &session{clientAddr: packet.ClientAddr, upstream: upstream}
+	sess.touch()
+	rp.sessions[key] = sess
+	rp.sessionChanged()
+	go rp.readUpstream(packet.Conn, key, sess)
+	return sess, nil
+}
+
+// readUpstream 将上游的回包写回客户端，上游连接关闭后退出
+func (rp *UdpReverseProxy) readUpstream(pc net.PacketConn, key string, sess *session) {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, err := sess.upstream.Read(buf)
+		if err != nil {
+			rp.removeSession(key, sess)
+			return
+		}
+		sess.touch()
+		if _, err := pc.WriteTo(buf[:n], sess.clientAddr); err != nil {
+			log.Printf("udpproxy: error writing to client %v: %v", sess.clientAddr, err)
+		}
+	}
+}
+
+func (rp *UdpReverseProxy) removeSession(key string, sess *session) {
+	rp.mu.Lock()
+	if cur, ok := rp.sessions[key]; ok && cur == sess {
+		delete(rp.sessions, key)
+		rp.sessionChanged()
+	}
+	rp.mu.Unlock()
+	sess.upstream.Close()
+}
+
+// expireLoop 定期清理空闲超时的会话
+func (rp *UdpReverseProxy) expireLoop() {
+	interval := rp.SessionTimeout / 2
+	if interval > 5*time.Second {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rp.done:
+			return
+		case <-ticker.C:
+		}
+		expired := map[string]*session{}
+		rp.mu.Lock()
+		for key, sess := range rp.sessions {
+			if sess.idle() > rp.SessionTimeout {
+				expired[key] = sess
+			}
+		}
+		rp.mu.Unlock()
+		for key, sess := range expired {
+			rp.removeSession(key, sess)
+		}
+	}
+}
+
+// sessionChanged 调用方需持有 rp.mu
+func (rp *UdpReverseProxy) sessionChanged() {
+	if rp.OnSessionChange != nil {
+		rp.OnSessionChange(len(rp.sessions))
+	}
+}
+
+// ActiveSessions 当前活跃会话数
+func (rp *UdpReverseProxy) ActiveSessions() int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return len(rp.sessions)
+}
+
+// Close 关闭所有会话并停止清理协程
+func (rp *UdpReverseProxy) Close() {
+	rp.once.Do(func() {
+		close(rp.done)
+		rp.mu.Lock()
+		sessions := rp.sessions
+		rp.sessions = map[string]*session{}
+		rp.sessionChanged()
+		rp.mu.Unlock()
+		for _, sess := range sessions {
+			sess.upstream.Close()
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"gateway/enity"
+	"gateway/metrics"
+	"gateway/proxy/load_balance"
+	"gateway/proxy/pkg"
+	"gateway/proxy/udp_proxy/middleware"
+	"gateway/proxy/udp_proxy/reverse_proxy"
+	"gateway/proxy/udp_proxy/server"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	udpServerList = []*server.UdpServer{}
+	udpProxyList  = []*reverse_proxy.UdpReverseProxy{}
+	udpListLock   sync.Mutex
+)
+
+func UdpProxyServerRun() {
+	serviceList := pkg.Cache.GetUdpServiceList()
+	for _, serviceItem := range serviceList {
+		tempItem := serviceItem
+		go func(serviceDetail *enity.ServiceDetail) {
+			addr := fmt.Sprintf(":%d", serviceDetail.UDPRule.Port)
+			if _, err := pkg.LoadBalanceTransport.GetLoadBalancer(serviceDetail); err != nil {
+				log.Fatalf(" [INFO] GetUdpLoadBalancer %v err:%v\n", addr, err)
+				return
+			}
+
+			serviceName := serviceDetail.Info.ServiceName
+			rp := reverse_proxy.NewUdpLoadBalanceReverseProxy(func() (load_balance.LoadBalance, error) {
+				return pkg.CurrentLoadBalancer(serviceDetail)
+			}, time.Duration(serviceDetail.UDPRule.SessionTimeout)*time.Second)
+			rp.OnSessionChange = func(activeSessions int) {
+				metrics.RecordUdpActiveSessionMetrics(serviceName, activeSessions)
+			}
+
+			//构建路由及设置中间件
+			router := middleware.NewUdpSliceRouter()
+			router.Use(
+				middleware.UDPFlowCountMiddleware(),
+				middleware.UDPFlowLimitMiddleware(),
+				middleware.UDPWhiteListMiddleware(),
+				middleware.UDPBlackListMiddleware(),
+			)
+
+			//构建回调handler
+			routerHandler := middleware.NewUdpSliceRouterHandler(
+				func(c *middleware.UdpSliceRouterContext) {
+					rp.Forward(c.Packet)
+				}, router)
+
+			baseCtx := context.WithValue(context.Background(), "service", serviceDetail)
+			udpServer := &server.UdpServer{
+				Addr:    addr,
+				Handler: routerHandler,
+				BaseCtx: baseCtx,
+			}
+			udpListLock.Lock()
+			udpServerList = append(udpServerList, udpServer)
+			udpProxyList = append(udpProxyList, rp)
+			udpListLock.Unlock()
+			log.Printf(" [INFO] udp_proxy_run %v\n", addr)
+			if err := udpServer.ListenAndServe(); err != nil && err != server.ErrServerClosed {
+				log.Fatalf(" [INFO] udp_proxy_run %v err:%v\n", addr, err)
+			}
+		}(tempItem)
+	}
+}
+
+func UdpProxyServerStop() {
+	udpListLock.Lock()
+	defer udpListLock.Unlock()
+	for _, udpServer := range udpServerList {
+		udpServer.Close()
+		log.Printf(" [INFO] udp_proxy_stop %v stopped\n", udpServer.Addr)
+	}
+	for _, rp := range udpProxyList {
+		rp.Close()
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrServerClosed  = fmt.Errorf("udp: Server closed")
+	ServerContextKey = &contextKey{"udp-server"}
+)
+
+// 单个 UDP 数据包最大长度
+const maxDatagramSize = 64 * 1024
+
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string {
+	return "udp_proxy context value " + k.name
+}
+
+// Packet 客户端发来的一个数据包
+type Packet struct {
+	Conn       net.PacketConn // 监听连接，用于向客户端回包
+	ClientAddr net.Addr       // 客户端地址
+	Data       []byte         // 数据内容
+}
+
+type UDPHandler interface {
+	ServeUDP(ctx context.Context, packet *Packet)
+}
+
+type UdpServer struct {
+	Addr    string
+	Handler UDPHandler
+	BaseCtx context.Context
+
+	mu         sync.Mutex
+	inShutdown int32
+	conn       net.PacketConn
+}
+
+func (s *UdpServer) shuttingDown() bool {
+	return atomic.LoadInt32(&s.inShutdown) != 0
+}
+
+func (srv *UdpServer) ListenAndServe() error {
+	if srv.shuttingDown() {
+		return ErrServerClosed
+	}
+	if srv.Addr == "" {
+		return errors.New("need addr")
+	}
+	pc, err := net.ListenPacket("udp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(pc)
+}
+
+func (srv *UdpServer) Close() error {
+	atomic.StoreInt32(&srv.inShutdown, 1)
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.conn != nil {
+		return srv.conn.Close()
+	}
+	return nil
+}
+
+// Serve 循环读取数据包并交给 Handler 处理，同一个客户端的数据包按到达顺序处理
+func (srv *UdpServer) Serve(pc net.PacketConn) error {
+	srv.mu.Lock()
+	srv.conn = pc
+	srv.mu.Unlock()
+	defer pc.Close()
+	if srv.Handler == nil {
+		return errors.New("handler empty")
+	}
+	if srv.BaseCtx == nil {
+		srv.BaseCtx = context.Background()
+	}
+	ctx := context.WithValue(srv.BaseCtx, ServerContextKey, srv)
+
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if srv.shuttingDown() {
+				return ErrServerClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		srv.serve(ctx, &Packet{Conn: pc, ClientAddr: addr, Data: data})
+	}
+}
+
+func (srv *UdpServer) serve(ctx context.Context, packet *Packet) {
+	defer func() {
+		if err := recover(); err != nil {
+			const size = 64 << 10
+			buf := make([]byte, size)
+			buf = buf[:runtime.Stack(buf, false)]
+			fmt.Printf("udp: panic serving %v: %v\n%s", packet.ClientAddr, err, buf)
+		}
+	}()
+	srv.Handler.ServeUDP(ctx, packet)
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"gateway/pkg/log"
 
 	"go.uber.org/zap"
@@ -21,3 +24,19 @@ func HashPassword(password string) (string, error) {
 func ComparePassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+// GenApiToken 生成随机的接口令牌, 以 prefix 开头便于识别和扫描泄露
+func GenApiToken(prefix string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Error("GenApiToken failed", zap.Error(err))
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+// HashApiToken 计算接口令牌的摘要, 令牌本身是高熵随机数, 用 sha256 即可按摘要直接查询
+func HashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
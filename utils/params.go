@@ -20,6 +20,11 @@ func DefaultGetValidParams(c *gin.Context, params interface{}) error {
 		return err
 	}
 
+	return ValidStruct(c, params)
+}
+
+// ValidStruct 使用上下文中的 validator 校验结构体, 错误信息经过翻译
+func ValidStruct(c *gin.Context, params interface{}) error {
 	// Get validator
 	valid, err := GetValidator(c)
 	if err != nil {
@@ -22,3 +22,14 @@ func InStringSlice(slice []string, str string) bool {
 func SplitStringByComma(data string) []string {
 	return strings.Split(data, ",")
 }
+
+// MatchLongestPrefix 在 prefixes 中查找与 str 匹配的最长前缀, 未匹配时返回 false
+func MatchLongestPrefix(str string, prefixes []string) (string, bool) {
+	matched, ok := "", false
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(str, prefix) && len(prefix) >= len(matched) {
+			matched, ok = prefix, true
+		}
+	}
+	return matched, ok
+}